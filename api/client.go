@@ -523,6 +523,36 @@ func (c Client) GetSettings(ctx context.Context) (map[string]SettingEntry, error
 	return out, nil
 }
 
+// GetLocationOrders reads the 'locations_spoolorders' setting: for each
+// location, the ordered spool IDs in it (printer slots use -1 for empty).
+// Returns an empty map when the setting is unset or empty.
+func (c Client) GetLocationOrders(ctx context.Context) (map[string][]int, error) {
+	settings, err := c.GetSettings(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch settings: %w", err)
+	}
+	entry, ok := settings["locations_spoolorders"]
+	if !ok {
+		return map[string][]int{}, nil
+	}
+
+	var rawString string
+	if err := json.Unmarshal(entry.Value, &rawString); err != nil {
+		return nil, fmt.Errorf("failed to decode settings value wrapper: %w", err)
+	}
+	if rawString == "" {
+		return map[string][]int{}, nil
+	}
+	var orders map[string][]int
+	if err := json.Unmarshal([]byte(rawString), &orders); err != nil {
+		return nil, fmt.Errorf("failed to parse locations_spoolorders JSON: %w", err)
+	}
+	if orders == nil {
+		orders = map[string][]int{}
+	}
+	return orders, nil
+}
+
 // PatchSettings sends a PATCH to /api/v1/setting/ with the provided fields.
 // The body should be a flat object of key -> value, where value matches what the server expects.
 // For complex settings that are represented as JSON strings in the API (e.g., arrays/objects),
@@ -818,3 +848,66 @@ func (c Client) CreateSpool(ctx context.Context, spool models.CreateSpoolRequest
 
 	return &result, nil
 }
+
+// EnsureSpoolField creates a text custom field on spools unless Spoolman
+// already has one with that key. Spoolman rejects writes to `extra` keys it
+// doesn't know, so call this before setting one.
+func (c Client) EnsureSpoolField(ctx context.Context, key, name string) error {
+	endpoint := c.base + "/api/v1/field/spool"
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("request failed: %w", err)
+	}
+	defer func() {
+		_ = resp.Body.Close()
+	}()
+
+	if resp.StatusCode != http.StatusOK {
+		b, _ := io.ReadAll(resp.Body)
+		return fmt.Errorf("api error: status %d: %s", resp.StatusCode, strings.TrimSpace(string(b)))
+	}
+
+	var fields []struct {
+		Key string `json:"key"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&fields); err != nil {
+		return fmt.Errorf("failed to decode fields response: %w", err)
+	}
+	for _, f := range fields {
+		if f.Key == key {
+			return nil
+		}
+	}
+
+	jsonBody, err := json.Marshal(map[string]string{"name": name, "field_type": "text"})
+	if err != nil {
+		return fmt.Errorf("failed to marshal body: %w", err)
+	}
+
+	req, err = http.NewRequestWithContext(ctx, http.MethodPost, endpoint+"/"+url.PathEscape(key), bytes.NewReader(jsonBody))
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	created, err := c.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("request failed: %w", err)
+	}
+	defer func() {
+		_ = created.Body.Close()
+	}()
+
+	if created.StatusCode != http.StatusOK && created.StatusCode != http.StatusCreated {
+		b, _ := io.ReadAll(created.Body)
+		return fmt.Errorf("api error: status %d: %s", created.StatusCode, strings.TrimSpace(string(b)))
+	}
+
+	return nil
+}
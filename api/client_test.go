@@ -190,3 +190,38 @@ func TestFindSpoolByID_NotFoundReturnsSentinel(t *testing.T) {
 		t.Fatalf("expected ErrSpoolNotFound, got %v", err)
 	}
 }
+
+func TestEnsureSpoolField_CreatesMissingField(t *testing.T) {
+	var created string
+	var gotBody map[string]string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.Method == http.MethodGet && r.URL.Path == "/api/v1/field/spool":
+			_, _ = w.Write([]byte(`[{"key":"td","name":"TD","field_type":"float"}]`))
+		case r.Method == http.MethodPost && strings.HasPrefix(r.URL.Path, "/api/v1/field/spool/"):
+			created = strings.TrimPrefix(r.URL.Path, "/api/v1/field/spool/")
+			body, _ := io.ReadAll(r.Body)
+			_ = json.Unmarshal(body, &gotBody)
+			_, _ = w.Write([]byte(`[]`))
+		default:
+			t.Errorf("unexpected %s %s", r.Method, r.URL.Path)
+		}
+	}))
+	defer srv.Close()
+
+	c := NewClient(srv.URL, false)
+	if err := c.EnsureSpoolField(context.Background(), "rfid", "RFID"); err != nil {
+		t.Fatalf("EnsureSpoolField: %v", err)
+	}
+	if created != "rfid" || gotBody["field_type"] != "text" || gotBody["name"] != "RFID" {
+		t.Errorf("created %q with %v", created, gotBody)
+	}
+
+	created = ""
+	if err := c.EnsureSpoolField(context.Background(), "td", "TD"); err != nil {
+		t.Fatalf("EnsureSpoolField: %v", err)
+	}
+	if created != "" {
+		t.Errorf("existing field was re-created as %q", created)
+	}
+}
@@ -198,6 +198,53 @@ func (c *PlanServerClient) PutSharedConfig(ctx context.Context, data []byte) err
 	return nil
 }
 
+// GetSchedule fetches the saved print queue as raw JSON.
+func (c *PlanServerClient) GetSchedule(ctx context.Context) ([]byte, error) {
+	endpoint := c.base + "/api/fil/schedule"
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+
+	resp, err := c.do(req)
+	if err != nil {
+		return nil, fmt.Errorf("plan server request failed: %w", err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	if resp.StatusCode != http.StatusOK {
+		b, _ := io.ReadAll(resp.Body)
+		return nil, fmt.Errorf("plan server error: status %d: %s", resp.StatusCode, strings.TrimSpace(string(b)))
+	}
+
+	return io.ReadAll(resp.Body)
+}
+
+// PutSchedule replaces the saved print queue with the given JSON.
+func (c *PlanServerClient) PutSchedule(ctx context.Context, data []byte) error {
+	endpoint := c.base + "/api/fil/schedule"
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPut, endpoint, bytes.NewReader(data))
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := c.do(req)
+	if err != nil {
+		return fmt.Errorf("plan server request failed: %w", err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	if resp.StatusCode != http.StatusNoContent {
+		b, _ := io.ReadAll(resp.Body)
+		return fmt.Errorf("plan server error: status %d: %s", resp.StatusCode, strings.TrimSpace(string(b)))
+	}
+
+	return nil
+}
+
 // compareSemver compares two version strings numerically.
 // Returns >0 if a > b, <0 if a < b, 0 if equal.
 // Strips a leading "v" prefix and compares up to three numeric parts (major.minor.patch).
@@ -253,6 +300,37 @@ func (c *PlanServerClient) PutAssembly(ctx context.Context, planName string, pdf
 	return result.Filename, nil
 }
 
+// PutJob uploads a sliced file for plates to send to their printer. Returns
+// the server-side filename to store in a plate's sliced field.
+func (c *PlanServerClient) PutJob(ctx context.Context, fileName string, data io.Reader) (string, error) {
+	endpoint := fmt.Sprintf("%s/api/fil/jobs/%s", c.base, url.PathEscape(fileName))
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPut, endpoint, data)
+	if err != nil {
+		return "", fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/octet-stream")
+
+	resp, err := c.do(req)
+	if err != nil {
+		return "", fmt.Errorf("plan server request failed: %w", err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	if resp.StatusCode != http.StatusCreated {
+		b, _ := io.ReadAll(resp.Body)
+		return "", fmt.Errorf("plan server error: status %d: %s", resp.StatusCode, strings.TrimSpace(string(b)))
+	}
+
+	var result struct {
+		Filename string `json:"filename"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return "", fmt.Errorf("failed to decode response: %w", err)
+	}
+	return result.Filename, nil
+}
+
 // GetAssembly downloads the assembly PDF for a plan. Returns the PDF bytes and the filename from Content-Disposition.
 func (c *PlanServerClient) GetAssembly(ctx context.Context, planName string) ([]byte, string, error) {
 	endpoint := fmt.Sprintf("%s/api/fil/plans/%s/assembly", c.base, planName)
@@ -332,6 +410,12 @@ type PrinterTrayStatus struct {
 	TrayID int    `json:"tray_id"`
 	Color  string `json:"color,omitempty"`
 	Type   string `json:"type,omitempty"`
+	// Remain is the printer's filament-left estimate in percent, -1 when it
+	// has none; TrayWeight is the spool's nominal grams, 0 when unknown.
+	Remain     int    `json:"remain"`
+	TrayWeight int    `json:"tray_weight,omitempty"`
+	UUID       string `json:"uuid,omitempty"`      // RFID tag, Bambu only
+	SubBrand   string `json:"sub_brand,omitempty"` // e.g. "PLA Basic", RFID spools only
 }
 
 // PrinterStatus represents a printer's current state as returned by the server.
@@ -346,6 +430,45 @@ type PrinterStatus struct {
 	TotalLayers   int                 `json:"total_layers,omitempty"`
 	ActiveTray    int                 `json:"active_tray,omitempty"`
 	Trays         []PrinterTrayStatus `json:"trays,omitempty"`
+	Speed         string              `json:"speed,omitempty"`
+	Light         string              `json:"light,omitempty"`
+	HMS           []HMSInfo           `json:"hms,omitempty"`
+	Telemetry     *Telemetry          `json:"telemetry,omitempty"`
+}
+
+// Telemetry mirrors the server's temperature, fan, and AMS environment
+// sample. Fans are percent on Bambu printers, RPM on Prusa.
+type Telemetry struct {
+	At           time.Time `json:"at"`
+	Nozzle       float64   `json:"nozzle,omitempty"`
+	NozzleTarget float64   `json:"nozzle_target,omitempty"`
+	Bed          float64   `json:"bed,omitempty"`
+	BedTarget    float64   `json:"bed_target,omitempty"`
+	Chamber      float64   `json:"chamber,omitempty"`
+	PartFan      int       `json:"part_fan,omitempty"`
+	AuxFan       int       `json:"aux_fan,omitempty"`
+	ChamberFan   int       `json:"chamber_fan,omitempty"`
+	HeatbreakFan int       `json:"heatbreak_fan,omitempty"`
+	PrintFanRPM  int       `json:"print_fan_rpm,omitempty"`
+	HotendFanRPM int       `json:"hotend_fan_rpm,omitempty"`
+	AMS          []AMSEnv  `json:"ams,omitempty"`
+}
+
+// AMSEnv mirrors one AMS unit's humidity and temperature.
+type AMSEnv struct {
+	ID            int     `json:"id"`
+	Humidity      int     `json:"humidity,omitempty"`
+	HumidityLevel int     `json:"humidity_level,omitempty"`
+	Temp          float64 `json:"temp,omitempty"`
+}
+
+// HMSInfo mirrors the server's catalog entry for an active HMS code. Only
+// Code is set when the catalog doesn't know it.
+type HMSInfo struct {
+	Code        string `json:"code"`
+	Description string `json:"description,omitempty"`
+	Severity    string `json:"severity,omitempty"`
+	Action      string `json:"action,omitempty"`
 }
 
 // GetPrinterStatus fetches the current status of all printers from the server.
@@ -374,6 +497,33 @@ func (c *PlanServerClient) GetPrinterStatus(ctx context.Context) ([]PrinterStatu
 	return statuses, nil
 }
 
+// GetPrinterTelemetry fetches a printer's recent telemetry samples, oldest
+// first.
+func (c *PlanServerClient) GetPrinterTelemetry(ctx context.Context, printerName string) ([]Telemetry, error) {
+	endpoint := fmt.Sprintf("%s/api/fil/printers/%s/telemetry", c.base, url.PathEscape(printerName))
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+
+	resp, err := c.do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("server returned status %d", resp.StatusCode)
+	}
+
+	var samples []Telemetry
+	if err := json.NewDecoder(resp.Body).Decode(&samples); err != nil {
+		return nil, fmt.Errorf("failed to decode telemetry: %w", err)
+	}
+	return samples, nil
+}
+
 // PushTray pushes filament metadata to a specific printer tray via the server.
 func (c *PlanServerClient) PushTray(ctx context.Context, printerName string, update TrayPushRequest) error {
 	endpoint := fmt.Sprintf("%s/api/fil/printers/%s/push-tray", c.base, printerName)
@@ -402,6 +552,75 @@ func (c *PlanServerClient) PushTray(ctx context.Context, printerName string, upd
 	return nil
 }
 
+// ControlPrinter sends a control action to a printer via the server. action
+// is the path after the printer name: "pause", "resume", "stop",
+// "light/on", "light/off", or "speed/<profile>".
+func (c *PlanServerClient) ControlPrinter(ctx context.Context, printerName, action string) error {
+	endpoint := fmt.Sprintf("%s/api/fil/printers/%s/%s", c.base, url.PathEscape(printerName), action)
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, nil)
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+
+	resp, err := c.do(req)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	if resp.StatusCode != http.StatusNoContent {
+		b, _ := io.ReadAll(resp.Body)
+		return fmt.Errorf("%s failed: status %d: %s", action, resp.StatusCode, strings.TrimSpace(string(b)))
+	}
+	return nil
+}
+
+// PrinterEvent mirrors one entry of the server's printer-events.jsonl: a
+// state transition, classified as start, finish, fail, pause, or resume.
+type PrinterEvent struct {
+	Timestamp   time.Time `json:"timestamp"`
+	Printer     string    `json:"printer"`
+	From        string    `json:"from"`
+	To          string    `json:"to"`
+	Kind        string    `json:"kind,omitempty"`
+	File        string    `json:"file,omitempty"`
+	Progress    int       `json:"progress,omitempty"`
+	Layer       int       `json:"layer,omitempty"`
+	TotalLayers int       `json:"total_layers,omitempty"`
+	HMS         []HMSInfo `json:"hms,omitempty"`
+}
+
+// GetPrinterEvents fetches a printer's logged state transitions, oldest
+// first. since (YYYY-MM-DD) is optional.
+func (c *PlanServerClient) GetPrinterEvents(ctx context.Context, printerName, since string) ([]PrinterEvent, error) {
+	endpoint := fmt.Sprintf("%s/api/fil/printers/%s/events", c.base, url.PathEscape(printerName))
+	if since != "" {
+		endpoint += "?" + url.Values{"since": {since}}.Encode()
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+
+	resp, err := c.do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("server returned status %d", resp.StatusCode)
+	}
+
+	var events []PrinterEvent
+	if err := json.NewDecoder(resp.Body).Decode(&events); err != nil {
+		return nil, fmt.Errorf("failed to decode printer events: %w", err)
+	}
+	return events, nil
+}
+
 // ScanEvent mirrors the server's ScanEvent struct for the scan-history JSONL.
 // One event per TD-1 scan attempt, including non-committed scans so color drift
 // can be analyzed later.
@@ -587,3 +806,172 @@ func (c *PlanServerClient) GetHistory(ctx context.Context, since, until, printer
 	}
 	return entries, nil
 }
+
+// PendingDeduction is one (spool, grams) pair on a pending event.
+type PendingDeduction struct {
+	SpoolID int     `json:"spool_id"`
+	Amount  float64 `json:"amount"`
+}
+
+// PendingPlate identifies one plate covered by a pending event.
+type PendingPlate struct {
+	Plan    string `json:"plan"`
+	Project string `json:"project"`
+	Plate   string `json:"plate"`
+}
+
+// PendingNeed mirrors the plate requirement the server couldn't match to a
+// loaded spool.
+type PendingNeed struct {
+	FilamentID int     `json:"filament_id,omitempty"`
+	Name       string  `json:"name"`
+	Material   string  `json:"material,omitempty"`
+	Color      string  `json:"color,omitempty"`
+	Amount     float64 `json:"amount"`
+}
+
+// PendingEvent mirrors a server-initiated transition awaiting confirmation.
+type PendingEvent struct {
+	ID         string             `json:"id"`
+	Kind       string             `json:"kind"`
+	Printer    string             `json:"printer"`
+	Plates     []PendingPlate     `json:"plates"`
+	OccurredAt time.Time          `json:"occurred_at"`
+	CreatedAt  time.Time          `json:"created_at"`
+	Deductions []PendingDeduction `json:"deductions,omitempty"`
+	Unmatched  []PendingNeed      `json:"unmatched,omitempty"`
+	Error      string             `json:"error,omitempty"`
+	Fail       *PendingFail       `json:"fail,omitempty"`
+	Job        string             `json:"job,omitempty"` // unmatched job name for kind "job"
+	Swap       *PendingSwap       `json:"swap,omitempty"`
+	Candidates []int              `json:"candidates,omitempty"` // other spools that fit, for kind "swap"
+	Register   *PendingRegister   `json:"register,omitempty"`
+}
+
+// PendingSwap is the move a "swap" event proposes: SpoolID into Location's
+// Slot, with Displaced (the spool recorded there) going back to From.
+type PendingSwap struct {
+	Location  string `json:"location"`
+	Slot      int    `json:"slot"`
+	SpoolID   int    `json:"spool_id"`
+	From      string `json:"from,omitempty"`
+	Displaced int    `json:"displaced,omitempty"`
+	Tray      string `json:"tray,omitempty"`
+	RFID      string `json:"rfid,omitempty"` // tag recorded on SpoolID once applied
+}
+
+// PendingRegister is the spool a "register" event offers to create for an
+// RFID tag Spoolman doesn't know, in Location's Slot.
+type PendingRegister struct {
+	Location  string  `json:"location"`
+	Slot      int     `json:"slot"`
+	RFID      string  `json:"rfid"`
+	Vendor    string  `json:"vendor"`
+	Name      string  `json:"name"`
+	Material  string  `json:"material"`
+	ColorHex  string  `json:"color_hex"`
+	Weight    float64 `json:"weight,omitempty"`
+	Remaining float64 `json:"remaining,omitempty"`
+}
+
+// PendingFail is the part of a proposed fail request the CLI shows and lets
+// the user adjust.
+type PendingFail struct {
+	Cause     string  `json:"cause"`
+	Reason    string  `json:"reason,omitempty"`
+	UsedGrams float64 `json:"used_grams,omitempty"`
+}
+
+// PendingAmendRequest corrects a pending event. Deductions apply to
+// "complete" events; UsedGrams, Cause, and Reason to "fail" events; SpoolID
+// to "swap" and "register" events.
+type PendingAmendRequest struct {
+	Deductions []PendingDeduction `json:"deductions,omitempty"`
+	UsedGrams  *float64           `json:"used_grams,omitempty"`
+	Cause      string             `json:"cause,omitempty"`
+	Reason     string             `json:"reason,omitempty"`
+	SpoolID    int                `json:"spool_id,omitempty"` // kinds "swap" and "register": the spool actually loaded
+}
+
+// ListPending fetches the events the server applied on its own and that are
+// still waiting for the user to accept or amend them.
+func (c *PlanServerClient) ListPending(ctx context.Context) ([]PendingEvent, error) {
+	endpoint := c.base + "/api/fil/pending"
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+
+	resp, err := c.do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("server returned status %d", resp.StatusCode)
+	}
+
+	var events []PendingEvent
+	if err := json.NewDecoder(resp.Body).Decode(&events); err != nil {
+		return nil, fmt.Errorf("failed to decode pending events: %w", err)
+	}
+	return events, nil
+}
+
+// AcceptPending confirms a pending event as recorded.
+func (c *PlanServerClient) AcceptPending(ctx context.Context, id string) error {
+	endpoint := fmt.Sprintf("%s/api/fil/pending/%s/accept", c.base, url.PathEscape(id))
+	return c.sendPending(ctx, http.MethodPost, endpoint, nil)
+}
+
+// AmendPending corrects a pending event. For completes the server writes only
+// the per-spool difference to Spoolman; for fails it runs the adjusted request.
+func (c *PlanServerClient) AmendPending(ctx context.Context, id string, amend PendingAmendRequest) error {
+	endpoint := fmt.Sprintf("%s/api/fil/pending/%s/amend", c.base, url.PathEscape(id))
+	body, err := json.Marshal(amend)
+	if err != nil {
+		return err
+	}
+	return c.sendPending(ctx, http.MethodPost, endpoint, body)
+}
+
+// LinkPending starts the given plate for an unmatched "job" event, as of the
+// moment the printer started the job.
+func (c *PlanServerClient) LinkPending(ctx context.Context, id, planName, project, plate string) error {
+	endpoint := fmt.Sprintf("%s/api/fil/pending/%s/link", c.base, url.PathEscape(id))
+	body, err := json.Marshal(map[string]string{"plan": planName, "project": project, "plate": plate})
+	if err != nil {
+		return err
+	}
+	return c.sendPending(ctx, http.MethodPost, endpoint, body)
+}
+
+// DismissPending drops a pending event without applying anything.
+func (c *PlanServerClient) DismissPending(ctx context.Context, id string) error {
+	endpoint := fmt.Sprintf("%s/api/fil/pending/%s", c.base, url.PathEscape(id))
+	return c.sendPending(ctx, http.MethodDelete, endpoint, nil)
+}
+
+func (c *PlanServerClient) sendPending(ctx context.Context, method, endpoint string, body []byte) error {
+	req, err := http.NewRequestWithContext(ctx, method, endpoint, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := c.do(req)
+	if err != nil {
+		return fmt.Errorf("plan server request failed: %w", err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	if resp.StatusCode != http.StatusNoContent {
+		b, _ := io.ReadAll(resp.Body)
+		return fmt.Errorf("plan server error: status %d: %s", resp.StatusCode, strings.TrimSpace(string(b)))
+	}
+	return nil
+}
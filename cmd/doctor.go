@@ -6,15 +6,18 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"maps"
 	"os"
 	"path/filepath"
 	"runtime"
+	"slices"
 	"sort"
 	"strings"
 	"time"
 
 	"github.com/dstockto/fil/api"
 	"github.com/dstockto/fil/devices"
+	"github.com/dstockto/fil/server"
 	"github.com/fatih/color"
 	"github.com/spf13/cobra"
 )
@@ -294,7 +297,7 @@ func printerSchemaChecks() []api.Check {
 		if p.Type == "" {
 			missing = append(missing, "type")
 		}
-		if p.IP == "" {
+		if p.IP == "" && p.Type != "sim" {
 			missing = append(missing, "ip")
 		}
 		switch p.Type {
@@ -312,6 +315,16 @@ func printerSchemaChecks() []api.Check {
 			if p.Password == "" {
 				missing = append(missing, "password")
 			}
+		case "klipper":
+			// api_key and mmu_macro are optional
+		case "octoprint":
+			if p.APIKey == "" {
+				missing = append(missing, "api_key")
+			}
+		case "sim":
+			if p.Scenario == "" {
+				missing = append(missing, "scenario")
+			}
 		case "":
 			// already flagged by missing type
 		default:
@@ -326,12 +339,35 @@ func printerSchemaChecks() []api.Check {
 		} else {
 			c.Status = api.StatusOK
 			c.Message = fmt.Sprintf("%s at %s", p.Type, p.IP)
+			if p.Type == "sim" {
+				c.Message = "sim playing " + p.Scenario
+			}
+			if problems := amsUnitProblems(p); len(problems) > 0 {
+				c.Status = api.StatusWarn
+				c.Message = "ams_units: " + strings.Join(problems, "; ")
+			}
 		}
 		out = append(out, c)
 	}
 	return out
 }
 
+// amsUnitProblems reports ams_units entries that name an unknown unit or a
+// location the printer doesn't have.
+func amsUnitProblems(p PrinterConfig) []string {
+	var problems []string
+	for _, loc := range slices.Sorted(maps.Keys(p.AMSUnits)) {
+		if !slices.Contains(p.Locations, loc) {
+			problems = append(problems, fmt.Sprintf("%q is not one of its locations", loc))
+			continue
+		}
+		if _, err := server.ParseTrayUnit(p.AMSUnits[loc]); err != nil {
+			problems = append(problems, fmt.Sprintf("%s: %v", loc, err))
+		}
+	}
+	return problems
+}
+
 // serverChecks verifies plan server reachability, version match, and fetches
 // the server-side health report. Returns the client-side checks and the
 // fetched report (or nil on fetch failure).
@@ -3,6 +3,7 @@ package cmd
 import (
 	"bytes"
 	"encoding/json"
+	"strings"
 	"testing"
 
 	"github.com/dstockto/fil/api"
@@ -196,3 +197,37 @@ func TestWriteHuman_IncludesStatusLabels(t *testing.T) {
 		}
 	}
 }
+
+func TestPrinterSchemaChecks_PerType(t *testing.T) {
+	oldCfg := Cfg
+	t.Cleanup(func() { Cfg = oldCfg })
+	Cfg = &Config{Printers: map[string]PrinterConfig{
+		"Ender": {Type: "octoprint", IP: "octopi.local"},
+		"Mini":  {Type: "octoprint", IP: "octopi2.local", APIKey: "k"},
+		"Voron": {Type: "klipper", IP: "voron.local"},
+		"Sim":   {Type: "sim", Scenario: "demo.yaml"},
+		"X1C": {Type: "bambu", IP: "x1c.local", Serial: "s", AccessCode: "c",
+			Locations: []string{"X1C AMS", "X1C External"},
+			AMSUnits:  map[string]string{"X1C External": "vt", "X1C HT": "ht1"}},
+	}}
+
+	got := map[string]api.Check{}
+	for _, c := range printerSchemaChecks() {
+		got[c.Name] = c
+	}
+	if c := got["printer:Ender"]; c.Status != api.StatusFail || c.Message != "missing: api_key" {
+		t.Errorf("Ender = %+v, want fail on missing api_key", c)
+	}
+	if c := got["printer:Mini"]; c.Status != api.StatusOK {
+		t.Errorf("Mini = %+v, want ok", c)
+	}
+	if c := got["printer:Voron"]; c.Status != api.StatusOK {
+		t.Errorf("Voron = %+v, want ok (api_key optional for klipper)", c)
+	}
+	if c := got["printer:Sim"]; c.Status != api.StatusOK {
+		t.Errorf("Sim = %+v, want ok without an ip", c)
+	}
+	if c := got["printer:X1C"]; c.Status != api.StatusWarn || !strings.Contains(c.Message, "X1C External") || !strings.Contains(c.Message, `"X1C HT"`) {
+		t.Errorf("X1C = %+v, want warn on bad unit and unknown location", c)
+	}
+}
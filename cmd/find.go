@@ -41,6 +41,7 @@ type spoolExport struct {
 	Location   string  `json:"location"`
 	Slot       int     `json:"slot,omitempty"`
 	RemainingG float64 `json:"remaining_g"`
+	ReservedG  float64 `json:"reserved_g,omitempty"`
 }
 
 // slotIndex maps a printer location to the 1-based slot number of each spool ID
@@ -282,6 +283,9 @@ func runFind(cmd *cobra.Command, args []string) error {
 	// exported slot and the printed "AMS B:4" label can never disagree.
 	slots := buildSlotIndex(orders)
 
+	// Grams held for plan needs (fil plan reserve), shown next to each spool.
+	reserved := loadReservations()
+
 	query, filters, err := buildFindQuery(cmd)
 	if err != nil {
 		return err
@@ -326,7 +330,7 @@ func runFind(cmd *cobra.Command, args []string) error {
 		if err != nil {
 			return err
 		}
-		c, ok := parseHexColor(scanned.Color)
+		c, ok := models.ParseHexColor(scanned.Color)
 		if !ok {
 			return fmt.Errorf("scanned color %q is not parseable", scanned.Color)
 		}
@@ -339,7 +343,7 @@ func runFind(cmd *cobra.Command, args []string) error {
 		// Re-use the --near code path by marking useNear = true.
 		useNear = true
 	} else if useNear {
-		c, ok := parseHexColor(nearHex)
+		c, ok := models.ParseHexColor(nearHex)
 		if !ok {
 			return fmt.Errorf("invalid --near color %q; expected hex like #ff5500", nearHex)
 		}
@@ -384,7 +388,7 @@ func runFind(cmd *cobra.Command, args []string) error {
 			deltas = make(map[int]float64, len(spools))
 			withColor := spools[:0]
 			for _, sp := range spools {
-				d := spoolColorDistance(sp, target)
+				d := models.SpoolColorDistance(sp, target)
 				if math.IsInf(d, 1) {
 					continue
 				}
@@ -462,7 +466,9 @@ func runFind(cmd *cobra.Command, args []string) error {
 				if useNear {
 					jsonDeltas[s.Id] = deltas[s.Id]
 				}
-				jsonSpools = append(jsonSpools, toExport(s, slots.slotOf(s.Location, s.Id)))
+				export := toExport(s, slots.slotOf(s.Location, s.Id))
+				export.ReservedG = reserved[s.Id]
+				jsonSpools = append(jsonSpools, export)
 			}
 			continue
 		}
@@ -518,7 +524,7 @@ func runFind(cmd *cobra.Command, args []string) error {
 				}
 				deltaStr := color.New(color.Faint).Sprintf("ΔE %5.1f", deltas[s.Id])
 				boldLabel := color.New(color.Bold).Sprintf("%-*s", maxLabel, loc)
-				_, _ = fmt.Fprintf(out, " %s  %s %s\n", deltaStr, boldLabel, s.StringNoLocation())
+				_, _ = fmt.Fprintf(out, " %s  %s %s%s\n", deltaStr, boldLabel, s.StringNoLocation(), reservedNote(s, reserved))
 				if showPurchase {
 					_, _ = fmt.Fprintf(out, "    %s\n", amazonLink(s.Filament.Vendor.Name, s.Filament.Name))
 				}
@@ -607,7 +613,7 @@ func runFind(cmd *cobra.Command, args []string) error {
 					dimmed := color.New(color.Faint).SprintFunc()
 					_, _ = fmt.Fprintf(out, " %s %s\n", boldLabel, dimmed("(empty)"))
 				} else {
-					_, _ = fmt.Fprintf(out, " %s %s\n", boldLabel, ls.spool.StringNoLocation())
+					_, _ = fmt.Fprintf(out, " %s %s%s\n", boldLabel, ls.spool.StringNoLocation(), reservedNote(ls.spool, reserved))
 					if showPurchase {
 						_, _ = fmt.Fprintf(out, "    %s\n", amazonLink(ls.spool.Filament.Vendor.Name, ls.spool.Filament.Name))
 					}
@@ -622,7 +628,7 @@ func runFind(cmd *cobra.Command, args []string) error {
 					loc = "N/A"
 				}
 				boldLabel := color.New(color.Bold).Sprintf("%s", loc)
-				_, _ = fmt.Fprintf(out, " %s %s\n", boldLabel, s.StringNoLocation())
+				_, _ = fmt.Fprintf(out, " %s %s%s\n", boldLabel, s.StringNoLocation(), reservedNote(s, reserved))
 				if showPurchase {
 					_, _ = fmt.Fprintf(out, "%s\n", amazonLink(s.Filament.Vendor.Name, s.Filament.Name))
 				}
@@ -16,7 +16,7 @@ import (
 var lowCmd = &cobra.Command{
 	Use:     "low [name|#id]",
 	Short:   "Show spools running low so you know what to reorder",
-	Long:    "List filaments that are running low based on remaining grams.",
+	Long:    "List filaments that are running low based on free grams: remaining minus what active plans have reserved.",
 	Aliases: []string{"reorder"},
 	RunE:    runLow,
 }
@@ -128,6 +128,10 @@ func runLow(cmd *cobra.Command, args []string) error {
 		query["manufacturer"] = manufacturer
 	}
 
+	// Grams reserved by plans aren't available for new prints, so low is
+	// judged on what's left after them.
+	reserved := loadReservations()
+
 	// Execute for each arg (name or #id)
 	for _, a := range args {
 		name := a
@@ -142,7 +146,7 @@ func runLow(cmd *cobra.Command, args []string) error {
 				// Skip ignored filaments
 				if !isIgnored(spool.Filament.Vendor.Name, spool.Filament.Name) {
 					// For a single spool, evaluate grams threshold with possible override
-					grpRemaining := spool.RemainingWeight
+					grpRemaining := spool.RemainingWeight - reserved[spool.Id]
 					thr := resolveThreshold(spool.Filament.Vendor.Name, spool.Filament.Name)
 
 					lowByGrams := thr > 0 && grpRemaining <= thr+1e-9
@@ -162,7 +166,7 @@ func runLow(cmd *cobra.Command, args []string) error {
 			color.Green(header)
 
 			for _, s := range spoolsToShow {
-				fmt.Printf(" - %s\n%s\n", s, amazonLink(s.Filament.Vendor.Name, s.Filament.Name))
+				fmt.Printf(" - %s%s\n%s\n", s, reservedNote(s, reserved), amazonLink(s.Filament.Vendor.Name, s.Filament.Name))
 			}
 
 			fmt.Println()
@@ -198,7 +202,7 @@ func runLow(cmd *cobra.Command, args []string) error {
 			}
 
 			g.Spools = append(g.Spools, s)
-			g.RemainSum += s.RemainingWeight
+			g.RemainSum += s.RemainingWeight - reserved[s.Id]
 			g.InitSum += s.InitialWeight
 		}
 
@@ -236,8 +240,9 @@ func runLow(cmd *cobra.Command, args []string) error {
 
 		for _, s := range spools {
 			fmt.Printf(
-				" - %s\n%s\n",
+				" - %s%s\n%s\n",
 				s,
+				reservedNote(s, reserved),
 				termLink("Amazon Order "+models.Sanitize(s.Filament.Name), makeAmazonSearch(s.Filament.Vendor.Name, s.Filament.Name)),
 			)
 		}
@@ -22,6 +22,9 @@ var newPlanCmd = &cobra.Command{
 		if moveFlag, _ := cmd.Flags().GetBool("move"); moveFlag {
 			fmt.Println("Note: --move is no longer needed; plans are created directly at their destination.")
 		}
+		if from3mf, _ := cmd.Flags().GetString("from-3mf"); from3mf != "" {
+			return newPlanFromThreeMF(cmd, from3mf, args)
+		}
 
 		cwd, err := os.Getwd()
 		if err != nil {
@@ -248,4 +251,5 @@ func init() {
 	newCmd.AddCommand(newPlanCmd)
 	newPlanCmd.Flags().BoolP("edit", "e", false, "Open the plan in your editor after creation")
 	newPlanCmd.Flags().BoolP("move", "m", false, "Deprecated: plans are now created directly at their destination")
+	newPlanCmd.Flags().String("from-3mf", "", "Build plates, needs, and durations from a sliced Bambu Studio/OrcaSlicer 3MF")
 }
@@ -0,0 +1,136 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/dstockto/fil/api"
+	"github.com/dstockto/fil/models"
+	"github.com/dstockto/fil/plan"
+	"github.com/dstockto/fil/slicer"
+	"github.com/spf13/cobra"
+)
+
+// newPlanFromThreeMF builds a plan from a sliced Bambu Studio / OrcaSlicer
+// project: one Plate per sliced plate with Needs and EstimatedDuration from
+// the slice info. Needs whose material and color pin down a single Spoolman
+// filament are linked automatically; the rest go through the same
+// interactive flow as `fil plan resolve`.
+func newPlanFromThreeMF(cmd *cobra.Command, path string, args []string) error {
+	if PlanOps == nil {
+		return fmt.Errorf("plan operations not configured (need either plans_server or api_base+plans_dir)")
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("failed to read %s: %w", path, err)
+	}
+	sliced, err := slicer.ParseThreeMF(data)
+	if err != nil {
+		return fmt.Errorf("%s: %w", path, err)
+	}
+
+	base := filepath.Base(path)
+	for _, ext := range []string{".gcode.3mf", ".3mf"} {
+		if strings.HasSuffix(strings.ToLower(base), ext) {
+			base = base[:len(base)-len(ext)]
+			break
+		}
+	}
+	filename := base + ".yaml"
+	if len(args) > 0 {
+		filename = args[0]
+		if !strings.HasSuffix(filename, ".yaml") && !strings.HasSuffix(filename, ".yml") {
+			filename += ".yaml"
+		}
+	}
+	projectName := ToProjectName(strings.TrimSuffix(strings.TrimSuffix(filename, ".yaml"), ".yml"))
+
+	plates := slicer.Plates(sliced, base)
+	ctx := cmd.Context()
+	var apiClient *api.Client
+	if Cfg.ApiBase != "" {
+		apiClient = api.NewClient(Cfg.ApiBase, Cfg.TLSSkipVerify)
+		if spools, err := apiClient.FindSpoolsByName(ctx, "*", nil, nil); err == nil {
+			slicer.MatchFilaments(plates, spools)
+		} else {
+			fmt.Printf("Warning: could not fetch spools to match filaments: %v\n", err)
+		}
+	}
+
+	newPlan := models.PlanFile{
+		Projects: []models.Project{{Name: projectName, Status: "todo", Plates: plates}},
+	}
+
+	if err := checkPlanFree(ctx, filename); err != nil {
+		return err
+	}
+	if err := PlanOps.SaveAll(ctx, filename, newPlan); err != nil {
+		return fmt.Errorf("failed to save plan: %w", err)
+	}
+	if Cfg.PlansServer != "" {
+		fmt.Printf("Created plan: <server>/%s\n", filename)
+	} else {
+		fmt.Printf("Created plan: %s\n", FormatPlanPath(filepath.Join(Cfg.PlansDir, filename)))
+	}
+
+	unresolved := 0
+	for _, p := range plates {
+		fmt.Printf("  %s (%s)\n", models.Sanitize(p.Name), p.EstimatedDuration)
+		for _, n := range p.Needs {
+			linked := fmt.Sprintf(" [#%d]", n.FilamentID)
+			if n.FilamentID == 0 {
+				linked = " — not linked"
+				unresolved++
+			}
+			fmt.Printf("    %.1fg %s %s%s\n", n.Amount, models.Sanitize(n.Material), models.Sanitize(n.Name), linked)
+		}
+	}
+
+	if unresolved == 0 {
+		return nil
+	}
+	if apiClient == nil || !isInteractiveAllowed(false) {
+		fmt.Printf("%d need(s) not linked to Spoolman filaments; run 'fil plan resolve'.\n", unresolved)
+		return nil
+	}
+	dp := DiscoveredPlan{DisplayName: filename, Plan: newPlan}
+	resolutions, err := collectResolutions(ctx, apiClient, &dp)
+	if err != nil {
+		return err
+	}
+	if err := PlanOps.Resolve(ctx, plan.ResolveRequest{Plan: filename, Resolutions: resolutions}); err != nil {
+		return fmt.Errorf("resolve: %w", err)
+	}
+	if len(resolutions) > 0 {
+		fmt.Printf("Resolved %d need(s) in %s.\n", len(resolutions), filename)
+	}
+	return nil
+}
+
+// checkPlanFree fails when a plan named filename already exists, in
+// plans_dir or on the plan-server, since SaveAll would overwrite it.
+func checkPlanFree(ctx context.Context, filename string) error {
+	if Cfg.PlansServer == "" {
+		if Cfg.PlansDir == "" {
+			return nil
+		}
+		if _, err := os.Stat(filepath.Join(Cfg.PlansDir, filename)); err == nil {
+			return fmt.Errorf("file %s already exists", filepath.Join(Cfg.PlansDir, filename))
+		}
+		return nil
+	}
+	client := api.NewPlanServerClient(Cfg.PlansServer, version, Cfg.TLSSkipVerify)
+	summaries, err := client.ListPlans(ctx, "")
+	if err != nil {
+		return fmt.Errorf("check existing plans: %w", err)
+	}
+	for _, p := range summaries {
+		if p.Name == filename {
+			return fmt.Errorf("plan %s already exists on the server", filename)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,45 @@
+package cmd
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestCheckPlanFree(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/fil/plans" {
+			http.NotFound(w, r)
+			return
+		}
+		_, _ = w.Write([]byte(`[{"name":"widget.yaml"}]`))
+	}))
+	defer srv.Close()
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "box.yaml"), nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	oldCfg := Cfg
+	t.Cleanup(func() { Cfg = oldCfg })
+	ctx := context.Background()
+
+	Cfg = &Config{PlansServer: srv.URL}
+	if err := checkPlanFree(ctx, "widget.yaml"); err == nil {
+		t.Error("remote: existing plan not reported")
+	}
+	if err := checkPlanFree(ctx, "gadget.yaml"); err != nil {
+		t.Errorf("remote: new plan: %v", err)
+	}
+
+	Cfg = &Config{PlansDir: dir}
+	if err := checkPlanFree(ctx, "box.yaml"); err == nil {
+		t.Error("local: existing plan not reported")
+	}
+	if err := checkPlanFree(ctx, "widget.yaml"); err != nil {
+		t.Errorf("local: new plan: %v", err)
+	}
+}
@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"sort"
 	"strings"
 
 	"github.com/dstockto/fil/api"
@@ -238,6 +239,10 @@ func discoverPlansWithFilter(includePaused, pausedOnly bool) ([]DiscoveredPlan,
 		}
 	}
 
+	// Higher-priority and sooner-due plans come first everywhere plans are
+	// listed or offered; otherwise discovery order stands.
+	sort.SliceStable(plans, func(i, j int) bool { return models.PlanBefore(plans[i].Plan, plans[j].Plan) })
+
 	return plans, nil
 }
 
@@ -6,6 +6,8 @@ import (
 	"strconv"
 	"strings"
 
+	"github.com/dstockto/fil/models"
+	"github.com/dstockto/fil/slicer"
 	"github.com/manifoldco/promptui"
 	"github.com/spf13/cobra"
 )
@@ -26,6 +28,9 @@ var planAmountsCmd = &cobra.Command{
 		if err != nil {
 			return err
 		}
+		if gcodePath, _ := cmd.Flags().GetString("from-gcode"); gcodePath != "" {
+			return amountsFromGcode(cmd, dp, gcodePath)
+		}
 
 		showAll, _ := cmd.Flags().GetBool("all")
 		plan := dp.Plan
@@ -131,7 +136,51 @@ var planAmountsCmd = &cobra.Command{
 	},
 }
 
+// amountsFromGcode fills the selected plate's need amounts from the slicer
+// stats in a G-code file. Filaments that match no need by color or material
+// are added as new needs so nothing the slicer counted is lost; they show up
+// for `fil plan resolve` like any other unlinked need.
+func amountsFromGcode(cmd *cobra.Command, dp *DiscoveredPlan, path string) error {
+	sliced, err := slicer.ReadGcodeFile(path)
+	if err != nil {
+		return fmt.Errorf("%s: %w", path, err)
+	}
+	if len(sliced.Filaments) == 0 {
+		return fmt.Errorf("%s: no filament usage found", path)
+	}
+
+	projIdx, plateIdx, err := selectOpenPlate(dp.Plan, "Which plate is this G-code for?")
+	if err != nil {
+		return err
+	}
+	if projIdx < 0 {
+		fmt.Println("No incomplete plates in this plan.")
+		return nil
+	}
+	plate := &dp.Plan.Projects[projIdx].Plates[plateIdx]
+
+	set, unmatched := slicer.ApplyAmounts(plate.Needs, sliced.Filaments)
+	for _, i := range set {
+		n := plate.Needs[i]
+		fmt.Printf("  %s %s: %.1fg\n", models.Sanitize(n.Name), models.Sanitize(n.Material), n.Amount)
+	}
+	for _, f := range unmatched {
+		need := models.PlateRequirement{Name: f.Type, Material: f.Type, Color: f.Color, Amount: RoundAmount(f.Grams)}
+		plate.Needs = append(plate.Needs, need)
+		fmt.Printf("  added %s [%s]: %.1fg (extruder %d matched no need; run 'fil plan resolve')\n",
+			models.Sanitize(need.Material), need.Color, need.Amount, f.Slot)
+	}
+
+	if err := PlanOps.SaveAll(cmd.Context(), planFileName(*dp), dp.Plan); err != nil {
+		return fmt.Errorf("failed to save plan: %w", err)
+	}
+	fmt.Printf("Updated %d amount(s) on %s / %s.\n", len(set)+len(unmatched),
+		models.Sanitize(dp.Plan.Projects[projIdx].Name), models.Sanitize(plate.Name))
+	return nil
+}
+
 func init() {
 	planAmountsCmd.Flags().Bool("all", false, "Edit all amounts, not just zero-value ones")
+	planAmountsCmd.Flags().String("from-gcode", "", "Fill one plate's amounts from a .gcode/.bgcode file's slicer stats")
 	planCmd.AddCommand(planAmountsCmd)
 }
@@ -7,6 +7,7 @@ import (
 
 	"github.com/dstockto/fil/api"
 	"github.com/dstockto/fil/models"
+	"github.com/dstockto/fil/plan"
 	"github.com/fatih/color"
 	"github.com/spf13/cobra"
 	"gopkg.in/yaml.v3"
@@ -16,10 +17,10 @@ var planCheckCmd = &cobra.Command{
 	Use:   "check [file]",
 	Short: "Check if enough filament is available for a plan",
 	RunE: func(cmd *cobra.Command, args []string) error {
-		if Cfg == nil || Cfg.ApiBase == "" {
+		lint, _ := cmd.Flags().GetBool("lint")
+		if !lint && (Cfg == nil || Cfg.ApiBase == "") {
 			return fmt.Errorf("api endpoint not configured")
 		}
-		apiClient := api.NewClient(Cfg.ApiBase, Cfg.TLSSkipVerify)
 		ctx := cmd.Context()
 
 		var discovered []DiscoveredPlan
@@ -51,6 +52,10 @@ var planCheckCmd = &cobra.Command{
 			return nil
 		}
 
+		if lint {
+			return lintPlans(discovered)
+		}
+
 		// Aggregate needs by FilamentID (if resolved) or Name+Material (if unresolved)
 		type projectUsage struct {
 			projectName string
@@ -65,6 +70,7 @@ var planCheckCmd = &cobra.Command{
 			multiColorHexes string
 			amount          float64
 			committed       float64 // amount from in-progress plates
+			reserved        float64 // grams these needs already hold on spools
 			projects        []projectUsage
 		}
 		needs := make(map[string]*totalNeed)
@@ -114,6 +120,7 @@ var planCheckCmd = &cobra.Command{
 							fmt.Printf("Note: Filament ID %d is used for both '%s' and '%s'. Aggregating needs.\n", req.FilamentID, models.Sanitize(needs[key].name), models.Sanitize(req.Name))
 						}
 						needs[key].amount += req.Amount
+						needs[key].reserved += req.ReservedGrams()
 						if isCommitted {
 							needs[key].committed += req.Amount
 						}
@@ -154,13 +161,21 @@ var planCheckCmd = &cobra.Command{
 		byProject, _ := cmd.Flags().GetBool("by-project")
 
 		// Get all spools from Spoolman
+		apiClient := api.NewClient(Cfg.ApiBase, Cfg.TLSSkipVerify)
 		allSpools, err := apiClient.FindSpoolsByName(ctx, "*", onlyStandardFilament, nil)
 		if err != nil {
 			return err
 		}
 
+		// Reservations from every active plan count against free grams, not
+		// just the plans being checked, so plans can't double-book a spool.
+		reservations := checkReservations(discovered, len(args) > 0)
+
 		// Inventory by Filament ID
 		inventory := make(map[int]float64)
+		reservedByFilament := make(map[int]float64)
+		overReserved := make(map[int]bool)
+		var overSpools []models.FindSpool
 		isLoaded := make(map[int]bool)
 		filamentColors := make(map[int]struct {
 			colorHex        string
@@ -181,6 +196,13 @@ var planCheckCmd = &cobra.Command{
 		for _, s := range allSpools {
 			if !s.Archived {
 				inventory[s.Filament.Id] += s.RemainingWeight
+				if r := reservations[s.Id]; r > 0 {
+					reservedByFilament[s.Filament.Id] += r
+					if r > s.RemainingWeight+0.05 {
+						overReserved[s.Filament.Id] = true
+						overSpools = append(overSpools, s)
+					}
+				}
 				if printerLocs[s.Location] {
 					isLoaded[s.Filament.Id] = true
 				}
@@ -206,10 +228,12 @@ var planCheckCmd = &cobra.Command{
 			colorBlock    string
 			displayStatus string
 			onHand        float64
+			reserved      string
 			loaded        string
 			status        string
 		}
 		displayInfo := make(map[string]*filamentDisplay)
+		hasReservations := len(reservedByFilament) > 0
 
 		allMet := true
 		for key, n := range needs {
@@ -231,7 +255,18 @@ var planCheckCmd = &cobra.Command{
 				d.status = "UNRESOLVED"
 			}
 
-			if d.onHand < n.amount {
+			// What's reserved for other needs isn't available to these.
+			available := d.onHand
+			if n.id != 0 {
+				available -= reservedByFilament[n.id] - n.reserved
+				if hasReservations {
+					d.reserved = fmt.Sprintf(" %9.1fg %9.1fg", reservedByFilament[n.id], d.onHand-reservedByFilament[n.id])
+				}
+			} else if hasReservations {
+				d.reserved = strings.Repeat(" ", 22)
+			}
+
+			if available < n.amount || overReserved[n.id] {
 				if d.status == "OK" {
 					d.status = "LOW"
 				}
@@ -240,7 +275,7 @@ var planCheckCmd = &cobra.Command{
 				// Check if projected amount is below threshold
 				info := filamentInfo[n.id]
 				threshold := ResolveLowThreshold(info.vendor, info.name)
-				if d.onHand-n.amount < threshold {
+				if available-n.amount < threshold {
 					d.status = "WARN"
 				}
 			}
@@ -290,16 +325,21 @@ var planCheckCmd = &cobra.Command{
 			neededWidth = 10
 		}
 
-		headerFmt := fmt.Sprintf("%%-5s %%-30s %%%ds %%10s %%10s %%6s\n", neededWidth)
-		rowFmt := fmt.Sprintf("%%s %%-30s %%%ds %%10.1fg %%s %%6s\n", neededWidth)
+		headerFmt := fmt.Sprintf("%%-5s %%-30s %%%ds %%10s%%s %%10s %%6s\n", neededWidth)
+		rowFmt := fmt.Sprintf("%%s %%-30s %%%ds %%10.1fg%%s %%s %%6s\n", neededWidth)
 		totalWidth := 5 + 1 + 30 + 1 + neededWidth + 1 + 10 + 10 + 1 + 6
-		fmt.Printf(headerFmt, "", "Filament", "Needed", "On Hand", "Status", "Loaded")
+		reservedHeader := ""
+		if hasReservations {
+			reservedHeader = fmt.Sprintf(" %10s %10s", "Reserved", "Free")
+			totalWidth += 22
+		}
+		fmt.Printf(headerFmt, "", "Filament", "Needed", "On Hand", reservedHeader, "Status", "Loaded")
 		fmt.Println(strings.Repeat("-", totalWidth))
 
 		if !byProject {
 			for key, n := range needs {
 				d := displayInfo[key]
-				fmt.Printf(rowFmt, d.colorBlock, TruncateFront(models.Sanitize(n.name), 30), neededStrs[key], d.onHand, d.displayStatus, d.loaded)
+				fmt.Printf(rowFmt, d.colorBlock, TruncateFront(models.Sanitize(n.name), 30), neededStrs[key], d.onHand, d.reserved, d.displayStatus, d.loaded)
 
 				if verbose {
 					for _, p := range n.projects {
@@ -353,7 +393,7 @@ var planCheckCmd = &cobra.Command{
 							}
 						}
 					}
-					fmt.Printf(rowFmt, d.colorBlock, TruncateFront(models.Sanitize(entry.need.name), 30), neededStr, d.onHand, d.displayStatus, d.loaded)
+					fmt.Printf(rowFmt, d.colorBlock, TruncateFront(models.Sanitize(entry.need.name), 30), neededStr, d.onHand, d.reserved, d.displayStatus, d.loaded)
 				}
 			}
 		}
@@ -364,6 +404,26 @@ var planCheckCmd = &cobra.Command{
 			fmt.Println("\nSome filaments are missing or low.")
 		}
 
+		// Aggregate totals can be met while a loaded spool still runs dry
+		// mid-plate, so walk the queue in print order as well.
+		sched, err := loadSchedule(ctx)
+		if err != nil {
+			sched = plan.Schedule{}
+		}
+		printers := make(map[string][]string, len(Cfg.Printers))
+		for name, pCfg := range Cfg.Printers {
+			printers[name] = pCfg.Locations
+		}
+		printRunOuts(simulateRunOuts(simulationQueue(discovered, sched), allSpools, printers))
+
+		if len(overSpools) > 0 {
+			fmt.Println()
+			for _, s := range overSpools {
+				fmt.Printf("%s Spool #%d (%s) has %.1fg left but %.1fg reserved\n",
+					color.YellowString("Warning:"), s.Id, models.Sanitize(s.Filament.Name), s.RemainingWeight, reservations[s.Id])
+			}
+		}
+
 		if len(zeroWarnings) > 0 {
 			fmt.Println()
 			warningLabel := color.YellowString("Warning:")
@@ -389,8 +449,48 @@ var planCheckCmd = &cobra.Command{
 	},
 }
 
+// lintPlans prints each plan's dependency and date problems and fails when
+// there are any.
+func lintPlans(discovered []DiscoveredPlan) error {
+	count := 0
+	for _, dp := range discovered {
+		for _, problem := range dp.Plan.Lint() {
+			fmt.Printf("%s: %s\n", dp.DisplayName, models.Sanitize(problem))
+			count++
+		}
+	}
+	if count > 0 {
+		return fmt.Errorf("%d problem(s) found", count)
+	}
+	fmt.Printf("No problems found in %d plan(s).\n", len(discovered))
+	return nil
+}
+
+// checkReservations totals spool reservations across the checked plans. When
+// the plans came from explicit files, the other active plans' reservations
+// are added too (skipping same-named files so they aren't counted twice).
+func checkReservations(checked []DiscoveredPlan, explicit bool) map[int]float64 {
+	var plans []models.PlanFile
+	names := make(map[string]bool)
+	for _, dp := range checked {
+		plans = append(plans, dp.Plan)
+		names[planFileName(dp)] = true
+	}
+	if explicit && Cfg != nil && (Cfg.PlansDir != "" || Cfg.PlansServer != "") {
+		if active, err := discoverPlans(); err == nil {
+			for _, dp := range active {
+				if !names[planFileName(dp)] {
+					plans = append(plans, dp.Plan)
+				}
+			}
+		}
+	}
+	return models.Reservations(plans...)
+}
+
 func init() {
 	planCmd.AddCommand(planCheckCmd)
 	planCheckCmd.Flags().BoolP("verbose", "v", false, "Show which projects use each filament")
 	planCheckCmd.Flags().Bool("by-project", false, "Group output by project instead of by filament")
+	planCheckCmd.Flags().Bool("lint", false, "Check plate 'after:' references for unknown plates and cycles instead of filament")
 }
@@ -91,6 +91,12 @@ var planCompleteCmd = &cobra.Command{
 // preferring in-progress plates at the top. Returns -1 indices when there's
 // nothing to complete.
 func selectPlateToComplete(planFile models.PlanFile) (int, int, error) {
+	return selectOpenPlate(planFile, "Which plate did you complete?")
+}
+
+// selectOpenPlate prompts for any plate that isn't completed yet, listing
+// in-progress plates first. Returns -1 indices when there are none.
+func selectOpenPlate(planFile models.PlanFile, label string) (int, int, error) {
 	type opt struct {
 		projIdx  int
 		plateIdx int
@@ -125,7 +131,7 @@ func selectPlateToComplete(planFile models.PlanFile) (int, int, error) {
 	}
 
 	prompt := promptui.Select{
-		Label:             "Which plate did you complete?",
+		Label:             label,
 		Items:             options,
 		Size:              10,
 		Stdout:            NoBellStdout,
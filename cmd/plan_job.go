@@ -0,0 +1,106 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/dstockto/fil/api"
+	"github.com/dstockto/fil/models"
+	"github.com/manifoldco/promptui"
+	"github.com/spf13/cobra"
+)
+
+var planJobCmd = &cobra.Command{
+	Use:   "job <sliced-file>",
+	Short: "Attach a sliced file to a plate so plan next can send it to the printer",
+	Long: `Uploads a sliced file (.3mf, .gcode.3mf, .bgcode, or .gcode) to the plan
+server and points a plate's sliced field at it. "fil plan next" then offers
+to send the file to the printer and start it.
+
+For a multi-plate Bambu 3MF, pass --plate-number to pick the plate to print.`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if Cfg == nil || Cfg.PlansServer == "" {
+			return fmt.Errorf("plans_server must be configured to attach sliced files")
+		}
+		if PlanOps == nil {
+			return fmt.Errorf("plan operations not configured (need either plans_server or api_base+plans_dir)")
+		}
+		path := args[0]
+		if models.JobExt(path) == "" {
+			return fmt.Errorf("%s is not a sliced file (.3mf, .bgcode, or .gcode)", path)
+		}
+		plateNumber, _ := cmd.Flags().GetInt("plate-number")
+
+		plans, err := discoverPlans()
+		if err != nil {
+			return err
+		}
+		dp, err := selectPlan("Select plan", plans)
+		if err != nil {
+			return err
+		}
+
+		type plateRef struct{ project, plate int }
+		var refs []plateRef
+		var items []string
+		for pi, proj := range dp.Plan.Projects {
+			if proj.Status == "completed" {
+				continue
+			}
+			for pj, plate := range proj.Plates {
+				if plate.Status == "completed" {
+					continue
+				}
+				refs = append(refs, plateRef{pi, pj})
+				items = append(items, fmt.Sprintf("%s - %s", models.Sanitize(proj.Name), models.Sanitize(plate.Name)))
+			}
+		}
+		if len(refs) == 0 {
+			return fmt.Errorf("no unfinished plates in %s", dp.DisplayName)
+		}
+		idx := 0
+		if len(refs) > 1 {
+			prompt := promptui.Select{
+				Label:  "Select plate",
+				Items:  items,
+				Stdout: NoBellStdout,
+			}
+			if idx, _, err = prompt.Run(); err != nil {
+				return err
+			}
+		}
+
+		f, err := os.Open(path)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		client := api.NewPlanServerClient(Cfg.PlansServer, version, Cfg.TLSSkipVerify)
+		stored, err := client.PutJob(cmd.Context(), filepath.Base(path), f)
+		if err != nil {
+			return fmt.Errorf("failed to upload sliced file: %w", err)
+		}
+
+		plate := &dp.Plan.Projects[refs[idx].project].Plates[refs[idx].plate]
+		plate.Sliced = stored
+		plate.SlicedPlate = plateNumber
+		if plate.File == "" {
+			// Lets auto-start recognize the job once it's on the printer.
+			name := filepath.Base(path)
+			plate.File = name[:len(name)-len(models.JobExt(name))]
+		}
+		if err := PlanOps.SaveAll(cmd.Context(), planFileName(*dp), dp.Plan); err != nil {
+			return fmt.Errorf("file uploaded but failed to update plan YAML: %w", err)
+		}
+
+		fmt.Printf("Attached %s to %s\n", filepath.Base(path), items[idx])
+		return nil
+	},
+}
+
+func init() {
+	planCmd.AddCommand(planJobCmd)
+	planJobCmd.Flags().Int("plate-number", 0, "plate to print from a multi-plate 3MF (default 1)")
+}
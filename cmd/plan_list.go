@@ -54,7 +54,18 @@ var planListCmd = &cobra.Command{
 			if p.Plan.Assembly != "" {
 				pdfIndicator = " [PDF]"
 			}
-			fmt.Printf("Plan: %s%s\n", p.DisplayName, pdfIndicator)
+			var order []string
+			if p.Plan.Priority != 0 {
+				order = append(order, fmt.Sprintf("priority %d", p.Plan.Priority))
+			}
+			if p.Plan.Due != "" {
+				order = append(order, "due "+models.Sanitize(p.Plan.Due))
+			}
+			orderInfo := ""
+			if len(order) > 0 {
+				orderInfo = " (" + strings.Join(order, ", ") + ")"
+			}
+			fmt.Printf("Plan: %s%s%s\n", p.DisplayName, pdfIndicator, orderInfo)
 			for _, proj := range p.Plan.Projects {
 				if proj.Status == "completed" {
 					continue
@@ -62,6 +73,7 @@ var planListCmd = &cobra.Command{
 				remainingCount := 0
 				total := len(proj.Plates)
 				var todoPlates []string
+				waiting := make(map[string][]string)
 				var printing []string
 				for _, plate := range proj.Plates {
 					if plate.Status != "completed" {
@@ -69,6 +81,7 @@ var planListCmd = &cobra.Command{
 					}
 					if plate.Status == "todo" {
 						todoPlates = append(todoPlates, plate.Name)
+						waiting[plate.Name] = p.Plan.Blockers(proj.Name, plate)
 					}
 					if plate.Status == "in-progress" && plate.Printer != "" {
 						printing = append(printing, plate.Printer)
@@ -101,6 +114,10 @@ var planListCmd = &cobra.Command{
 
 				if remaining {
 					for _, name := range todoPlates {
+						if w := waiting[name]; len(w) > 0 {
+							fmt.Printf("    - %s (blocked: waiting on %s)\n", models.Sanitize(name), models.Sanitize(strings.Join(w, ", ")))
+							continue
+						}
 						fmt.Printf("    - %s\n", models.Sanitize(name))
 					}
 				}
@@ -0,0 +1,473 @@
+package cmd
+
+import (
+	"fmt"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/dstockto/fil/api"
+	"github.com/dstockto/fil/models"
+	"github.com/dstockto/fil/plan"
+	"github.com/manifoldco/promptui"
+	"github.com/spf13/cobra"
+)
+
+var planLoadoutCmd = &cobra.Command{
+	Use:   "loadout",
+	Short: "Plan which spools to load so the upcoming plates print without swaps",
+	Long: `Works out which spools should sit in a printer's slots so that as many of the
+upcoming plates as possible print back to back without a swap. Upcoming plates
+are the next --plates todo plates across the active plans, or the printer's
+queue from 'fil plan schedule --save' with --schedule.
+
+Spools already loaded are kept when they're still needed. New loads prefer
+partially used spools that have enough left for the covered plates. Loaded
+spools none of those plates need are listed as free to go back to the shelf.
+
+The result is printed as a single 'fil move' batch; --apply runs it (pushing
+tray info to the printer just like 'fil move').`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if Cfg == nil || Cfg.ApiBase == "" {
+			return fmt.Errorf("api endpoint not configured")
+		}
+		if len(Cfg.Printers) == 0 {
+			return fmt.Errorf("no printers configured in config.json")
+		}
+		ctx := cmd.Context()
+		apiClient := api.NewClient(Cfg.ApiBase, Cfg.TLSSkipVerify)
+
+		printerName, _ := cmd.Flags().GetString("printer")
+		if printerName == "" {
+			var names []string
+			for name := range Cfg.Printers {
+				names = append(names, name)
+			}
+			sort.Strings(names)
+			if len(names) == 1 {
+				printerName = names[0]
+			} else {
+				prompt := promptui.Select{Label: "Which printer?", Items: names, Stdout: NoBellStdout}
+				_, picked, err := prompt.Run()
+				if err != nil {
+					return err
+				}
+				printerName = picked
+			}
+		}
+		printerCfg, ok := Cfg.Printers[printerName]
+		if !ok {
+			return fmt.Errorf("unknown printer %q", printerName)
+		}
+
+		discovered, err := discoverPlans()
+		if err != nil {
+			return err
+		}
+		count, _ := cmd.Flags().GetInt("plates")
+		var upcoming []upcomingPlate
+		if useSchedule, _ := cmd.Flags().GetBool("schedule"); useSchedule {
+			sched, err := loadSchedule(ctx)
+			if err != nil {
+				return fmt.Errorf("failed to load schedule: %w", err)
+			}
+			upcoming = scheduledPlates(discovered, sched.Queues[printerName], count)
+		} else {
+			upcoming = nextTodoPlates(discovered, count)
+		}
+		if len(upcoming) == 0 {
+			fmt.Println("No upcoming plates to load for.")
+			return nil
+		}
+
+		spools, err := apiClient.FindSpoolsByName(ctx, "*", onlyStandardFilament, nil)
+		if err != nil {
+			return fmt.Errorf("failed to fetch spools: %w", err)
+		}
+		orders, err := LoadLocationOrders(ctx, apiClient)
+		if err != nil {
+			return err
+		}
+
+		otherPrinters := map[string]bool{}
+		for name, pc := range Cfg.Printers {
+			if name == printerName {
+				continue
+			}
+			for _, loc := range pc.Locations {
+				otherPrinters[loc] = true
+			}
+		}
+		slots := printerSlots(printerCfg.Locations, orders, spools)
+		lo := planLoadout(slots, upcoming, spools, otherPrinters)
+
+		shelf, _ := cmd.Flags().GetString("shelf")
+		printLoadout(printerName, lo, upcoming)
+		batch := lo.moveArgs(shelf)
+		if len(batch) == 0 {
+			fmt.Println("Nothing to move.")
+			return nil
+		}
+		quoted := make([]string, len(batch))
+		for i, a := range batch {
+			quoted[i] = shellQuote(a)
+		}
+		fmt.Printf("\nfil move %s\n", strings.Join(quoted, " "))
+
+		if apply, _ := cmd.Flags().GetBool("apply"); !apply {
+			return nil
+		}
+		fmt.Println()
+		dryRun, _ := cmd.Flags().GetBool("dry-run")
+		moveCmd.SetContext(ctx)
+		_ = moveCmd.Flags().Set("dry-run", strconv.FormatBool(dryRun))
+		return runMove(moveCmd, batch)
+	},
+}
+
+// upcomingPlate is a plate the loadout should cover, in print order.
+type upcomingPlate struct {
+	Project string
+	Plate   string
+	Printer string // printer it's on or queued for, when known
+	Needs   []models.PlateRequirement
+}
+
+// nextTodoPlates returns the first n todo plates across the plans, in the
+// order plans and plates appear — except that a plate waiting on another todo
+// plate (`after:`) moves behind it. Plates waiting on one that is still
+// printing, or stuck in a dependency cycle, are left out.
+func nextTodoPlates(discovered []DiscoveredPlan, n int) []upcomingPlate {
+	type todo struct {
+		key      string
+		after    []string
+		upcoming upcomingPlate
+	}
+	var todos []todo
+	for _, dp := range discovered {
+		name := planFileName(dp)
+		for _, proj := range dp.Plan.Projects {
+			if proj.Status == "completed" {
+				continue
+			}
+			for _, plate := range proj.Plates {
+				if plate.Status != "todo" {
+					continue
+				}
+				t := todo{
+					key:      name + "/" + models.PlateKey(proj.Name, plate.Name),
+					upcoming: upcomingPlate{Project: proj.Name, Plate: plate.Name, Needs: plate.Needs},
+				}
+				for _, key := range dp.Plan.Blockers(proj.Name, plate) {
+					t.after = append(t.after, name+"/"+key)
+				}
+				todos = append(todos, t)
+			}
+		}
+	}
+
+	var out []upcomingPlate
+	queued := map[string]bool{}
+	for progress := true; progress && len(todos) > 0; {
+		progress = false
+		for i := 0; i < len(todos); i++ {
+			ready := true
+			for _, dep := range todos[i].after {
+				if !queued[dep] {
+					ready = false
+					break
+				}
+			}
+			if !ready {
+				continue
+			}
+			if n > 0 && len(out) >= n {
+				return out
+			}
+			out = append(out, todos[i].upcoming)
+			queued[todos[i].key] = true
+			todos = append(todos[:i], todos[i+1:]...)
+			progress = true
+			break // restart so earlier plates unblocked by this one go next
+		}
+	}
+	return out
+}
+
+// scheduledPlates looks up the first n still-todo entries of a saved queue.
+func scheduledPlates(discovered []DiscoveredPlan, queue []plan.ScheduledPlate, n int) []upcomingPlate {
+	var out []upcomingPlate
+	for _, e := range queue {
+		if n > 0 && len(out) >= n {
+			break
+		}
+		for _, dp := range discovered {
+			if planFileName(dp) != e.Plan {
+				continue
+			}
+			for _, proj := range dp.Plan.Projects {
+				for _, plate := range proj.Plates {
+					if proj.Name == e.Project && plate.Name == e.Plate && plate.Status == "todo" {
+						out = append(out, upcomingPlate{Project: proj.Name, Plate: plate.Name, Needs: plate.Needs})
+					}
+				}
+			}
+		}
+	}
+	return out
+}
+
+// loadoutSlot is one physical slot of a printer. SpoolID is EmptySlot when
+// nothing is loaded.
+type loadoutSlot struct {
+	Location string
+	Pos      int
+	SpoolID  int
+}
+
+func (s loadoutSlot) String() string {
+	return fmt.Sprintf("%s:%d", s.Location, s.Pos)
+}
+
+// printerSlots lays out a printer's slots from locations_spoolorders, padded
+// to each location's capacity. Order entries whose spool is no longer in that
+// location count as empty; spools in the location that the orders miss take
+// the first empty slot, or go after the known ones.
+func printerSlots(locations []string, orders map[string][]int, spools []models.FindSpool) []loadoutSlot {
+	var slots []loadoutSlot
+	for _, loc := range locations {
+		in := map[int]bool{}
+		for _, s := range spools {
+			if s.Location == loc {
+				in[s.Id] = true
+			}
+		}
+		ids := PadToCapacity(loc, append([]int(nil), orders[loc]...))
+		if len(ids) == 0 {
+			ids = []int{EmptySlot}
+		}
+		for i, id := range ids {
+			if !in[id] {
+				ids[i] = EmptySlot
+			}
+		}
+		for _, s := range spools {
+			if s.Location != loc || slices.Contains(ids, s.Id) {
+				continue
+			}
+			if i := FirstEmptySlot(ids); i >= 0 {
+				ids[i] = s.Id
+			} else {
+				ids = append(ids, s.Id)
+			}
+		}
+		for i, id := range ids {
+			slots = append(slots, loadoutSlot{Location: loc, Pos: i + 1, SpoolID: id})
+		}
+	}
+	return slots
+}
+
+// loadoutMove is a spool going into a slot or out to the shelf.
+type loadoutMove struct {
+	Spool models.FindSpool
+	Slot  loadoutSlot
+}
+
+type loadout struct {
+	// Covered is how many of the upcoming plates print without a swap.
+	Covered int
+	Keep    []loadoutMove
+	Load    []loadoutMove
+	// Unload must leave to make room; Spare are loaded but unneeded and can
+	// stay or go back to the shelf.
+	Unload []loadoutMove
+	Spare  []loadoutMove
+	// Missing are needs for covered plates that no available spool can fill.
+	Missing []models.PlateRequirement
+}
+
+// planLoadout picks the filaments for the longest run of upcoming plates
+// that fits in the printer's slots, keeps loaded spools that match, and
+// chooses spools for the rest: not in another printer, enough remaining for
+// the run when possible, partially used before fresh, smallest remainder
+// first so partial spools get used up.
+func planLoadout(slots []loadoutSlot, upcoming []upcomingPlate, spools []models.FindSpool, otherPrinters map[string]bool) loadout {
+	var lo loadout
+	byID := map[int]models.FindSpool{}
+	for _, s := range spools {
+		byID[s.Id] = s
+	}
+
+	// Filaments (in first-needed order) and grams for the covered run.
+	var want []int
+	grams := map[int]float64{}
+	needFor := map[int]models.PlateRequirement{}
+	for _, p := range upcoming {
+		var add []int
+		for _, n := range p.Needs {
+			if n.FilamentID == 0 || slices.Contains(want, n.FilamentID) || slices.Contains(add, n.FilamentID) {
+				continue
+			}
+			add = append(add, n.FilamentID)
+		}
+		if len(want)+len(add) > len(slots) {
+			break
+		}
+		want = append(want, add...)
+		for _, n := range p.Needs {
+			if n.FilamentID != 0 {
+				grams[n.FilamentID] += n.Amount
+				if _, ok := needFor[n.FilamentID]; !ok {
+					needFor[n.FilamentID] = n
+				}
+			}
+		}
+		lo.Covered++
+	}
+
+	// Keep what's already loaded and wanted; everything else is free.
+	have := map[int]bool{}
+	var free []loadoutSlot
+	var removable []loadoutMove
+	for _, sl := range slots {
+		if sl.SpoolID == EmptySlot {
+			free = append(free, sl)
+			continue
+		}
+		s := byID[sl.SpoolID]
+		if slices.Contains(want, s.Filament.Id) && !have[s.Filament.Id] {
+			have[s.Filament.Id] = true
+			lo.Keep = append(lo.Keep, loadoutMove{Spool: s, Slot: sl})
+			continue
+		}
+		removable = append(removable, loadoutMove{Spool: s, Slot: sl})
+	}
+
+	for _, fid := range want {
+		if have[fid] {
+			continue
+		}
+		best, ok := pickLoadSpool(spools, fid, grams[fid], slots, otherPrinters)
+		if !ok {
+			lo.Missing = append(lo.Missing, needFor[fid])
+			continue
+		}
+		var sl loadoutSlot
+		if len(free) > 0 {
+			sl, free = free[0], free[1:]
+		} else {
+			out := removable[0]
+			removable = removable[1:]
+			lo.Unload = append(lo.Unload, out)
+			sl = out.Slot
+		}
+		lo.Load = append(lo.Load, loadoutMove{Spool: best, Slot: sl})
+	}
+	lo.Spare = removable
+	return lo
+}
+
+func pickLoadSpool(spools []models.FindSpool, filamentID int, grams float64, slots []loadoutSlot, otherPrinters map[string]bool) (models.FindSpool, bool) {
+	var candidates []models.FindSpool
+	for _, s := range spools {
+		if s.Archived || s.Filament.Id != filamentID || otherPrinters[s.Location] {
+			continue
+		}
+		loaded := false
+		for _, sl := range slots {
+			if sl.SpoolID == s.Id {
+				loaded = true
+				break
+			}
+		}
+		if !loaded {
+			candidates = append(candidates, s)
+		}
+	}
+	if len(candidates) == 0 {
+		return models.FindSpool{}, false
+	}
+	sort.SliceStable(candidates, func(i, j int) bool {
+		a, b := candidates[i], candidates[j]
+		if enoughA, enoughB := a.RemainingWeight >= grams, b.RemainingWeight >= grams; enoughA != enoughB {
+			return enoughA
+		}
+		if partA, partB := a.UsedWeight > 0, b.UsedWeight > 0; partA != partB {
+			return partA
+		}
+		if a.RemainingWeight != b.RemainingWeight {
+			return a.RemainingWeight < b.RemainingWeight
+		}
+		return a.Id < b.Id
+	})
+	return candidates[0], true
+}
+
+// moveArgs renders the loadout as `fil move` arguments: unloads first so
+// their slots are empty by the time the loads land in them. shelf is where
+// unloaded spools go; empty means "_", which prompts for a destination.
+func (lo loadout) moveArgs(shelf string) []string {
+	if shelf == "" {
+		shelf = "_"
+	}
+	var args []string
+	for _, m := range lo.Unload {
+		args = append(args, strconv.Itoa(m.Spool.Id), shelf)
+	}
+	for _, m := range lo.Load {
+		args = append(args, strconv.Itoa(m.Spool.Id), m.Slot.String())
+	}
+	return args
+}
+
+func printLoadout(printerName string, lo loadout, upcoming []upcomingPlate) {
+	fmt.Printf("Loadout for %s covers %d of the next %d plate(s) without swaps:\n",
+		models.Sanitize(printerName), lo.Covered, len(upcoming))
+	for i, p := range upcoming[:lo.Covered] {
+		fmt.Printf("  %d. %s - %s\n", i+1, models.Sanitize(p.Project), models.Sanitize(p.Plate))
+	}
+	fmt.Println()
+	for _, m := range lo.Keep {
+		fmt.Printf("  keep    %-10s #%d %s (%.1fg)\n", m.Slot, m.Spool.Id, models.Sanitize(m.Spool.Filament.Name), m.Spool.RemainingWeight)
+	}
+	for _, m := range lo.Unload {
+		fmt.Printf("  unload  %-10s #%d %s\n", m.Slot, m.Spool.Id, models.Sanitize(m.Spool.Filament.Name))
+	}
+	for _, m := range lo.Load {
+		from := m.Spool.Location
+		if from == "" {
+			from = "nowhere"
+		}
+		fmt.Printf("  load    %-10s #%d %s (%.1fg, from %s)\n", m.Slot, m.Spool.Id, models.Sanitize(m.Spool.Filament.Name), m.Spool.RemainingWeight, models.Sanitize(from))
+	}
+	for _, n := range lo.Missing {
+		fmt.Printf("  ! no spool available for %s (#%d)\n", models.Sanitize(n.Name), n.FilamentID)
+	}
+	if len(lo.Spare) > 0 {
+		fmt.Println("\nCan go back to the shelf (not needed by these plates):")
+		for _, m := range lo.Spare {
+			fmt.Printf("  %-10s #%d %s\n", m.Slot, m.Spool.Id, models.Sanitize(m.Spool.Filament.Name))
+		}
+	}
+}
+
+// shellQuote quotes a `fil move` argument for copy-pasting when it contains
+// anything a shell would split or expand.
+func shellQuote(s string) string {
+	if s != "" && !strings.ContainsAny(s, " \t'\"\\$`!*?[]{}()<>|&;#~") {
+		return s
+	}
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+func init() {
+	planLoadoutCmd.Flags().String("printer", "", "printer to plan for (prompts when several are configured)")
+	planLoadoutCmd.Flags().Int("plates", 5, "how many upcoming plates to consider")
+	planLoadoutCmd.Flags().Bool("schedule", false, "use the printer's saved queue from 'fil plan schedule --save'")
+	planLoadoutCmd.Flags().String("shelf", "", "where unloaded spools go (default: prompt per spool)")
+	planLoadoutCmd.Flags().Bool("apply", false, "run the move batch now")
+	planLoadoutCmd.Flags().Bool("dry-run", false, "with --apply, show what the moves would do without changing anything")
+	planCmd.AddCommand(planLoadoutCmd)
+}
@@ -0,0 +1,129 @@
+package cmd
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/dstockto/fil/models"
+)
+
+func loadoutSpool(id int, location string, filamentID int, remaining, used float64) models.FindSpool {
+	s := makeScheduleSpool(id, location, filamentID)
+	s.RemainingWeight = remaining
+	s.UsedWeight = used
+	return s
+}
+
+func TestPlanLoadout(t *testing.T) {
+	slots := []loadoutSlot{
+		{Location: "AMS A", Pos: 1, SpoolID: 1}, // black, wanted: keep
+		{Location: "AMS A", Pos: 2, SpoolID: 2}, // red, not wanted
+		{Location: "AMS A", Pos: 3, SpoolID: EmptySlot},
+	}
+	spools := []models.FindSpool{
+		loadoutSpool(1, "AMS A", 10, 500, 500),
+		loadoutSpool(2, "AMS A", 20, 800, 200),
+		loadoutSpool(3, "Shelf", 30, 1000, 0),  // white, fresh
+		loadoutSpool(4, "Shelf", 30, 300, 700), // white, partial with enough
+		loadoutSpool(5, "Shelf", 30, 40, 960),  // white, partial but too little
+		loadoutSpool(6, "AMS B", 40, 900, 100), // grey, in another printer
+		loadoutSpool(7, "Shelf", 50, 1000, 0),  // blue
+	}
+	upcoming := []upcomingPlate{
+		{Plate: "1", Needs: []models.PlateRequirement{{FilamentID: 10, Amount: 50}, {FilamentID: 30, Amount: 60}}},
+		{Plate: "2", Needs: []models.PlateRequirement{{FilamentID: 50, Amount: 20}, {FilamentID: 30, Amount: 40}}},
+		{Plate: "3", Needs: []models.PlateRequirement{{FilamentID: 40, Amount: 10}}}, // would need a 4th slot
+	}
+
+	lo := planLoadout(slots, upcoming, spools, map[string]bool{"AMS B": true})
+
+	if lo.Covered != 2 {
+		t.Errorf("Covered = %d, want 2", lo.Covered)
+	}
+	if len(lo.Keep) != 1 || lo.Keep[0].Spool.Id != 1 {
+		t.Errorf("Keep = %+v, want spool 1", lo.Keep)
+	}
+	var loads []int
+	for _, m := range lo.Load {
+		loads = append(loads, m.Spool.Id)
+	}
+	if !reflect.DeepEqual(loads, []int{4, 7}) {
+		t.Errorf("loaded spools = %v, want [4 7] (partial white with 100g+, then blue)", loads)
+	}
+	if len(lo.Unload) != 1 || lo.Unload[0].Spool.Id != 2 {
+		t.Errorf("Unload = %+v, want spool 2", lo.Unload)
+	}
+	if len(lo.Spare) != 0 {
+		t.Errorf("Spare = %+v, want none", lo.Spare)
+	}
+
+	got := lo.moveArgs("Shelf 3")
+	want := []string{"2", "Shelf 3", "4", "AMS A:3", "7", "AMS A:2"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("moveArgs = %q, want %q", got, want)
+	}
+}
+
+func TestPlanLoadout_SpareWhenSlotsSuffice(t *testing.T) {
+	slots := []loadoutSlot{
+		{Location: "AMS A", Pos: 1, SpoolID: 2},
+		{Location: "AMS A", Pos: 2, SpoolID: EmptySlot},
+	}
+	spools := []models.FindSpool{
+		loadoutSpool(2, "AMS A", 20, 800, 200),
+		loadoutSpool(3, "Shelf", 30, 1000, 0),
+	}
+	upcoming := []upcomingPlate{{Plate: "1", Needs: []models.PlateRequirement{{FilamentID: 30, Amount: 10}}}}
+
+	lo := planLoadout(slots, upcoming, spools, nil)
+
+	if len(lo.Unload) != 0 || len(lo.Spare) != 1 || lo.Spare[0].Spool.Id != 2 {
+		t.Errorf("Unload = %+v, Spare = %+v; want spool 2 spare only", lo.Unload, lo.Spare)
+	}
+	if got := lo.moveArgs(""); !reflect.DeepEqual(got, []string{"3", "AMS A:2"}) {
+		t.Errorf("moveArgs = %q", got)
+	}
+}
+
+func TestPrinterSlots(t *testing.T) {
+	orders := map[string][]int{"AMS A": {1, 9}}
+	spools := []models.FindSpool{
+		makeScheduleSpool(1, "AMS A", 10),
+		makeScheduleSpool(9, "Shelf", 90), // stale order entry
+		makeScheduleSpool(5, "External", 50),
+	}
+	got := printerSlots([]string{"AMS A", "External"}, orders, spools)
+	want := []loadoutSlot{
+		{Location: "AMS A", Pos: 1, SpoolID: 1},
+		{Location: "AMS A", Pos: 2, SpoolID: EmptySlot},
+		{Location: "External", Pos: 1, SpoolID: 5},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("printerSlots = %+v\nwant %+v", got, want)
+	}
+}
+
+func TestNextTodoPlates_RespectsAfter(t *testing.T) {
+	discovered := []DiscoveredPlan{{
+		Path: "/plans/box.yaml",
+		Plan: models.PlanFile{Projects: []models.Project{{
+			Name: "Box",
+			Plates: []models.Plate{
+				{Name: "Lid", Status: "todo", After: []string{"Base"}},
+				{Name: "Base", Status: "todo", After: []string{"Fit"}},
+				{Name: "Fit", Status: "completed"},
+				{Name: "Handle", Status: "todo"},
+				{Name: "Loop", Status: "todo", After: []string{"Loop"}},
+				{Name: "Hinge", Status: "in-progress"},
+				{Name: "Latch", Status: "todo", After: []string{"Hinge"}},
+			},
+		}}},
+	}}
+	var got []string
+	for _, p := range nextTodoPlates(discovered, 0) {
+		got = append(got, p.Plate)
+	}
+	if want := []string{"Base", "Lid", "Handle"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("order = %v, want %v", got, want)
+	}
+}
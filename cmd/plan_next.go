@@ -1,6 +1,7 @@
 package cmd
 
 import (
+	"errors"
 	"fmt"
 	"strings"
 	"time"
@@ -182,6 +183,15 @@ var planNextCmd = &cobra.Command{
 		}
 		var busyElsewhere []inProgressElsewhere
 
+		// Plates whose `after:` prerequisites aren't completed yet are shown
+		// but not offered.
+		type blockedPlate struct {
+			projectName string
+			plateName   string
+			waitingOn   []string
+		}
+		var blocked []blockedPlate
+
 		// Get current inventory & loaded spools
 		allSpools, _ := apiClient.FindSpoolsByName(ctx, "*", onlyStandardFilament, nil)
 		loadedSpools := make(map[string]models.FindSpool)
@@ -213,6 +223,10 @@ var planNextCmd = &cobra.Command{
 						}
 						continue
 					}
+					if waiting := dp.Plan.Blockers(proj.Name, plate); len(waiting) > 0 {
+						blocked = append(blocked, blockedPlate{projectName: proj.Name, plateName: plate.Name, waitingOn: waiting})
+						continue
+					}
 
 					// Calculate swap cost and readiness
 					cost := 0
@@ -269,6 +283,14 @@ var planNextCmd = &cobra.Command{
 			fmt.Println()
 		}
 
+		if len(blocked) > 0 {
+			fmt.Println("Blocked:")
+			for _, b := range blocked {
+				fmt.Printf("  %s - %s (waiting on %s)\n", models.Sanitize(b.projectName), models.Sanitize(b.plateName), models.Sanitize(strings.Join(b.waitingOn, ", ")))
+			}
+			fmt.Println()
+		}
+
 		if len(options) == 0 {
 			fmt.Println("No pending plates found.")
 			return nil
@@ -285,10 +307,41 @@ var planNextCmd = &cobra.Command{
 			}
 		}
 
+		// A saved `fil plan schedule` queue wins: its first still-todo plate
+		// for this printer goes to the top of the list.
+		schedIdx := -1
+		if sched, err := loadSchedule(ctx); err == nil {
+			for _, e := range sched.Queues[printerName] {
+				for i, o := range options {
+					if planFileName(discovered[o.discoveredIdx]) == e.Plan && o.projectName == e.Project && o.plate.Name == e.Plate {
+						schedIdx = i
+						break
+					}
+				}
+				if schedIdx >= 0 {
+					break
+				}
+			}
+		}
+		if schedIdx > 0 {
+			scheduled := options[schedIdx]
+			options = append(options[:schedIdx], options[schedIdx+1:]...)
+			options = append([]plateOption{scheduled}, options...)
+			switch {
+			case bestIdx == schedIdx:
+				bestIdx = 0
+			case bestIdx >= 0 && bestIdx < schedIdx:
+				bestIdx++
+			}
+			schedIdx = 0
+		}
+
 		var items []string
 		for i, o := range options {
 			prefix := "  "
-			if i == bestIdx {
+			if i == schedIdx {
+				prefix = "* [SCHED] "
+			} else if i == bestIdx {
 				prefix = "* [REC] "
 			}
 			readyStr := ""
@@ -793,8 +846,29 @@ var planNextCmd = &cobra.Command{
 			loadedSpools[targetLoc+"_"+fmt.Sprint(bestSpool.Id)] = *bestSpool
 		}
 
+		// Offer to start the plate's sliced file. Only the plan-server holds
+		// printer connections, so Local Mode just prints the reminder.
+		send := false
+		if choice.plate.Sliced != "" {
+			send, _ = cmd.Flags().GetBool("send")
+			if Cfg.PlansServer == "" {
+				fmt.Printf("\nSliced file %s attached; sending it needs plans_server.\n", choice.plate.Sliced)
+				send = false
+			} else if !send {
+				confirmPrompt := promptui.Prompt{
+					Label:     fmt.Sprintf("Send %s to %s and start printing", choice.plate.Sliced, printerName),
+					IsConfirm: true,
+					Stdout:    NoBellStdout,
+				}
+				_, err := confirmPrompt.Run()
+				send = err == nil
+			}
+		}
+
 		// Mark the plate as in-progress via PlanOps so the same verb runs
 		// whether the CLI is in Local Mode or delegating to a plan-server.
+		// With send, the server only records it once the printer accepts
+		// the job.
 		dp := discovered[choice.discoveredIdx]
 		_, err = PlanOps.Next(ctx, plan.NextRequest{
 			Plan:      planFileName(dp),
@@ -802,9 +876,20 @@ var planNextCmd = &cobra.Command{
 			Plate:     choice.plate.Name,
 			Printer:   printerName,
 			StartedAt: time.Now().UTC(),
+			Send:      send,
 		})
-		if err != nil {
+		switch {
+		case errors.Is(err, plan.ErrNotRecorded):
+			// The printer took the job; only the plan update failed.
+			fmt.Printf("Sent %s to %s; it is printing.\n", choice.plate.Sliced, printerName)
+			fmt.Printf("Warning: %v\nMark %s - %s in-progress by hand (fil plan edit).\n", err, models.Sanitize(choice.projectName), models.Sanitize(choice.plate.Name))
+		case err != nil:
+			if send {
+				return fmt.Errorf("job not started, plate left as todo: %w", err)
+			}
 			fmt.Printf("Warning: failed to save in-progress state: %v\n", err)
+		case send:
+			fmt.Printf("Sent %s to %s\n", choice.plate.Sliced, printerName)
 		}
 
 		if swapsPerformed {
@@ -818,4 +903,5 @@ var planNextCmd = &cobra.Command{
 
 func init() {
 	planCmd.AddCommand(planNextCmd)
+	planNextCmd.Flags().Bool("send", false, "send the plate's sliced file to the printer without asking")
 }
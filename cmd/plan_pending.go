@@ -0,0 +1,498 @@
+package cmd
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/dstockto/fil/api"
+	"github.com/dstockto/fil/models"
+	"github.com/manifoldco/promptui"
+	"github.com/spf13/cobra"
+)
+
+var planPendingCmd = &cobra.Command{
+	Use:     "pending",
+	Aliases: []string{"confirm"},
+	Short:   "Review plate transitions the plan server applied on its own",
+	Long: `Lists events the plan server detected without a human in the loop.
+
+Auto-completed plates (a printer reported FINISH) are already applied to the
+plan and Spoolman: accept them if the planned filament amounts were right,
+or amend with the real usage and the server writes the difference.
+
+Auto-detected failures (a printer reported failed, or a job was stopped
+early) are not applied yet: accept logs the fail with the estimated waste,
+amend lets you correct the grams and cause first, and dismiss drops it —
+use that when the stop was a deliberate cancel.
+
+Jobs a printer started that no plate's file matched are listed too: link
+starts the plate they belong to as of the job's real start time.
+
+Spool swaps (a printer slot started reporting a filament that doesn't match
+the spool recorded there) propose the spool that fits best: accept moves it
+into the slot, amend picks a different spool, dismiss leaves Spoolman alone.
+
+RFID spools Spoolman doesn't know yet (a new Bambu spool in an AMS) are
+offered for registration: accept creates the filament and spool in that
+slot, tagged so it's recognized from then on; amend names an existing spool
+instead, which gets the tag.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		client, err := pendingClient()
+		if err != nil {
+			return err
+		}
+		events, err := client.ListPending(cmd.Context())
+		if err != nil {
+			return fmt.Errorf("failed to fetch pending events: %w", err)
+		}
+		if len(events) == 0 {
+			fmt.Println("Nothing pending.")
+			return nil
+		}
+		for _, ev := range events {
+			printPendingEvent(ev)
+		}
+		fmt.Println("Run 'fil plan pending accept', 'amend', 'link', or 'dismiss' to confirm.")
+		return nil
+	},
+}
+
+var planPendingAcceptCmd = &cobra.Command{
+	Use:   "accept [id]",
+	Short: "Accept a pending event as recorded",
+	Args:  cobra.MaximumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		client, err := pendingClient()
+		if err != nil {
+			return err
+		}
+		all, _ := cmd.Flags().GetBool("all")
+		events, err := client.ListPending(cmd.Context())
+		if err != nil {
+			return fmt.Errorf("failed to fetch pending events: %w", err)
+		}
+		if all {
+			for _, ev := range events {
+				if err := client.AcceptPending(cmd.Context(), ev.ID); err != nil {
+					return err
+				}
+				fmt.Printf("Accepted %s.\n", pendingLabel(ev))
+			}
+			return nil
+		}
+		ev, err := selectPendingEvent(events, args)
+		if err != nil || ev == nil {
+			return err
+		}
+		if err := client.AcceptPending(cmd.Context(), ev.ID); err != nil {
+			return err
+		}
+		fmt.Printf("Accepted %s.\n", pendingLabel(*ev))
+		return nil
+	},
+}
+
+var planPendingAmendCmd = &cobra.Command{
+	Use:   "amend [id]",
+	Short: "Correct the filament deducted for a pending event",
+	Args:  cobra.MaximumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		client, err := pendingClient()
+		if err != nil {
+			return err
+		}
+		events, err := client.ListPending(cmd.Context())
+		if err != nil {
+			return fmt.Errorf("failed to fetch pending events: %w", err)
+		}
+		ev, err := selectPendingEvent(events, args)
+		if err != nil || ev == nil {
+			return err
+		}
+		if ev.Kind == "job" {
+			return fmt.Errorf("nothing to amend on an unmatched job; use 'fil plan pending link %s'", ev.ID)
+		}
+		printPendingEvent(*ev)
+
+		var amend api.PendingAmendRequest
+		switch ev.Kind {
+		case "fail":
+			amend, err = promptAmendedFail(*ev)
+		case "swap":
+			amend.SpoolID, err = promptSwapSpool(*ev)
+		case "register":
+			amend.SpoolID, err = promptRegisteredSpool(*ev)
+		default:
+			amend.Deductions, err = promptAmendedDeductions(*ev)
+			if err == nil && amend.Deductions == nil {
+				amend.Deductions = []api.PendingDeduction{}
+			}
+		}
+		if err != nil {
+			return err
+		}
+		if err := client.AmendPending(cmd.Context(), ev.ID, amend); err != nil {
+			return err
+		}
+		fmt.Printf("Amended %s.\n", pendingLabel(*ev))
+		return nil
+	},
+}
+
+var planPendingLinkCmd = &cobra.Command{
+	Use:   "link [id]",
+	Short: "Start the plate an unmatched printer job was printing",
+	Args:  cobra.MaximumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		client, err := pendingClient()
+		if err != nil {
+			return err
+		}
+		events, err := client.ListPending(cmd.Context())
+		if err != nil {
+			return fmt.Errorf("failed to fetch pending events: %w", err)
+		}
+		var jobs []api.PendingEvent
+		for _, ev := range events {
+			if ev.Kind == "job" {
+				jobs = append(jobs, ev)
+			}
+		}
+		ev, err := selectPendingEvent(jobs, args)
+		if err != nil || ev == nil {
+			return err
+		}
+
+		discovered, _ := discoverPlans()
+		type todoPlate struct {
+			plan    string
+			project string
+			plate   string
+		}
+		var plates []todoPlate
+		var items []string
+		for _, dp := range discovered {
+			for _, proj := range dp.Plan.Projects {
+				if proj.Status == "completed" {
+					continue
+				}
+				for _, plate := range proj.Plates {
+					if plate.Status != "todo" {
+						continue
+					}
+					plates = append(plates, todoPlate{plan: planFileName(dp), project: proj.Name, plate: plate.Name})
+					items = append(items, fmt.Sprintf("%s - %s (%s)", models.Sanitize(proj.Name), models.Sanitize(plate.Name), FormatDiscoveredPlan(dp)))
+				}
+			}
+		}
+		if len(plates) == 0 {
+			return fmt.Errorf("no todo plates to link %q to", ev.Job)
+		}
+		prompt := promptui.Select{
+			Label:             fmt.Sprintf("Which plate was %s printing?", models.Sanitize(ev.Job)),
+			Items:             items,
+			Stdout:            NoBellStdout,
+			StartInSearchMode: true,
+			Searcher: func(input string, index int) bool {
+				return strings.Contains(strings.ToLower(items[index]), strings.ToLower(input))
+			},
+		}
+		idx, _, err := prompt.Run()
+		if err != nil {
+			return err
+		}
+		p := plates[idx]
+		if err := client.LinkPending(cmd.Context(), ev.ID, p.plan, p.project, p.plate); err != nil {
+			return err
+		}
+		fmt.Printf("Started %s - %s on %s as of %s.\n", models.Sanitize(p.project), models.Sanitize(p.plate),
+			models.Sanitize(ev.Printer), ev.OccurredAt.Local().Format("Jan 2 15:04"))
+		return nil
+	},
+}
+
+var planPendingDismissCmd = &cobra.Command{
+	Use:   "dismiss [id]",
+	Short: "Drop a pending event without applying it",
+	Args:  cobra.MaximumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		client, err := pendingClient()
+		if err != nil {
+			return err
+		}
+		events, err := client.ListPending(cmd.Context())
+		if err != nil {
+			return fmt.Errorf("failed to fetch pending events: %w", err)
+		}
+		ev, err := selectPendingEvent(events, args)
+		if err != nil || ev == nil {
+			return err
+		}
+		if err := client.DismissPending(cmd.Context(), ev.ID); err != nil {
+			return err
+		}
+		fmt.Printf("Dismissed %s.\n", pendingLabel(*ev))
+		return nil
+	},
+}
+
+func pendingClient() (*api.PlanServerClient, error) {
+	if Cfg == nil || Cfg.PlansServer == "" {
+		return nil, fmt.Errorf("plans_server must be configured")
+	}
+	return api.NewPlanServerClient(Cfg.PlansServer, version, Cfg.TLSSkipVerify), nil
+}
+
+// pendingLabel is the one-line description used in prompts and summaries.
+func pendingLabel(ev api.PendingEvent) string {
+	if ev.Kind == "job" {
+		return fmt.Sprintf("unmatched job %s on %s", models.Sanitize(ev.Job), models.Sanitize(ev.Printer))
+	}
+	if ev.Kind == "swap" && ev.Swap != nil {
+		return fmt.Sprintf("spool swap: #%d into %s:%d", ev.Swap.SpoolID, models.Sanitize(ev.Swap.Location), ev.Swap.Slot)
+	}
+	if ev.Kind == "register" && ev.Register != nil {
+		r := ev.Register
+		return fmt.Sprintf("new spool: %s %s into %s:%d", models.Sanitize(r.Vendor), models.Sanitize(r.Name), models.Sanitize(r.Location), r.Slot)
+	}
+	var plates []string
+	for _, p := range ev.Plates {
+		plates = append(plates, fmt.Sprintf("%s / %s", p.Project, p.Plate))
+	}
+	return fmt.Sprintf("%s %s on %s", ev.Kind, models.Sanitize(strings.Join(plates, ", ")), models.Sanitize(ev.Printer))
+}
+
+func printPendingEvent(ev api.PendingEvent) {
+	fmt.Printf("[%s] %s (%s)\n", ev.ID, pendingLabel(ev), ev.OccurredAt.Local().Format("Jan 2 15:04"))
+	if ev.Kind == "job" {
+		fmt.Printf("    no plate's file matched; link it with: fil plan pending link %s\n", ev.ID)
+	}
+	if ev.Swap != nil {
+		fmt.Printf("    printer reports %s; not yet moved", models.Sanitize(ev.Swap.Tray))
+		if ev.Swap.From != "" {
+			fmt.Printf(" (#%d is recorded in %s)", ev.Swap.SpoolID, models.Sanitize(ev.Swap.From))
+		}
+		fmt.Println()
+		if ev.Swap.Displaced > 0 {
+			fmt.Printf("    replaces #%d\n", ev.Swap.Displaced)
+		}
+		if len(ev.Candidates) > 0 {
+			fmt.Printf("    also fits: %s\n", spoolIDList(ev.Candidates))
+		}
+	}
+	if r := ev.Register; r != nil {
+		fmt.Printf("    RFID %s: %s #%s", models.Sanitize(r.RFID), models.Sanitize(r.Material), models.Sanitize(r.ColorHex))
+		if r.Weight > 0 {
+			fmt.Printf(", %.0fg", r.Weight)
+		}
+		if r.Remaining > 0 {
+			fmt.Printf(" (~%.0fg left)", r.Remaining)
+		}
+		fmt.Println("; not yet in Spoolman")
+	}
+	if ev.Fail != nil {
+		fmt.Printf("    not yet logged: ~%.1fg wasted, cause=%s\n", ev.Fail.UsedGrams, ev.Fail.Cause)
+		if ev.Fail.Reason != "" {
+			fmt.Printf("    %s\n", models.Sanitize(ev.Fail.Reason))
+		}
+	}
+	for _, d := range ev.Deductions {
+		fmt.Printf("    deducted %.1fg from spool #%d\n", d.Amount, d.SpoolID)
+	}
+	for _, n := range ev.Unmatched {
+		fmt.Printf("    not deducted: %s %.1fg (no single matching spool loaded)\n", models.Sanitize(n.Name), n.Amount)
+	}
+	if ev.Error != "" {
+		fmt.Printf("    warning: %s\n", models.Sanitize(ev.Error))
+	}
+}
+
+// selectPendingEvent resolves the event to act on: by ID when given, the only
+// event when there's exactly one, otherwise via a prompt. Returns nil with no
+// error when there's nothing pending.
+func selectPendingEvent(events []api.PendingEvent, args []string) (*api.PendingEvent, error) {
+	if len(events) == 0 {
+		fmt.Println("Nothing pending.")
+		return nil, nil
+	}
+	if len(args) == 1 {
+		for i := range events {
+			if events[i].ID == args[0] {
+				return &events[i], nil
+			}
+		}
+		return nil, fmt.Errorf("no pending event with id %q", args[0])
+	}
+	if len(events) == 1 {
+		return &events[0], nil
+	}
+	items := make([]string, 0, len(events))
+	for _, ev := range events {
+		items = append(items, pendingLabel(ev))
+	}
+	prompt := promptui.Select{
+		Label:             "Which event?",
+		Items:             items,
+		Stdout:            NoBellStdout,
+		StartInSearchMode: true,
+		Searcher: func(input string, index int) bool {
+			return strings.Contains(strings.ToLower(items[index]), strings.ToLower(input))
+		},
+	}
+	idx, _, err := prompt.Run()
+	if err != nil {
+		return nil, err
+	}
+	return &events[idx], nil
+}
+
+// promptAmendedDeductions walks the recorded deductions asking for the real
+// grams (blank keeps the recorded value), then lets the user add spools for
+// anything the server couldn't match.
+func promptAmendedDeductions(ev api.PendingEvent) ([]api.PendingDeduction, error) {
+	var out []api.PendingDeduction
+	for _, d := range ev.Deductions {
+		grams, err := readGramsDefault(fmt.Sprintf("Spool #%d grams used (default %.1fg): ", d.SpoolID, d.Amount), d.Amount)
+		if err != nil {
+			return nil, err
+		}
+		if grams > 0 {
+			out = append(out, api.PendingDeduction{SpoolID: d.SpoolID, Amount: grams})
+		}
+	}
+	for {
+		s, err := readLine("Additional spool ID to deduct from (blank to finish): ")
+		if err != nil {
+			return nil, err
+		}
+		s = strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(s), "#"))
+		if s == "" {
+			return out, nil
+		}
+		id, err := strconv.Atoi(s)
+		if err != nil || id <= 0 {
+			fmt.Println("  enter a spool ID")
+			continue
+		}
+		grams, err := readGramsDefault(fmt.Sprintf("Spool #%d grams used: ", id), 0)
+		if err != nil {
+			return nil, err
+		}
+		if grams > 0 {
+			out = append(out, api.PendingDeduction{SpoolID: id, Amount: grams})
+		}
+	}
+}
+
+// promptAmendedFail asks for the real waste and cause of an auto-detected
+// failure, defaulting to the server's estimate.
+func promptAmendedFail(ev api.PendingEvent) (api.PendingAmendRequest, error) {
+	var out api.PendingAmendRequest
+	estimate := 0.0
+	if ev.Fail != nil {
+		estimate = ev.Fail.UsedGrams
+	}
+	grams, err := readGramsDefault(fmt.Sprintf("Total grams wasted (default %.1fg): ", estimate), estimate)
+	if err != nil {
+		return out, err
+	}
+	out.UsedGrams = &grams
+	cause, err := selectFailCause()
+	if err != nil {
+		return out, err
+	}
+	out.Cause = cause
+	reason, err := readLine("Reason (optional, press enter to keep the detected one): ")
+	if err != nil {
+		return out, err
+	}
+	out.Reason = strings.TrimSpace(reason)
+	return out, nil
+}
+
+// promptSwapSpool asks which spool is really in a swapped slot, offering the
+// server's other candidates; blank keeps the proposed one.
+func promptSwapSpool(ev api.PendingEvent) (int, error) {
+	if ev.Swap == nil {
+		return 0, fmt.Errorf("pending swap has no move")
+	}
+	prompt := fmt.Sprintf("Spool ID in %s:%d (default #%d", ev.Swap.Location, ev.Swap.Slot, ev.Swap.SpoolID)
+	if len(ev.Candidates) > 0 {
+		prompt += ", or " + spoolIDList(ev.Candidates)
+	}
+	prompt += "): "
+	for {
+		s, err := readLine(prompt)
+		if err != nil {
+			return 0, err
+		}
+		s = strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(s), "#"))
+		if s == "" {
+			return ev.Swap.SpoolID, nil
+		}
+		id, err := strconv.Atoi(s)
+		if err != nil || id <= 0 {
+			fmt.Println("  enter a spool ID")
+			continue
+		}
+		return id, nil
+	}
+}
+
+// promptRegisteredSpool asks for the existing spool an unknown RFID tag
+// belongs to.
+func promptRegisteredSpool(ev api.PendingEvent) (int, error) {
+	if ev.Register == nil {
+		return 0, fmt.Errorf("pending registration has no spool")
+	}
+	for {
+		s, err := readLine(fmt.Sprintf("Existing spool ID in %s:%d: ", ev.Register.Location, ev.Register.Slot))
+		if err != nil {
+			return 0, err
+		}
+		id, err := strconv.Atoi(strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(s), "#")))
+		if err != nil || id <= 0 {
+			fmt.Println("  enter a spool ID")
+			continue
+		}
+		return id, nil
+	}
+}
+
+func spoolIDList(ids []int) string {
+	parts := make([]string, 0, len(ids))
+	for _, id := range ids {
+		parts = append(parts, fmt.Sprintf("#%d", id))
+	}
+	return strings.Join(parts, ", ")
+}
+
+// readGramsDefault is readGrams with a caller-chosen value for blank input.
+func readGramsDefault(prompt string, def float64) (float64, error) {
+	for {
+		s, err := readLine(prompt)
+		if err != nil {
+			return 0, err
+		}
+		s = strings.TrimSpace(strings.TrimSuffix(strings.TrimSpace(s), "g"))
+		if s == "" {
+			return def, nil
+		}
+		v, err := strconv.ParseFloat(s, 64)
+		if err != nil || v < 0 {
+			fmt.Println("  enter a non-negative number")
+			continue
+		}
+		return v, nil
+	}
+}
+
+func init() {
+	planPendingAcceptCmd.Flags().Bool("all", false, "accept every pending event")
+	planPendingCmd.AddCommand(planPendingAcceptCmd)
+	planPendingCmd.AddCommand(planPendingAmendCmd)
+	planPendingCmd.AddCommand(planPendingLinkCmd)
+	planPendingCmd.AddCommand(planPendingDismissCmd)
+	planCmd.AddCommand(planPendingCmd)
+}
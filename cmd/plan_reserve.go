@@ -0,0 +1,204 @@
+package cmd
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/dstockto/fil/api"
+	"github.com/dstockto/fil/models"
+	"github.com/spf13/cobra"
+)
+
+var planReserveCmd = &cobra.Command{
+	Use:   "reserve [plan...]",
+	Short: "Reserve grams on specific spools for plan needs",
+	Long: `Earmarks grams of specific spools for each unreserved need of the plates still
+to print, so two plans can't both count on the same spool. Reservations are
+stored on the needs in the plan YAML; 'fil plan check', 'fil find', and 'fil low'
+show reserved vs free grams. Completing a plate releases its reservations, and
+auto-complete and fail draw from the reserved spool when it's loaded.
+
+A need goes to the spool with the least free grams that still covers it (so
+partly used spools go first); if no spool covers it, it is split across
+spools with the most free grams. Without arguments every active plan is
+reserved; --clear drops the reservations instead.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if Cfg == nil || Cfg.ApiBase == "" {
+			return fmt.Errorf("api endpoint not configured")
+		}
+		if PlanOps == nil {
+			return fmt.Errorf("plan operations not configured (need either plans_server or api_base+plans_dir)")
+		}
+		ctx := cmd.Context()
+		release, _ := cmd.Flags().GetBool("clear")
+		dryRun, _ := cmd.Flags().GetBool("dry-run")
+
+		discovered, err := discoverPlans()
+		if err != nil {
+			return err
+		}
+		targets := discovered
+		if len(args) > 0 {
+			targets = nil
+			for _, dp := range discovered {
+				name := planFileName(dp)
+				for _, a := range args {
+					if name == a || strings.TrimSuffix(name, ".yaml") == strings.TrimSuffix(a, ".yaml") {
+						targets = append(targets, dp)
+						break
+					}
+				}
+			}
+			if len(targets) == 0 {
+				return fmt.Errorf("no active plan matches %s", strings.Join(args, ", "))
+			}
+		}
+
+		var spools []models.FindSpool
+		reserved := map[int]float64{}
+		if !release {
+			apiClient := api.NewClient(Cfg.ApiBase, Cfg.TLSSkipVerify)
+			spools, err = apiClient.FindSpoolsByName(ctx, "*", onlyStandardFilament, nil)
+			if err != nil {
+				return fmt.Errorf("failed to fetch spools: %w", err)
+			}
+			var plans []models.PlanFile
+			for _, dp := range discovered {
+				plans = append(plans, dp.Plan)
+			}
+			reserved = models.Reservations(plans...)
+		}
+
+		for _, dp := range targets {
+			changed := 0
+			for pi := range dp.Plan.Projects {
+				proj := &dp.Plan.Projects[pi]
+				if proj.Status == "completed" {
+					continue
+				}
+				for pj := range proj.Plates {
+					plate := &proj.Plates[pj]
+					if plate.Status == "completed" {
+						continue
+					}
+					for k := range plate.Needs {
+						need := &plate.Needs[k]
+						label := fmt.Sprintf("%s / %s: %s", models.Sanitize(proj.Name), models.Sanitize(plate.Name), models.Sanitize(need.Name))
+						if release {
+							if len(need.Reserved) > 0 {
+								fmt.Printf("  %s — released %.1fg\n", label, need.ReservedGrams())
+								need.Reserved = nil
+								changed++
+							}
+							continue
+						}
+						want := need.Amount - need.ReservedGrams()
+						if need.FilamentID == 0 || want <= 0.05 {
+							continue
+						}
+						got, short := reserveGrams(spools, need.FilamentID, want, reserved)
+						for _, r := range got {
+							need.Reserved = append(need.Reserved, r)
+							fmt.Printf("  %s — %.1fg on #%d\n", label, r.Grams, r.SpoolID)
+							changed++
+						}
+						if short > 0.05 {
+							fmt.Printf("  ! %s — %.1fg could not be reserved (not enough free filament)\n", label, short)
+						}
+					}
+				}
+			}
+			if changed == 0 {
+				continue
+			}
+			if dryRun {
+				fmt.Printf("Would update %d reservation(s) in %s\n", changed, FormatDiscoveredPlan(dp))
+				continue
+			}
+			if err := PlanOps.SaveAll(ctx, planFileName(dp), dp.Plan); err != nil {
+				return fmt.Errorf("failed to save %s: %w", planFileName(dp), err)
+			}
+			fmt.Printf("Updated %d reservation(s) in %s\n", changed, FormatDiscoveredPlan(dp))
+		}
+		return nil
+	},
+}
+
+// reserveGrams picks spools of filamentID to hold grams, given what's already
+// reserved per spool (updated in place). The spool with the least free grams
+// that still covers the whole amount wins; otherwise the amount is split
+// across the spools with the most free grams. Returns the reservations and
+// any shortfall.
+func reserveGrams(spools []models.FindSpool, filamentID int, grams float64, reserved map[int]float64) ([]models.SpoolReservation, float64) {
+	type candidate struct {
+		id   int
+		free float64
+	}
+	var cands []candidate
+	for _, s := range spools {
+		if s.Archived || s.Filament.Id != filamentID {
+			continue
+		}
+		if free := s.RemainingWeight - reserved[s.Id]; free > 0.05 {
+			cands = append(cands, candidate{id: s.Id, free: free})
+		}
+	}
+
+	sort.SliceStable(cands, func(i, j int) bool {
+		if cands[i].free != cands[j].free {
+			return cands[i].free < cands[j].free
+		}
+		return cands[i].id < cands[j].id
+	})
+	for _, c := range cands {
+		if c.free >= grams {
+			g := RoundAmount(grams)
+			reserved[c.id] += g
+			return []models.SpoolReservation{{SpoolID: c.id, Grams: g}}, 0
+		}
+	}
+
+	var out []models.SpoolReservation
+	for i := len(cands) - 1; i >= 0 && grams > 0.05; i-- {
+		g := RoundAmount(min(grams, cands[i].free))
+		reserved[cands[i].id] += g
+		out = append(out, models.SpoolReservation{SpoolID: cands[i].id, Grams: g})
+		grams -= g
+	}
+	return out, max(grams, 0)
+}
+
+// loadReservations totals reserved grams per spool across the active plans.
+// Nil when no plans are configured or they can't be read — reservations are
+// an annotation, never a reason for find/low to fail.
+func loadReservations() map[int]float64 {
+	if Cfg == nil || (Cfg.PlansDir == "" && Cfg.PlansServer == "") {
+		return nil
+	}
+	discovered, err := discoverPlans()
+	if err != nil {
+		return nil
+	}
+	var plans []models.PlanFile
+	for _, dp := range discovered {
+		plans = append(plans, dp.Plan)
+	}
+	return models.Reservations(plans...)
+}
+
+// reservedNote renders " [Xg reserved, Yg free]" for a spool with
+// reservations, or "" without.
+func reservedNote(s models.FindSpool, reserved map[int]float64) string {
+	r := reserved[s.Id]
+	if r <= 0 {
+		return ""
+	}
+	return fmt.Sprintf(" [%.1fg reserved, %.1fg free]", r, s.RemainingWeight-r)
+}
+
+func init() {
+	planReserveCmd.Flags().Bool("clear", false, "release the reservations instead of making them")
+	planReserveCmd.Flags().Bool("dry-run", false, "show the reservations without saving")
+	planCmd.AddCommand(planReserveCmd)
+}
@@ -0,0 +1,53 @@
+package cmd
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/dstockto/fil/models"
+)
+
+func TestReserveGrams(t *testing.T) {
+	spools := []models.FindSpool{
+		loadoutSpool(1, "Shelf", 10, 1000, 0),
+		loadoutSpool(2, "Shelf", 10, 300, 700),
+		loadoutSpool(3, "Shelf", 10, 120, 880),
+		loadoutSpool(4, "Shelf", 20, 50, 950),
+	}
+
+	t.Run("smallest covering spool", func(t *testing.T) {
+		reserved := map[int]float64{}
+		got, short := reserveGrams(spools, 10, 100, reserved)
+		want := []models.SpoolReservation{{SpoolID: 3, Grams: 100}}
+		if !reflect.DeepEqual(got, want) || short != 0 {
+			t.Errorf("got %+v short %v, want %+v", got, short, want)
+		}
+		if reserved[3] != 100 {
+			t.Errorf("reserved[3] = %v, want 100", reserved[3])
+		}
+	})
+
+	t.Run("existing reservations reduce free grams", func(t *testing.T) {
+		reserved := map[int]float64{3: 100, 2: 250}
+		got, _ := reserveGrams(spools, 10, 100, reserved)
+		if len(got) != 1 || got[0].SpoolID != 1 {
+			t.Errorf("got %+v, want spool 1 (others lack free grams)", got)
+		}
+	})
+
+	t.Run("split when nothing covers", func(t *testing.T) {
+		reserved := map[int]float64{1: 900}
+		got, short := reserveGrams(spools, 10, 450, reserved)
+		want := []models.SpoolReservation{{SpoolID: 2, Grams: 300}, {SpoolID: 3, Grams: 120}, {SpoolID: 1, Grams: 30}}
+		if !reflect.DeepEqual(got, want) || short != 0 {
+			t.Errorf("got %+v short %v, want %+v", got, short, want)
+		}
+	})
+
+	t.Run("shortfall", func(t *testing.T) {
+		got, short := reserveGrams(spools, 20, 80, map[int]float64{})
+		if len(got) != 1 || got[0].Grams != 50 || short != 30 {
+			t.Errorf("got %+v short %v, want 50g and 30g short", got, short)
+		}
+	})
+}
@@ -0,0 +1,233 @@
+package cmd
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/dstockto/fil/models"
+	"github.com/dstockto/fil/plan"
+	"github.com/fatih/color"
+)
+
+// runOut is a need whose spool would run dry partway through its plate.
+type runOut struct {
+	Plate     upcomingPlate
+	Need      models.PlateRequirement
+	Spool     models.FindSpool // the spool the plate would start on
+	Available float64          // grams that spool has left when the plate starts
+	Swap      *models.FindSpool
+	Instead   *models.FindSpool
+}
+
+// Percent is how far into the need the spool runs dry.
+func (r runOut) Percent() float64 {
+	if r.Need.Amount <= 0 {
+		return 0
+	}
+	return r.Available / r.Need.Amount * 100
+}
+
+// simulationQueue orders the unfinished plates as they'll be printed:
+// in-progress plates first, then the saved schedule by start time, then any
+// todo plates the schedule doesn't cover, in plan order.
+func simulationQueue(discovered []DiscoveredPlan, sched plan.Schedule) []upcomingPlate {
+	var queue []upcomingPlate
+	for _, dp := range discovered {
+		for _, proj := range dp.Plan.Projects {
+			if proj.Status == "completed" {
+				continue
+			}
+			for _, plate := range proj.Plates {
+				if plate.Status == "in-progress" {
+					queue = append(queue, upcomingPlate{Project: proj.Name, Plate: plate.Name, Printer: plate.Printer, Needs: plate.Needs})
+				}
+			}
+		}
+	}
+
+	type entry struct {
+		printer string
+		plate   plan.ScheduledPlate
+	}
+	var scheduled []entry
+	for printer, q := range sched.Queues {
+		for _, e := range q {
+			scheduled = append(scheduled, entry{printer: printer, plate: e})
+		}
+	}
+	sort.SliceStable(scheduled, func(i, j int) bool {
+		a, b := scheduled[i], scheduled[j]
+		if !a.plate.Start.Equal(b.plate.Start) {
+			return a.plate.Start.Before(b.plate.Start)
+		}
+		return a.printer < b.printer
+	})
+	seen := map[string]bool{}
+	for _, e := range scheduled {
+		for _, p := range scheduledPlates(discovered, []plan.ScheduledPlate{e.plate}, 1) {
+			p.Printer = e.printer
+			queue = append(queue, p)
+			seen[p.Project+"\x00"+p.Plate] = true
+		}
+	}
+
+	for _, p := range nextTodoPlates(discovered, 0) {
+		if !seen[p.Project+"\x00"+p.Plate] {
+			queue = append(queue, p)
+		}
+	}
+	return queue
+}
+
+// simulateRunOuts walks the queue plate by plate, deducting each need from the
+// spool that would actually feed it, and reports every need whose spool runs
+// dry mid-plate. The feeding spool is a reserved spool loaded in the printer,
+// then any loaded spool of that filament (most left first), then a reserved
+// spool, then the shelf spool with the least left that still covers the need.
+// printers maps printer name to its locations; a plate without a known
+// printer can use any of them.
+func simulateRunOuts(queue []upcomingPlate, spools []models.FindSpool, printers map[string][]string) []runOut {
+	left := make(map[int]float64, len(spools))
+	for _, s := range spools {
+		left[s.Id] = s.RemainingWeight
+	}
+	allPrinterLocs := map[string]bool{}
+	for _, locs := range printers {
+		for _, l := range locs {
+			allPrinterLocs[l] = true
+		}
+	}
+
+	var out []runOut
+	for _, p := range queue {
+		loaded := allPrinterLocs
+		if locs, ok := printers[p.Printer]; ok {
+			loaded = map[string]bool{}
+			for _, l := range locs {
+				loaded[l] = true
+			}
+		}
+		for _, need := range p.Needs {
+			if need.FilamentID == 0 || need.Amount <= 0 {
+				continue
+			}
+			var cands []models.FindSpool
+			for _, s := range spools {
+				if !s.Archived && s.Filament.Id == need.FilamentID && left[s.Id] > 0.05 {
+					cands = append(cands, s)
+				}
+			}
+			spool, ok := feedingSpool(cands, need, loaded, left)
+			if !ok {
+				continue
+			}
+			if left[spool.Id] >= need.Amount {
+				left[spool.Id] -= need.Amount
+				continue
+			}
+
+			r := runOut{Plate: p, Need: need, Spool: spool, Available: left[spool.Id]}
+			short := need.Amount - left[spool.Id]
+			r.Swap = smallestCovering(cands, short, spool.Id, left)
+			r.Instead = smallestCovering(cands, need.Amount, spool.Id, left)
+			out = append(out, r)
+
+			left[spool.Id] = 0
+			if r.Swap != nil {
+				left[r.Swap.Id] -= short
+			}
+		}
+	}
+	return out
+}
+
+// feedingSpool picks the spool a need would print from; see simulateRunOuts.
+func feedingSpool(cands []models.FindSpool, need models.PlateRequirement, loaded map[string]bool, left map[int]float64) (models.FindSpool, bool) {
+	reserved := map[int]bool{}
+	for _, r := range need.Reserved {
+		reserved[r.SpoolID] = true
+	}
+	var inPrinter []models.FindSpool
+	for _, s := range cands {
+		if loaded[s.Location] {
+			if reserved[s.Id] {
+				return s, true
+			}
+			inPrinter = append(inPrinter, s)
+		}
+	}
+	if len(inPrinter) > 0 {
+		sort.SliceStable(inPrinter, func(i, j int) bool { return left[inPrinter[i].Id] > left[inPrinter[j].Id] })
+		return inPrinter[0], true
+	}
+	for _, s := range cands {
+		if reserved[s.Id] {
+			return s, true
+		}
+	}
+	if s := smallestCovering(cands, need.Amount, 0, left); s != nil {
+		return *s, true
+	}
+	if len(cands) == 0 {
+		return models.FindSpool{}, false
+	}
+	best := cands[0]
+	for _, s := range cands[1:] {
+		if left[s.Id] > left[best.Id] {
+			best = s
+		}
+	}
+	return best, true
+}
+
+// smallestCovering returns the spool, other than skip, with the least left
+// that still holds grams; nil when none does. The copy's RemainingWeight is
+// what's left at that point in the queue.
+func smallestCovering(cands []models.FindSpool, grams float64, skip int, left map[int]float64) *models.FindSpool {
+	var best *models.FindSpool
+	for i := range cands {
+		s := &cands[i]
+		if s.Id == skip || left[s.Id] < grams {
+			continue
+		}
+		if best == nil || left[s.Id] < left[best.Id] || (left[s.Id] == left[best.Id] && s.Id < best.Id) {
+			best = s
+		}
+	}
+	if best == nil {
+		return nil
+	}
+	c := *best
+	c.RemainingWeight = left[c.Id]
+	return &c
+}
+
+// printRunOuts lists each predicted run-out with what to do about it.
+func printRunOuts(runOuts []runOut) {
+	if len(runOuts) == 0 {
+		return
+	}
+	fmt.Printf("\n%s\n", color.New(color.Bold).Sprint("Run-out forecast (queue order):"))
+	for _, r := range runOuts {
+		loc := models.Sanitize(r.Spool.Location)
+		if loc == "" {
+			loc = "N/A"
+		}
+		fmt.Printf("  %s %s / %s: %s needs %.1fg, spool #%d in %s has %.1fg — runs dry about %.0f%% through\n",
+			color.RedString("!"),
+			models.Sanitize(r.Plate.Project), models.Sanitize(r.Plate.Plate), models.Sanitize(r.Need.Name),
+			r.Need.Amount, r.Spool.Id, loc, r.Available, r.Percent())
+		switch {
+		case r.Instead != nil:
+			fmt.Printf("      use #%d (%.1fg, %s) for the whole plate", r.Instead.Id, r.Instead.RemainingWeight, models.Sanitize(r.Instead.Location))
+			if r.Swap != nil && r.Swap.Id != r.Instead.Id {
+				fmt.Printf(", or swap in #%d (%.1fg) mid-print", r.Swap.Id, r.Swap.RemainingWeight)
+			}
+			fmt.Println()
+		case r.Swap != nil:
+			fmt.Printf("      swap in #%d (%.1fg, %s) mid-print\n", r.Swap.Id, r.Swap.RemainingWeight, models.Sanitize(r.Swap.Location))
+		default:
+			fmt.Printf("      no other spool can cover the remaining %.1fg\n", r.Need.Amount-r.Available)
+		}
+	}
+}
@@ -0,0 +1,98 @@
+package cmd
+
+import (
+	"testing"
+	"time"
+
+	"github.com/dstockto/fil/models"
+	"github.com/dstockto/fil/plan"
+)
+
+func TestSimulateRunOuts(t *testing.T) {
+	spools := []models.FindSpool{
+		loadoutSpool(1, "AMS B", 10, 40, 960),  // loaded, too little for the plate
+		loadoutSpool(2, "Shelf", 10, 30, 970),  // enough for the rest
+		loadoutSpool(3, "Shelf", 10, 800, 200), // enough for the whole plate
+		loadoutSpool(4, "AMS B", 20, 500, 500),
+	}
+	queue := []upcomingPlate{
+		{Project: "Widget", Plate: "Base", Needs: []models.PlateRequirement{
+			{Name: "Black", FilamentID: 10, Amount: 60},
+			{Name: "White", FilamentID: 20, Amount: 100},
+		}},
+		{Project: "Widget", Plate: "Lid", Needs: []models.PlateRequirement{{Name: "White", FilamentID: 20, Amount: 450}}},
+	}
+
+	got := simulateRunOuts(queue, spools, map[string][]string{"X1C": {"AMS B"}})
+
+	if len(got) != 2 {
+		t.Fatalf("got %d run-outs, want 2: %+v", len(got), got)
+	}
+	base := got[0]
+	if base.Plate.Plate != "Base" || base.Spool.Id != 1 || base.Available != 40 {
+		t.Errorf("first run-out = %+v, want Base on spool 1 with 40g", base)
+	}
+	if p := base.Percent(); p < 66 || p > 67 {
+		t.Errorf("Percent = %.1f, want ~66.7", p)
+	}
+	if base.Swap == nil || base.Swap.Id != 2 {
+		t.Errorf("Swap = %+v, want spool 2 (smallest that covers the last 20g)", base.Swap)
+	}
+	if base.Instead == nil || base.Instead.Id != 3 {
+		t.Errorf("Instead = %+v, want spool 3", base.Instead)
+	}
+
+	// White had 500g; after Base's 100g only 400g is left for Lid's 450g.
+	lid := got[1]
+	if lid.Plate.Plate != "Lid" || lid.Spool.Id != 4 || lid.Available != 400 {
+		t.Errorf("second run-out = %+v, want Lid on spool 4 with 400g", lid)
+	}
+	if lid.Swap != nil || lid.Instead != nil {
+		t.Errorf("Lid suggestions = %+v / %+v, want none", lid.Swap, lid.Instead)
+	}
+}
+
+func TestSimulateRunOuts_ShelfPicksCoveringSpool(t *testing.T) {
+	spools := []models.FindSpool{
+		loadoutSpool(1, "Shelf", 10, 40, 960),
+		loadoutSpool(2, "Shelf", 10, 800, 200),
+	}
+	queue := []upcomingPlate{{Plate: "1", Needs: []models.PlateRequirement{{FilamentID: 10, Amount: 60}}}}
+	if got := simulateRunOuts(queue, spools, nil); len(got) != 0 {
+		t.Errorf("got %+v, want no run-outs (spool 2 covers the plate)", got)
+	}
+}
+
+func TestSimulationQueue(t *testing.T) {
+	discovered := []DiscoveredPlan{{
+		Path: "/plans/widget.yaml",
+		Plan: models.PlanFile{Projects: []models.Project{{
+			Name: "Widget",
+			Plates: []models.Plate{
+				{Name: "A", Status: "todo"},
+				{Name: "B", Status: "todo"},
+				{Name: "C", Status: "in-progress", Printer: "MK4"},
+				{Name: "D", Status: "todo"},
+			},
+		}}},
+	}}
+	t0 := time.Date(2026, 5, 1, 12, 0, 0, 0, time.UTC)
+	sched := plan.Schedule{Queues: map[string][]plan.ScheduledPlate{
+		"X1C": {{Plan: "widget.yaml", Project: "Widget", Plate: "D", Start: t0}},
+		"MK4": {{Plan: "widget.yaml", Project: "Widget", Plate: "B", Start: t0.Add(time.Hour)}},
+	}}
+
+	var got []string
+	for _, p := range simulationQueue(discovered, sched) {
+		got = append(got, p.Plate+"@"+p.Printer)
+	}
+	want := []string{"C@MK4", "D@X1C", "B@MK4", "A@"}
+	if len(got) != len(want) {
+		t.Fatalf("queue = %v, want %v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("queue = %v, want %v", got, want)
+		}
+	}
+}
@@ -0,0 +1,257 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/dstockto/fil/api"
+	"github.com/dstockto/fil/models"
+	"github.com/dstockto/fil/plan"
+	"github.com/spf13/cobra"
+)
+
+var planScheduleCmd = &cobra.Command{
+	Use:   "schedule",
+	Short: "Queue every todo plate across all printers, minimizing spool swaps",
+	Long: `Builds an ordered queue per configured printer from every todo plate in the
+active plans. Plates go to whichever printer frees up first, picking the plate
+that needs the fewest spool loads given what that printer has (or will have)
+loaded, and keeping a plan's plates together where that costs nothing extra.
+Higher-priority (then sooner-due) plans go first, and a plate with 'after:'
+prerequisites is queued to start once they finish. Printer slots come from the
+capacity of each printer's locations.
+
+With --save, the queue is stored (on the plan server when one is configured)
+and 'fil plan next' offers each printer's scheduled plate first.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if Cfg == nil || Cfg.ApiBase == "" {
+			return fmt.Errorf("api endpoint not configured")
+		}
+		if len(Cfg.Printers) == 0 {
+			return fmt.Errorf("no printers configured in config.json")
+		}
+		ctx := cmd.Context()
+		apiClient := api.NewClient(Cfg.ApiBase, Cfg.TLSSkipVerify)
+
+		discovered, err := discoverPlans()
+		if err != nil {
+			return err
+		}
+		spools, err := apiClient.FindSpoolsByName(ctx, "*", onlyStandardFilament, nil)
+		if err != nil {
+			return fmt.Errorf("failed to fetch spools: %w", err)
+		}
+
+		now := time.Now().UTC()
+		printers, plates := scheduleInputs(discovered, Cfg.Printers, Cfg.LocationCapacity, spools, now)
+		sched := plan.BuildSchedule(now, printers, plates)
+
+		if asJSON, _ := cmd.Flags().GetBool("json"); asJSON {
+			enc := json.NewEncoder(cmd.OutOrStdout())
+			enc.SetIndent("", "  ")
+			if err := enc.Encode(sched); err != nil {
+				return err
+			}
+		} else {
+			queued := 0
+			for _, q := range sched.Queues {
+				queued += len(q)
+			}
+			printSchedule(sched, printers, queued, filamentLabels(spools))
+			if left := len(plates) - queued; left > 0 {
+				fmt.Printf("%d plate(s) left out: an 'after:' prerequisite is neither schedulable nor printing, or the prerequisites form a cycle (see 'fil plan check --lint').\n", left)
+			}
+		}
+
+		if save, _ := cmd.Flags().GetBool("save"); save {
+			if err := saveSchedule(ctx, sched); err != nil {
+				return fmt.Errorf("failed to save schedule: %w", err)
+			}
+			if asJSON, _ := cmd.Flags().GetBool("json"); !asJSON {
+				fmt.Println("Schedule saved; 'fil plan next' will offer these plates first.")
+			}
+		}
+		return nil
+	},
+}
+
+// scheduleInputs turns the discovered plans and printer config into the
+// scheduler's inputs. Printer slots are the summed capacity of its locations
+// (a location without a configured capacity counts as one slot); loaded
+// filaments are the spools currently in those locations; a printer with an
+// in-progress plate is busy until that plate's estimate runs out. Plates carry
+// their plan's rank and their unfinished `after:` prerequisites.
+func scheduleInputs(discovered []DiscoveredPlan, printerCfg map[string]PrinterConfig, capacity map[string]LocationCapacity, spools []models.FindSpool, now time.Time) ([]plan.SchedulePrinter, []plan.SchedulePlate) {
+	var names []string
+	for name := range printerCfg {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	printers := make([]plan.SchedulePrinter, 0, len(names))
+	index := map[string]int{}
+	for _, name := range names {
+		p := plan.SchedulePrinter{Name: name}
+		for _, loc := range printerCfg[name].Locations {
+			if c, ok := capacity[loc]; ok && c.Capacity > 0 {
+				p.Slots += c.Capacity
+			} else {
+				p.Slots++
+			}
+			for _, s := range spools {
+				if s.Location == loc && !s.Archived {
+					p.Loaded = append(p.Loaded, s.Filament.Id)
+				}
+			}
+		}
+		index[name] = len(printers)
+		printers = append(printers, p)
+	}
+
+	// discovered is in priority order; plans that tie share a rank.
+	var plates []plan.SchedulePlate
+	rank := 0
+	for di, dp := range discovered {
+		if di > 0 && models.PlanBefore(discovered[di-1].Plan, dp.Plan) {
+			rank++
+		}
+		name := planFileName(dp)
+		for _, proj := range dp.Plan.Projects {
+			if proj.Status == "completed" {
+				continue
+			}
+			for _, plate := range proj.Plates {
+				dur, _ := time.ParseDuration(plate.EstimatedDuration)
+				switch plate.Status {
+				case "in-progress":
+					if i, ok := index[plate.Printer]; ok {
+						p := &printers[i]
+						p.Plan = name
+						p.Printing = append(p.Printing, name+"/"+models.PlateKey(proj.Name, plate.Name))
+						if started, err := time.Parse(time.RFC3339, plate.StartedAt); err == nil && dur > 0 {
+							p.BusyFor = max(p.BusyFor, started.Add(dur).Sub(now))
+						}
+					}
+					continue
+				case "completed":
+					continue
+				}
+				sp := plan.SchedulePlate{Plan: name, Project: proj.Name, Plate: plate.Name, Duration: dur, Rank: rank}
+				for _, key := range dp.Plan.Blockers(proj.Name, plate) {
+					sp.After = append(sp.After, name+"/"+key)
+				}
+				for _, n := range plate.Needs {
+					if n.FilamentID != 0 {
+						sp.Filaments = append(sp.Filaments, n.FilamentID)
+					}
+				}
+				plates = append(plates, sp)
+			}
+		}
+	}
+	return printers, plates
+}
+
+// filamentLabels maps filament IDs to "Name Material" for display.
+func filamentLabels(spools []models.FindSpool) map[int]string {
+	labels := map[int]string{}
+	for _, s := range spools {
+		if _, ok := labels[s.Filament.Id]; !ok {
+			labels[s.Filament.Id] = strings.TrimSpace(s.Filament.Name + " " + s.Filament.Material)
+		}
+	}
+	return labels
+}
+
+func printSchedule(sched plan.Schedule, printers []plan.SchedulePrinter, plateCount int, labels map[int]string) {
+	if plateCount == 0 {
+		fmt.Println("No todo plates to schedule.")
+		return
+	}
+	describe := func(ids []int, sign string) []string {
+		var out []string
+		for _, id := range ids {
+			label := labels[id]
+			if label == "" {
+				label = fmt.Sprintf("filament #%d", id)
+			}
+			out = append(out, sign+models.Sanitize(label))
+		}
+		return out
+	}
+
+	for _, p := range printers {
+		queue := sched.Queues[p.Name]
+		fmt.Printf("%s (%d slots)\n", models.Sanitize(p.Name), p.Slots)
+		if len(queue) == 0 {
+			fmt.Println("  (nothing scheduled)")
+			fmt.Println()
+			continue
+		}
+		fmt.Printf("  %-3s %-11s %-40s %-7s %s\n", "#", "Start", "Plate", "Time", "Swaps")
+		for i, e := range queue {
+			label := fmt.Sprintf("%s / %s", models.Sanitize(e.Project), models.Sanitize(e.Plate))
+			if len(label) > 40 {
+				label = label[:37] + "..."
+			}
+			dur := e.Duration
+			if dur == "" {
+				dur = "?"
+			}
+			swaps := append(describe(e.Load, "+"), describe(e.Unload, "-")...)
+			swapStr := "-"
+			if len(swaps) > 0 {
+				swapStr = strings.Join(swaps, ", ")
+			}
+			fmt.Printf("  %-3d %-11s %-40s %-7s %s\n", i+1, e.Start.Local().Format("Mon 15:04"), label, dur, swapStr)
+		}
+		fmt.Println()
+	}
+	fmt.Printf("%d plate(s) across %d printer(s), %d spool load(s).\n", plateCount, len(printers), sched.Swaps())
+}
+
+// saveSchedule stores the queue on the plan server in Remote Mode, or in
+// plans_dir otherwise.
+func saveSchedule(ctx context.Context, sched plan.Schedule) error {
+	if Cfg.PlansServer != "" {
+		data, err := json.Marshal(sched)
+		if err != nil {
+			return err
+		}
+		return api.NewPlanServerClient(Cfg.PlansServer, version, Cfg.TLSSkipVerify).PutSchedule(ctx, data)
+	}
+	if Cfg.PlansDir == "" {
+		return fmt.Errorf("plans_dir or plans_server must be configured")
+	}
+	return plan.WriteSchedule(filepath.Join(Cfg.PlansDir, plan.ScheduleFileName), sched)
+}
+
+// loadSchedule returns the saved queue; an unsaved one is empty.
+func loadSchedule(ctx context.Context) (plan.Schedule, error) {
+	if Cfg.PlansServer != "" {
+		data, err := api.NewPlanServerClient(Cfg.PlansServer, version, Cfg.TLSSkipVerify).GetSchedule(ctx)
+		if err != nil {
+			return plan.Schedule{}, err
+		}
+		var sched plan.Schedule
+		if err := json.Unmarshal(data, &sched); err != nil {
+			return plan.Schedule{}, fmt.Errorf("decode schedule: %w", err)
+		}
+		return sched, nil
+	}
+	if Cfg.PlansDir == "" {
+		return plan.Schedule{}, nil
+	}
+	return plan.ReadSchedule(filepath.Join(Cfg.PlansDir, plan.ScheduleFileName))
+}
+
+func init() {
+	planScheduleCmd.Flags().Bool("json", false, "output the schedule as JSON instead of a table")
+	planScheduleCmd.Flags().Bool("save", false, "save the schedule so 'fil plan next' offers the scheduled plate first")
+	planCmd.AddCommand(planScheduleCmd)
+}
@@ -0,0 +1,79 @@
+package cmd
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/dstockto/fil/models"
+)
+
+func TestScheduleInputs(t *testing.T) {
+	now := time.Date(2026, 5, 1, 12, 0, 0, 0, time.UTC)
+	discovered := []DiscoveredPlan{{
+		Path: "/plans/widget.yaml",
+		Plan: models.PlanFile{Projects: []models.Project{{
+			Name:   "Widget",
+			Status: "in-progress",
+			Plates: []models.Plate{
+				{Name: "Base", Status: "in-progress", Printer: "X1C", StartedAt: "2026-05-01T11:00:00Z", EstimatedDuration: "3h"},
+				{Name: "Lid", Status: "todo", EstimatedDuration: "1h30m", Needs: []models.PlateRequirement{
+					{Name: "Black", FilamentID: 100},
+					{Name: "Mystery"},
+				}},
+				{Name: "Old", Status: "completed"},
+			},
+		}}},
+	}}
+	printerCfg := map[string]PrinterConfig{
+		"X1C": {Locations: []string{"AMS A", "External"}},
+		"MK4": {Locations: []string{"MK4 Spool"}},
+	}
+	capacity := map[string]LocationCapacity{"AMS A": {Capacity: 4}}
+	spools := []models.FindSpool{
+		makeScheduleSpool(1, "AMS A", 100),
+		makeScheduleSpool(2, "Shelf", 200),
+		makeScheduleSpool(3, "MK4 Spool", 300),
+	}
+
+	printers, plates := scheduleInputs(discovered, printerCfg, capacity, spools, now)
+
+	if len(printers) != 2 || printers[0].Name != "MK4" || printers[1].Name != "X1C" {
+		t.Fatalf("printers = %+v, want MK4 then X1C", printers)
+	}
+	x1c := printers[1]
+	if x1c.Slots != 5 {
+		t.Errorf("X1C slots = %d, want 5 (4 AMS + 1 external)", x1c.Slots)
+	}
+	if !reflect.DeepEqual(x1c.Loaded, []int{100}) {
+		t.Errorf("X1C loaded = %v, want [100]", x1c.Loaded)
+	}
+	if x1c.BusyFor != 2*time.Hour || x1c.Plan != "widget.yaml" {
+		t.Errorf("X1C busy = %v plan %q, want 2h widget.yaml", x1c.BusyFor, x1c.Plan)
+	}
+	if !reflect.DeepEqual(x1c.Printing, []string{"widget.yaml/Widget/Base"}) {
+		t.Errorf("X1C printing = %v", x1c.Printing)
+	}
+	if printers[0].Slots != 1 || !reflect.DeepEqual(printers[0].Loaded, []int{300}) {
+		t.Errorf("MK4 = %+v", printers[0])
+	}
+
+	if len(plates) != 1 {
+		t.Fatalf("plates = %+v, want only Lid", plates)
+	}
+	lid := plates[0]
+	if lid.Plan != "widget.yaml" || lid.Plate != "Lid" || lid.Duration != 90*time.Minute {
+		t.Errorf("lid = %+v", lid)
+	}
+	if !reflect.DeepEqual(lid.Filaments, []int{100}) {
+		t.Errorf("lid filaments = %v, want [100] (unresolved need skipped)", lid.Filaments)
+	}
+}
+
+func makeScheduleSpool(id int, location string, filamentID int) models.FindSpool {
+	var s models.FindSpool
+	s.Id = id
+	s.Location = location
+	s.Filament.Id = filamentID
+	return s
+}
@@ -12,6 +12,7 @@ import (
 
 	"github.com/dstockto/fil/api"
 	"github.com/dstockto/fil/models"
+	"github.com/dstockto/fil/server"
 	"github.com/fatih/color"
 	"github.com/spf13/cobra"
 )
@@ -153,6 +154,7 @@ func printStatus() error {
 			}
 			fmt.Println(line)
 		}
+		printHMSLines(liveStatus[name])
 	}
 
 	for _, name := range idle {
@@ -162,6 +164,7 @@ func printStatus() error {
 		} else {
 			fmt.Printf("%s: (idle)\n", name)
 		}
+		printHMSLines(liveStatus[name])
 	}
 
 	// Check for tray mismatches if we have live data
@@ -208,6 +211,35 @@ func activeTrayColorSwatch(status api.PrinterStatus) string {
 	return ""
 }
 
+// hmsLines describes a printer's active HMS codes: what happened, how
+// serious it is, and what to do. Unknown codes get a lookup link instead.
+func hmsLines(status api.PrinterStatus) []string {
+	var lines []string
+	for _, h := range status.HMS {
+		line := h.Code
+		if h.Description != "" {
+			line = h.Description
+		}
+		if h.Severity != "" {
+			line += " (" + h.Severity + ")"
+		}
+		if h.Action != "" {
+			line += " — " + h.Action
+		} else if h.Description == "" {
+			line += " — see " + server.HMSLookupURL(h.Code)
+		}
+		lines = append(lines, line)
+	}
+	return lines
+}
+
+func printHMSLines(status api.PrinterStatus) {
+	warn := color.New(color.FgYellow).SprintFunc()
+	for _, line := range hmsLines(status) {
+		fmt.Printf("  %s %s\n", warn("⚠"), models.Sanitize(line))
+	}
+}
+
 func formatLiveStatus(status api.PrinterStatus) string {
 	parts := []string{fmt.Sprintf("%d%%", status.Progress)}
 
@@ -4,6 +4,8 @@ import (
 	"strings"
 	"testing"
 	"time"
+
+	"github.com/dstockto/fil/api"
 )
 
 // TestFormatTimeInfoRendersInLocalZone is a regression test for the bug where
@@ -57,3 +59,19 @@ func TestFormatTimeInfoETAUsesLocalZone(t *testing.T) {
 		t.Errorf("got %q, want contains 'done ~7:00am'", got)
 	}
 }
+
+func TestHMSLines(t *testing.T) {
+	lines := hmsLines(api.PrinterStatus{HMS: []api.HMSInfo{
+		{Code: "0C00-0300-0003-0008", Description: "possible spaghetti defects", Severity: "serious", Action: "Check the print"},
+		{Code: "1234-0000-0001-0002"},
+	}})
+	if len(lines) != 2 {
+		t.Fatalf("lines = %q", lines)
+	}
+	if lines[0] != "possible spaghetti defects (serious) — Check the print" {
+		t.Errorf("known = %q", lines[0])
+	}
+	if !strings.HasPrefix(lines[1], "1234-0000-0001-0002 — see https://") || !strings.Contains(lines[1], "12340000000100") {
+		t.Errorf("unknown = %q", lines[1])
+	}
+}
@@ -6,6 +6,7 @@ import (
 	"time"
 
 	"github.com/dstockto/fil/models"
+	"github.com/dstockto/fil/slicer"
 	"github.com/manifoldco/promptui"
 	"github.com/spf13/cobra"
 )
@@ -14,8 +15,12 @@ var planTimeCmd = &cobra.Command{
 	Use:     "time [duration]",
 	Aliases: []string{"t"},
 	Short:   "Set estimated print time for an in-progress plate",
-	Long:    "Set the estimated remaining print time for an in-progress plate. Duration is always interpreted as remaining from now (e.g. 6h25m, 2h, 45m).",
-	Args:    cobra.MaximumNArgs(1),
+	Long: `Set the estimated remaining print time for an in-progress plate. Duration is always interpreted as remaining from now (e.g. 6h25m, 2h, 45m).
+
+With --from-gcode, the slicer's total print time is read from a .gcode or
+.bgcode file and set on any incomplete plate instead; the start time is left
+alone since the estimate covers the whole print.`,
+	Args: cobra.MaximumNArgs(1),
 	RunE: func(cmd *cobra.Command, args []string) error {
 		if PlanOps == nil {
 			return fmt.Errorf("plan operations not configured (need either plans_server or api_base+plans_dir)")
@@ -24,6 +29,9 @@ var planTimeCmd = &cobra.Command{
 		if err != nil {
 			return err
 		}
+		if gcodePath, _ := cmd.Flags().GetString("from-gcode"); gcodePath != "" {
+			return timeFromGcode(cmd, plans, gcodePath)
+		}
 
 		type inProgressPlate struct {
 			discoveredIdx     int
@@ -146,6 +154,39 @@ var planTimeCmd = &cobra.Command{
 	},
 }
 
+// timeFromGcode sets a plate's EstimatedDuration from the slicer's predicted
+// print time in a G-code file.
+func timeFromGcode(cmd *cobra.Command, plans []DiscoveredPlan, path string) error {
+	sliced, err := slicer.ReadGcodeFile(path)
+	if err != nil {
+		return fmt.Errorf("%s: %w", path, err)
+	}
+	if sliced.Duration == 0 {
+		return fmt.Errorf("%s: no estimated print time found", path)
+	}
+	dp, err := selectPlan("Select plan", plans)
+	if err != nil {
+		return err
+	}
+	projIdx, plateIdx, err := selectOpenPlate(dp.Plan, "Which plate is this G-code for?")
+	if err != nil {
+		return err
+	}
+	if projIdx < 0 {
+		fmt.Println("No incomplete plates in this plan.")
+		return nil
+	}
+	plate := &dp.Plan.Projects[projIdx].Plates[plateIdx]
+	plate.EstimatedDuration = slicer.FormatDuration(sliced.Duration)
+	if err := PlanOps.SaveAll(cmd.Context(), planFileName(*dp), dp.Plan); err != nil {
+		return fmt.Errorf("failed to save plan: %w", err)
+	}
+	fmt.Printf("Set %s - %s: %s estimated print time\n",
+		models.Sanitize(dp.Plan.Projects[projIdx].Name), models.Sanitize(plate.Name), plate.EstimatedDuration)
+	return nil
+}
+
 func init() {
+	planTimeCmd.Flags().String("from-gcode", "", "Read the total print time from a .gcode/.bgcode file")
 	planCmd.AddCommand(planTimeCmd)
 }
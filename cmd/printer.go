@@ -0,0 +1,118 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/dstockto/fil/api"
+	"github.com/dstockto/fil/server"
+	"github.com/manifoldco/promptui"
+	"github.com/spf13/cobra"
+)
+
+var printerCmd = &cobra.Command{
+	Use:   "printer",
+	Short: "Control printers connected to the plan server, or find new ones",
+}
+
+var printerPauseCmd = &cobra.Command{
+	Use:   "pause <printer>",
+	Short: "Pause the current print",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return controlPrinter(args[0], "pause", "Paused")
+	},
+}
+
+var printerResumeCmd = &cobra.Command{
+	Use:   "resume <printer>",
+	Short: "Resume a paused print",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return controlPrinter(args[0], "resume", "Resumed")
+	},
+}
+
+var printerStopCmd = &cobra.Command{
+	Use:     "stop <printer>",
+	Aliases: []string{"cancel"},
+	Short:   "Cancel the current print",
+	Long: `Cancels the print on the printer. This can't be undone. It only stops
+the printer: use "fil plan fail" or "fil plan stop" to record what happened
+to the plate.`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		yes, _ := cmd.Flags().GetBool("yes")
+		if !yes {
+			confirmPrompt := promptui.Prompt{
+				Label:     fmt.Sprintf("Cancel the print on %s", args[0]),
+				IsConfirm: true,
+				Stdout:    NoBellStdout,
+			}
+			if _, err := confirmPrompt.Run(); err != nil {
+				if errors.Is(err, promptui.ErrAbort) {
+					fmt.Println("Not stopped.")
+					return nil
+				}
+				return err
+			}
+		}
+		return controlPrinter(args[0], "stop", "Stopped")
+	},
+}
+
+var printerLightCmd = &cobra.Command{
+	Use:       "light <printer> on|off",
+	Short:     "Switch the chamber light",
+	Args:      cobra.ExactArgs(2),
+	ValidArgs: []string{"on", "off"},
+	RunE: func(cmd *cobra.Command, args []string) error {
+		mode := strings.ToLower(args[1])
+		if mode != "on" && mode != "off" {
+			return fmt.Errorf("light must be on or off, got %q", args[1])
+		}
+		return controlPrinter(args[0], "light/"+mode, "Light "+mode)
+	},
+}
+
+var printerSpeedCmd = &cobra.Command{
+	Use:   "speed <printer> <profile>",
+	Short: "Set the print speed profile (silent, standard, sport, ludicrous)",
+	Long: `Sets the print speed. Bambu printers switch speed level; Klipper and
+OctoPrint printers get the profile's feed-rate override (M220): silent 50%,
+standard 100%, sport 124%, ludicrous 166%.`,
+	Args: cobra.ExactArgs(2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		profile, err := server.LookupSpeedProfile(args[1])
+		if err != nil {
+			return err
+		}
+		return controlPrinter(args[0], "speed/"+profile.Name, "Speed "+profile.Name)
+	},
+}
+
+// controlPrinter sends action to the named printer through the plan server,
+// which holds the live connections.
+func controlPrinter(name, action, done string) error {
+	if Cfg == nil || Cfg.PlansServer == "" {
+		return fmt.Errorf("plans_server must be configured")
+	}
+	if _, ok := Cfg.Printers[name]; !ok && len(Cfg.Printers) > 0 {
+		return fmt.Errorf("unknown printer %q", name)
+	}
+
+	client := api.NewPlanServerClient(Cfg.PlansServer, version, Cfg.TLSSkipVerify)
+	if err := client.ControlPrinter(context.Background(), name, action); err != nil {
+		return fmt.Errorf("%s: %w", name, err)
+	}
+	fmt.Printf("%s: %s\n", name, done)
+	return nil
+}
+
+func init() {
+	printerStopCmd.Flags().BoolP("yes", "y", false, "don't ask for confirmation")
+	printerCmd.AddCommand(printerPauseCmd, printerResumeCmd, printerStopCmd, printerLightCmd, printerSpeedCmd)
+	rootCmd.AddCommand(printerCmd)
+}
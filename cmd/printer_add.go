@@ -15,182 +15,273 @@ var printerAddCmd = &cobra.Command{
 	Use:   "add-printer",
 	Short: "Interactively add a new printer to the config",
 	RunE: func(cmd *cobra.Command, args []string) error {
-		if Cfg == nil {
-			Cfg = &Config{}
+		return addPrinter("", PrinterConfig{})
+	},
+}
+
+// addPrinter walks through adding a printer to the shared config. name and
+// preset (Type, IP, Serial) pre-fill the prompts, e.g. from discovery; a
+// preset Type isn't asked again.
+func addPrinter(name string, preset PrinterConfig) error {
+	if Cfg == nil {
+		Cfg = &Config{}
+	}
+	if Cfg.Printers == nil {
+		Cfg.Printers = map[string]PrinterConfig{}
+	}
+
+	// Printer name
+	namePrompt := promptui.Prompt{
+		Label:   "Printer name",
+		Default: name,
+		Stdout:  NoBellStdout,
+	}
+	name, err := namePrompt.Run()
+	if err != nil {
+		return err
+	}
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return fmt.Errorf("printer name cannot be empty")
+	}
+	if _, exists := Cfg.Printers[name]; exists {
+		return fmt.Errorf("printer %q already exists", name)
+	}
+
+	// Printer type
+	printerType := preset.Type
+	if printerType == "" {
+		typePrompt := promptui.Select{
+			Label:  "Printer type",
+			Items:  []string{"bambu", "prusa", "klipper", "octoprint", "sim"},
+			Stdout: NoBellStdout,
 		}
-		if Cfg.Printers == nil {
-			Cfg.Printers = map[string]PrinterConfig{}
+		_, printerType, err = typePrompt.Run()
+		if err != nil {
+			return err
 		}
+	}
 
-		// Printer name
-		namePrompt := promptui.Prompt{
-			Label:  "Printer name",
+	// IP address (a simulated printer has none)
+	var ip string
+	if printerType != "sim" {
+		ipPrompt := promptui.Prompt{
+			Label:   "Printer IP address",
+			Default: preset.IP,
+			Stdout:  NoBellStdout,
+		}
+		ip, err = ipPrompt.Run()
+		if err != nil {
+			return err
+		}
+		ip = strings.TrimSpace(ip)
+	}
+
+	pCfg := PrinterConfig{
+		Type: printerType,
+		IP:   ip,
+	}
+
+	// Type-specific fields
+	switch printerType {
+	case "bambu":
+		serialPrompt := promptui.Prompt{
+			Label:   "Serial number (from printer LCD or slicer)",
+			Default: preset.Serial,
+			Stdout:  NoBellStdout,
+		}
+		serial, err := serialPrompt.Run()
+		if err != nil {
+			return err
+		}
+		pCfg.Serial = strings.TrimSpace(serial)
+
+		codePrompt := promptui.Prompt{
+			Label:  "Access code (from printer LCD: Settings → Network)",
 			Stdout: NoBellStdout,
 		}
-		name, err := namePrompt.Run()
+		code, err := codePrompt.Run()
 		if err != nil {
 			return err
 		}
-		name = strings.TrimSpace(name)
-		if name == "" {
-			return fmt.Errorf("printer name cannot be empty")
+		pCfg.AccessCode = strings.TrimSpace(code)
+
+	case "prusa":
+		userPrompt := promptui.Prompt{
+			Label:   "Username",
+			Default: "maker",
+			Stdout:  NoBellStdout,
 		}
-		if _, exists := Cfg.Printers[name]; exists {
-			return fmt.Errorf("printer %q already exists", name)
+		username, err := userPrompt.Run()
+		if err != nil {
+			return err
 		}
+		pCfg.Username = strings.TrimSpace(username)
 
-		// Printer type
-		typePrompt := promptui.Select{
-			Label:  "Printer type",
-			Items:  []string{"bambu", "prusa"},
+		passPrompt := promptui.Prompt{
+			Label:  "Password",
 			Stdout: NoBellStdout,
 		}
-		_, printerType, err := typePrompt.Run()
+		password, err := passPrompt.Run()
 		if err != nil {
 			return err
 		}
+		pCfg.Password = strings.TrimSpace(password)
 
-		// IP address
-		ipPrompt := promptui.Prompt{
-			Label:  "Printer IP address",
+	case "klipper":
+		keyPrompt := promptui.Prompt{
+			Label:  "Moonraker API key (blank if not required)",
 			Stdout: NoBellStdout,
 		}
-		ip, err := ipPrompt.Run()
+		key, err := keyPrompt.Run()
 		if err != nil {
 			return err
 		}
-		ip = strings.TrimSpace(ip)
+		pCfg.APIKey = strings.TrimSpace(key)
 
-		pCfg := PrinterConfig{
-			Type: printerType,
-			IP:   ip,
-		}
-
-		// Type-specific fields
-		switch printerType {
-		case "bambu":
-			serialPrompt := promptui.Prompt{
-				Label:  "Serial number (from printer LCD or slicer)",
-				Stdout: NoBellStdout,
-			}
-			serial, err := serialPrompt.Run()
-			if err != nil {
-				return err
-			}
-			pCfg.Serial = strings.TrimSpace(serial)
-
-			codePrompt := promptui.Prompt{
-				Label:  "Access code (from printer LCD: Settings → Network)",
-				Stdout: NoBellStdout,
-			}
-			code, err := codePrompt.Run()
-			if err != nil {
-				return err
-			}
-			pCfg.AccessCode = strings.TrimSpace(code)
-
-		case "prusa":
-			userPrompt := promptui.Prompt{
-				Label:   "Username",
-				Default: "maker",
-				Stdout:  NoBellStdout,
-			}
-			username, err := userPrompt.Run()
-			if err != nil {
-				return err
-			}
-			pCfg.Username = strings.TrimSpace(username)
-
-			passPrompt := promptui.Prompt{
-				Label:  "Password",
-				Stdout: NoBellStdout,
-			}
-			password, err := passPrompt.Run()
-			if err != nil {
-				return err
-			}
-			pCfg.Password = strings.TrimSpace(password)
-		}
-
-		// Locations
-		fmt.Println("\nAdd locations for this printer (e.g. AMS A, AMS B, Prusa).")
-		fmt.Println("Enter locations one at a time. Leave blank when done.")
-		var locations []string
-		for {
-			locPrompt := promptui.Prompt{
-				Label:  fmt.Sprintf("Location %d (blank to finish)", len(locations)+1),
-				Stdout: NoBellStdout,
-			}
-			loc, err := locPrompt.Run()
-			if err != nil {
-				return err
-			}
-			loc = strings.TrimSpace(loc)
-			if loc == "" {
-				break
-			}
-			locations = append(locations, loc)
-		}
-
-		if len(locations) == 0 {
-			return fmt.Errorf("at least one location is required")
-		}
-		pCfg.Locations = locations
-
-		// Show summary
-		fmt.Printf("\nPrinter to add:\n")
-		fmt.Printf("  Name:      %s\n", name)
-		fmt.Printf("  Type:      %s\n", pCfg.Type)
-		fmt.Printf("  IP:        %s\n", pCfg.IP)
-		switch pCfg.Type {
-		case "bambu":
-			fmt.Printf("  Serial:    %s\n", pCfg.Serial)
-			fmt.Printf("  Access:    %s\n", pCfg.AccessCode)
-		case "prusa":
-			fmt.Printf("  Username:  %s\n", pCfg.Username)
-			fmt.Printf("  Password:  %s\n", pCfg.Password)
-		}
-		fmt.Printf("  Locations: %s\n", strings.Join(pCfg.Locations, ", "))
-
-		confirmPrompt := promptui.Select{
-			Label:  "Save this printer?",
-			Items:  []string{"Yes", "No"},
+		macroPrompt := promptui.Prompt{
+			Label:  "MMU gate macro, e.g. MMU_GATE_MAP (blank if no MMU/ERCF)",
 			Stdout: NoBellStdout,
 		}
-		idx, _, err := confirmPrompt.Run()
-		if err != nil || idx != 0 {
-			fmt.Println("Canceled.")
-			return nil
+		macro, err := macroPrompt.Run()
+		if err != nil {
+			return err
 		}
+		pCfg.MMUMacro = strings.TrimSpace(macro)
 
-		// Save to config
-		Cfg.Printers[name] = pCfg
+	case "octoprint":
+		keyPrompt := promptui.Prompt{
+			Label:  "OctoPrint API key (Settings → Application Keys)",
+			Stdout: NoBellStdout,
+		}
+		key, err := keyPrompt.Run()
+		if err != nil {
+			return err
+		}
+		pCfg.APIKey = strings.TrimSpace(key)
 
-		// Write shared config
-		shared := Cfg.ToSharedConfig()
-		data, err := json.MarshalIndent(shared, "", "  ")
+		pushPrompt := promptui.Select{
+			Label:  "Listen for live updates on OctoPrint's push socket? (otherwise poll every 30s)",
+			Items:  []string{"Yes", "No"},
+			Stdout: NoBellStdout,
+		}
+		pushIdx, _, err := pushPrompt.Run()
 		if err != nil {
-			return fmt.Errorf("failed to marshal config: %w", err)
+			return err
 		}
+		pCfg.Push = pushIdx == 0
 
-		home, _ := os.UserHomeDir()
-		configDir := filepath.Join(home, ".config", "fil")
-		if err := os.MkdirAll(configDir, 0755); err != nil {
-			return fmt.Errorf("failed to create config dir: %w", err)
+	case "sim":
+		scenarioPrompt := promptui.Prompt{
+			Label:  "Scenario file (YAML)",
+			Stdout: NoBellStdout,
 		}
+		scenario, err := scenarioPrompt.Run()
+		if err != nil {
+			return err
+		}
+		pCfg.Scenario = strings.TrimSpace(scenario)
+	}
 
-		configPath := filepath.Join(configDir, "shared-config.json")
-		if err := os.WriteFile(configPath, data, 0644); err != nil {
-			return fmt.Errorf("failed to write config: %w", err)
+	// Locations
+	fmt.Println("\nAdd locations for this printer (e.g. AMS A, AMS B, Prusa).")
+	fmt.Println("Enter locations one at a time. Leave blank when done.")
+	var locations []string
+	for {
+		locPrompt := promptui.Prompt{
+			Label:  fmt.Sprintf("Location %d (blank to finish)", len(locations)+1),
+			Stdout: NoBellStdout,
 		}
+		loc, err := locPrompt.Run()
+		if err != nil {
+			return err
+		}
+		loc = strings.TrimSpace(loc)
+		if loc == "" {
+			break
+		}
+		locations = append(locations, loc)
+	}
 
-		fmt.Printf("\nPrinter %q added to %s\n", name, configPath)
-		if Cfg.PlansServer != "" {
-			fmt.Println("Run 'fil config push' to sync to the server, then restart the server.")
+	if len(locations) == 0 {
+		return fmt.Errorf("at least one location is required")
+	}
+	pCfg.Locations = locations
+
+	// Show summary
+	fmt.Printf("\nPrinter to add:\n")
+	fmt.Printf("  Name:      %s\n", name)
+	fmt.Printf("  Type:      %s\n", pCfg.Type)
+	fmt.Printf("  IP:        %s\n", pCfg.IP)
+	switch pCfg.Type {
+	case "bambu":
+		fmt.Printf("  Serial:    %s\n", pCfg.Serial)
+		fmt.Printf("  Access:    %s\n", pCfg.AccessCode)
+	case "prusa":
+		fmt.Printf("  Username:  %s\n", pCfg.Username)
+		fmt.Printf("  Password:  %s\n", pCfg.Password)
+	case "klipper":
+		if pCfg.APIKey != "" {
+			fmt.Printf("  API key:   %s\n", pCfg.APIKey)
+		}
+		if pCfg.MMUMacro != "" {
+			fmt.Printf("  MMU macro: %s\n", pCfg.MMUMacro)
 		}
+	case "octoprint":
+		fmt.Printf("  API key:   %s\n", pCfg.APIKey)
+		fmt.Printf("  Push:      %v\n", pCfg.Push)
+	case "sim":
+		fmt.Printf("  Scenario:  %s\n", pCfg.Scenario)
+	}
+	fmt.Printf("  Locations: %s\n", strings.Join(pCfg.Locations, ", "))
 
+	confirmPrompt := promptui.Select{
+		Label:  "Save this printer?",
+		Items:  []string{"Yes", "No"},
+		Stdout: NoBellStdout,
+	}
+	idx, _, err := confirmPrompt.Run()
+	if err != nil || idx != 0 {
+		fmt.Println("Canceled.")
 		return nil
-	},
+	}
+
+	// Save to config
+	Cfg.Printers[name] = pCfg
+
+	configPath, err := saveSharedConfig()
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("\nPrinter %q added to %s\n", name, configPath)
+	if Cfg.PlansServer != "" {
+		fmt.Println("Run 'fil config push' to sync to the server, then restart the server.")
+	}
+
+	return nil
+}
+
+// saveSharedConfig writes Cfg's shared fields to the local shared config
+// and returns its path.
+func saveSharedConfig() (string, error) {
+	shared := Cfg.ToSharedConfig()
+	data, err := json.MarshalIndent(shared, "", "  ")
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal config: %w", err)
+	}
+
+	home, _ := os.UserHomeDir()
+	configDir := filepath.Join(home, ".config", "fil")
+	if err := os.MkdirAll(configDir, 0755); err != nil {
+		return "", fmt.Errorf("failed to create config dir: %w", err)
+	}
+
+	configPath := filepath.Join(configDir, "shared-config.json")
+	if err := os.WriteFile(configPath, data, 0644); err != nil {
+		return "", fmt.Errorf("failed to write config: %w", err)
+	}
+	return configPath, nil
 }
 
 func init() {
@@ -0,0 +1,200 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/dstockto/fil/server"
+	"github.com/fatih/color"
+	"github.com/manifoldco/promptui"
+	"github.com/spf13/cobra"
+)
+
+var printerDiscoverCmd = &cobra.Command{
+	Use:   "discover",
+	Short: "Find printers on the LAN and add them to the config",
+	Long: `Listens for the announcements Bambu printers broadcast every few seconds
+(UDP port 2021) and browses mDNS for PrusaLink, OctoPrint, and Moonraker
+hosts.
+
+Printers already in the config are matched by serial (Bambu) or address.
+A Bambu printer whose IP changed is flagged and can be updated in place.
+New printers can be added through the same prompts as "fil add-printer",
+with what was discovered filled in; Bambu access codes still come from the
+printer's screen.
+
+Bambu Studio or OrcaSlicer running on this machine may hold port 2021;
+close them if no Bambu printers show up.`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		timeout, _ := cmd.Flags().GetDuration("timeout")
+		listOnly, _ := cmd.Flags().GetBool("list")
+		if Cfg == nil {
+			Cfg = &Config{}
+		}
+
+		fmt.Printf("Listening for printers for %s...\n", timeout)
+		found, warns, err := server.Discover(cmd.Context(), timeout)
+		if err != nil {
+			return err
+		}
+		for _, w := range warns {
+			color.Yellow("warning: %v", w)
+		}
+		if len(found) == 0 {
+			fmt.Println("No printers found.")
+			return nil
+		}
+
+		var fresh []server.DiscoveredPrinter
+		var moved []string
+		movedTo := map[string]server.DiscoveredPrinter{}
+		for _, d := range found {
+			name, changed := matchDiscovered(Cfg.Printers, d)
+			status := color.GreenString("new")
+			switch {
+			case changed:
+				status = color.YellowString("%s, IP changed from %s", name, Cfg.Printers[name].IP)
+				moved = append(moved, name)
+				movedTo[name] = d
+			case name != "":
+				status = "configured as " + name
+			default:
+				fresh = append(fresh, d)
+			}
+			fmt.Printf("  %s (%s)\n", discoveredLabel(d), status)
+		}
+		if listOnly {
+			return nil
+		}
+
+		updated := false
+		for _, name := range moved {
+			d := movedTo[name]
+			confirm := promptui.Prompt{
+				Label:     fmt.Sprintf("Update %s's IP from %s to %s", name, Cfg.Printers[name].IP, d.Host()),
+				IsConfirm: true,
+				Stdout:    NoBellStdout,
+			}
+			if _, err := confirm.Run(); err != nil {
+				if errors.Is(err, promptui.ErrAbort) {
+					continue
+				}
+				return err
+			}
+			p := Cfg.Printers[name]
+			p.IP = d.Host()
+			Cfg.Printers[name] = p
+			updated = true
+		}
+		if updated {
+			configPath, err := saveSharedConfig()
+			if err != nil {
+				return err
+			}
+			fmt.Printf("Updated %s\n", configPath)
+			if Cfg.PlansServer != "" {
+				fmt.Println("Run 'fil config push' to sync to the server, then restart the server.")
+			}
+		}
+
+		for len(fresh) > 0 {
+			items := make([]string, 0, len(fresh)+1)
+			for _, d := range fresh {
+				items = append(items, discoveredLabel(d))
+			}
+			items = append(items, "Done")
+			sel := promptui.Select{
+				Label:  "Add a printer",
+				Items:  items,
+				Stdout: NoBellStdout,
+			}
+			idx, _, err := sel.Run()
+			if err != nil || idx == len(fresh) {
+				return nil
+			}
+			d := fresh[idx]
+			fresh = append(fresh[:idx], fresh[idx+1:]...)
+			preset := PrinterConfig{Type: d.Type, IP: d.Host(), Serial: d.Serial}
+			if err := addPrinter(suggestedPrinterName(d), preset); err != nil {
+				return err
+			}
+		}
+		return nil
+	},
+}
+
+// matchDiscovered finds the configured printer a discovered one is: by
+// serial for Bambu, otherwise by type and address. changed reports a Bambu
+// printer whose configured IP differs from the one it announced.
+func matchDiscovered(printers map[string]PrinterConfig, d server.DiscoveredPrinter) (name string, changed bool) {
+	names := make([]string, 0, len(printers))
+	for n := range printers {
+		names = append(names, n)
+	}
+	sort.Strings(names)
+
+	for _, n := range names {
+		p := printers[n]
+		if d.Serial != "" && strings.EqualFold(p.Serial, d.Serial) {
+			return n, configHost(p.IP) != d.IP
+		}
+	}
+	for _, n := range names {
+		p := printers[n]
+		if d.Serial != "" && p.Serial != "" {
+			continue // a different printer that got this one's old address
+		}
+		if p.Type == d.Type && (p.IP == d.Host() || configHost(p.IP) == d.IP) {
+			return n, false
+		}
+	}
+	return "", false
+}
+
+// configHost is the bare host of a printer's configured address, which may
+// carry a scheme or port ("http://10.0.0.5:5000").
+func configHost(addr string) string {
+	if strings.Contains(addr, "://") {
+		if u, err := url.Parse(addr); err == nil {
+			return u.Hostname()
+		}
+	}
+	if host, _, err := net.SplitHostPort(addr); err == nil {
+		return host
+	}
+	return addr
+}
+
+func discoveredLabel(d server.DiscoveredPrinter) string {
+	label := fmt.Sprintf("%-9s %s", d.Type, d.Name)
+	if d.Model != "" && !strings.Contains(d.Name, d.Model) {
+		label += " [" + d.Model + "]"
+	}
+	label += " at " + d.Host()
+	if d.Serial != "" {
+		label += ", serial " + d.Serial
+	}
+	return label
+}
+
+// suggestedPrinterName is the discovered name, without spaces so it's easy
+// to type in commands.
+func suggestedPrinterName(d server.DiscoveredPrinter) string {
+	name := d.Name
+	if name == "" {
+		name = d.Model
+	}
+	return strings.Join(strings.Fields(name), "-")
+}
+
+func init() {
+	printerDiscoverCmd.Flags().Duration("timeout", 6*time.Second, "how long to listen")
+	printerDiscoverCmd.Flags().Bool("list", false, "only list what's found, don't offer to add or update")
+	printerCmd.AddCommand(printerDiscoverCmd)
+}
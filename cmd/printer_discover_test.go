@@ -0,0 +1,45 @@
+package cmd
+
+import (
+	"testing"
+
+	"github.com/dstockto/fil/server"
+)
+
+func TestMatchDiscovered(t *testing.T) {
+	printers := map[string]PrinterConfig{
+		"X1C":   {Type: "bambu", IP: "192.168.1.40", Serial: "00M09A350100123"},
+		"Voron": {Type: "klipper", IP: "192.168.1.60:7125"},
+		"MK3":   {Type: "octoprint", IP: "http://192.168.1.70:5000"},
+	}
+	tests := []struct {
+		name        string
+		d           server.DiscoveredPrinter
+		wantName    string
+		wantChanged bool
+	}{
+		{"bambu moved", server.DiscoveredPrinter{Type: "bambu", IP: "192.168.1.41", Serial: "00m09a350100123"}, "X1C", true},
+		{"bambu same IP", server.DiscoveredPrinter{Type: "bambu", IP: "192.168.1.40", Serial: "00M09A350100123"}, "X1C", false},
+		{"new bambu", server.DiscoveredPrinter{Type: "bambu", IP: "192.168.1.40", Serial: "01P00A000000001"}, "", false},
+		{"klipper by address", server.DiscoveredPrinter{Type: "klipper", IP: "192.168.1.60", Port: 7125}, "Voron", false},
+		{"octoprint by URL host", server.DiscoveredPrinter{Type: "octoprint", IP: "192.168.1.70", Port: 5000}, "MK3", false},
+		{"new prusa", server.DiscoveredPrinter{Type: "prusa", IP: "192.168.1.60", Port: 80}, "", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			name, changed := matchDiscovered(printers, tt.d)
+			if name != tt.wantName || changed != tt.wantChanged {
+				t.Errorf("matchDiscovered = %q, %v; want %q, %v", name, changed, tt.wantName, tt.wantChanged)
+			}
+		})
+	}
+}
+
+func TestSuggestedPrinterName(t *testing.T) {
+	if got := suggestedPrinterName(server.DiscoveredPrinter{Name: "Garage  P1S"}); got != "Garage-P1S" {
+		t.Errorf("got %q, want Garage-P1S", got)
+	}
+	if got := suggestedPrinterName(server.DiscoveredPrinter{Model: "A1 mini"}); got != "A1-mini" {
+		t.Errorf("got %q, want A1-mini", got)
+	}
+}
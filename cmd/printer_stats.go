@@ -0,0 +1,207 @@
+package cmd
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/dstockto/fil/api"
+	"github.com/dstockto/fil/models"
+	"github.com/dstockto/fil/server"
+	"github.com/spf13/cobra"
+)
+
+var printerStatsCmd = &cobra.Command{
+	Use:   "stats [printer]",
+	Short: "Show utilization, idle gaps, pauses, and failures from the printer event log",
+	Long: `Summarizes each printer's logged state transitions over the last --days:
+
+  utilization   share of the window spent printing
+  idle gaps     time from a FINISH to the next print starting
+  pauses        pauses grouped by HMS code ("user" when the printer gave none)
+  MTBF          printing hours per failed or cancelled print`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if Cfg == nil || Cfg.PlansServer == "" {
+			return fmt.Errorf("plans_server must be configured")
+		}
+		days, _ := cmd.Flags().GetInt("days")
+		if days < 1 {
+			return fmt.Errorf("--days must be at least 1")
+		}
+
+		ctx := cmd.Context()
+		client := api.NewPlanServerClient(Cfg.PlansServer, version, Cfg.TLSSkipVerify)
+
+		var names []string
+		if len(args) == 1 {
+			names = args
+		} else {
+			statuses, err := client.GetPrinterStatus(ctx)
+			if err != nil {
+				return fmt.Errorf("failed to fetch printers: %w", err)
+			}
+			for _, st := range statuses {
+				names = append(names, st.Name)
+			}
+			sort.Strings(names)
+		}
+		if len(names) == 0 {
+			fmt.Println("No printers connected.")
+			return nil
+		}
+
+		now := time.Now()
+		from := now.AddDate(0, 0, -days)
+		for i, name := range names {
+			events, err := client.GetPrinterEvents(ctx, name, from.Format("2006-01-02"))
+			if err != nil {
+				return fmt.Errorf("%s: %w", name, err)
+			}
+			if i > 0 {
+				fmt.Println()
+			}
+			printPrinterStats(name, buildPrinterStats(events, from, now))
+		}
+		return nil
+	},
+}
+
+// printerStats summarizes one printer's events over a window.
+type printerStats struct {
+	window   time.Duration
+	printing time.Duration
+	started  int
+	finished int
+	failed   int
+	idleGaps []time.Duration
+	pauses   map[string]int // HMS code, or "user", to count
+	hms      map[string]api.HMSInfo
+}
+
+func (s printerStats) utilization() float64 {
+	if s.window <= 0 {
+		return 0
+	}
+	return 100 * s.printing.Seconds() / s.window.Seconds()
+}
+
+// mtbf is printing time per failure; zero when nothing failed.
+func (s printerStats) mtbf() time.Duration {
+	if s.failed == 0 {
+		return 0
+	}
+	return s.printing / time.Duration(s.failed)
+}
+
+// buildPrinterStats walks events (oldest first) across [from, to]. The time
+// before the first event is credited to that event's From state, since it
+// is what the printer was doing when the window opened.
+func buildPrinterStats(events []api.PrinterEvent, from, to time.Time) printerStats {
+	stats := printerStats{window: to.Sub(from), pauses: map[string]int{}, hms: map[string]api.HMSInfo{}}
+	if len(events) == 0 {
+		return stats
+	}
+
+	state := events[0].From
+	at := from
+	var lastFinish time.Time
+	var pauseCodes map[string]bool // codes already counted for the current pause
+	for _, ev := range events {
+		ts := ev.Timestamp
+		if ts.Before(from) {
+			ts = from
+		}
+		if state == "printing" {
+			stats.printing += ts.Sub(at)
+		}
+		state, at = ev.To, ts
+
+		switch ev.Kind {
+		case "start":
+			stats.started++
+			if !lastFinish.IsZero() {
+				stats.idleGaps = append(stats.idleGaps, ts.Sub(lastFinish))
+			}
+			lastFinish = time.Time{}
+		case "finish":
+			stats.finished++
+			lastFinish = ts
+		case "fail":
+			stats.failed++
+		case "pause":
+			if ev.From != "paused" {
+				pauseCodes = map[string]bool{}
+				if len(ev.HMS) == 0 {
+					stats.pauses["user"]++
+				}
+			}
+			for _, h := range ev.HMS {
+				if !pauseCodes[h.Code] {
+					pauseCodes[h.Code] = true
+					stats.pauses[h.Code]++
+					stats.hms[h.Code] = h
+				}
+			}
+		}
+	}
+	if state == "printing" && to.After(at) {
+		stats.printing += to.Sub(at)
+	}
+	return stats
+}
+
+func printPrinterStats(name string, s printerStats) {
+	fmt.Printf("%s\n", models.Sanitize(name))
+	fmt.Printf("  Utilization:  %.1f%% (%s printing over %s)\n", s.utilization(), formatDuration(s.printing), formatDuration(s.window))
+	fmt.Printf("  Prints:       %d started, %d finished, %d failed\n", s.started, s.finished, s.failed)
+
+	if len(s.idleGaps) > 0 {
+		var total, longest time.Duration
+		for _, g := range s.idleGaps {
+			total += g
+			longest = max(longest, g)
+		}
+		fmt.Printf("  Idle gaps:    %d, mean %s, longest %s\n", len(s.idleGaps), formatDuration(total/time.Duration(len(s.idleGaps))), formatDuration(longest))
+	} else {
+		fmt.Printf("  Idle gaps:    —\n")
+	}
+
+	if mtbf := s.mtbf(); mtbf > 0 {
+		fmt.Printf("  MTBF:         %s printing per failure\n", formatDuration(mtbf))
+	} else {
+		fmt.Printf("  MTBF:         — (no failures)\n")
+	}
+
+	if len(s.pauses) == 0 {
+		fmt.Printf("  Pauses:       none\n")
+		return
+	}
+	codes := make([]string, 0, len(s.pauses))
+	for code := range s.pauses {
+		codes = append(codes, code)
+	}
+	sort.Slice(codes, func(i, j int) bool {
+		if s.pauses[codes[i]] != s.pauses[codes[j]] {
+			return s.pauses[codes[i]] > s.pauses[codes[j]]
+		}
+		return codes[i] < codes[j]
+	})
+	fmt.Printf("  Pauses:\n")
+	for _, code := range codes {
+		line := fmt.Sprintf("    %3d  %s", s.pauses[code], code)
+		if h, ok := s.hms[code]; ok {
+			if h.Description != "" {
+				line += "  " + h.Description
+			} else {
+				line += "  unknown, see " + server.HMSLookupURL(code)
+			}
+		}
+		fmt.Println(line)
+	}
+}
+
+func init() {
+	printerStatsCmd.Flags().Int("days", 30, "number of days to summarize")
+	printerCmd.AddCommand(printerStatsCmd)
+}
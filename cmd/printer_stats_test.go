@@ -0,0 +1,67 @@
+package cmd
+
+import (
+	"testing"
+	"time"
+
+	"github.com/dstockto/fil/api"
+)
+
+func TestBuildPrinterStats(t *testing.T) {
+	from := time.Date(2026, 5, 1, 0, 0, 0, 0, time.UTC)
+	at := func(h int) time.Time { return from.Add(time.Duration(h) * time.Hour) }
+	ev := func(h int, fromState, to, kind string, codes ...string) api.PrinterEvent {
+		e := api.PrinterEvent{Timestamp: at(h), From: fromState, To: to, Kind: kind}
+		for _, c := range codes {
+			e.HMS = append(e.HMS, api.HMSInfo{Code: c})
+		}
+		return e
+	}
+
+	events := []api.PrinterEvent{
+		// Printing when the window opened: hours 0-2 count.
+		ev(2, "printing", "finished", "finish"),
+		ev(5, "finished", "printing", "start"),
+		ev(6, "printing", "paused", "pause", "0C00-0300-0003-0008"),
+		ev(6, "paused", "paused", "pause", "0C00-0300-0003-0008", "0700-7000-0002-0007"),
+		ev(7, "paused", "printing", "resume"),
+		ev(8, "printing", "paused", "pause"),
+		ev(9, "paused", "idle", "fail"),
+		ev(10, "idle", "printing", "start"),
+	}
+	s := buildPrinterStats(events, from, at(12))
+
+	// 0-2, 5-6, 7-8, 10-12.
+	if s.printing != 6*time.Hour {
+		t.Errorf("printing = %v, want 6h", s.printing)
+	}
+	if got := s.utilization(); got != 50 {
+		t.Errorf("utilization = %v, want 50", got)
+	}
+	if s.started != 2 || s.finished != 1 || s.failed != 1 {
+		t.Errorf("counts = %d/%d/%d", s.started, s.finished, s.failed)
+	}
+	if len(s.idleGaps) != 1 || s.idleGaps[0] != 3*time.Hour {
+		t.Errorf("idle gaps = %v, want [3h]", s.idleGaps)
+	}
+	if s.mtbf() != 6*time.Hour {
+		t.Errorf("mtbf = %v, want 6h", s.mtbf())
+	}
+	want := map[string]int{"0C00-0300-0003-0008": 1, "0700-7000-0002-0007": 1, "user": 1}
+	for code, n := range want {
+		if s.pauses[code] != n {
+			t.Errorf("pauses[%s] = %d, want %d", code, s.pauses[code], n)
+		}
+	}
+	if len(s.pauses) != len(want) {
+		t.Errorf("pauses = %v", s.pauses)
+	}
+}
+
+func TestBuildPrinterStatsNoEvents(t *testing.T) {
+	from := time.Date(2026, 5, 1, 0, 0, 0, 0, time.UTC)
+	s := buildPrinterStats(nil, from, from.Add(24*time.Hour))
+	if s.utilization() != 0 || s.mtbf() != 0 || len(s.idleGaps) != 0 {
+		t.Errorf("stats = %+v", s)
+	}
+}
@@ -11,6 +11,7 @@ import (
 
 	"github.com/dstockto/fil/api"
 	"github.com/dstockto/fil/plan"
+	"github.com/dstockto/fil/server"
 	"github.com/fatih/color"
 	"github.com/spf13/cobra"
 )
@@ -38,14 +39,58 @@ type NotificationConfig struct {
 	QuietEnd          string `json:"quiet_end,omitempty"`   // e.g. "07:00"
 }
 
+// WeightSyncConfig turns on the plan server's periodic check of AMS
+// remaining estimates against Spoolman. Local-only, like ApiBaseInternal.
+type WeightSyncConfig struct {
+	Interval   string  `json:"interval,omitempty"`    // e.g. "1h" (default)
+	Tolerance  float64 `json:"tolerance,omitempty"`   // grams; default 25
+	Correct    bool    `json:"correct,omitempty"`     // write the AMS estimate to Spoolman
+	MaxCorrect float64 `json:"max_correct,omitempty"` // grams; larger drifts are only flagged, default 100
+}
+
+// SpoolSwapConfig turns on the plan server's detection of spools swapped in
+// a printer slot by hand. Local-only, like WeightSyncConfig.
+type SpoolSwapConfig struct {
+	Interval  string  `json:"interval,omitempty"`    // e.g. "15s" (default)
+	MaxDeltaE float64 `json:"max_delta_e,omitempty"` // color match threshold; default 10
+	// AutoApply moves the spool without asking when exactly one spool fits.
+	AutoApply bool `json:"auto_apply,omitempty"`
+}
+
 type PrinterConfig struct {
 	Locations  []string `json:"locations"`
-	Type       string   `json:"type,omitempty"` // "bambu" or "prusa"
-	IP         string   `json:"ip,omitempty"`
+	Type       string   `json:"type,omitempty"`        // "bambu", "prusa", "klipper", "octoprint", or "sim"
+	IP         string   `json:"ip,omitempty"`          // Klipper: Moonraker host, port optional (default 7125); OctoPrint: host[:port] or URL
 	Serial     string   `json:"serial,omitempty"`      // Bambu only
 	AccessCode string   `json:"access_code,omitempty"` // Bambu only
 	Username   string   `json:"username,omitempty"`    // Prusa only
 	Password   string   `json:"password,omitempty"`    // Prusa only
+	APIKey     string   `json:"api_key,omitempty"`     // OctoPrint (required), Klipper (if Moonraker requires one)
+	Push       bool     `json:"push,omitempty"`        // OctoPrint: also listen on the SockJS push socket
+	// MMUMacro is the Klipper macro that sets an MMU/ERCF gate's filament
+	// (e.g. "MMU_GATE_MAP"); without it fil can't push tray data to Klipper.
+	MMUMacro string `json:"mmu_macro,omitempty"`
+	// Scenario is the YAML file a "sim" printer plays instead of talking
+	// to hardware; see server.SimScenario.
+	Scenario string `json:"scenario,omitempty"`
+	// AutoComplete lets the plan server complete the in-progress plate when
+	// this printer reports FINISH, leaving a pending confirmation behind.
+	AutoComplete bool `json:"auto_complete,omitempty"`
+	// AutoFail records a pending fail, with waste estimated from progress,
+	// when this printer aborts a job. Nothing is deducted until confirmed.
+	AutoFail bool `json:"auto_fail,omitempty"`
+	// AutoStart starts the todo plate whose file matches the job this
+	// printer begins, leaving a pending event when nothing matches.
+	AutoStart bool `json:"auto_start,omitempty"`
+	// AMSUnits maps a location to the Bambu unit behind it: "ams1"-"ams4",
+	// "ht1"-"ht8" (AMS HT), or "external" (the spool holder). Locations not
+	// listed use their position in Locations, so the first is ams1.
+	AMSUnits map[string]string `json:"ams_units,omitempty"`
+}
+
+// LocationAmsIDs is the AmsID behind each entry in Locations.
+func (p PrinterConfig) LocationAmsIDs() []int {
+	return server.LocationAmsIDs(p.Locations, p.AMSUnits)
 }
 
 type Config struct {
@@ -68,6 +113,9 @@ type Config struct {
 	TLSSkipVerify   bool                     `json:"tls_skip_verify"`
 	SharedConfigDir string                   `json:"shared_config_dir"`
 	AssembliesDir   string                   `json:"assemblies_dir"`
+	JobsDir         string                   `json:"jobs_dir"`
+	WeightSync      *WeightSyncConfig        `json:"weight_sync,omitempty"`
+	SpoolSwap       *SpoolSwapConfig         `json:"spool_swap,omitempty"`
 }
 
 // SharedConfig contains only the fields that are synced between machines via the server.
@@ -372,12 +420,24 @@ func mergeInto(dst, src *Config) {
 		dst.AssembliesDir = src.AssembliesDir
 	}
 
+	if src.JobsDir != "" {
+		dst.JobsDir = src.JobsDir
+	}
+
 	if src.Notifications != nil {
 		if dst.Notifications == nil {
 			dst.Notifications = &NotificationConfig{}
 		}
 		mergeNotifications(dst.Notifications, src.Notifications)
 	}
+
+	if src.WeightSync != nil {
+		dst.WeightSync = src.WeightSync
+	}
+
+	if src.SpoolSwap != nil {
+		dst.SpoolSwap = src.SpoolSwap
+	}
 }
 
 // mergeNotifications copies non-empty fields from src into dst. Avoids the
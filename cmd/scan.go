@@ -266,7 +266,7 @@ func (s *scanSession) handleScan(r devices.ScanResult) (bool, error) {
 // Spools are pre-ranked by CIEDE2000 ΔE against the scan so the most likely
 // match appears first. The previously-sticky spool (if any) is also floated.
 func (s *scanSession) pickTarget(scannedHex string) (models.FindSpool, bool, error) {
-	target, _ := parseHexColor(scannedHex)
+	target, _ := models.ParseHexColor(scannedHex)
 
 	ranked := rankSpoolsByDistance(s.allSpools, target)
 	if s.lastUsed != nil {
@@ -286,8 +286,8 @@ func rankSpoolsByDistance(spools []models.FindSpool, target colorful.Color) []mo
 	out := make([]models.FindSpool, len(spools))
 	copy(out, spools)
 	sort.SliceStable(out, func(i, j int) bool {
-		di := spoolColorDistance(out[i], target)
-		dj := spoolColorDistance(out[j], target)
+		di := models.SpoolColorDistance(out[i], target)
+		dj := models.SpoolColorDistance(out[j], target)
 		if math.IsInf(di, 1) && math.IsInf(dj, 1) {
 			return false
 		}
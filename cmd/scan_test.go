@@ -13,7 +13,7 @@ func newSpool(id int, hex string) models.FindSpool {
 }
 
 func TestRankSpoolsByDistance(t *testing.T) {
-	target, ok := parseHexColor("#ff0000") // red
+	target, ok := models.ParseHexColor("#ff0000") // red
 	if !ok {
 		t.Fatal("failed to parse target hex")
 	}
@@ -28,6 +28,7 @@ var serveCmd = &cobra.Command{
 
 		port, _ := cmd.Flags().GetInt("port")
 		bind, _ := cmd.Flags().GetString("bind")
+		captureDir, _ := cmd.Flags().GetString("capture")
 
 		// Determine config directory for shared config storage
 		configDir := Cfg.SharedConfigDir
@@ -43,8 +44,14 @@ var serveCmd = &cobra.Command{
 			assembliesDir = filepath.Join(Cfg.PlansDir, "..", "assemblies")
 		}
 
+		// Determine sliced jobs directory
+		jobsDir := Cfg.JobsDir
+		if jobsDir == "" {
+			jobsDir = filepath.Join(Cfg.PlansDir, "..", "jobs")
+		}
+
 		// Ensure directories exist
-		for _, dir := range []string{Cfg.PlansDir, Cfg.PauseDir, Cfg.ArchiveDir, configDir, assembliesDir} {
+		for _, dir := range []string{Cfg.PlansDir, Cfg.PauseDir, Cfg.ArchiveDir, configDir, assembliesDir, jobsDir} {
 			if dir == "" {
 				continue
 			}
@@ -63,6 +70,7 @@ var serveCmd = &cobra.Command{
 			ArchiveDir:      Cfg.ArchiveDir,
 			ConfigDir:       configDir,
 			AssembliesDir:   assembliesDir,
+			JobsDir:         jobsDir,
 			Version:         version,
 			ApiBase:         Cfg.ApiBase,
 			ApiBaseInternal: Cfg.ApiBaseInternal,
@@ -70,43 +78,66 @@ var serveCmd = &cobra.Command{
 			StartedAt:       time.Now(),
 		}
 
+		if configDir != "" {
+			if n, err := server.LoadHMSOverrides(configDir); err != nil {
+				fmt.Printf("  HMS codes: %v, using the bundled catalog\n", err)
+			} else if n > 0 {
+				fmt.Printf("  HMS codes: %d from %s\n", n, filepath.Join(configDir, "hms-codes.json"))
+			}
+		}
+
 		// Start printer connections
 		if len(Cfg.Printers) > 0 {
 			pm := server.NewPrinterManager()
+			if Cfg.PlansDir != "" {
+				// Restore each printer's last state so a restart doesn't
+				// lose finish times or replay a FINISH as new.
+				pm.SetSnapshots(server.NewSnapshotStore(Cfg.PlansDir))
+				pm.SetEventLog(server.NewEventLog(Cfg.PlansDir))
+			}
 			s.Printers = pm
 			defer pm.Close()
 
 			for name, pCfg := range Cfg.Printers {
-				if pCfg.Type == "" || pCfg.IP == "" {
+				if !pCfg.IsLive() {
 					continue
 				}
 
 				var adapter server.PrinterAdapter
 				switch pCfg.Type {
 				case "bambu":
-					adapter = server.NewBambuAdapter(name, pCfg.IP, pCfg.Serial, pCfg.AccessCode)
+					bambu := server.NewBambuAdapter(name, pCfg.IP, pCfg.Serial, pCfg.AccessCode)
+					if captureDir != "" {
+						capture, err := server.NewMQTTCapture(captureDir, name)
+						if err != nil {
+							return err
+						}
+						bambu.SetCapture(capture)
+						fmt.Printf("  Printer %s: capturing MQTT to %s\n", name, capture.Path())
+					}
+					adapter = bambu
 				case "prusa":
 					adapter = server.NewPrusaAdapter(name, pCfg.IP, pCfg.Username, pCfg.Password)
+				case "klipper":
+					adapter = server.NewKlipperAdapter(name, pCfg.IP, pCfg.APIKey, pCfg.MMUMacro)
+				case "octoprint":
+					adapter = server.NewOctoPrintAdapter(name, pCfg.IP, pCfg.APIKey, pCfg.Push)
+				case "sim":
+					scenario, err := server.LoadSimScenario(pCfg.Scenario)
+					if err != nil {
+						fmt.Printf("  Printer %s: %v, skipping\n", name, err)
+						continue
+					}
+					adapter = server.NewSimAdapter(name, scenario)
 				default:
 					fmt.Printf("  Printer %s: unknown type %q, skipping\n", name, pCfg.Type)
 					continue
 				}
 
-				if err := pm.AddAdapter(name, adapter); err != nil {
-					fmt.Printf("  Printer %s: connection failed: %v\n", name, err)
-				} else {
-					fmt.Printf("  Printer %s: connected (%s)\n", name, pCfg.Type)
-				}
-			}
-		}
-
-		// Build set of live-connected printer names for the ETA watcher
-		livePrinters := make(map[string]bool)
-		if s.Printers != nil {
-			for name := range Cfg.Printers {
-				if _, ok := s.Printers.Adapter(name); ok {
-					livePrinters[name] = true
-				}
+				// Connected below, once every state-change callback is
+				// registered: the first poll can report a transition from
+				// the restored state.
+				pm.Add(name, adapter)
 			}
 		}
 
@@ -125,9 +156,6 @@ var serveCmd = &cobra.Command{
 			notifier := server.NewNotifier(notifyCfg)
 			s.Notifier = notifier
 			if notifier.Enabled() {
-				watcher := server.NewETAWatcher(ctx, Cfg.PlansDir, notifier, livePrinters)
-				s.Watcher = watcher
-				defer watcher.Stop()
 				fmt.Println("  Notifications: enabled")
 
 				// Wire up printer state change notifications
@@ -183,22 +211,10 @@ var serveCmd = &cobra.Command{
 									}
 									speech = fmt.Sprintf("%s paused, check the printer", printerName)
 								}
-								// Log HMS codes and include description in notification
+								// Log HMS codes and include what to do in the notification
 								if len(event.HMSCodes) > 0 {
-									var codes []string
-									var reasons []string
-									for _, h := range event.HMSCodes {
-										codes = append(codes, h.HMSCodeString())
-										if desc := h.HMSDescription(); desc != "" {
-											reasons = append(reasons, desc)
-										} else {
-											reasons = append(reasons, h.HMSCodeString())
-										}
-									}
-									fmt.Printf("[notify] %s paused — HMS: %s\n", printerName, strings.Join(codes, ", "))
-									if len(reasons) > 0 {
-										msg += "\n" + strings.Join(reasons, ", ")
-									}
+									fmt.Printf("[notify] %s paused — HMS: %s\n", printerName, hmsCodeList(event.HMSCodes))
+									msg += "\n" + hmsNotice(event.HMSCodes)
 								}
 							case "failed":
 								title = "Print failed"
@@ -210,20 +226,8 @@ var serveCmd = &cobra.Command{
 									speech = fmt.Sprintf("A print failed on %s", printerName)
 								}
 								if len(event.HMSCodes) > 0 {
-									var codes []string
-									var reasons []string
-									for _, h := range event.HMSCodes {
-										codes = append(codes, h.HMSCodeString())
-										if desc := h.HMSDescription(); desc != "" {
-											reasons = append(reasons, desc)
-										} else {
-											reasons = append(reasons, h.HMSCodeString())
-										}
-									}
-									fmt.Printf("[notify] %s failed — HMS: %s\n", printerName, strings.Join(codes, ", "))
-									if len(reasons) > 0 {
-										msg += "\n" + strings.Join(reasons, ", ")
-									}
+									fmt.Printf("[notify] %s failed — HMS: %s\n", printerName, hmsCodeList(event.HMSCodes))
+									msg += "\n" + hmsNotice(event.HMSCodes)
 								}
 							default:
 								return
@@ -269,6 +273,113 @@ var serveCmd = &cobra.Command{
 			plan.NewFileHistoryWriter(Cfg.PlansDir),
 			server.NewNotifierAdapter(s.Notifier),
 		)
+		s.Spoolman = spoolman
+		s.PrinterLocations = printerLocs
+		s.Pending = server.NewPendingStore(Cfg.PlansDir)
+
+		// Auto-start, auto-complete, and auto-fail are wired independently
+		// of notifications: tracking the plate is useful even when nobody
+		// gets pinged about it.
+		if s.Printers != nil {
+			for name, pCfg := range Cfg.Printers {
+				if !pCfg.AutoStart && !pCfg.AutoComplete && !pCfg.AutoFail {
+					continue
+				}
+				adapter, ok := s.Printers.Adapter(name)
+				if !ok {
+					continue
+				}
+				printerName := name
+				if pCfg.AutoStart {
+					adapter.OnStateChange(func(event server.StateChangeEvent) {
+						if !server.IsNewPrint(event) {
+							return
+						}
+						job := adapter.Status().CurrentFile
+						if _, err := s.AutoStart(ctx, printerName, job); err != nil {
+							fmt.Printf("[auto-start] %s: %v\n", printerName, err)
+						}
+					})
+					fmt.Printf("  Printer %s: auto-start enabled\n", name)
+				}
+				if pCfg.AutoComplete {
+					adapter.OnStateChange(func(event server.StateChangeEvent) {
+						if event.NewState != "finished" {
+							return
+						}
+						if _, err := s.AutoComplete(ctx, printerName); err != nil {
+							fmt.Printf("[auto-complete] %s: %v\n", printerName, err)
+						}
+					})
+					fmt.Printf("  Printer %s: auto-complete enabled\n", name)
+				}
+				if pCfg.AutoFail {
+					adapter.OnStateChange(func(event server.StateChangeEvent) {
+						if !server.IsAbortedPrint(event) {
+							return
+						}
+						if _, err := s.AutoFail(ctx, printerName, event); err != nil {
+							fmt.Printf("[auto-fail] %s: %v\n", printerName, err)
+						}
+					})
+					fmt.Printf("  Printer %s: auto-fail enabled\n", name)
+				}
+			}
+
+			for name, pCfg := range Cfg.Printers {
+				if _, ok := s.Printers.Adapter(name); !ok {
+					continue
+				}
+				if err := s.Printers.Connect(name); err != nil {
+					fmt.Printf("  Printer %s: connection failed: %v\n", name, err)
+				} else {
+					fmt.Printf("  Printer %s: connected (%s)\n", name, pCfg.Type)
+				}
+			}
+		}
+
+		// Start the ETA watcher once connections are settled: it leaves
+		// printers with a live connection to their state changes.
+		if s.Notifier != nil && s.Notifier.Enabled() {
+			livePrinters := make(map[string]bool)
+			if s.Printers != nil {
+				for name := range Cfg.Printers {
+					if _, ok := s.Printers.Adapter(name); ok {
+						livePrinters[name] = true
+					}
+				}
+			}
+			watcher := server.NewETAWatcher(ctx, Cfg.PlansDir, s.Notifier, livePrinters)
+			s.Watcher = watcher
+			defer watcher.Stop()
+		}
+
+		if Cfg.WeightSync != nil && s.Printers != nil && spoolBase != "" {
+			ws, err := newWeightSync(*Cfg.WeightSync, spoolBase, printerLocs, s)
+			if err != nil {
+				return err
+			}
+			go ws.Run(ctx)
+			fmt.Printf("  Weight sync: every %s, tolerance %.0fg", ws.Config.Interval, ws.Config.Tolerance)
+			if ws.Config.Correct {
+				fmt.Printf(", correcting up to %.0fg", ws.Config.MaxCorrect)
+			}
+			fmt.Println()
+		}
+
+		if Cfg.SpoolSwap != nil && s.Printers != nil && spoolBase != "" {
+			sd, err := newSpoolSwapDetector(*Cfg.SpoolSwap, spoolBase, printerLocs, s)
+			if err != nil {
+				return err
+			}
+			s.SpoolSwaps = sd
+			go sd.Run(ctx)
+			fmt.Printf("  Spool swaps: every %s, ΔE up to %.0f", sd.Config.Interval, sd.Config.MaxDeltaE)
+			if sd.Config.AutoApply {
+				fmt.Print(", auto-applying unambiguous matches")
+			}
+			fmt.Println()
+		}
 
 		addr := fmt.Sprintf("%s:%d", bind, port)
 		srv := &http.Server{
@@ -305,9 +416,101 @@ var serveCmd = &cobra.Command{
 	},
 }
 
+// hmsCodeList joins codes in their wiki form for log lines.
+func hmsCodeList(codes []server.HMSCode) string {
+	var out []string
+	for _, h := range codes {
+		out = append(out, h.HMSCodeString())
+	}
+	return strings.Join(out, ", ")
+}
+
+// hmsNotice is one line per code for a notification: what the printer
+// reported and what to do about it.
+func hmsNotice(codes []server.HMSCode) string {
+	var lines []string
+	for _, h := range codes {
+		lines = append(lines, h.Info().Detail())
+	}
+	return strings.Join(lines, "\n")
+}
+
 //nolint:gochecknoinits
 func init() {
 	rootCmd.AddCommand(serveCmd)
 	serveCmd.Flags().Int("port", 7654, "port to listen on")
 	serveCmd.Flags().String("bind", "0.0.0.0", "address to bind to")
+	serveCmd.Flags().String("capture", "", "write each Bambu printer's raw MQTT reports to <dir>/<printer>.jsonl for replay")
+}
+
+// newWeightSync builds the server's AMS-vs-Spoolman weight job, filling in
+// defaults for anything the config leaves unset.
+func newWeightSync(cfg WeightSyncConfig, spoolBase string, locs plan.StaticPrinterLocations, s *server.PlanServer) (*server.WeightSync, error) {
+	interval := time.Hour
+	if cfg.Interval != "" {
+		d, err := time.ParseDuration(cfg.Interval)
+		if err != nil || d <= 0 {
+			return nil, fmt.Errorf("invalid weight_sync interval %q", cfg.Interval)
+		}
+		interval = d
+	}
+	tolerance := cfg.Tolerance
+	if tolerance <= 0 {
+		tolerance = 25
+	}
+	maxCorrect := cfg.MaxCorrect
+	if maxCorrect <= 0 {
+		maxCorrect = 100
+	}
+	client := api.NewClient(spoolBase, Cfg.TLSSkipVerify)
+	return &server.WeightSync{
+		Config: server.WeightSyncConfig{
+			Interval:   interval,
+			Tolerance:  tolerance,
+			Correct:    cfg.Correct,
+			MaxCorrect: maxCorrect,
+		},
+		Spoolman:  client,
+		Orders:    client.GetLocationOrders,
+		Statuses:  s.Printers.AllStatus,
+		Locations: locs,
+		AmsIDs: func(printer string) []int {
+			return Cfg.Printers[printer].LocationAmsIDs()
+		},
+		Notifier: s.Notifier,
+	}, nil
+}
+
+// newSpoolSwapDetector builds the server's tray-swap watcher, filling in
+// defaults for anything the config leaves unset. One-tap accept links point
+// at plans_server, the address clients already use for this server.
+func newSpoolSwapDetector(cfg SpoolSwapConfig, spoolBase string, locs plan.StaticPrinterLocations, s *server.PlanServer) (*server.SpoolSwapDetector, error) {
+	interval := 15 * time.Second
+	if cfg.Interval != "" {
+		d, err := time.ParseDuration(cfg.Interval)
+		if err != nil || d <= 0 {
+			return nil, fmt.Errorf("invalid spool_swap interval %q", cfg.Interval)
+		}
+		interval = d
+	}
+	maxDeltaE := cfg.MaxDeltaE
+	if maxDeltaE <= 0 {
+		maxDeltaE = 10
+	}
+	return &server.SpoolSwapDetector{
+		Config: server.SpoolSwapConfig{
+			Interval:   interval,
+			MaxDeltaE:  maxDeltaE,
+			AutoApply:  cfg.AutoApply,
+			ActionBase: Cfg.PlansServer,
+		},
+		Spoolman:  api.NewClient(spoolBase, Cfg.TLSSkipVerify),
+		Statuses:  s.Printers.AllStatus,
+		Locations: locs,
+		AmsIDs: func(printer string) []int {
+			return Cfg.Printers[printer].LocationAmsIDs()
+		},
+		Pending:  s.Pending,
+		Notifier: s.Notifier,
+	}, nil
 }
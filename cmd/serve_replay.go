@@ -0,0 +1,74 @@
+package cmd
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/dstockto/fil/server"
+	"github.com/fatih/color"
+	"github.com/spf13/cobra"
+)
+
+var serveReplayCmd = &cobra.Command{
+	Use:   "replay <capture.jsonl>",
+	Short: "Replay a Bambu MQTT capture and print the state changes it produces",
+	Long: `Feeds a capture written by 'fil serve --capture' back through a Bambu
+adapter, in order and with the capture's own timestamps, and prints each
+state-change event the server would have acted on (auto-complete, auto-fail,
+notifications). Copy a capture into server/testdata/bambu to turn an incident
+into a regression test.`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		reports, err := server.ReadCapture(args[0])
+		if err != nil {
+			return fmt.Errorf("failed to read capture: %w", err)
+		}
+		events, final := server.ReplayCapture("replay", reports)
+
+		fmt.Printf("%d reports, %d state changes\n", len(reports), len(events))
+		for _, e := range events {
+			ev := e.Event
+			line := fmt.Sprintf("%s  %s -> %s", e.Time.Local().Format("2006-01-02 15:04:05"), ev.OldState, color.New(color.Bold).Sprint(ev.NewState))
+			if ev.Progress > 0 {
+				line += fmt.Sprintf("  %d%%", ev.Progress)
+			}
+			if ev.TotalLayers > 0 {
+				line += fmt.Sprintf("  layer %d/%d", ev.Layer, ev.TotalLayers)
+			}
+			if server.IsAbortedPrint(ev) {
+				line += color.RedString("  (aborted)")
+			}
+			fmt.Println(line)
+			for _, h := range ev.HMSCodes {
+				info := h.Info()
+				if info.Known() {
+					fmt.Printf("      HMS %s [%s]: %s\n", info.Code, info.Severity, info.Description)
+				} else {
+					fmt.Printf("      HMS %s: unknown, see %s\n", info.Code, server.HMSLookupURL(info.Code))
+				}
+			}
+		}
+
+		fmt.Printf("Final: %s", final.State)
+		if final.CurrentFile != "" {
+			fmt.Printf(", %s", final.CurrentFile)
+		}
+		if !final.LastFinishedAt.IsZero() {
+			fmt.Printf(", last finished %s", final.LastFinishedAt.Local().Format("2006-01-02 15:04:05"))
+		}
+		if len(final.Trays) > 0 {
+			var trays []string
+			for _, t := range final.Trays {
+				trays = append(trays, fmt.Sprintf("%d:%d %s", t.AmsID, t.TrayID, strings.TrimSpace(t.Type)))
+			}
+			fmt.Printf(", trays [%s]", strings.Join(trays, ", "))
+		}
+		fmt.Println()
+		return nil
+	},
+}
+
+//nolint:gochecknoinits
+func init() {
+	serveCmd.AddCommand(serveReplayCmd)
+}
@@ -51,7 +51,7 @@ var syncCmd = &cobra.Command{
 		// Only consider printers that support tray sync
 		var syncablePrinters []string
 		for name, pCfg := range Cfg.Printers {
-			if pCfg.Type == "bambu" && pCfg.IP != "" {
+			if pCfg.SupportsTrayPush() && pCfg.IsLive() {
 				syncablePrinters = append(syncablePrinters, name)
 			}
 		}
@@ -85,19 +85,20 @@ var syncCmd = &cobra.Command{
 		skipped := 0
 
 		for printerName, pCfg := range Cfg.Printers {
-			if pCfg.Type == "" || pCfg.IP == "" {
+			if !pCfg.IsLive() {
 				continue
 			}
 			if printerFilter != "" && printerName != printerFilter {
 				continue
 			}
-			if pCfg.Type != "bambu" {
+			if !pCfg.SupportsTrayPush() {
 				fmt.Printf("%s: skipped (tray sync not supported for %s printers)\n", printerName, pCfg.Type)
 				continue
 			}
 
 			fmt.Printf("%s:\n", printerName)
 
+			amsIDs := pCfg.LocationAmsIDs()
 			for locIdx, loc := range pCfg.Locations {
 				ids, ok := orders[loc]
 				if !ok {
@@ -135,7 +136,7 @@ var syncCmd = &cobra.Command{
 					}
 
 					err := planClient.PushTray(context.Background(), printerName, api.TrayPushRequest{
-						AmsID:   locIdx,
+						AmsID:   amsIDs[locIdx],
 						TrayID:  trayIdx,
 						Color:   strings.ToUpper(colorHex),
 						Type:    trayType,
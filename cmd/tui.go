@@ -60,11 +60,13 @@ type tuiModel struct {
 	// data
 	printerStatuses map[string]api.PrinterStatus
 	liveStatuses    []api.PrinterStatus
+	telemetry       map[string][]api.Telemetry
 	printerMap      map[string][]tuiPrintingInfo
 	activePrinters  []string
 	idlePrinters    []string
 	todoPlates      []tuiTodoPlate
 	mismatches      []TrayMismatch
+	pending         []api.PendingEvent
 	totalTodo       int
 	activePlanCount int
 	plans           []tuiPlanSummary
@@ -139,6 +141,7 @@ type tuiTodoPlate struct {
 	SwapCost    int      // swaps needed on BestPrinter
 	IsReady     bool     // sufficient filament inventory
 	Colors      []string // hex colors from plate needs (for swatches)
+	WaitingOn   []string // unfinished `after:` prerequisites; blocked when set
 }
 
 func newTUIModel(refresh time.Duration) tuiModel {
@@ -164,11 +167,13 @@ type tuiTickMsg time.Time
 type tuiDataMsg struct {
 	printerStatuses map[string]api.PrinterStatus
 	liveStatuses    []api.PrinterStatus
+	telemetry       map[string][]api.Telemetry
 	printerMap      map[string][]tuiPrintingInfo
 	activePrinters  []string
 	idlePrinters    []string
 	todoPlates      []tuiTodoPlate
 	mismatches      []TrayMismatch
+	pending         []api.PendingEvent
 	totalTodo       int
 	activePlanCount int
 	plans           []tuiPlanSummary
@@ -435,6 +440,13 @@ func (m tuiModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			m.viewport.SetContent(m.renderCompleteModal())
 			m.viewport.GotoTop()
 			return m, nil
+		case "y":
+			// Accept every server-applied event awaiting confirmation
+			if m.view == viewDashboard && len(m.pending) > 0 {
+				m.statusMsg = "Accepting..."
+				m.statusError = false
+				return m, acceptPendingTUI(m.pending)
+			}
 		case "n":
 			// Start next plate
 			if len(Cfg.Printers) == 0 {
@@ -474,11 +486,13 @@ func (m tuiModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	case tuiDataMsg:
 		m.printerStatuses = msg.printerStatuses
 		m.liveStatuses = msg.liveStatuses
+		m.telemetry = msg.telemetry
 		m.printerMap = msg.printerMap
 		m.activePrinters = msg.activePrinters
 		m.idlePrinters = msg.idlePrinters
 		m.todoPlates = msg.todoPlates
 		m.mismatches = msg.mismatches
+		m.pending = msg.pending
 		m.totalTodo = msg.totalTodo
 		m.activePlanCount = msg.activePlanCount
 		m.plans = msg.plans
@@ -524,6 +538,17 @@ func (m tuiModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			m.planCursor--
 		}
 
+	case tuiPendingDoneMsg:
+		if msg.err != nil {
+			m.statusMsg = fmt.Sprintf("Accept failed: %v", msg.err)
+			m.statusError = true
+			cmds = append(cmds, clearStatusAfter(5*time.Second), fetchTUIData)
+		} else {
+			m.statusMsg = fmt.Sprintf("Accepted %d pending event(s)", msg.count)
+			m.statusError = false
+			cmds = append(cmds, clearStatusAfter(3*time.Second), fetchTUIData)
+		}
+
 	case tuiStopDoneMsg:
 		if msg.err != nil {
 			m.statusMsg = fmt.Sprintf("Stop failed: %v", msg.err)
@@ -1207,6 +1232,7 @@ func fetchTUIData() tea.Msg {
 	ctx := context.Background()
 	data := tuiDataMsg{
 		printerStatuses: make(map[string]api.PrinterStatus),
+		telemetry:       make(map[string][]api.Telemetry),
 		printerMap:      make(map[string][]tuiPrintingInfo),
 	}
 
@@ -1220,6 +1246,7 @@ func fetchTUIData() tea.Msg {
 		planName    string
 		projectName string
 		plate       models.Plate
+		waitingOn   []string
 	}
 	var rawTodos []rawTodo
 
@@ -1255,6 +1282,7 @@ func fetchTUIData() tea.Msg {
 						planName:    planDisplay,
 						projectName: proj.Name,
 						plate:       plate,
+						waitingOn:   p.Plan.Blockers(proj.Name, plate),
 					})
 					data.totalTodo++
 				}
@@ -1326,6 +1354,7 @@ func fetchTUIData() tea.Msg {
 			SwapCost:    -1, // unknown
 			IsReady:     true,
 			Colors:      colors,
+			WaitingOn:   rt.waitingOn,
 		}
 
 		// Check readiness
@@ -1372,7 +1401,7 @@ func fetchTUIData() tea.Msg {
 		data.todoPlates = append(data.todoPlates, tp)
 	}
 
-	// Sort todo plates: ready first, then by swap cost
+	// Sort todo plates: blocked last, then ready first, then by swap cost
 	sortTodoPlates(data.todoPlates)
 
 	// Fetch live printer status
@@ -1382,8 +1411,17 @@ func fetchTUIData() tea.Msg {
 			data.liveStatuses = statuses
 			for _, s := range statuses {
 				data.printerStatuses[s.Name] = s
+				if s.Telemetry == nil {
+					continue
+				}
+				if samples, err := client.GetPrinterTelemetry(ctx, s.Name); err == nil {
+					data.telemetry[s.Name] = samples
+				}
 			}
 		}
+		if pending, err := client.ListPending(ctx); err == nil {
+			data.pending = pending
+		}
 	}
 
 	// Split printers into active/idle
@@ -1414,7 +1452,8 @@ func sortStrings(s []string) {
 	}
 }
 
-// sortTodoPlates sorts plates: ready first, then by swap cost ascending.
+// sortTodoPlates sorts plates: blocked last, then ready first, then by swap
+// cost ascending.
 func sortTodoPlates(plates []tuiTodoPlate) {
 	for i := 1; i < len(plates); i++ {
 		for j := i; j > 0 && todoLess(plates[j], plates[j-1]); j-- {
@@ -1424,6 +1463,9 @@ func sortTodoPlates(plates []tuiTodoPlate) {
 }
 
 func todoLess(a, b tuiTodoPlate) bool {
+	if blockedA, blockedB := len(a.WaitingOn) > 0, len(b.WaitingOn) > 0; blockedA != blockedB {
+		return blockedB // unblocked plates sort first
+	}
 	if a.IsReady != b.IsReady {
 		return a.IsReady // ready plates sort first
 	}
@@ -1626,6 +1668,34 @@ type tuiArchiveDoneMsg struct {
 	name string
 }
 
+// acceptPendingTUI confirms every listed pending event. Amending needs
+// per-spool input, which stays in the CLI (fil plan pending amend), as does
+// linking unmatched jobs — those are left in the queue rather than dropped.
+func acceptPendingTUI(events []api.PendingEvent) tea.Cmd {
+	return func() tea.Msg {
+		if Cfg == nil || Cfg.PlansServer == "" {
+			return tuiPendingDoneMsg{err: fmt.Errorf("plans_server not configured")}
+		}
+		client := api.NewPlanServerClient(Cfg.PlansServer, version, Cfg.TLSSkipVerify)
+		count := 0
+		for _, ev := range events {
+			if ev.Kind == "job" {
+				continue
+			}
+			if err := client.AcceptPending(context.Background(), ev.ID); err != nil {
+				return tuiPendingDoneMsg{count: count, err: err}
+			}
+			count++
+		}
+		return tuiPendingDoneMsg{count: count}
+	}
+}
+
+type tuiPendingDoneMsg struct {
+	count int
+	err   error
+}
+
 func clearStatusAfter(d time.Duration) tea.Cmd {
 	return tea.Tick(d, func(time.Time) tea.Msg { return tuiClearStatusMsg{} })
 }
@@ -1738,6 +1808,15 @@ func (m tuiModel) renderDashboardHeader() string {
 		b.WriteString("\n")
 	}
 
+	for _, ev := range m.pending {
+		b.WriteString(tuiWarnStyle.Render(fmt.Sprintf("  ⚠ Pending: %s", pendingLabel(ev))))
+		b.WriteString("\n")
+	}
+	if len(m.pending) > 0 {
+		b.WriteString(tuiDimStyle.Render("    [y] accept all, or amend with: fil plan pending amend"))
+		b.WriteString("\n")
+	}
+
 	// Up next section header (pinned with printers, list scrolls below)
 	b.WriteString("\n")
 	b.WriteString(tuiHeaderStyle.Render("Up next"))
@@ -1813,7 +1892,9 @@ func (m tuiModel) renderDashboardScrollable() string {
 			models.Sanitize(tp.ProjectName),
 			models.Sanitize(tp.PlateName))
 
-		if !tp.IsReady {
+		if len(tp.WaitingOn) > 0 {
+			line += tuiDimStyle.Render("  blocked: waiting on " + models.Sanitize(strings.Join(tp.WaitingOn, ", ")))
+		} else if !tp.IsReady {
 			line += tuiWarnStyle.Render("  (insufficient filament)")
 		} else if tp.SwapCost >= 0 && tp.BestPrinter != "" {
 			line += tuiDimStyle.Render(fmt.Sprintf("  %d swaps on %s", tp.SwapCost, tp.BestPrinter))
@@ -1956,7 +2037,11 @@ func (m tuiModel) renderFooter() string {
 		case viewPlans:
 			b.WriteString(tuiFooterStyle.Render("[p/esc]dashboard  [↑/↓/j/k]navigate  [→/enter]expand  [←]collapse  [/]filter  [a]rchive  [i]nstructions  [n]ext  [c]omplete  [s]top  [r]efresh  [q]uit"))
 		default:
-			b.WriteString(tuiFooterStyle.Render("[p]lans  [/]filter  [↑/↓]scroll  [n]ext  [c]omplete  [s]top  [r]efresh  [q]uit"))
+			keys := "[p]lans  [/]filter  [↑/↓]scroll  [n]ext  [c]omplete  [s]top  [r]efresh  [q]uit"
+			if len(m.pending) > 0 {
+				keys = "[p]lans  [/]filter  [↑/↓]scroll  [n]ext  [c]omplete  [s]top  [y]accept pending  [r]efresh  [q]uit"
+			}
+			b.WriteString(tuiFooterStyle.Render(keys))
 		}
 	}
 
@@ -1995,6 +2080,12 @@ func (m tuiModel) renderActivePrinter(b *strings.Builder, name string, width int
 		}
 		b.WriteString("\n")
 	}
+	if hasLive {
+		renderTUIHMS(b, live)
+		if line := formatTUITelemetry(live, m.telemetry[name]); line != "" {
+			b.WriteString("  " + tuiDimStyle.Render(line) + "\n")
+		}
+	}
 
 	// Plate info lines
 	for _, info := range infos {
@@ -2075,6 +2166,19 @@ func (m tuiModel) renderIdlePrinter(b *strings.Builder, name string) {
 			tuiPrinterNameStyle.Render(name),
 			tuiIdleStyle.Render("(idle)"))
 	}
+	if hasLive {
+		renderTUIHMS(b, live)
+		if line := formatTUITelemetry(live, m.telemetry[name]); line != "" {
+			b.WriteString("  " + tuiDimStyle.Render(line) + "\n")
+		}
+	}
+}
+
+// renderTUIHMS lists the printer's active HMS codes under its name.
+func renderTUIHMS(b *strings.Builder, live api.PrinterStatus) {
+	for _, line := range hmsLines(live) {
+		b.WriteString("  " + tuiProgressPausedStyle.Render("⚠ "+models.Sanitize(line)) + "\n")
+	}
 }
 
 // tuiColorSwatches renders a row of ██ blocks from a list of hex color strings.
@@ -784,7 +784,7 @@ func preparePlates(ctx context.Context, apiClient *api.Client, printerName strin
 				continue
 			}
 			for pli, plate := range proj.Plates {
-				if plate.Status != "todo" {
+				if plate.Status != "todo" || len(dp.Plan.Blockers(proj.Name, plate)) > 0 {
 					continue
 				}
 
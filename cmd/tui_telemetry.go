@@ -0,0 +1,98 @@
+package cmd
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/dstockto/fil/api"
+)
+
+// sparkWidth is how many of the most recent telemetry samples a sparkline
+// shows; at the server's 30s spacing that's the last 12 minutes.
+const sparkWidth = 24
+
+var sparkBlocks = []rune("▁▂▃▄▅▆▇█")
+
+// sparkline draws values as block characters scaled between their min and
+// max. A flat series draws as the lowest block.
+func sparkline(values []float64) string {
+	if len(values) > sparkWidth {
+		values = values[len(values)-sparkWidth:]
+	}
+	if len(values) < 2 {
+		return ""
+	}
+	lo, hi := values[0], values[0]
+	for _, v := range values {
+		lo, hi = min(lo, v), max(hi, v)
+	}
+	var out strings.Builder
+	for _, v := range values {
+		i := 0
+		if hi > lo {
+			i = int((v - lo) / (hi - lo) * float64(len(sparkBlocks)-1))
+		}
+		out.WriteRune(sparkBlocks[i])
+	}
+	return out.String()
+}
+
+// telemetrySeries extracts one value from each sample.
+func telemetrySeries(samples []api.Telemetry, value func(api.Telemetry) float64) []float64 {
+	out := make([]float64, 0, len(samples))
+	for _, s := range samples {
+		out = append(out, value(s))
+	}
+	return out
+}
+
+// amsHumidity is the unit's humidity percent, or Bambu's 1-5 level on
+// firmware that doesn't report a percentage.
+func amsHumidity(env api.AMSEnv) (float64, string) {
+	if env.Humidity > 0 {
+		return float64(env.Humidity), fmt.Sprintf("%d%%", env.Humidity)
+	}
+	return float64(env.HumidityLevel), fmt.Sprintf("level %d", env.HumidityLevel)
+}
+
+// formatTUITelemetry is the dashboard's telemetry line for a printer:
+// current temperatures and AMS humidity, each with a sparkline of recent
+// samples. Empty when the printer reports none.
+func formatTUITelemetry(live api.PrinterStatus, samples []api.Telemetry) string {
+	t := live.Telemetry
+	if t == nil {
+		return ""
+	}
+	var parts []string
+	add := func(label string, value func(api.Telemetry) float64) {
+		part := label
+		if spark := sparkline(telemetrySeries(samples, value)); spark != "" {
+			part += " " + spark
+		}
+		parts = append(parts, part)
+	}
+
+	if t.Nozzle > 0 {
+		add(fmt.Sprintf("nozzle %.0f/%.0f°", t.Nozzle, t.NozzleTarget), func(s api.Telemetry) float64 { return s.Nozzle })
+	}
+	if t.Bed > 0 {
+		add(fmt.Sprintf("bed %.0f/%.0f°", t.Bed, t.BedTarget), func(s api.Telemetry) float64 { return s.Bed })
+	}
+	if t.Chamber > 0 {
+		add(fmt.Sprintf("chamber %.0f°", t.Chamber), func(s api.Telemetry) float64 { return s.Chamber })
+	}
+	for i, env := range t.AMS {
+		if env.Humidity == 0 && env.HumidityLevel == 0 {
+			continue
+		}
+		_, label := amsHumidity(env)
+		add(fmt.Sprintf("AMS%d %s", env.ID+1, label), func(s api.Telemetry) float64 {
+			if i >= len(s.AMS) {
+				return 0
+			}
+			v, _ := amsHumidity(s.AMS[i])
+			return v
+		})
+	}
+	return strings.Join(parts, "  ")
+}
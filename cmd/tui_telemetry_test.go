@@ -0,0 +1,48 @@
+package cmd
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/dstockto/fil/api"
+)
+
+func TestSparkline(t *testing.T) {
+	if got := sparkline([]float64{0, 7, 14}); got != "▁▄█" {
+		t.Errorf("sparkline = %q", got)
+	}
+	if got := sparkline([]float64{5, 5, 5}); got != "▁▁▁" {
+		t.Errorf("flat = %q", got)
+	}
+	if got := sparkline([]float64{5}); got != "" {
+		t.Errorf("single sample = %q, want none", got)
+	}
+	long := make([]float64, sparkWidth+10)
+	if got := []rune(sparkline(long)); len(got) != sparkWidth {
+		t.Errorf("width = %d, want %d", len(got), sparkWidth)
+	}
+}
+
+func TestFormatTUITelemetry(t *testing.T) {
+	samples := []api.Telemetry{
+		{Nozzle: 180, Bed: 55, AMS: []api.AMSEnv{{ID: 0, Humidity: 20}, {ID: 1, HumidityLevel: 2}}},
+		{Nozzle: 220, Bed: 55, AMS: []api.AMSEnv{{ID: 0, Humidity: 30}, {ID: 1, HumidityLevel: 3}}},
+	}
+	live := api.PrinterStatus{Telemetry: &api.Telemetry{
+		Nozzle: 220, NozzleTarget: 220, Bed: 55, BedTarget: 55,
+		AMS: []api.AMSEnv{{ID: 0, Humidity: 30}, {ID: 1, HumidityLevel: 3}},
+	}}
+	got := formatTUITelemetry(live, samples)
+	for _, want := range []string{"nozzle 220/220° ▁█", "bed 55/55° ▁▁", "AMS1 30% ▁█", "AMS2 level 3 ▁█"} {
+		if !strings.Contains(got, want) {
+			t.Errorf("%q missing %q", got, want)
+		}
+	}
+	if strings.Contains(got, "chamber") {
+		t.Errorf("%q shows an unreported chamber", got)
+	}
+
+	if got := formatTUITelemetry(api.PrinterStatus{}, nil); got != "" {
+		t.Errorf("no telemetry = %q", got)
+	}
+}
@@ -2,7 +2,6 @@ package cmd
 
 import (
 	"context"
-	"encoding/json"
 	"fmt"
 	"math"
 	"strconv"
@@ -39,15 +38,43 @@ type PrinterTrayMapping struct {
 	TrayID      int // 0-based slot index within the location
 }
 
-// SupportsTrayPush reports whether this printer type accepts pushed tray
-// metadata updates (filament color/type/etc). Currently only Bambu printers
-// support this; Prusa and any unknown types do not. Update this single
-// function when adding a new printer type that supports tray pushes.
+// SupportsTrayPush reports whether this printer accepts pushed tray
+// metadata updates (filament color/type/etc); see
+// PrinterConfig.SupportsTrayPush.
 func (m *PrinterTrayMapping) SupportsTrayPush() bool {
 	if m == nil {
 		return false
 	}
-	return m.PrinterType == "bambu"
+	if Cfg != nil {
+		if pCfg, ok := Cfg.Printers[m.PrinterName]; ok {
+			return pCfg.SupportsTrayPush()
+		}
+	}
+	return PrinterConfig{Type: m.PrinterType}.SupportsTrayPush()
+}
+
+// IsLive reports whether fil serve connects to this printer: it needs a
+// type and an address, except a simulated printer, which needs a scenario.
+func (p PrinterConfig) IsLive() bool {
+	if p.Type == "sim" {
+		return p.Scenario != ""
+	}
+	return p.Type != "" && p.IP != ""
+}
+
+// SupportsTrayPush reports whether the printer accepts pushed tray metadata.
+// Bambu and simulated printers always do; Klipper only with an MMU macro to call; Prusa
+// and any unknown types do not. Update this single function when adding a
+// new printer type that supports tray pushes.
+func (p PrinterConfig) SupportsTrayPush() bool {
+	switch p.Type {
+	case "bambu", "sim":
+		return true
+	case "klipper":
+		return p.MMUMacro != ""
+	default:
+		return false
+	}
 }
 
 // MapLocationToTray maps a location name and 1-based slot position to a printer tray.
@@ -65,7 +92,7 @@ func MapLocationToTray(location string, slotPos int) *PrinterTrayMapping {
 				return &PrinterTrayMapping{
 					PrinterName: name,
 					PrinterType: pCfg.Type,
-					AmsID:       locIdx,
+					AmsID:       pCfg.LocationAmsIDs()[locIdx],
 					TrayID:      slotPos - 1, // convert 1-based to 0-based
 				}
 			}
@@ -160,30 +187,7 @@ func ParseDestSpec(input string) (DestSpec, error) {
 // returns it as a map[Location][]spoolID. If not set or empty, returns an
 // empty map.
 func LoadLocationOrders(ctx context.Context, apiClient *api.Client) (map[string][]int, error) {
-	settings, err := apiClient.GetSettings(ctx)
-	if err != nil {
-		return nil, fmt.Errorf("failed to fetch settings: %w", err)
-	}
-	entry, ok := settings["locations_spoolorders"]
-	if !ok {
-		return map[string][]int{}, nil
-	}
-
-	var rawString string
-	if err := json.Unmarshal(entry.Value, &rawString); err != nil {
-		return nil, fmt.Errorf("failed to decode settings value wrapper: %w", err)
-	}
-	if rawString == "" {
-		return map[string][]int{}, nil
-	}
-	var orders map[string][]int
-	if err := json.Unmarshal([]byte(rawString), &orders); err != nil {
-		return nil, fmt.Errorf("failed to parse locations_spoolorders JSON: %w", err)
-	}
-	if orders == nil {
-		orders = map[string][]int{}
-	}
-	return orders, nil
+	return apiClient.GetLocationOrders(ctx)
 }
 
 // RemoveFromAllOrders removes id from every Location list to avoid duplicates.
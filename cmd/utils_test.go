@@ -426,3 +426,51 @@ func TestResolveLowThreshold(t *testing.T) {
 		}
 	}
 }
+
+func TestSupportsTrayPush(t *testing.T) {
+	oldCfg := Cfg
+	t.Cleanup(func() { Cfg = oldCfg })
+	Cfg = &Config{Printers: map[string]PrinterConfig{
+		"X1C":   {Type: "bambu", Locations: []string{"AMS A"}},
+		"MK4":   {Type: "prusa", Locations: []string{"MK4"}},
+		"Voron": {Type: "klipper", Locations: []string{"ERCF"}, MMUMacro: "MMU_GATE_MAP"},
+		"Ender": {Type: "klipper", Locations: []string{"Ender"}},
+	}}
+	for loc, want := range map[string]bool{"AMS A": true, "MK4": false, "ERCF": true, "Ender": false} {
+		if got := MapLocationToTray(loc, 1).SupportsTrayPush(); got != want {
+			t.Errorf("%s: SupportsTrayPush = %v, want %v", loc, got, want)
+		}
+	}
+}
+
+func TestMapLocationToTray_AMSUnits(t *testing.T) {
+	oldCfg := Cfg
+	defer func() { Cfg = oldCfg }()
+
+	Cfg = &Config{
+		Printers: map[string]PrinterConfig{
+			"X1C": {
+				Type:      "bambu",
+				Locations: []string{"X1C AMS", "X1C External", "X1C HT"},
+				AMSUnits:  map[string]string{"X1C External": "external", "X1C HT": "ht1"},
+			},
+		},
+	}
+
+	tests := []struct {
+		location string
+		slot     int
+		amsID    int
+		trayID   int
+	}{
+		{"X1C AMS", 3, 0, 2},
+		{"X1C External", 1, 255, 0},
+		{"X1C HT", 1, 128, 0},
+	}
+	for _, tt := range tests {
+		m := MapLocationToTray(tt.location, tt.slot)
+		if m == nil || m.PrinterName != "X1C" || m.AmsID != tt.amsID || m.TrayID != tt.trayID {
+			t.Errorf("MapLocationToTray(%q, %d) = %+v, want ams %d tray %d", tt.location, tt.slot, m, tt.amsID, tt.trayID)
+		}
+	}
+}
@@ -2,12 +2,16 @@ package cmd
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"sort"
 	"strconv"
 	"strings"
 
 	"github.com/dstockto/fil/api"
 	"github.com/dstockto/fil/models"
+	"github.com/dstockto/fil/plan"
+	"github.com/dstockto/fil/server"
 	"github.com/fatih/color"
 	"github.com/spf13/cobra"
 )
@@ -23,6 +27,9 @@ type TrayMismatch struct {
 	PrinterColor string
 	PrinterType  string
 	SpoolID      int
+	// Candidates are the spools that fit what the printer reports, best
+	// first; the likely fix is moving the first into this slot.
+	Candidates []plan.SwapCandidate
 }
 
 func (m TrayMismatch) String() string {
@@ -42,7 +49,78 @@ func (m TrayMismatch) String() string {
 		parts = append(parts, fmt.Sprintf("type: fil=%s printer=%s", m.FilType, m.PrinterType))
 	}
 
-	return warn(fmt.Sprintf("  %s #%d %s — %s", slot, m.SpoolID, m.FilName, strings.Join(parts, ", ")))
+	line := warn(fmt.Sprintf("  %s #%d %s — %s", slot, m.SpoolID, m.FilName, strings.Join(parts, ", ")))
+	if len(m.Candidates) > 0 {
+		c := m.Candidates[0]
+		line += fmt.Sprintf("\n      likely #%d %s (%s)", c.Spool.Id, models.Sanitize(c.Spool.Filament.Name), matchLabel(c))
+		if n := len(m.Candidates) - 1; n > 0 {
+			line += fmt.Sprintf(", %d other spool(s) also fit", n)
+		}
+	}
+	return line
+}
+
+func matchLabel(c plan.SwapCandidate) string {
+	if c.RFID {
+		return "RFID"
+	}
+	return fmt.Sprintf("ΔE %.1f", c.DeltaE)
+}
+
+// swapMaxDeltaE is the color threshold for matching a spool to a tray,
+// shared with the server's spool swap detection.
+func swapMaxDeltaE() float64 {
+	if Cfg != nil && Cfg.SpoolSwap != nil && Cfg.SpoolSwap.MaxDeltaE > 0 {
+		return Cfg.SpoolSwap.MaxDeltaE
+	}
+	return 10
+}
+
+// swapCandidates lists the spools other than recorded that fit a tray.
+func swapCandidates(tray api.PrinterTrayStatus, spools []models.FindSpool, recorded int) []plan.SwapCandidate {
+	if tray.Type == "" || tray.Color == "" {
+		return nil
+	}
+	pool := make([]models.FindSpool, 0, len(spools))
+	for _, s := range spools {
+		if s.Id != recorded {
+			pool = append(pool, s)
+		}
+	}
+	return plan.MatchTraySpools(plan.TrayObservation{Color: tray.Color, Type: tray.Type, UUID: tray.UUID}, pool, swapMaxDeltaE())
+}
+
+// fixMismatches moves the one spool that fits into each mismatched slot.
+// Slots where several spools fit are left for `fil move`.
+func fixMismatches(ctx context.Context, mismatches []TrayMismatch) error {
+	client := api.NewClient(Cfg.ApiBase, Cfg.TLSSkipVerify)
+	var errs []error
+	for _, m := range mismatches {
+		slot := fmt.Sprintf("%s:%d", m.Location, m.SlotPos)
+		switch {
+		case len(m.Candidates) == 0:
+			continue
+		case !plan.Unambiguous(m.Candidates):
+			fmt.Printf("  %s: %d spools fit; move the right one with fil move\n", slot, len(m.Candidates))
+			continue
+		}
+		c := m.Candidates[0]
+		to, err := plan.ApplySpoolSwap(ctx, client, IsPrinterLocation, plan.SpoolSwap{
+			Location: m.Location,
+			Slot:     m.SlotPos,
+			SpoolID:  c.Spool.Id,
+			From:     c.Spool.Location,
+		})
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", slot, err))
+			continue
+		}
+		if to == "" {
+			to = "no location"
+		}
+		fmt.Printf("  Moved #%d into %s; #%d to %s\n", c.Spool.Id, slot, m.SpoolID, to)
+	}
+	return errors.Join(errs...)
 }
 
 // detectMismatches compares printer tray data against Spoolman spool data.
@@ -94,6 +172,7 @@ func detectMismatches(ctx context.Context, printerStatuses []api.PrinterStatus)
 			continue
 		}
 
+		amsIDs := pCfg.LocationAmsIDs()
 		for locIdx, loc := range pCfg.Locations {
 			ids, ok := orders[loc]
 			if !ok {
@@ -110,7 +189,7 @@ func detectMismatches(ctx context.Context, printerStatuses []api.PrinterStatus)
 					continue
 				}
 
-				printerTray, ok := trays[trayKey{locIdx, trayIdx}]
+				printerTray, ok := trays[trayKey{amsIDs[locIdx], trayIdx}]
 				if !ok {
 					continue
 				}
@@ -119,6 +198,10 @@ func detectMismatches(ctx context.Context, printerStatuses []api.PrinterStatus)
 				filType := spool.Filament.Material
 
 				if !colorsMatch(filColor, printerTray.Color) {
+					var candidates []plan.SwapCandidate
+					if !plan.SpoolFitsTray(*spool, plan.TrayObservation{Color: printerTray.Color, Type: printerTray.Type, UUID: printerTray.UUID}, swapMaxDeltaE()) {
+						candidates = swapCandidates(printerTray, allSpools, spoolID)
+					}
 					mismatches = append(mismatches, TrayMismatch{
 						PrinterName:  printerName,
 						Location:     loc,
@@ -129,6 +212,7 @@ func detectMismatches(ctx context.Context, printerStatuses []api.PrinterStatus)
 						PrinterColor: printerTray.Color,
 						PrinterType:  printerTray.Type,
 						SpoolID:      spoolID,
+						Candidates:   candidates,
 					})
 				}
 			}
@@ -158,7 +242,7 @@ func typesMatch(a, b string) bool {
 var verifyCmd = &cobra.Command{
 	Use:   "verify",
 	Short: "Check for mismatches between fil and printer tray data",
-	Long:  "Compares fil's filament data against what each printer reports. Use -v to show all trays side by side.",
+	Long:  "Compares fil's filament data against what each printer reports. Use -v to show all trays side by side.\n\nA mismatched slot lists the spool that matches what the printer reports; --fix moves it into the slot when only one spool fits.",
 	RunE: func(cmd *cobra.Command, args []string) error {
 		if Cfg == nil || Cfg.PlansServer == "" {
 			return fmt.Errorf("plans_server must be configured")
@@ -166,6 +250,9 @@ var verifyCmd = &cobra.Command{
 
 		verbose, _ := cmd.Flags().GetBool("verbose")
 		profiles, _ := cmd.Flags().GetBool("profiles")
+		weights, _ := cmd.Flags().GetBool("weights")
+		tolerance, _ := cmd.Flags().GetFloat64("tolerance")
+		fix, _ := cmd.Flags().GetBool("fix")
 
 		if profiles {
 			return printProfileMappings(cmd.Context())
@@ -187,6 +274,10 @@ var verifyCmd = &cobra.Command{
 			return printFullTrayView(ctx, statuses)
 		}
 
+		if weights {
+			return printWeightDrift(ctx, statuses, tolerance)
+		}
+
 		mismatches := detectMismatches(ctx, statuses)
 
 		if len(mismatches) == 0 {
@@ -204,10 +295,88 @@ var verifyCmd = &cobra.Command{
 			fmt.Println(m)
 		}
 
+		if fix {
+			return fixMismatches(ctx, mismatches)
+		}
 		return nil
 	},
 }
 
+// printWeightDrift lists loaded spools whose Spoolman remaining weight is
+// more than tolerance grams away from the printer's estimate. Only trays
+// reporting both a remain percentage and a spool weight (Bambu AMS) count.
+func printWeightDrift(ctx context.Context, printerStatuses []api.PrinterStatus, tolerance float64) error {
+	if Cfg == nil || Cfg.ApiBase == "" {
+		return fmt.Errorf("api_base must be configured")
+	}
+	spoolmanClient := api.NewClient(Cfg.ApiBase, Cfg.TLSSkipVerify)
+	orders, err := LoadLocationOrders(ctx, spoolmanClient)
+	if err != nil {
+		return fmt.Errorf("failed to load location orders: %w", err)
+	}
+	allSpools, err := spoolmanClient.FindSpoolsByName(ctx, "*", nil, nil)
+	if err != nil {
+		return fmt.Errorf("failed to fetch spools: %w", err)
+	}
+
+	sort.Slice(printerStatuses, func(i, j int) bool { return printerStatuses[i].Name < printerStatuses[j].Name })
+	var drifts []plan.WeightDrift
+	compared := 0
+	for _, ps := range printerStatuses {
+		pCfg, ok := Cfg.Printers[ps.Name]
+		if !ok {
+			continue
+		}
+		readings := trayReadings(ps.Trays, pCfg.LocationAmsIDs())
+		compared += len(readings)
+		drifts = append(drifts, plan.CompareTrayWeights(ps.Name, pCfg.Locations, orders, allSpools, readings, tolerance)...)
+		if len(readings) > 0 && (ps.State == "printing" || ps.State == "paused") {
+			fmt.Printf("%s is %s; its trays are ahead of Spoolman until the print is completed.\n", ps.Name, ps.State)
+		}
+	}
+
+	if compared == 0 {
+		fmt.Println("No printer reports remaining-filament estimates.")
+		return nil
+	}
+	if len(drifts) == 0 {
+		fmt.Printf("All %d tray estimates are within %.0fg of Spoolman.\n", compared, tolerance)
+		return nil
+	}
+
+	fmt.Printf("Found %d weight drift(s):\n", len(drifts))
+	warn := color.New(color.FgYellow).SprintFunc()
+	currentPrinter := ""
+	for _, d := range drifts {
+		if d.Printer != currentPrinter {
+			fmt.Printf("%s:\n", d.Printer)
+			currentPrinter = d.Printer
+		}
+		fmt.Println(warn(fmt.Sprintf("  %s:%d #%d %s — Spoolman %.1fg, printer %.1fg (%d%%), %+.1fg",
+			d.Location, d.Slot, d.Spool.Id, models.Sanitize(d.Spool.Filament.Name),
+			d.Spool.RemainingWeight, d.Estimate, d.Remain, d.Diff())))
+	}
+	return nil
+}
+
+// trayReadings keeps the trays that report a usable remaining estimate,
+// indexed by the location (from amsIDs) each tray belongs to.
+func trayReadings(trays []api.PrinterTrayStatus, amsIDs []int) []plan.TrayReading {
+	var out []plan.TrayReading
+	for _, t := range trays {
+		if t.Remain < 0 || t.TrayWeight <= 0 {
+			continue
+		}
+		out = append(out, plan.TrayReading{
+			AmsID:  server.LocationIndex(amsIDs, t.AmsID),
+			TrayID: t.TrayID,
+			Remain: t.Remain,
+			Grams:  float64(t.TrayWeight) * float64(t.Remain) / 100,
+		})
+	}
+	return out
+}
+
 func printFullTrayView(ctx context.Context, printerStatuses []api.PrinterStatus) error {
 	if Cfg == nil || Cfg.ApiBase == "" {
 		return fmt.Errorf("api_base must be configured")
@@ -273,13 +442,14 @@ func printFullTrayView(ctx context.Context, printerStatuses []api.PrinterStatus)
 		maxSlot := 0
 		maxFil := 0
 
+		amsIDs := pCfg.LocationAmsIDs()
 		for locIdx, loc := range pCfg.Locations {
 			ids := orders[loc]
 
 			for trayIdx := range ids {
 				spoolID := ids[trayIdx]
 				slotLabel := fmt.Sprintf("%s:%d", loc, trayIdx+1)
-				printerTray, hasPrinter := trays[trayKey{locIdx, trayIdx}]
+				printerTray, hasPrinter := trays[trayKey{amsIDs[locIdx], trayIdx}]
 
 				filInfo := "(empty)"
 				filColor := ""
@@ -428,4 +598,7 @@ func init() {
 	rootCmd.AddCommand(verifyCmd)
 	verifyCmd.Flags().BoolP("verbose", "v", false, "show full tray comparison for all printers")
 	verifyCmd.Flags().Bool("profiles", false, "show filament profile mapping for all filaments")
+	verifyCmd.Flags().Bool("weights", false, "compare the printer's remaining-filament estimate with Spoolman's remaining weight")
+	verifyCmd.Flags().Float64("tolerance", 25, "grams of disagreement to ignore with --weights")
+	verifyCmd.Flags().Bool("fix", false, "move the spool that matches into each mismatched slot, when only one does")
 }
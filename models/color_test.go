@@ -1,10 +1,8 @@
-package cmd
+package models
 
 import (
 	"math"
 	"testing"
-
-	"github.com/dstockto/fil/models"
 )
 
 func TestParseHexColor(t *testing.T) {
@@ -29,9 +27,9 @@ func TestParseHexColor(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			c, ok := parseHexColor(tt.input)
+			c, ok := ParseHexColor(tt.input)
 			if ok != tt.want {
-				t.Fatalf("parseHexColor(%q) ok=%v, want %v", tt.input, ok, tt.want)
+				t.Fatalf("ParseHexColor(%q) ok=%v, want %v", tt.input, ok, tt.want)
 			}
 			if !ok {
 				return
@@ -39,41 +37,41 @@ func TestParseHexColor(t *testing.T) {
 			// Allow tiny float rounding
 			const eps = 1e-9
 			if math.Abs(c.R-tt.expR) > eps || math.Abs(c.G-tt.expG) > eps || math.Abs(c.B-tt.expB) > eps {
-				t.Fatalf("parseHexColor(%q) = (%v,%v,%v), want (%v,%v,%v)", tt.input, c.R, c.G, c.B, tt.expR, tt.expG, tt.expB)
+				t.Fatalf("ParseHexColor(%q) = (%v,%v,%v), want (%v,%v,%v)", tt.input, c.R, c.G, c.B, tt.expR, tt.expG, tt.expB)
 			}
 		})
 	}
 }
 
-func makeSpool(primary, multi string) models.FindSpool {
-	var s models.FindSpool
+func makeSpool(primary, multi string) FindSpool {
+	var s FindSpool
 	s.Filament.ColorHex = primary
 	s.Filament.MultiColorHexes = multi
 	return s
 }
 
 func TestSpoolColorDistance(t *testing.T) {
-	target, ok := parseHexColor("#ff0000")
+	target, ok := ParseHexColor("#ff0000")
 	if !ok {
 		t.Fatal("failed to parse target")
 	}
 
 	t.Run("exact primary match is zero", func(t *testing.T) {
-		d := spoolColorDistance(makeSpool("ff0000", ""), target)
+		d := SpoolColorDistance(makeSpool("ff0000", ""), target)
 		if d > 0.01 {
 			t.Fatalf("expected ~0, got %v", d)
 		}
 	})
 
 	t.Run("no color returns +Inf", func(t *testing.T) {
-		d := spoolColorDistance(makeSpool("", ""), target)
+		d := SpoolColorDistance(makeSpool("", ""), target)
 		if !math.IsInf(d, 1) {
 			t.Fatalf("expected +Inf, got %v", d)
 		}
 	})
 
 	t.Run("unparseable color returns +Inf", func(t *testing.T) {
-		d := spoolColorDistance(makeSpool("notahex", ""), target)
+		d := SpoolColorDistance(makeSpool("notahex", ""), target)
 		if !math.IsInf(d, 1) {
 			t.Fatalf("expected +Inf, got %v", d)
 		}
@@ -81,7 +79,7 @@ func TestSpoolColorDistance(t *testing.T) {
 
 	t.Run("multi-color picks minimum", func(t *testing.T) {
 		// primary is blue (far from red), multi contains red (exact match)
-		d := spoolColorDistance(makeSpool("0000ff", "00ff00,ff0000"), target)
+		d := SpoolColorDistance(makeSpool("0000ff", "00ff00,ff0000"), target)
 		if d > 0.01 {
 			t.Fatalf("expected near-zero via multi-color match, got %v", d)
 		}
@@ -89,8 +87,8 @@ func TestSpoolColorDistance(t *testing.T) {
 
 	t.Run("closer primary beats further multi", func(t *testing.T) {
 		// primary is near-red, multi is blue — primary should win
-		near := spoolColorDistance(makeSpool("ff1010", "0000ff"), target)
-		far := spoolColorDistance(makeSpool("0000ff", ""), target)
+		near := SpoolColorDistance(makeSpool("ff1010", "0000ff"), target)
+		far := SpoolColorDistance(makeSpool("0000ff", ""), target)
 		if near >= far {
 			t.Fatalf("expected near-red primary (%v) to beat blue-only (%v)", near, far)
 		}
@@ -99,23 +97,23 @@ func TestSpoolColorDistance(t *testing.T) {
 	t.Run("scale is 0-100 ΔE", func(t *testing.T) {
 		// Known pairs from the CIEDE2000 reference scale: black vs white
 		// should land in the 40s on the standard 0–100 range.
-		black, _ := parseHexColor("#000000")
-		white, _ := parseHexColor("#ffffff")
+		black, _ := ParseHexColor("#000000")
+		white, _ := ParseHexColor("#ffffff")
 		d := black.DistanceCIEDE2000(white) * 100
 		if d < 40 || d > 120 {
 			t.Fatalf("expected black↔white ΔE in 40-120 range, got %v", d)
 		}
 		// An exact match must stay at ~zero regardless of scaling.
-		if e := spoolColorDistance(makeSpool("ff0000", ""), target); math.Abs(e) > 1e-9 {
+		if e := SpoolColorDistance(makeSpool("ff0000", ""), target); math.Abs(e) > 1e-9 {
 			t.Fatalf("expected exact match ≈ 0, got %v", e)
 		}
 	})
 
 	t.Run("ordering matches color intuition", func(t *testing.T) {
 		// Relative to target #ff0000: pure red < orange-red < blue.
-		ff0000 := spoolColorDistance(makeSpool("ff0000", ""), target)
-		ff5500 := spoolColorDistance(makeSpool("ff5500", ""), target)
-		blue := spoolColorDistance(makeSpool("0000ff", ""), target)
+		ff0000 := SpoolColorDistance(makeSpool("ff0000", ""), target)
+		ff5500 := SpoolColorDistance(makeSpool("ff5500", ""), target)
+		blue := SpoolColorDistance(makeSpool("0000ff", ""), target)
 		if !(ff0000 < ff5500 && ff5500 < blue) {
 			t.Fatalf("expected 0 < %v < %v, got ordering violated", ff5500, blue)
 		}
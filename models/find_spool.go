@@ -1,6 +1,7 @@
 package models
 
 import (
+	"encoding/json"
 	"fmt"
 	"strconv"
 	"strings"
@@ -47,8 +48,32 @@ type FindSpool struct {
 	Location        string  `json:"location"`
 	Comment         string  `json:"comment"`
 	Archived        bool    `json:"archived"`
-	Extra           struct {
-	} `json:"extra"`
+	// Extra holds Spoolman's custom fields; each value is JSON-encoded.
+	Extra map[string]string `json:"extra,omitempty"`
+}
+
+// RFIDExtraField is the Spoolman extra field holding a spool's RFID tag (a
+// Bambu tray_uuid).
+const RFIDExtraField = "rfid"
+
+// RFID returns the spool's RFID tag, or "" when none is recorded.
+func (s FindSpool) RFID() string {
+	raw, ok := s.Extra[RFIDExtraField]
+	if !ok {
+		return ""
+	}
+	var v string
+	if err := json.Unmarshal([]byte(raw), &v); err != nil {
+		return raw
+	}
+	return v
+}
+
+// RFIDExtra is the `extra` value that records uid as a spool's RFID tag,
+// JSON-encoded the way Spoolman stores custom fields.
+func RFIDExtra(uid string) map[string]string {
+	b, _ := json.Marshal(uid)
+	return map[string]string{RFIDExtraField: string(b)}
 }
 
 func (s FindSpool) String() string {
@@ -143,3 +143,24 @@ func TestFindSpool_String(t *testing.T) {
 		}
 	})
 }
+
+func TestFindSpool_RFID(t *testing.T) {
+	var s FindSpool
+	if got := s.RFID(); got != "" {
+		t.Errorf("no extra: RFID() = %q, want empty", got)
+	}
+	s.Extra = map[string]string{RFIDExtraField: `"A1B2C3"`}
+	if got := s.RFID(); got != "A1B2C3" {
+		t.Errorf("RFID() = %q, want A1B2C3", got)
+	}
+}
+
+func TestRFIDExtra_RoundTrips(t *testing.T) {
+	s := FindSpool{Extra: RFIDExtra("A1B2C3")}
+	if got := s.Extra[RFIDExtraField]; got != `"A1B2C3"` {
+		t.Errorf("extra = %q, want JSON-quoted", got)
+	}
+	if got := s.RFID(); got != "A1B2C3" {
+		t.Errorf("RFID() = %q, want A1B2C3", got)
+	}
+}
@@ -1,11 +1,36 @@
 package models
 
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
 type PlateRequirement struct {
 	FilamentID int     `yaml:"filament_id,omitempty" json:"filament_id,omitempty"`
 	Name       string  `yaml:"name,omitempty" json:"name,omitempty"`
 	Material   string  `yaml:"material,omitempty" json:"material,omitempty"`
 	Color      string  `yaml:"color,omitempty" json:"color,omitempty"`
 	Amount     float64 `yaml:"amount" json:"amount"`
+	// Reserved earmarks grams of specific spools for this need so plans
+	// don't count the same spool twice. Released when the plate completes;
+	// a failed print shrinks it by what it drew.
+	Reserved []SpoolReservation `yaml:"reserved,omitempty" json:"reserved,omitempty"`
+}
+
+// SpoolReservation holds Grams of spool SpoolID for one plate need.
+type SpoolReservation struct {
+	SpoolID int     `yaml:"spool" json:"spool"`
+	Grams   float64 `yaml:"grams" json:"grams"`
+}
+
+// ReservedGrams is the total this need has reserved across spools.
+func (r PlateRequirement) ReservedGrams() float64 {
+	total := 0.0
+	for _, res := range r.Reserved {
+		total += res.Grams
+	}
+	return total
 }
 
 type Plate struct {
@@ -14,9 +39,48 @@ type Plate struct {
 	Printer           string             `yaml:"printer,omitempty"`            // printer name when in-progress
 	StartedAt         string             `yaml:"started_at,omitempty"`         // RFC3339 timestamp when printing started
 	EstimatedDuration string             `yaml:"estimated_duration,omitempty"` // e.g. "6h25m"
+	File              string             `yaml:"file,omitempty"`               // sliced job name, matched against the printer's current job
+	Sliced            string             `yaml:"sliced,omitempty"`             // sliced file in the server's jobs_dir, sent by plan next
+	SlicedPlate       int                `yaml:"sliced_plate,omitempty"`       // plate number within a multi-plate 3MF (default 1)
+	After             []string           `yaml:"after,omitempty"`              // plates that must complete first: "Plate" in this project or "Project/Plate"
 	Needs             []PlateRequirement `yaml:"needs"`
 }
 
+// MatchesJob reports whether this plate's File names the job a printer is
+// running. Directories, case, and slicer extensions are ignored, so a plate
+// declaring "bracket" matches "Bracket.gcode.3mf" from a Bambu and
+// "/usb/BRACKET.bgcode" from PrusaLink.
+func (p Plate) MatchesJob(job string) bool {
+	if p.File == "" {
+		return false
+	}
+	want := NormalizeJobName(p.File)
+	return want != "" && want == NormalizeJobName(job)
+}
+
+// NormalizeJobName reduces a job or file name to the form MatchesJob compares.
+func NormalizeJobName(name string) string {
+	name = strings.ReplaceAll(strings.TrimSpace(name), "\\", "/")
+	if i := strings.LastIndex(name, "/"); i >= 0 {
+		name = name[i+1:]
+	}
+	name = strings.ToLower(name)
+	name = strings.TrimSuffix(name, JobExt(name))
+	return strings.TrimSpace(name)
+}
+
+// JobExt returns the slicer extension name ends in (".gcode.3mf", ".3mf",
+// ".bgcode", or ".gcode"), or "" if it has none of them.
+func JobExt(name string) string {
+	lower := strings.ToLower(name)
+	for _, ext := range []string{".gcode.3mf", ".3mf", ".bgcode", ".gcode"} {
+		if strings.HasSuffix(lower, ext) {
+			return ext
+		}
+	}
+	return ""
+}
+
 func (p *Plate) DefaultStatus() {
 	if p.Status == "" {
 		p.Status = "todo"
@@ -39,10 +103,174 @@ func (p *Project) DefaultStatus() {
 }
 
 type PlanFile struct {
-	Assembly string    `yaml:"assembly,omitempty"`
+	Assembly string `yaml:"assembly,omitempty"`
+	// Priority orders plans against each other; higher prints first.
+	Priority int `yaml:"priority,omitempty"`
+	// Due is an optional deadline (YYYY-MM-DD). Among plans of equal
+	// priority, the one due soonest goes first.
+	Due      string    `yaml:"due,omitempty"`
 	Projects []Project `yaml:"projects"`
 }
 
+// DueDate parses Due; ok is false when it's unset or malformed.
+func (p PlanFile) DueDate() (time.Time, bool) {
+	if p.Due == "" {
+		return time.Time{}, false
+	}
+	t, err := time.Parse(time.DateOnly, p.Due)
+	return t, err == nil
+}
+
+// PlanBefore reports whether plan a should print before plan b: higher
+// priority first, then the earlier due date (plans without one last).
+func PlanBefore(a, b PlanFile) bool {
+	if a.Priority != b.Priority {
+		return a.Priority > b.Priority
+	}
+	da, okA := a.DueDate()
+	db, okB := b.DueDate()
+	if okA != okB {
+		return okA
+	}
+	return okA && da.Before(db)
+}
+
+// PlateKey names a plate unambiguously within a plan, as "Project/Plate".
+func PlateKey(project, plate string) string {
+	return project + "/" + plate
+}
+
+// findAfter resolves an After reference made from a plate in project: a bare
+// name is a plate of the same project, "Project/Plate" reaches across.
+func (p PlanFile) findAfter(project, ref string) (*Project, *Plate) {
+	for i := range p.Projects {
+		if p.Projects[i].Name != project {
+			continue
+		}
+		for j := range p.Projects[i].Plates {
+			if p.Projects[i].Plates[j].Name == ref {
+				return &p.Projects[i], &p.Projects[i].Plates[j]
+			}
+		}
+	}
+	projName, plateName, ok := strings.Cut(ref, "/")
+	if !ok {
+		return nil, nil
+	}
+	for i := range p.Projects {
+		if p.Projects[i].Name != projName {
+			continue
+		}
+		for j := range p.Projects[i].Plates {
+			if p.Projects[i].Plates[j].Name == plateName {
+				return &p.Projects[i], &p.Projects[i].Plates[j]
+			}
+		}
+	}
+	return nil, nil
+}
+
+// Blockers returns the PlateKeys of the plates this one is still waiting on.
+// References that don't resolve don't block; Lint reports them.
+func (p PlanFile) Blockers(project string, plate Plate) []string {
+	var out []string
+	for _, ref := range plate.After {
+		proj, dep := p.findAfter(project, ref)
+		if dep == nil || proj.Status == "completed" || dep.Status == "completed" {
+			continue
+		}
+		out = append(out, PlateKey(proj.Name, dep.Name))
+	}
+	return out
+}
+
+// Lint reports After references that don't resolve and dependency cycles.
+func (p PlanFile) Lint() []string {
+	var problems []string
+	edges := map[string][]string{}
+	for _, proj := range p.Projects {
+		for _, plate := range proj.Plates {
+			key := PlateKey(proj.Name, plate.Name)
+			for _, ref := range plate.After {
+				depProj, dep := p.findAfter(proj.Name, ref)
+				if dep == nil {
+					problems = append(problems, fmt.Sprintf("%s: after %q matches no plate", key, ref))
+					continue
+				}
+				edges[key] = append(edges[key], PlateKey(depProj.Name, dep.Name))
+			}
+		}
+	}
+
+	// Depth-first search; reaching a plate still on the stack closes a cycle.
+	const (
+		visiting = 1
+		done     = 2
+	)
+	state := map[string]int{}
+	var stack []string
+	var visit func(key string)
+	visit = func(key string) {
+		state[key] = visiting
+		stack = append(stack, key)
+		for _, dep := range edges[key] {
+			switch state[dep] {
+			case visiting:
+				start := 0
+				for i, k := range stack {
+					if k == dep {
+						start = i
+						break
+					}
+				}
+				cycle := append(append([]string{}, stack[start:]...), dep)
+				problems = append(problems, "dependency cycle: "+strings.Join(cycle, " -> "))
+			case 0:
+				visit(dep)
+			}
+		}
+		stack = stack[:len(stack)-1]
+		state[key] = done
+	}
+	for _, proj := range p.Projects {
+		for _, plate := range proj.Plates {
+			if key := PlateKey(proj.Name, plate.Name); state[key] == 0 {
+				visit(key)
+			}
+		}
+	}
+	if p.Due != "" {
+		if _, ok := p.DueDate(); !ok {
+			problems = append(problems, fmt.Sprintf("due %q is not a YYYY-MM-DD date", p.Due))
+		}
+	}
+	return problems
+}
+
+// Reservations totals reserved grams per spool ID across the plans, counting
+// only plates that still have to print.
+func Reservations(plans ...PlanFile) map[int]float64 {
+	out := map[int]float64{}
+	for _, plan := range plans {
+		for _, proj := range plan.Projects {
+			if proj.Status == "completed" {
+				continue
+			}
+			for _, plate := range proj.Plates {
+				if plate.Status == "completed" {
+					continue
+				}
+				for _, n := range plate.Needs {
+					for _, res := range n.Reserved {
+						out[res.SpoolID] += res.Grams
+					}
+				}
+			}
+		}
+	}
+	return out
+}
+
 func (p *PlanFile) DefaultStatus() {
 	for i := range p.Projects {
 		p.Projects[i].DefaultStatus()
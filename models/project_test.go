@@ -0,0 +1,113 @@
+package models
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestPlateMatchesJob(t *testing.T) {
+	tests := []struct {
+		file string
+		job  string
+		want bool
+	}{
+		{"bracket", "Bracket.gcode.3mf", true},
+		{"Bracket.3mf", "/usb/BRACKET.bgcode", true},
+		{"bracket.gcode", "bracket", true},
+		{`C:\slices\bracket.gcode.3mf`, "bracket", true},
+		{"bracket", "bracket v2", false},
+		{"", "bracket", false},
+		{"bracket", "", false},
+	}
+	for _, tt := range tests {
+		p := Plate{File: tt.file}
+		if got := p.MatchesJob(tt.job); got != tt.want {
+			t.Errorf("Plate{File: %q}.MatchesJob(%q) = %v, want %v", tt.file, tt.job, got, tt.want)
+		}
+	}
+}
+
+func TestReservations(t *testing.T) {
+	res := func(id int, g float64) []SpoolReservation { return []SpoolReservation{{SpoolID: id, Grams: g}} }
+	a := PlanFile{Projects: []Project{
+		{Name: "A", Status: "in-progress", Plates: []Plate{
+			{Name: "1", Status: "todo", Needs: []PlateRequirement{{FilamentID: 1, Amount: 40, Reserved: res(7, 40)}}},
+			{Name: "2", Status: "completed", Needs: []PlateRequirement{{FilamentID: 1, Amount: 10, Reserved: res(7, 10)}}},
+		}},
+		{Name: "Done", Status: "completed", Plates: []Plate{
+			{Name: "1", Status: "todo", Needs: []PlateRequirement{{Reserved: res(7, 99)}}},
+		}},
+	}}
+	b := PlanFile{Projects: []Project{
+		{Name: "B", Status: "todo", Plates: []Plate{
+			{Name: "1", Status: "in-progress", Needs: []PlateRequirement{
+				{FilamentID: 1, Amount: 25, Reserved: []SpoolReservation{{SpoolID: 7, Grams: 20}, {SpoolID: 8, Grams: 5}}},
+			}},
+		}},
+	}}
+
+	got := Reservations(a, b)
+	if got[7] != 60 || got[8] != 5 || len(got) != 2 {
+		t.Errorf("Reservations = %v, want map[7:60 8:5]", got)
+	}
+	if g := b.Projects[0].Plates[0].Needs[0].ReservedGrams(); g != 25 {
+		t.Errorf("ReservedGrams = %v, want 25", g)
+	}
+}
+
+func TestBlockers(t *testing.T) {
+	p := PlanFile{Projects: []Project{
+		{Name: "Box", Plates: []Plate{
+			{Name: "Base", Status: "completed"},
+			{Name: "Test fit", Status: "todo"},
+			{Name: "Lid", Status: "todo", After: []string{"Base", "Test fit", "Nope"}},
+		}},
+		{Name: "Stand", Plates: []Plate{
+			{Name: "Arm", Status: "todo", After: []string{"Box/Lid"}},
+		}},
+	}}
+
+	if got := p.Blockers("Box", p.Projects[0].Plates[2]); !reflect.DeepEqual(got, []string{"Box/Test fit"}) {
+		t.Errorf("Lid blockers = %v, want [Box/Test fit]", got)
+	}
+	if got := p.Blockers("Stand", p.Projects[1].Plates[0]); !reflect.DeepEqual(got, []string{"Box/Lid"}) {
+		t.Errorf("Arm blockers = %v, want [Box/Lid]", got)
+	}
+	if got := p.Blockers("Box", p.Projects[0].Plates[1]); len(got) != 0 {
+		t.Errorf("Test fit blockers = %v, want none", got)
+	}
+}
+
+func TestLint(t *testing.T) {
+	p := PlanFile{Due: "soon", Projects: []Project{
+		{Name: "Box", Plates: []Plate{
+			{Name: "A", After: []string{"C"}},
+			{Name: "B", After: []string{"A"}},
+			{Name: "C", After: []string{"B", "Missing"}},
+		}},
+	}}
+	want := []string{
+		`Box/C: after "Missing" matches no plate`,
+		"dependency cycle: Box/A -> Box/C -> Box/B -> Box/A",
+		`due "soon" is not a YYYY-MM-DD date`,
+	}
+	if got := p.Lint(); !reflect.DeepEqual(got, want) {
+		t.Errorf("Lint =\n%q\nwant\n%q", got, want)
+	}
+	if got := (PlanFile{Due: "2026-06-01", Projects: p.Projects[:0]}).Lint(); len(got) != 0 {
+		t.Errorf("clean plan Lint = %q", got)
+	}
+}
+
+func TestPlanBefore(t *testing.T) {
+	urgent := PlanFile{Priority: 1}
+	soon := PlanFile{Due: "2026-05-01"}
+	later := PlanFile{Due: "2026-06-01"}
+	none := PlanFile{}
+	if !PlanBefore(urgent, soon) || !PlanBefore(soon, later) || !PlanBefore(later, none) {
+		t.Error("want priority, then earliest due, then no due")
+	}
+	if PlanBefore(none, none) || PlanBefore(later, soon) {
+		t.Error("PlanBefore should be a strict order")
+	}
+}
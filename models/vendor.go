@@ -40,4 +40,8 @@ type CreateSpoolRequest struct {
 	FilamentId int     `json:"filament_id"`
 	Price      float64 `json:"price,omitempty"`
 	Location   string  `json:"location,omitempty"`
+	// RemainingWeight starts a partly used spool; 0 means full.
+	RemainingWeight float64 `json:"remaining_weight,omitempty"`
+	// Extra sets Spoolman custom fields; see RFIDExtra.
+	Extra map[string]string `json:"extra,omitempty"`
 }
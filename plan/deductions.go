@@ -0,0 +1,56 @@
+package plan
+
+import "github.com/dstockto/fil/models"
+
+// MatchDeductions resolves a Plate's Needs to Spools loaded in the printer's
+// locations without prompting. It's the non-interactive counterpart to the
+// CLI's collectDeductions, used when the plan-server closes out a plate on
+// its own (e.g. auto-complete on FINISH). Needs that don't resolve to exactly
+// one loaded Spool are returned as unmatched so the caller can surface them
+// for a human to deduct by hand — guessing wrong would silently drain the
+// wrong spool.
+//
+// Two Needs that land on the same Spool are folded into one deduction so
+// LocalPlanOps only issues a single Spoolman write per Spool.
+func MatchDeductions(spools []models.FindSpool, printerLocations []string, needs []models.PlateRequirement) ([]SpoolDeduction, []models.PlateRequirement) {
+	var deductions []SpoolDeduction
+	var unmatched []models.PlateRequirement
+	bySpool := map[int]int{} // spool ID → index into deductions
+
+	for _, need := range needs {
+		if need.Amount <= 0 {
+			continue
+		}
+		spool := spoolForNeed(spools, printerLocations, need)
+		if spool == nil {
+			unmatched = append(unmatched, need)
+			continue
+		}
+		if idx, ok := bySpool[spool.Id]; ok {
+			deductions[idx].Amount += need.Amount
+			continue
+		}
+		bySpool[spool.Id] = len(deductions)
+		deductions = append(deductions, SpoolDeduction{SpoolID: spool.Id, Amount: need.Amount})
+	}
+	return deductions, unmatched
+}
+
+// spoolForNeed picks the spool a need drew from. A reserved spool loaded in
+// the printer wins outright — that's the spool the reservation earmarked —
+// otherwise it falls back to the single matching spool in the printer.
+func spoolForNeed(spools []models.FindSpool, printerLocations []string, need models.PlateRequirement) *models.FindSpool {
+	for _, res := range need.Reserved {
+		for _, s := range spools {
+			if s.Id != res.SpoolID || s.Archived {
+				continue
+			}
+			for _, loc := range printerLocations {
+				if s.Location == loc {
+					return &s
+				}
+			}
+		}
+	}
+	return findPrinterSpool(spools, printerLocations, need)
+}
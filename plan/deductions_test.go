@@ -0,0 +1,76 @@
+package plan
+
+import (
+	"testing"
+
+	"github.com/dstockto/fil/models"
+)
+
+func TestMatchDeductionsResolvesLoadedSpools(t *testing.T) {
+	spools := []models.FindSpool{
+		makeFailSpool(1, "AMS A1", 500, 100, "PLA white"),
+		makeFailSpool(2, "AMS A2", 500, 200, "PETG black"),
+		makeFailSpool(3, "Shelf 6B", 500, 300, "PLA red"),
+	}
+	needs := []models.PlateRequirement{
+		{FilamentID: 100, Name: "PLA white", Amount: 12.5},
+		{FilamentID: 200, Name: "PETG black", Amount: 4},
+		{FilamentID: 300, Name: "PLA red", Amount: 7}, // on the shelf, not loaded
+	}
+
+	got, unmatched := MatchDeductions(spools, []string{"AMS A1", "AMS A2"}, needs)
+	if len(got) != 2 {
+		t.Fatalf("got %d deductions, want 2: %+v", len(got), got)
+	}
+	if got[0] != (SpoolDeduction{SpoolID: 1, Amount: 12.5}) {
+		t.Errorf("deduction[0] = %+v", got[0])
+	}
+	if got[1] != (SpoolDeduction{SpoolID: 2, Amount: 4}) {
+		t.Errorf("deduction[1] = %+v", got[1])
+	}
+	if len(unmatched) != 1 || unmatched[0].FilamentID != 300 {
+		t.Errorf("unmatched = %+v, want the shelf-only PLA red need", unmatched)
+	}
+}
+
+// Two Needs for the same filament (e.g. a plate that uses white for both the
+// body and the text) must collapse onto one deduction so Spoolman sees a
+// single write for the spool.
+func TestMatchDeductionsFoldsSameSpool(t *testing.T) {
+	spools := []models.FindSpool{makeFailSpool(1, "AMS A1", 500, 100, "PLA white")}
+	needs := []models.PlateRequirement{
+		{FilamentID: 100, Amount: 10},
+		{FilamentID: 100, Amount: 5},
+		{FilamentID: 100, Amount: 0}, // zero-amount needs are ignored
+	}
+
+	got, unmatched := MatchDeductions(spools, []string{"AMS A1"}, needs)
+	if len(got) != 1 || got[0].Amount != 15 {
+		t.Errorf("got %+v, want a single 15g deduction", got)
+	}
+	if len(unmatched) != 0 {
+		t.Errorf("unexpected unmatched: %+v", unmatched)
+	}
+}
+
+// With two loaded spools of the same filament findPrinterSpool can't pick,
+// but a reservation on one of them settles it.
+func TestMatchDeductionsPrefersReservedSpool(t *testing.T) {
+	spools := []models.FindSpool{
+		makeFailSpool(1, "AMS A1", 500, 100, "PLA white"),
+		makeFailSpool(2, "AMS A2", 300, 100, "PLA white"),
+		makeFailSpool(3, "Shelf 6B", 900, 100, "PLA white"),
+	}
+	loaded := []string{"AMS A1", "AMS A2"}
+
+	need := models.PlateRequirement{FilamentID: 100, Amount: 10}
+	if got, _ := MatchDeductions(spools, loaded, []models.PlateRequirement{need}); len(got) != 0 {
+		t.Fatalf("without a reservation got %+v, want ambiguous", got)
+	}
+
+	need.Reserved = []models.SpoolReservation{{SpoolID: 3, Grams: 10}, {SpoolID: 2, Grams: 10}}
+	got, unmatched := MatchDeductions(spools, loaded, []models.PlateRequirement{need})
+	if len(got) != 1 || got[0].SpoolID != 2 || len(unmatched) != 0 {
+		t.Errorf("got %+v (unmatched %+v), want spool 2 — reserved and loaded", got, unmatched)
+	}
+}
@@ -55,9 +55,10 @@ func (l *LocalPlanOps) fetchSpoolsByID(ctx context.Context, deductions []SpoolDe
 }
 
 // Complete mutates the Plan: sets the named Plate's Status to "completed",
-// clears Plate.Printer, and cascades Project.Status to "completed" if every
-// Plate in the project is now done. Save the YAML first, then deduct via
-// Spoolman, then write history, then notify.
+// clears Plate.Printer and its Needs' spool reservations, and cascades
+// Project.Status to "completed" if every Plate in the project is now done.
+// Save the YAML first, then deduct via Spoolman, then write history, then
+// notify.
 //
 // Order rationale: if YAML save and Spoolman were swapped, a YAML-save
 // failure after a successful deduction would leave the Plate looking
@@ -89,6 +90,11 @@ func (l *LocalPlanOps) Complete(ctx context.Context, req CompleteRequest) (Compl
 	plate := &plan.Projects[projIdx].Plates[plateIdx]
 	plate.Status = "completed"
 	plate.Printer = ""
+	// The deductions below are the real usage; the plate's spool
+	// reservations have served their purpose.
+	for i := range plate.Needs {
+		plate.Needs[i].Reserved = nil
+	}
 
 	cascaded := false
 	allDone := true
@@ -216,6 +216,31 @@ func TestLocalCompleteCascadesProjectStatus(t *testing.T) {
 	}
 }
 
+func TestLocalCompleteReleasesReservations(t *testing.T) {
+	sm := newFakeSpoolman(makeFailSpool(101, "AMS A1", 800, 100, "PLA white"))
+	store := newMemPlanStore()
+	plan := samplePlan()
+	plan.Projects[0].Plates[0].Needs[0].Reserved = []models.SpoolReservation{{SpoolID: 101, Grams: 50}}
+	plan.Projects[0].Plates[1].Needs[0].Reserved = []models.SpoolReservation{{SpoolID: 101, Grams: 30}}
+	store.plans["test.yaml"] = plan
+	ops := newLocalWithStore(t, sm, store, &recordingHistory{}, NoopNotifier{})
+
+	if _, err := ops.Complete(context.Background(), CompleteRequest{
+		Plan: "test.yaml", Project: "Proj", Plate: "P1",
+		Deductions: []SpoolDeduction{{SpoolID: 101, Amount: 50}},
+	}); err != nil {
+		t.Fatalf("Complete: %v", err)
+	}
+
+	saved := store.plans["test.yaml"].Projects[0].Plates
+	if len(saved[0].Needs[0].Reserved) != 0 {
+		t.Errorf("P1 reservations = %+v, want released", saved[0].Needs[0].Reserved)
+	}
+	if len(saved[1].Needs[0].Reserved) != 1 {
+		t.Errorf("P2 reservations = %+v, want untouched", saved[1].Needs[0].Reserved)
+	}
+}
+
 func TestLocalCompleteErrorsOnUnknownPlate(t *testing.T) {
 	store := newMemPlanStore()
 	store.plans["test.yaml"] = samplePlan()
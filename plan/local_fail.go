@@ -4,16 +4,20 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"math"
+	"slices"
 	"strings"
 	"time"
 
 	"github.com/dstockto/fil/models"
 )
 
-// Fail runs the full fail flow: allocate, deduct from Spoolman, append to
-// history, notify. Order is Spoolman → history → notify; if Spoolman partial
-// failure occurs we still write history (the failure happened either way) and
-// return a joined error so the caller can warn.
+// Fail runs the full fail flow: allocate, deduct from Spoolman, shrink the
+// spool reservations the deductions drew on, append to history, notify.
+// Order is Spoolman → plan → history → notify; if Spoolman partial failure
+// occurs we still write history (the failure happened either way) and return
+// a joined error so the caller can warn. A retry carrying req.Deducted only
+// writes the per-spool difference.
 func (l *LocalPlanOps) Fail(ctx context.Context, req FailRequest) (FailResult, error) {
 	if req.FailedAt.IsZero() {
 		req.FailedAt = time.Now().UTC()
@@ -27,6 +31,7 @@ func (l *LocalPlanOps) Fail(ctx context.Context, req FailRequest) (FailResult, e
 	if len(allocations) > 0 {
 		s, err := l.spoolman.FindSpoolsByName(ctx, l.spoolPattern, nil, nil)
 		if err != nil {
+			result.Deducted = req.Deducted
 			return result, fmt.Errorf("list spools: %w", err)
 		}
 		allSpools = s
@@ -43,10 +48,11 @@ func (l *LocalPlanOps) Fail(ctx context.Context, req FailRequest) (FailResult, e
 	}
 	bySpool := map[int]*pending{}
 	var unmatched []FailUnmatched
+	var draws []reservationDraw
 
 	for _, a := range allocations {
 		need := req.Plates[a.plateRef].Needs[a.needIdx]
-		spool := findPrinterSpool(allSpools, printerLocs, need)
+		spool := spoolForNeed(allSpools, printerLocs, need)
 		if spool == nil {
 			unmatched = append(unmatched, FailUnmatched{
 				Project:      req.Plates[a.plateRef].Project,
@@ -56,6 +62,7 @@ func (l *LocalPlanOps) Fail(ctx context.Context, req FailRequest) (FailResult, e
 			})
 			continue
 		}
+		draws = append(draws, reservationDraw{plateRef: a.plateRef, needIdx: a.needIdx, spoolID: spool.Id, grams: a.shareGrams})
 		if cur, ok := bySpool[spool.Id]; ok {
 			cur.grams += a.shareGrams
 		} else {
@@ -64,19 +71,71 @@ func (l *LocalPlanOps) Fail(ctx context.Context, req FailRequest) (FailResult, e
 		}
 	}
 
+	// Only the difference from an earlier attempt goes to Spoolman; a spool
+	// it no longer allocates gets its grams back.
+	held := map[int]float64{}
+	for _, d := range req.Deducted {
+		held[d.SpoolID] += d.Amount
+	}
+	ids := make([]int, 0, len(bySpool)+len(held))
+	for id := range bySpool {
+		ids = append(ids, id)
+	}
+	for id := range held {
+		if _, ok := bySpool[id]; !ok {
+			ids = append(ids, id)
+		}
+	}
+	slices.Sort(ids)
+
 	var deductErrs []error
-	for _, p := range bySpool {
-		if err := l.useFilamentSafely(ctx, p.spool, p.grams); err != nil {
-			deductErrs = append(deductErrs, fmt.Errorf("deduct %s: %w", p.label, err))
+	drawn := map[int]float64{} // share of each spool's target taken now
+	for _, id := range ids {
+		target := 0.0
+		label := fmt.Sprintf("#%d", id)
+		if p, ok := bySpool[id]; ok {
+			target, label = p.grams, p.label
+		}
+		delta := target - held[id]
+		var err error
+		switch {
+		case math.Abs(delta) < 0.05:
+		case delta > 0:
+			err = l.useFilamentSafely(ctx, bySpool[id].spool, delta)
+		default:
+			err = l.spoolman.UseFilament(ctx, id, delta)
+		}
+		if err != nil {
+			deductErrs = append(deductErrs, fmt.Errorf("deduct %s: %w", label, err))
 			continue
 		}
-		result.Allocations = append(result.Allocations, FailAllocation{
-			SpoolID: p.spool.Id,
-			Label:   p.label,
-			Grams:   p.grams,
-		})
+		held[id] = target
+		if delta > 0 {
+			drawn[id] = delta / target
+		}
+		if target > 0 {
+			result.Allocations = append(result.Allocations, FailAllocation{
+				SpoolID: id,
+				Label:   label,
+				Grams:   target,
+			})
+		}
 	}
 	result.Unmatched = unmatched
+	for _, id := range ids {
+		if held[id] > 0 {
+			result.Deducted = append(result.Deducted, SpoolDeduction{SpoolID: id, Amount: held[id]})
+		}
+	}
+
+	var shrink []reservationDraw
+	for _, d := range draws {
+		if f := drawn[d.spoolID]; f > 0 {
+			d.grams *= f
+			shrink = append(shrink, d)
+		}
+	}
+	reserveErr := l.shrinkReservations(ctx, req.Plates, shrink)
 
 	// Always write history, even on partial deduction failure — the print
 	// failed, so the audit record needs to exist regardless.
@@ -110,6 +169,7 @@ func (l *LocalPlanOps) Fail(ctx context.Context, req FailRequest) (FailResult, e
 	if l.history != nil && len(entries) > 0 {
 		historyErr = l.history.AppendFail(ctx, entries)
 	}
+	result.Recorded = historyErr == nil
 
 	if l.notifier != nil {
 		l.notifier.Notify(ctx, "Print failed", failNotificationBody(req, result, perPlate))
@@ -117,6 +177,9 @@ func (l *LocalPlanOps) Fail(ctx context.Context, req FailRequest) (FailResult, e
 
 	var allErrs []error
 	allErrs = append(allErrs, deductErrs...)
+	if reserveErr != nil {
+		allErrs = append(allErrs, fmt.Errorf("update reservations: %w", reserveErr))
+	}
 	if historyErr != nil {
 		allErrs = append(allErrs, fmt.Errorf("write history: %w", historyErr))
 	}
@@ -126,6 +189,70 @@ func (l *LocalPlanOps) Fail(ctx context.Context, req FailRequest) (FailResult, e
 	return result, nil
 }
 
+// reservationDraw is the share of a failed plate's need taken from one spool.
+type reservationDraw struct {
+	plateRef int
+	needIdx  int
+	spoolID  int
+	grams    float64
+}
+
+// shrinkReservations takes what a failed print drew from reserved spools out
+// of the needs' reservations, dropping any that are used up, with one save per
+// plan. The plate still needs its full amount for the reprint; the grams the
+// failure consumed just aren't on the spool to earmark any more.
+func (l *LocalPlanOps) shrinkReservations(ctx context.Context, plates []FailPlate, draws []reservationDraw) error {
+	if l.plans == nil || len(draws) == 0 {
+		return nil
+	}
+	byPlan := map[string][]reservationDraw{}
+	var order []string
+	for _, d := range draws {
+		name := plates[d.plateRef].Plan
+		if _, ok := byPlan[name]; !ok {
+			order = append(order, name)
+		}
+		byPlan[name] = append(byPlan[name], d)
+	}
+
+	var errs []error
+	for _, name := range order {
+		pf, err := l.plans.Load(ctx, name)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		changed := false
+		for _, d := range byPlan[name] {
+			fp := plates[d.plateRef]
+			pi, pj, err := findPlate(pf, fp.Project, fp.Plate)
+			if err != nil {
+				errs = append(errs, err)
+				continue
+			}
+			needs := pf.Projects[pi].Plates[pj].Needs
+			if d.needIdx >= len(needs) {
+				continue
+			}
+			need := &needs[d.needIdx]
+			for i := range need.Reserved {
+				if need.Reserved[i].SpoolID == d.spoolID {
+					need.Reserved[i].Grams -= d.grams
+					changed = true
+				}
+			}
+			need.Reserved = slices.DeleteFunc(need.Reserved, func(r models.SpoolReservation) bool { return r.Grams <= 0 })
+		}
+		if !changed {
+			continue
+		}
+		if err := l.plans.Save(ctx, name, pf); err != nil {
+			errs = append(errs, fmt.Errorf("save plan: %w", err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
 // useFilamentSafely mirrors cmd.UseFilamentSafely: when the requested amount
 // would push remaining_weight negative, bump initial_weight by the overage
 // first so Spoolman doesn't reject the write.
@@ -4,6 +4,7 @@ import (
 	"context"
 	"errors"
 	"path/filepath"
+	"reflect"
 	"testing"
 	"time"
 
@@ -99,8 +100,8 @@ func newLocalForTest(t *testing.T, sm *fakeSpoolman, h HistoryWriter, n Notifier
 	printers := StaticPrinterLocations{
 		"Bambu X1C": {"AMS A1", "AMS A2", "AMS A3", "AMS A4"},
 	}
-	// Fail tests don't exercise the PlanStore — pass nil; LocalPlanOps.Fail
-	// never reads or writes plan files. Complete tests use newLocalWithStore.
+	// Most Fail tests don't exercise the PlanStore — pass nil; LocalPlanOps.Fail
+	// then leaves reservations alone. Complete tests use newLocalWithStore.
 	return NewLocal(sm, printers, nil, h, n)
 }
 
@@ -238,6 +239,109 @@ func TestLocalFailContinuesHistoryAfterDeductError(t *testing.T) {
 	}
 }
 
+func TestLocalFailRetryDeductsOnlyTheDifference(t *testing.T) {
+	sm := newFakeSpoolman(
+		makeFailSpool(101, "AMS A1", 800, 100, "PLA white"),
+		makeFailSpool(102, "AMS A2", 800, 200, "PETG black"),
+	)
+	sm.failOn[102] = errors.New("spoolman down")
+	hist := &recordingHistory{err: errors.New("disk full")}
+	ops := newLocalForTest(t, sm, hist, NoopNotifier{})
+
+	req := FailRequest{
+		Printer:   "Bambu X1C",
+		Cause:     "bed_adhesion",
+		UsedGrams: 20,
+		Plates: []FailPlate{{
+			Plan: "p.yaml", Project: "Proj", Plate: "1",
+			Needs: []models.PlateRequirement{
+				{FilamentID: 100, Name: "PLA white", Amount: 50},
+				{FilamentID: 200, Name: "PETG black", Amount: 50},
+			},
+		}},
+	}
+
+	res, err := ops.Fail(context.Background(), req)
+	if err == nil || res.Recorded {
+		t.Fatalf("Fail = %+v, %v; want unrecorded error", res, err)
+	}
+	if want := []SpoolDeduction{{SpoolID: 101, Amount: 10}}; !reflect.DeepEqual(res.Deducted, want) {
+		t.Fatalf("deducted = %+v, want %+v", res.Deducted, want)
+	}
+
+	// The retry picks up the spool that failed and an amended total.
+	delete(sm.failOn, 102)
+	hist.err = nil
+	req.Deducted = res.Deducted
+	req.UsedGrams = 30
+	res, err = ops.Fail(context.Background(), req)
+	if err != nil || !res.Recorded {
+		t.Fatalf("retry = %+v, %v", res, err)
+	}
+	if sm.useCalls[101] != 15 || sm.useCalls[102] != 15 {
+		t.Errorf("use calls = %v, want 15g from each in total", sm.useCalls)
+	}
+	if len(hist.failEntries) != 1 || hist.failEntries[0].UsedGrams != 30 {
+		t.Errorf("history = %+v", hist.failEntries)
+	}
+
+	// Amending down hands filament back.
+	req.Deducted = res.Deducted
+	req.UsedGrams = 0
+	if _, err := ops.Fail(context.Background(), req); err != nil {
+		t.Fatal(err)
+	}
+	if sm.useCalls[101] != 0 || sm.useCalls[102] != 0 {
+		t.Errorf("use calls = %v, want everything returned", sm.useCalls)
+	}
+}
+
+func TestLocalFailShrinksReservations(t *testing.T) {
+	sm := newFakeSpoolman(makeFailSpool(101, "AMS A1", 800, 100, "PLA white"))
+	store := newMemPlanStore()
+	pf := samplePlan()
+	pf.Projects[0].Plates[0].Needs[0].Reserved = []models.SpoolReservation{{SpoolID: 101, Grams: 40}, {SpoolID: 102, Grams: 10}}
+	pf.Projects[0].Plates[1].Needs[0].Reserved = []models.SpoolReservation{{SpoolID: 101, Grams: 30}}
+	store.plans["test.yaml"] = pf
+	ops := newLocalWithStore(t, sm, store, &recordingHistory{}, NoopNotifier{})
+
+	fail := func(grams float64) {
+		t.Helper()
+		_, err := ops.Fail(context.Background(), FailRequest{
+			Printer:   "Bambu X1C",
+			Cause:     "spaghetti",
+			UsedGrams: grams,
+			Plates: []FailPlate{{
+				Plan: "test.yaml", Project: "Proj", Plate: "P1",
+				Needs: store.plans["test.yaml"].Projects[0].Plates[0].Needs,
+			}},
+		})
+		if err != nil {
+			t.Fatalf("Fail: %v", err)
+		}
+	}
+
+	fail(25)
+	saved := store.plans["test.yaml"].Projects[0].Plates
+	want := []models.SpoolReservation{{SpoolID: 101, Grams: 15}, {SpoolID: 102, Grams: 10}}
+	if !reflect.DeepEqual(saved[0].Needs[0].Reserved, want) {
+		t.Errorf("P1 reservations = %+v, want %+v", saved[0].Needs[0].Reserved, want)
+	}
+	if len(store.saveCalls) != 1 {
+		t.Errorf("saves = %d, want 1", len(store.saveCalls))
+	}
+
+	fail(20)
+	saved = store.plans["test.yaml"].Projects[0].Plates
+	want = []models.SpoolReservation{{SpoolID: 102, Grams: 10}}
+	if !reflect.DeepEqual(saved[0].Needs[0].Reserved, want) {
+		t.Errorf("P1 reservations = %+v, want %+v (used up)", saved[0].Needs[0].Reserved, want)
+	}
+	if got := saved[1].Needs[0].Reserved; len(got) != 1 || got[0].Grams != 30 {
+		t.Errorf("P2 reservations = %+v, want untouched", got)
+	}
+}
+
 func TestLocalFailZeroGramsSkipsSpoolman(t *testing.T) {
 	sm := newFakeSpoolman(makeFailSpool(101, "AMS A1", 800, 100, "PLA white"))
 	hist := &recordingHistory{}
@@ -18,6 +18,9 @@ func (l *LocalPlanOps) Next(ctx context.Context, req NextRequest) (NextResult, e
 	if req.Plan == "" || req.Project == "" || req.Plate == "" || req.Printer == "" {
 		return NextResult{}, errors.New("plan, project, plate, and printer are required")
 	}
+	if req.Send {
+		return NextResult{}, errors.New("sending a job to the printer needs plans_server")
+	}
 	if req.StartedAt.IsZero() {
 		req.StartedAt = time.Now().UTC()
 	}
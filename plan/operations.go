@@ -11,6 +11,7 @@ package plan
 
 import (
 	"context"
+	"errors"
 	"time"
 
 	"github.com/dstockto/fil/models"
@@ -22,7 +23,8 @@ import (
 // edits) still live in cmd/plan_*.go for now.
 type PlanOperations interface {
 	// Fail logs a print failure: deducts wasted filament from the matching
-	// Spool(s), records one history entry per plate, and fires a notification.
+	// Spool(s), shrinks the reservations it drew on, records one history
+	// entry per plate, and fires a notification.
 	// Plate lifecycle status is not changed — callers who also want plates
 	// returned to "todo" run plan stop separately. See CONTEXT.md.
 	Fail(ctx context.Context, req FailRequest) (FailResult, error)
@@ -98,7 +100,7 @@ type PlanOperations interface {
 // Callers populate Plates from their already-loaded view of the world: the
 // CLI from discoverPlans(), the plan-server from its on-disk YAML. Each
 // FailPlate carries the Needs that drive share allocation, so LocalPlanOps
-// never has to load a plan file itself.
+// only loads a plan file to update the reservations a deduction drew on.
 type FailRequest struct {
 	Plates    []FailPlate `json:"plates"`
 	Printer   string      `json:"printer,omitempty"`
@@ -106,6 +108,11 @@ type FailRequest struct {
 	Reason    string      `json:"reason,omitempty"`
 	UsedGrams float64     `json:"used_grams,omitempty"`
 	FailedAt  time.Time   `json:"failed_at,omitempty"`
+
+	// Deducted is what an earlier attempt at this fail already took from
+	// each spool. A retry only writes the difference, so a changed
+	// UsedGrams is reconciled and finished spools aren't charged twice.
+	Deducted []SpoolDeduction `json:"deducted,omitempty"`
 }
 
 // FailPlate identifies one plate inside a batch failure. Needs must match the
@@ -126,6 +133,14 @@ type FailPlate struct {
 type FailResult struct {
 	Allocations []FailAllocation
 	Unmatched   []FailUnmatched
+
+	// Recorded is true once the history entry is written; a fail that
+	// errored without it can be retried.
+	Recorded bool
+	// Deducted is what Spoolman now holds against each spool for this
+	// fail, including earlier attempts; pass it back as FailRequest.Deducted
+	// on a retry.
+	Deducted []SpoolDeduction
 }
 
 // FailAllocation is one Spoolman deduction that the operation performed.
@@ -181,8 +196,17 @@ type NextRequest struct {
 	Plate     string    `json:"plate"`
 	Printer   string    `json:"printer"`
 	StartedAt time.Time `json:"started_at,omitempty"`
+	// Send asks the plan-server to upload the Plate's sliced file to the
+	// printer and start it; the Plate is only marked in-progress once the
+	// printer accepts the job. Only a plan-server can honor it.
+	Send bool `json:"send,omitempty"`
 }
 
+// ErrNotRecorded is returned by a Send Next when the printer took the job but
+// the Plate couldn't be marked in-progress: the print is running and the plan
+// needs updating by hand.
+var ErrNotRecorded = errors.New("print started, but the plate was not marked in-progress")
+
 // NextResult reports whether the Plate's parent Project transitioned from
 // "todo" to "in-progress" as a side effect.
 type NextResult struct {
@@ -0,0 +1,155 @@
+package plan
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/dstockto/fil/models"
+)
+
+// BambuVendor is the Spoolman vendor RFID-registered spools are filed under;
+// only Bambu Lab spools carry tags the printers read.
+const BambuVendor = "Bambu Lab"
+
+// Defaults for what a tray doesn't report, matching `fil new filament`.
+const (
+	defaultDiameter    = 1.75
+	defaultDensity     = 1.24
+	defaultNetWeight   = 1000
+	defaultSpoolWeight = 140
+)
+
+// materialDensities are typical densities (g/cm³) by tray type, so length
+// estimates are right for the common materials.
+var materialDensities = map[string]float64{
+	"PLA":  1.24,
+	"PETG": 1.27,
+	"ABS":  1.04,
+	"ASA":  1.07,
+	"TPU":  1.21,
+	"PC":   1.20,
+	"PA":   1.14,
+}
+
+// SpoolRegistration is an RFID spool a printer reported that Spoolman has no
+// spool for, with everything needed to create one in its slot.
+type SpoolRegistration struct {
+	Location string  `json:"location"`
+	Slot     int     `json:"slot"` // 1-based
+	RFID     string  `json:"rfid"`
+	Vendor   string  `json:"vendor"`
+	Name     string  `json:"name"`      // e.g. "PLA Basic"
+	Material string  `json:"material"`  // tray type, e.g. "PLA"
+	ColorHex string  `json:"color_hex"` // RRGGBB
+	Weight   float64 `json:"weight,omitempty"`
+	// Remaining is the printer's estimate of grams left, 0 when unknown.
+	Remaining float64 `json:"remaining,omitempty"`
+}
+
+// SpoolCreator is the slice of the Spoolman API that registering a spool
+// needs. *api.Client satisfies it.
+type SpoolCreator interface {
+	GetVendors(ctx context.Context) ([]models.Vendor, error)
+	CreateVendor(ctx context.Context, name string) (*models.Vendor, error)
+	GetFilaments(ctx context.Context) ([]models.FilamentResponse, error)
+	CreateFilament(ctx context.Context, filament models.CreateFilamentRequest) (*models.FilamentResponse, error)
+	CreateSpool(ctx context.Context, spool models.CreateSpoolRequest) (*models.FindSpool, error)
+	EnsureSpoolField(ctx context.Context, key, name string) error
+}
+
+// RegisterSpool creates a spool for reg at reg.Location, tagged with its
+// RFID. The vendor and filament are reused when Spoolman already has them
+// (same vendor, name, material and color) and created otherwise. The spool
+// still has to be placed in its slot's location order; see ApplySpoolSwap.
+func RegisterSpool(ctx context.Context, sc SpoolCreator, reg SpoolRegistration) (*models.FindSpool, error) {
+	if reg.RFID == "" || reg.Material == "" || reg.ColorHex == "" {
+		return nil, fmt.Errorf("registration needs an RFID, material and color: %+v", reg)
+	}
+	if reg.Vendor == "" {
+		reg.Vendor = BambuVendor
+	}
+	if reg.Name == "" {
+		reg.Name = reg.Material
+	}
+	if reg.Weight <= 0 {
+		reg.Weight = defaultNetWeight
+	}
+
+	if err := sc.EnsureSpoolField(ctx, models.RFIDExtraField, "RFID"); err != nil {
+		return nil, fmt.Errorf("ensure rfid field: %w", err)
+	}
+	vendorID, err := findOrCreateVendor(ctx, sc, reg.Vendor)
+	if err != nil {
+		return nil, err
+	}
+	filamentID, err := findOrCreateFilament(ctx, sc, vendorID, reg)
+	if err != nil {
+		return nil, err
+	}
+
+	req := models.CreateSpoolRequest{
+		FilamentId: filamentID,
+		Location:   reg.Location,
+		Extra:      models.RFIDExtra(reg.RFID),
+	}
+	if reg.Remaining > 0 && reg.Remaining < reg.Weight {
+		req.RemainingWeight = reg.Remaining
+	}
+	spool, err := sc.CreateSpool(ctx, req)
+	if err != nil {
+		return nil, fmt.Errorf("create spool: %w", err)
+	}
+	return spool, nil
+}
+
+func findOrCreateVendor(ctx context.Context, sc SpoolCreator, name string) (int, error) {
+	vendors, err := sc.GetVendors(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("list vendors: %w", err)
+	}
+	for _, v := range vendors {
+		if strings.EqualFold(v.Name, name) {
+			return v.Id, nil
+		}
+	}
+	v, err := sc.CreateVendor(ctx, name)
+	if err != nil {
+		return 0, fmt.Errorf("create vendor %q: %w", name, err)
+	}
+	return v.Id, nil
+}
+
+func findOrCreateFilament(ctx context.Context, sc SpoolCreator, vendorID int, reg SpoolRegistration) (int, error) {
+	filaments, err := sc.GetFilaments(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("list filaments: %w", err)
+	}
+	for _, f := range filaments {
+		if f.Vendor.Id == vendorID &&
+			strings.EqualFold(f.Name, reg.Name) &&
+			strings.EqualFold(f.Material, reg.Material) &&
+			strings.EqualFold(strings.TrimPrefix(f.ColorHex, "#"), reg.ColorHex) {
+			return f.Id, nil
+		}
+	}
+
+	density, ok := materialDensities[strings.ToUpper(reg.Material)]
+	if !ok {
+		density = defaultDensity
+	}
+	f, err := sc.CreateFilament(ctx, models.CreateFilamentRequest{
+		Name:        reg.Name,
+		VendorId:    vendorID,
+		Material:    reg.Material,
+		Density:     density,
+		Diameter:    defaultDiameter,
+		Weight:      reg.Weight,
+		SpoolWeight: defaultSpoolWeight,
+		ColorHex:    strings.ToUpper(reg.ColorHex),
+	})
+	if err != nil {
+		return 0, fmt.Errorf("create filament %q: %w", reg.Name, err)
+	}
+	return f.Id, nil
+}
@@ -0,0 +1,92 @@
+package plan
+
+import (
+	"context"
+	"testing"
+
+	"github.com/dstockto/fil/models"
+)
+
+type fakeCreator struct {
+	vendors   []models.Vendor
+	filaments []models.FilamentResponse
+	fields    []string
+
+	createdFilaments []models.CreateFilamentRequest
+	createdSpools    []models.CreateSpoolRequest
+}
+
+func (f *fakeCreator) GetVendors(context.Context) ([]models.Vendor, error) {
+	return f.vendors, nil
+}
+
+func (f *fakeCreator) CreateVendor(_ context.Context, name string) (*models.Vendor, error) {
+	v := models.Vendor{Id: 100 + len(f.vendors), Name: name}
+	f.vendors = append(f.vendors, v)
+	return &v, nil
+}
+
+func (f *fakeCreator) GetFilaments(context.Context) ([]models.FilamentResponse, error) {
+	return f.filaments, nil
+}
+
+func (f *fakeCreator) CreateFilament(_ context.Context, req models.CreateFilamentRequest) (*models.FilamentResponse, error) {
+	f.createdFilaments = append(f.createdFilaments, req)
+	return &models.FilamentResponse{Id: 200 + len(f.createdFilaments), Name: req.Name}, nil
+}
+
+func (f *fakeCreator) CreateSpool(_ context.Context, req models.CreateSpoolRequest) (*models.FindSpool, error) {
+	f.createdSpools = append(f.createdSpools, req)
+	return &models.FindSpool{Id: 300 + len(f.createdSpools), Location: req.Location}, nil
+}
+
+func (f *fakeCreator) EnsureSpoolField(_ context.Context, key, _ string) error {
+	f.fields = append(f.fields, key)
+	return nil
+}
+
+func TestRegisterSpool_CreatesVendorFilamentAndSpool(t *testing.T) {
+	sc := &fakeCreator{}
+	reg := SpoolRegistration{Location: "AMS A", Slot: 2, RFID: "UUID1", Name: "PLA Basic", Material: "PETG", ColorHex: "ff0000", Weight: 1000, Remaining: 800}
+	spool, err := RegisterSpool(context.Background(), sc, reg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if spool.Id != 301 {
+		t.Errorf("spool id = %d, want 301", spool.Id)
+	}
+	if len(sc.vendors) != 1 || sc.vendors[0].Name != BambuVendor {
+		t.Errorf("vendors = %+v, want Bambu Lab created", sc.vendors)
+	}
+	if len(sc.fields) != 1 || sc.fields[0] != models.RFIDExtraField {
+		t.Errorf("fields ensured = %v", sc.fields)
+	}
+	f := sc.createdFilaments[0]
+	if f.VendorId != 100 || f.ColorHex != "FF0000" || f.Density != 1.27 || f.Weight != 1000 {
+		t.Errorf("filament = %+v", f)
+	}
+	s := sc.createdSpools[0]
+	if s.FilamentId != 201 || s.Location != "AMS A" || s.RemainingWeight != 800 || s.Extra[models.RFIDExtraField] != `"UUID1"` {
+		t.Errorf("spool = %+v", s)
+	}
+}
+
+func TestRegisterSpool_ReusesMatchingFilament(t *testing.T) {
+	sc := &fakeCreator{
+		vendors: []models.Vendor{{Id: 7, Name: "bambu lab"}},
+		filaments: []models.FilamentResponse{
+			{Id: 40, Name: "PLA Basic", Material: "PLA", ColorHex: "00FF00", Vendor: models.Vendor{Id: 7}},
+			{Id: 41, Name: "PLA Basic", Material: "PLA", ColorHex: "FF0000", Vendor: models.Vendor{Id: 7}},
+		},
+	}
+	reg := SpoolRegistration{Location: "AMS A", Slot: 1, RFID: "UUID2", Name: "PLA Basic", Material: "PLA", ColorHex: "ff0000"}
+	if _, err := RegisterSpool(context.Background(), sc, reg); err != nil {
+		t.Fatal(err)
+	}
+	if len(sc.vendors) != 1 || len(sc.createdFilaments) != 0 {
+		t.Errorf("created vendors %v / filaments %v, want none", sc.vendors, sc.createdFilaments)
+	}
+	if got := sc.createdSpools[0]; got.FilamentId != 41 || got.RemainingWeight != 0 {
+		t.Errorf("spool = %+v, want full spool of filament 41", got)
+	}
+}
@@ -38,5 +38,5 @@ func (r *RemotePlanOps) Fail(ctx context.Context, req FailRequest) (FailResult,
 		b, _ := io.ReadAll(resp.Body)
 		return FailResult{}, fmt.Errorf("plan-fail failed: status %d: %s", resp.StatusCode, strings.TrimSpace(string(b)))
 	}
-	return FailResult{}, nil
+	return FailResult{Recorded: true}, nil
 }
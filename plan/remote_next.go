@@ -36,6 +36,11 @@ func (r *RemotePlanOps) Next(ctx context.Context, req NextRequest) (NextResult,
 	}
 	defer func() { _ = resp.Body.Close() }()
 
+	if resp.StatusCode == http.StatusAccepted {
+		// The server sent the job but couldn't record the plate.
+		b, _ := io.ReadAll(resp.Body)
+		return NextResult{}, fmt.Errorf("%w: %s", ErrNotRecorded, strings.TrimSpace(string(b)))
+	}
 	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusNoContent {
 		b, _ := io.ReadAll(resp.Body)
 		return NextResult{}, fmt.Errorf("next failed: status %d: %s", resp.StatusCode, strings.TrimSpace(string(b)))
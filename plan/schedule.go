@@ -0,0 +1,271 @@
+package plan
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"slices"
+	"sort"
+	"time"
+
+	"github.com/dstockto/fil/models"
+	"github.com/dstockto/fil/slicer"
+)
+
+// ScheduleFileName is the JSON file (in the plans dir) holding the saved
+// print queue that `fil plan next` offers first.
+const ScheduleFileName = "schedule.json"
+
+// defaultPlateDuration stands in for plates with no EstimatedDuration so
+// they still occupy their printer for a plausible amount of time.
+const defaultPlateDuration = time.Hour
+
+// SchedulePlate is a todo Plate as the scheduler sees it.
+type SchedulePlate struct {
+	Plan    string
+	Project string
+	Plate   string
+	// Filaments are the Spoolman filament IDs the plate needs. Unresolved
+	// needs (FilamentID 0) are left out — they can't be matched to a slot.
+	Filaments []int
+	Duration  time.Duration
+	// Rank orders plates by their plan's priority and due date; lower goes
+	// first, and plates of equal rank compete on swaps.
+	Rank int
+	// After are the Keys of plates that must finish before this one starts:
+	// todo plates being scheduled, or plates a printer is on now (see
+	// SchedulePrinter.Printing). Completed plates shouldn't be listed; any
+	// other Key keeps the plate out of the schedule.
+	After []string
+}
+
+// Key identifies the plate across plans, as "plan/Project/Plate".
+func (p SchedulePlate) Key() string {
+	return p.Plan + "/" + models.PlateKey(p.Project, p.Plate)
+}
+
+// SchedulePrinter is a printer's capacity and current state.
+type SchedulePrinter struct {
+	Name string
+	// Slots is how many spools the printer holds at once (AMS slots).
+	Slots int
+	// Loaded are the filament IDs in its slots right now.
+	Loaded []int
+	// BusyFor is how long until the printer's current job finishes.
+	BusyFor time.Duration
+	// Plan is the plan of the plate currently printing, if any, so the
+	// scheduler can keep following it.
+	Plan string
+	// Printing are the Keys of the plates in progress on it, which finish
+	// after BusyFor.
+	Printing []string
+}
+
+// ScheduledPlate is one entry in a printer's queue. Load and Unload are the
+// filament IDs to swap in and out before starting it.
+type ScheduledPlate struct {
+	Plan     string    `json:"plan"`
+	Project  string    `json:"project"`
+	Plate    string    `json:"plate"`
+	Start    time.Time `json:"start"`
+	Duration string    `json:"duration"`
+	Load     []int     `json:"load,omitempty"`
+	Unload   []int     `json:"unload,omitempty"`
+}
+
+// Schedule is an ordered queue of plates per printer.
+type Schedule struct {
+	GeneratedAt time.Time                   `json:"generated_at"`
+	Queues      map[string][]ScheduledPlate `json:"queues"`
+}
+
+// Swaps counts the spool loads across every queue.
+func (s Schedule) Swaps() int {
+	n := 0
+	for _, q := range s.Queues {
+		for _, p := range q {
+			n += len(p.Load)
+		}
+	}
+	return n
+}
+
+// BuildSchedule assigns every plate to a printer queue. It's a greedy list
+// scheduler: whichever printer frees up first takes, among the plates whose
+// After prerequisites are already queued or printing, the lowest-ranked one
+// that needs the fewest spool loads given what that printer will have
+// loaded, preferring the plan it printed last so a plan's plates stay
+// together, then the input order. A plate never starts before its
+// prerequisites end. When slots run out, the filaments the remaining plates
+// use least are unloaded first. Plates caught in a dependency cycle, or
+// waiting on a plate that is neither scheduled nor printing, are left out.
+func BuildSchedule(now time.Time, printers []SchedulePrinter, plates []SchedulePlate) Schedule {
+	sched := Schedule{GeneratedAt: now, Queues: map[string][]ScheduledPlate{}}
+	if len(printers) == 0 {
+		return sched
+	}
+
+	type printerState struct {
+		name     string
+		slots    int
+		loaded   []int
+		free     time.Time
+		lastPlan string
+	}
+	states := make([]*printerState, len(printers))
+	for i, p := range printers {
+		slots := max(p.Slots, 1)
+		states[i] = &printerState{
+			name:     p.Name,
+			slots:    slots,
+			loaded:   slices.Clone(p.Loaded),
+			free:     now.Add(max(p.BusyFor, 0)),
+			lastPlan: p.Plan,
+		}
+	}
+
+	ends := map[string]time.Time{}
+	for i, p := range printers {
+		for _, key := range p.Printing {
+			ends[key] = states[i].free
+		}
+	}
+
+	remaining := slices.Clone(plates)
+	for len(remaining) > 0 {
+		ps := states[0]
+		for _, s := range states[1:] {
+			if s.free.Before(ps.free) || (s.free.Equal(ps.free) && s.name < ps.name) {
+				ps = s
+			}
+		}
+
+		best, bestRank, bestLoads, bestSwitch := -1, 0, 0, 0
+		for i, pl := range remaining {
+			ready := true
+			for _, dep := range pl.After {
+				if _, done := ends[dep]; !done {
+					ready = false
+					break
+				}
+			}
+			if !ready {
+				continue
+			}
+			loads := len(missing(pl.Filaments, ps.loaded))
+			sw := 0
+			if pl.Plan != ps.lastPlan {
+				sw = 1
+			}
+			if best < 0 || pl.Rank < bestRank ||
+				(pl.Rank == bestRank && (loads < bestLoads || (loads == bestLoads && sw < bestSwitch))) {
+				best, bestRank, bestLoads, bestSwitch = i, pl.Rank, loads, sw
+			}
+		}
+		if best < 0 {
+			break // everything left waits on a cycle or an unscheduled plate
+		}
+		pl := remaining[best]
+		remaining = slices.Delete(remaining, best, best+1)
+
+		load := missing(pl.Filaments, ps.loaded)
+		var unload []int
+		if over := len(ps.loaded) + len(load) - ps.slots; over > 0 {
+			unload = evictions(ps.loaded, pl.Filaments, remaining, over)
+			ps.loaded = slices.DeleteFunc(ps.loaded, func(id int) bool { return slices.Contains(unload, id) })
+		}
+		ps.loaded = append(ps.loaded, load...)
+
+		dur, durStr := pl.Duration, ""
+		if dur > 0 {
+			durStr = slicer.FormatDuration(dur)
+		} else {
+			dur = defaultPlateDuration
+		}
+		start := ps.free
+		for _, dep := range pl.After {
+			if end, ok := ends[dep]; ok && end.After(start) {
+				start = end
+			}
+		}
+		sched.Queues[ps.name] = append(sched.Queues[ps.name], ScheduledPlate{
+			Plan:     pl.Plan,
+			Project:  pl.Project,
+			Plate:    pl.Plate,
+			Start:    start,
+			Duration: durStr,
+			Load:     load,
+			Unload:   unload,
+		})
+		ps.free = start.Add(dur)
+		ends[pl.Key()] = ps.free
+		ps.lastPlan = pl.Plan
+	}
+	return sched
+}
+
+// missing returns the distinct IDs in need that aren't in loaded.
+func missing(need, loaded []int) []int {
+	var out []int
+	for _, id := range need {
+		if !slices.Contains(loaded, id) && !slices.Contains(out, id) {
+			out = append(out, id)
+		}
+	}
+	return out
+}
+
+// evictions picks up to n loaded filaments to unload, skipping those keep
+// needs. Filaments fewer remaining plates use go first; ties go by ID so the
+// result is stable. If keep itself needs more than the printer holds, fewer
+// than n come back and the extra swaps happen mid-print.
+func evictions(loaded, keep []int, remaining []SchedulePlate, n int) []int {
+	uses := map[int]int{}
+	for _, pl := range remaining {
+		for _, id := range pl.Filaments {
+			uses[id]++
+		}
+	}
+	var candidates []int
+	for _, id := range loaded {
+		if !slices.Contains(keep, id) {
+			candidates = append(candidates, id)
+		}
+	}
+	sort.SliceStable(candidates, func(i, j int) bool {
+		if uses[candidates[i]] != uses[candidates[j]] {
+			return uses[candidates[i]] < uses[candidates[j]]
+		}
+		return candidates[i] < candidates[j]
+	})
+	if len(candidates) > n {
+		candidates = candidates[:n]
+	}
+	return candidates
+}
+
+// ReadSchedule loads a saved schedule. A missing file is an empty schedule.
+func ReadSchedule(path string) (Schedule, error) {
+	var s Schedule
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return s, nil
+	}
+	if err != nil {
+		return s, err
+	}
+	if err := json.Unmarshal(data, &s); err != nil {
+		return s, fmt.Errorf("parse %s: %w", path, err)
+	}
+	return s, nil
+}
+
+// WriteSchedule saves a schedule as indented JSON.
+func WriteSchedule(path string, s Schedule) error {
+	data, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, append(data, '\n'), 0644)
+}
@@ -0,0 +1,176 @@
+package plan
+
+import (
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+var scheduleNow = time.Date(2026, 5, 1, 9, 0, 0, 0, time.UTC)
+
+func plateNames(q []ScheduledPlate) []string {
+	var out []string
+	for _, p := range q {
+		out = append(out, p.Plate)
+	}
+	return out
+}
+
+func TestBuildSchedule_PrefersLoadedFilament(t *testing.T) {
+	printers := []SchedulePrinter{
+		{Name: "X1C", Slots: 4, Loaded: []int{1, 2}},
+		{Name: "MK4", Slots: 1, Loaded: []int{3}},
+	}
+	plates := []SchedulePlate{
+		{Plan: "a.yaml", Plate: "red", Filaments: []int{3}, Duration: time.Hour},
+		{Plan: "a.yaml", Plate: "white-black", Filaments: []int{1, 2}, Duration: time.Hour},
+	}
+	s := BuildSchedule(scheduleNow, printers, plates)
+
+	if got := plateNames(s.Queues["MK4"]); !reflect.DeepEqual(got, []string{"red"}) {
+		t.Errorf("MK4 queue = %v, want [red]", got)
+	}
+	if got := plateNames(s.Queues["X1C"]); !reflect.DeepEqual(got, []string{"white-black"}) {
+		t.Errorf("X1C queue = %v, want [white-black]", got)
+	}
+	if s.Swaps() != 0 {
+		t.Errorf("Swaps = %d, want 0", s.Swaps())
+	}
+}
+
+func TestBuildSchedule_BalancesByDuration(t *testing.T) {
+	printers := []SchedulePrinter{
+		{Name: "A", Slots: 4, Loaded: []int{1}},
+		{Name: "B", Slots: 4, Loaded: []int{1}, BusyFor: 3 * time.Hour},
+	}
+	plates := []SchedulePlate{
+		{Plan: "p.yaml", Plate: "1", Filaments: []int{1}, Duration: 2 * time.Hour},
+		{Plan: "p.yaml", Plate: "2", Filaments: []int{1}, Duration: 2 * time.Hour},
+		{Plan: "p.yaml", Plate: "3", Filaments: []int{1}, Duration: 2 * time.Hour},
+	}
+	s := BuildSchedule(scheduleNow, printers, plates)
+
+	// A takes 1 (t=0) and 2 (t=2h); B frees at 3h and takes 3 before A frees at 4h.
+	if got := plateNames(s.Queues["A"]); !reflect.DeepEqual(got, []string{"1", "2"}) {
+		t.Errorf("A queue = %v, want [1 2]", got)
+	}
+	if got := plateNames(s.Queues["B"]); !reflect.DeepEqual(got, []string{"3"}) {
+		t.Errorf("B queue = %v, want [3]", got)
+	}
+	if want := scheduleNow.Add(3 * time.Hour); !s.Queues["B"][0].Start.Equal(want) {
+		t.Errorf("B start = %v, want %v", s.Queues["B"][0].Start, want)
+	}
+	if s.Queues["A"][1].Duration != "2h" {
+		t.Errorf("Duration = %q, want 2h", s.Queues["A"][1].Duration)
+	}
+}
+
+func TestBuildSchedule_KeepsPlanTogether(t *testing.T) {
+	printers := []SchedulePrinter{{Name: "A", Slots: 4, Loaded: []int{1}}}
+	plates := []SchedulePlate{
+		{Plan: "one.yaml", Plate: "1a", Filaments: []int{1}},
+		{Plan: "two.yaml", Plate: "2a", Filaments: []int{1}},
+		{Plan: "one.yaml", Plate: "1b", Filaments: []int{1}},
+	}
+	s := BuildSchedule(scheduleNow, printers, plates)
+	if got := plateNames(s.Queues["A"]); !reflect.DeepEqual(got, []string{"1a", "1b", "2a"}) {
+		t.Errorf("queue = %v, want [1a 1b 2a]", got)
+	}
+}
+
+func TestBuildSchedule_UnloadsLeastUsedFilament(t *testing.T) {
+	printers := []SchedulePrinter{{Name: "A", Slots: 2, Loaded: []int{1, 2}}}
+	plates := []SchedulePlate{
+		{Plan: "p.yaml", Plate: "three", Filaments: []int{3}},
+		{Plan: "p.yaml", Plate: "one-three", Filaments: []int{1, 3}},
+	}
+	s := BuildSchedule(scheduleNow, printers, plates)
+	q := s.Queues["A"]
+
+	if got := plateNames(q); !reflect.DeepEqual(got, []string{"three", "one-three"}) {
+		t.Fatalf("queue = %v", got)
+	}
+	// Loading 3 needs a free slot; 1 is still wanted by one-three, so 2 goes.
+	if !reflect.DeepEqual(q[0].Load, []int{3}) || !reflect.DeepEqual(q[0].Unload, []int{2}) {
+		t.Errorf("three load/unload = %v/%v, want [3]/[2]", q[0].Load, q[0].Unload)
+	}
+	if len(q[1].Load) != 0 || len(q[1].Unload) != 0 {
+		t.Errorf("one-three load/unload = %v/%v, want none", q[1].Load, q[1].Unload)
+	}
+	if s.Swaps() != 1 {
+		t.Errorf("Swaps = %d, want 1", s.Swaps())
+	}
+}
+
+func TestBuildSchedule_RespectsAfterAndRank(t *testing.T) {
+	printers := []SchedulePrinter{
+		{Name: "A", Slots: 4, Loaded: []int{1}},
+		{Name: "B", Slots: 4, Loaded: []int{2}},
+	}
+	plates := []SchedulePlate{
+		{Plan: "p.yaml", Project: "Box", Plate: "Lid", Filaments: []int{2}, Duration: time.Hour, After: []string{"p.yaml/Box/Base"}},
+		{Plan: "p.yaml", Project: "Box", Plate: "Base", Filaments: []int{1}, Duration: 3 * time.Hour},
+		{Plan: "q.yaml", Project: "Toy", Plate: "Urgent", Filaments: []int{3}, Duration: time.Hour, Rank: -1},
+		{Plan: "q.yaml", Project: "Toy", Plate: "Loop1", After: []string{"q.yaml/Toy/Loop2"}},
+		{Plan: "q.yaml", Project: "Toy", Plate: "Loop2", After: []string{"q.yaml/Toy/Loop1"}},
+	}
+	s := BuildSchedule(scheduleNow, printers, plates)
+
+	// A frees first (name tie) and takes the top-ranked plate despite the
+	// load; B can't start Lid before Base, so it takes Base and Lid waits.
+	if got := plateNames(s.Queues["A"]); !reflect.DeepEqual(got, []string{"Urgent", "Lid"}) {
+		t.Errorf("A queue = %v, want [Urgent Lid]", got)
+	}
+	if got := plateNames(s.Queues["B"]); !reflect.DeepEqual(got, []string{"Base"}) {
+		t.Errorf("B queue = %v, want [Base]", got)
+	}
+	if lid := s.Queues["A"][1]; !lid.Start.Equal(scheduleNow.Add(3 * time.Hour)) {
+		t.Errorf("Lid starts %v, want once Base ends at +3h", lid.Start)
+	}
+}
+
+func TestBuildSchedule_WaitsForPrintingPrerequisite(t *testing.T) {
+	printers := []SchedulePrinter{
+		{Name: "A", Slots: 4, BusyFor: 2 * time.Hour, Printing: []string{"p.yaml/Box/Base"}},
+		{Name: "B", Slots: 4},
+	}
+	plates := []SchedulePlate{
+		{Plan: "p.yaml", Project: "Box", Plate: "Lid", Duration: time.Hour, After: []string{"p.yaml/Box/Base"}},
+		{Plan: "p.yaml", Project: "Box", Plate: "Hinge", Duration: time.Hour, After: []string{"p.yaml/Box/Gone"}},
+	}
+	s := BuildSchedule(scheduleNow, printers, plates)
+
+	if got := plateNames(s.Queues["B"]); !reflect.DeepEqual(got, []string{"Lid"}) {
+		t.Fatalf("B queue = %v, want [Lid]", got)
+	}
+	if lid := s.Queues["B"][0]; !lid.Start.Equal(scheduleNow.Add(2 * time.Hour)) {
+		t.Errorf("Lid starts %v, want once Base ends at +2h", lid.Start)
+	}
+	if len(s.Queues["A"]) != 0 {
+		t.Errorf("A queue = %v, want Hinge left out", plateNames(s.Queues["A"]))
+	}
+}
+
+func TestSchedule_ReadWriteRoundTrip(t *testing.T) {
+	path := filepath.Join(t.TempDir(), ScheduleFileName)
+
+	empty, err := ReadSchedule(path)
+	if err != nil || len(empty.Queues) != 0 {
+		t.Fatalf("missing file: got %+v, %v", empty, err)
+	}
+
+	s := BuildSchedule(scheduleNow,
+		[]SchedulePrinter{{Name: "A", Slots: 1}},
+		[]SchedulePlate{{Plan: "p.yaml", Project: "P", Plate: "1", Filaments: []int{7}, Duration: 90 * time.Minute}})
+	if err := WriteSchedule(path, s); err != nil {
+		t.Fatal(err)
+	}
+	got, err := ReadSchedule(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got, s) {
+		t.Errorf("round trip = %+v, want %+v", got, s)
+	}
+}
@@ -0,0 +1,189 @@
+package plan
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+
+	"github.com/dstockto/fil/models"
+)
+
+// TrayObservation is what a printer reports for a slot: the filament it
+// thinks is loaded there.
+type TrayObservation struct {
+	Color string // hex RRGGBB
+	Type  string // e.g. "PLA"
+	UUID  string // RFID tag (Bambu tray_uuid), "" for spools without one
+}
+
+// SwapCandidate is a spool that could be the one now in a tray.
+type SwapCandidate struct {
+	Spool  models.FindSpool
+	DeltaE float64 // CIEDE2000 against the tray color; 0 for an RFID match
+	RFID   bool    // the spool's recorded RFID matches the tray's
+}
+
+// MatchTraySpools ranks the spools that could be loaded in a tray, best
+// first. An RFID match is returned alone; otherwise a spool qualifies when
+// its material fits the tray type and its color is within maxDeltaE.
+// Archived spools never match.
+func MatchTraySpools(obs TrayObservation, spools []models.FindSpool, maxDeltaE float64) []SwapCandidate {
+	if obs.UUID != "" {
+		for _, s := range spools {
+			if !s.Archived && strings.EqualFold(s.RFID(), obs.UUID) {
+				return []SwapCandidate{{Spool: s, RFID: true}}
+			}
+		}
+	}
+	target, ok := models.ParseHexColor(obs.Color)
+	if !ok {
+		return nil
+	}
+
+	var out []SwapCandidate
+	for _, s := range spools {
+		if s.Archived || !materialFitsTray(s.Filament.Material, obs.Type) {
+			continue
+		}
+		// A spool tagged with a different RFID can't be this one.
+		if obs.UUID != "" && s.RFID() != "" {
+			continue
+		}
+		d := models.SpoolColorDistance(s, target)
+		if d <= maxDeltaE {
+			out = append(out, SwapCandidate{Spool: s, DeltaE: d})
+		}
+	}
+	sort.SliceStable(out, func(i, j int) bool { return out[i].DeltaE < out[j].DeltaE })
+	return out
+}
+
+// Unambiguous reports whether the best candidate is safe to apply without
+// asking: an RFID match, or the only spool that fits.
+func Unambiguous(candidates []SwapCandidate) bool {
+	return len(candidates) == 1
+}
+
+// SpoolFitsTray reports whether spool could be what the printer shows in
+// the tray, so a slot whose recorded spool already fits needs no swap.
+func SpoolFitsTray(spool models.FindSpool, obs TrayObservation, maxDeltaE float64) bool {
+	if obs.UUID != "" && spool.RFID() != "" {
+		return strings.EqualFold(spool.RFID(), obs.UUID)
+	}
+	if !materialFitsTray(spool.Filament.Material, obs.Type) {
+		return false
+	}
+	target, ok := models.ParseHexColor(obs.Color)
+	if !ok {
+		return true
+	}
+	d := models.SpoolColorDistance(spool, target)
+	return !math.IsInf(d, 1) && d <= maxDeltaE
+}
+
+// materialFitsTray compares a Spoolman material ("Matte PLA") with a
+// printer's tray type ("PLA") by word, ignoring case.
+func materialFitsTray(material, trayType string) bool {
+	if trayType == "" || material == "" {
+		return true
+	}
+	m, t := strings.ToUpper(material), strings.ToUpper(trayType)
+	if m == t {
+		return true
+	}
+	for _, w := range strings.FieldsFunc(m, func(r rune) bool { return r == ' ' || r == '-' || r == '+' }) {
+		if w == t {
+			return true
+		}
+	}
+	return false
+}
+
+// SpoolSwap records a spool found in a printer slot other than the one the
+// location orders place there.
+type SpoolSwap struct {
+	Location  string `json:"location"`
+	Slot      int    `json:"slot"` // 1-based
+	SpoolID   int    `json:"spool_id"`
+	From      string `json:"from,omitempty"`      // the spool's recorded location
+	Displaced int    `json:"displaced,omitempty"` // spool recorded in the slot before
+	Tray      string `json:"tray,omitempty"`      // what the printer reported, for display
+	RFID      string `json:"rfid,omitempty"`      // tray's tag, to record on SpoolID once applied
+}
+
+// SpoolMover is the slice of the Spoolman API that moving spools needs.
+// *api.Client satisfies it.
+type SpoolMover interface {
+	GetLocationOrders(ctx context.Context) (map[string][]int, error)
+	PostSettingObject(ctx context.Context, key string, obj any) error
+	MoveSpool(ctx context.Context, spoolID int, to string) error
+}
+
+// ApplySpoolSwap records swap.SpoolID in its slot the way `fil move` would:
+// the spool leaves every other list (printer slots keep their position as
+// empty), and the displaced spool goes back to where the new one came from,
+// on the assumption the two traded places. When the new spool came from a
+// printer slot the displaced one is left without a location. isPrinter
+// reports whether a location is a printer's. It returns where the displaced
+// spool went.
+func ApplySpoolSwap(ctx context.Context, sm SpoolMover, isPrinter func(string) bool, swap SpoolSwap) (string, error) {
+	if swap.SpoolID <= 0 || swap.Slot < 1 || swap.Location == "" {
+		return "", fmt.Errorf("invalid swap %+v", swap)
+	}
+	orders, err := sm.GetLocationOrders(ctx)
+	if err != nil {
+		return "", fmt.Errorf("load location orders: %w", err)
+	}
+	if orders == nil {
+		orders = map[string][]int{}
+	}
+
+	for loc, ids := range orders {
+		if isPrinter(loc) {
+			for i, id := range ids {
+				if id == swap.SpoolID {
+					ids[i] = -1
+				}
+			}
+			continue
+		}
+		kept := ids[:0:0]
+		for _, id := range ids {
+			if id != swap.SpoolID {
+				kept = append(kept, id)
+			}
+		}
+		orders[loc] = kept
+	}
+
+	list := orders[swap.Location]
+	for len(list) < swap.Slot {
+		list = append(list, -1)
+	}
+	occupant := list[swap.Slot-1]
+	list[swap.Slot-1] = swap.SpoolID
+	orders[swap.Location] = list
+
+	displacedTo := ""
+	if occupant > 0 && swap.From != "" && !isPrinter(swap.From) {
+		displacedTo = swap.From
+		orders[displacedTo] = append(orders[displacedTo], occupant)
+	}
+
+	if err := sm.PostSettingObject(ctx, "locations_spoolorders", orders); err != nil {
+		return "", fmt.Errorf("update locations_spoolorders: %w", err)
+	}
+	var errs []error
+	if err := sm.MoveSpool(ctx, swap.SpoolID, swap.Location); err != nil {
+		errs = append(errs, fmt.Errorf("move spool #%d: %w", swap.SpoolID, err))
+	}
+	if occupant > 0 {
+		if err := sm.MoveSpool(ctx, occupant, displacedTo); err != nil {
+			errs = append(errs, fmt.Errorf("move spool #%d: %w", occupant, err))
+		}
+	}
+	return displacedTo, errors.Join(errs...)
+}
@@ -0,0 +1,122 @@
+package plan
+
+import (
+	"context"
+	"slices"
+	"testing"
+
+	"github.com/dstockto/fil/models"
+)
+
+func swapSpool(id int, loc, material, hex string) models.FindSpool {
+	s := models.FindSpool{Id: id, Location: loc}
+	s.Filament.Material = material
+	s.Filament.ColorHex = hex
+	return s
+}
+
+func TestMatchTraySpools(t *testing.T) {
+	tagged := swapSpool(5, "Shelf", "PLA", "00FF00")
+	tagged.Extra = map[string]string{models.RFIDExtraField: `"ABC123"`}
+	archived := swapSpool(6, "", "PLA", "FF0000")
+	archived.Archived = true
+	spools := []models.FindSpool{
+		swapSpool(1, "Shelf", "Matte PLA", "FF1010"),
+		swapSpool(2, "Shelf", "PETG", "FF0000"), // wrong material
+		swapSpool(3, "Shelf", "PLA", "0000FF"),  // too far
+		swapSpool(4, "Shelf", "PLA", "FF0000"),
+		tagged,
+		archived,
+	}
+
+	got := MatchTraySpools(TrayObservation{Color: "FF0000", Type: "PLA"}, spools, 10)
+	var ids []int
+	for _, c := range got {
+		ids = append(ids, c.Spool.Id)
+	}
+	if !slices.Equal(ids, []int{4, 1}) {
+		t.Errorf("color match = %v, want [4 1]", ids)
+	}
+	if Unambiguous(got) {
+		t.Error("two candidates should be ambiguous")
+	}
+
+	got = MatchTraySpools(TrayObservation{Color: "FF0000", Type: "PLA", UUID: "abc123"}, spools, 10)
+	if len(got) != 1 || got[0].Spool.Id != 5 || !got[0].RFID || !Unambiguous(got) {
+		t.Errorf("RFID match = %+v, want spool 5 alone", got)
+	}
+
+	// An unknown tag still matches by color, skipping spools tagged otherwise.
+	got = MatchTraySpools(TrayObservation{Color: "00FF00", Type: "PLA", UUID: "OTHER"}, spools, 10)
+	if len(got) != 0 {
+		t.Errorf("unknown tag = %+v, want no match (only green spool is tagged differently)", got)
+	}
+}
+
+func TestSpoolFitsTray(t *testing.T) {
+	s := swapSpool(1, "AMS A", "PLA", "FF0000")
+	if !SpoolFitsTray(s, TrayObservation{Color: "FF0505", Type: "PLA"}, 10) {
+		t.Error("near-identical PLA should fit")
+	}
+	if SpoolFitsTray(s, TrayObservation{Color: "FF0000", Type: "PETG"}, 10) {
+		t.Error("PETG tray should not fit a PLA spool")
+	}
+	if SpoolFitsTray(s, TrayObservation{Color: "0000FF", Type: "PLA"}, 10) {
+		t.Error("blue tray should not fit a red spool")
+	}
+}
+
+type fakeMover struct {
+	orders map[string][]int
+	posted map[string][]int
+	moves  map[int]string
+}
+
+func (f *fakeMover) GetLocationOrders(context.Context) (map[string][]int, error) {
+	return f.orders, nil
+}
+
+func (f *fakeMover) PostSettingObject(_ context.Context, key string, obj any) error {
+	f.posted = obj.(map[string][]int)
+	return nil
+}
+
+func (f *fakeMover) MoveSpool(_ context.Context, id int, to string) error {
+	f.moves[id] = to
+	return nil
+}
+
+func TestApplySpoolSwap(t *testing.T) {
+	isPrinter := func(loc string) bool { return loc == "AMS A" || loc == "AMS B" }
+
+	t.Run("from a shelf", func(t *testing.T) {
+		sm := &fakeMover{orders: map[string][]int{"AMS A": {10, 11}, "Shelf": {20, 21}}, moves: map[int]string{}}
+		to, err := ApplySpoolSwap(context.Background(), sm, isPrinter, SpoolSwap{Location: "AMS A", Slot: 2, SpoolID: 21, From: "Shelf"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if to != "Shelf" {
+			t.Errorf("displaced went to %q, want Shelf", to)
+		}
+		if !slices.Equal(sm.posted["AMS A"], []int{10, 21}) || !slices.Equal(sm.posted["Shelf"], []int{20, 11}) {
+			t.Errorf("orders = %v", sm.posted)
+		}
+		if sm.moves[21] != "AMS A" || sm.moves[11] != "Shelf" {
+			t.Errorf("moves = %v", sm.moves)
+		}
+	})
+
+	t.Run("from another printer slot", func(t *testing.T) {
+		sm := &fakeMover{orders: map[string][]int{"AMS A": {10, 11}, "AMS B": {30}}, moves: map[int]string{}}
+		to, err := ApplySpoolSwap(context.Background(), sm, isPrinter, SpoolSwap{Location: "AMS A", Slot: 1, SpoolID: 30, From: "AMS B"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if to != "" || sm.moves[10] != "" {
+			t.Errorf("displaced #10 went to %q, want no location", to)
+		}
+		if !slices.Equal(sm.posted["AMS A"], []int{30, 11}) || !slices.Equal(sm.posted["AMS B"], []int{-1}) {
+			t.Errorf("orders = %v", sm.posted)
+		}
+	})
+}
@@ -0,0 +1,73 @@
+package plan
+
+import (
+	"math"
+
+	"github.com/dstockto/fil/models"
+)
+
+// TrayReading is a printer's filament estimate for one tray. AmsID indexes
+// the printer's configured locations and TrayID the slot within that
+// location — the same mapping tray pushes and `fil verify` use.
+type TrayReading struct {
+	AmsID  int
+	TrayID int
+	Remain int     // percent the printer reports
+	Grams  float64 // Remain applied to the spool's nominal weight
+}
+
+// WeightDrift is a loaded spool whose Spoolman remaining weight disagrees
+// with the printer's estimate.
+type WeightDrift struct {
+	Printer  string
+	Location string
+	Slot     int // 1-based
+	Spool    models.FindSpool
+	Remain   int
+	Estimate float64 // grams, per the printer
+}
+
+// Diff is how far Spoolman is from the printer's estimate; positive means
+// Spoolman thinks there's more on the spool than the printer does.
+func (d WeightDrift) Diff() float64 {
+	return d.Spool.RemainingWeight - d.Estimate
+}
+
+// CompareTrayWeights lines the printer's readings up with the spools the
+// location orders place in each slot and returns those that differ by more
+// than tolerance grams. Slots that are empty, unknown to Spoolman, or
+// without a reading are skipped.
+func CompareTrayWeights(printer string, locations []string, orders map[string][]int, spools []models.FindSpool, readings []TrayReading, tolerance float64) []WeightDrift {
+	byID := make(map[int]models.FindSpool, len(spools))
+	for _, s := range spools {
+		byID[s.Id] = s
+	}
+
+	var out []WeightDrift
+	for _, r := range readings {
+		if r.AmsID < 0 || r.AmsID >= len(locations) {
+			continue
+		}
+		loc := locations[r.AmsID]
+		ids := orders[loc]
+		if r.TrayID < 0 || r.TrayID >= len(ids) {
+			continue
+		}
+		spool, ok := byID[ids[r.TrayID]]
+		if !ok {
+			continue
+		}
+		d := WeightDrift{
+			Printer:  printer,
+			Location: loc,
+			Slot:     r.TrayID + 1,
+			Spool:    spool,
+			Remain:   r.Remain,
+			Estimate: r.Grams,
+		}
+		if math.Abs(d.Diff()) > tolerance {
+			out = append(out, d)
+		}
+	}
+	return out
+}
@@ -0,0 +1,41 @@
+package plan
+
+import (
+	"testing"
+
+	"github.com/dstockto/fil/models"
+)
+
+func TestCompareTrayWeights(t *testing.T) {
+	spool := func(id int, remaining float64) models.FindSpool {
+		var s models.FindSpool
+		s.Id = id
+		s.RemainingWeight = remaining
+		return s
+	}
+	spools := []models.FindSpool{spool(10, 500), spool(11, 300), spool(12, 800)}
+	orders := map[string][]int{
+		"AMS A": {10, 11, -1, 99},
+		"AMS B": {12},
+	}
+	readings := []TrayReading{
+		{AmsID: 0, TrayID: 0, Remain: 42, Grams: 420}, // 80g drift
+		{AmsID: 0, TrayID: 1, Remain: 29, Grams: 290}, // within tolerance
+		{AmsID: 0, TrayID: 2, Remain: 50, Grams: 500}, // empty slot
+		{AmsID: 0, TrayID: 3, Remain: 50, Grams: 500}, // spool not in Spoolman
+		{AmsID: 1, TrayID: 0, Remain: 90, Grams: 900}, // 100g under
+		{AmsID: 2, TrayID: 0, Remain: 90, Grams: 900}, // no such location
+	}
+
+	got := CompareTrayWeights("X1C", []string{"AMS A", "AMS B"}, orders, spools, readings, 25)
+
+	if len(got) != 2 {
+		t.Fatalf("got %d drifts, want 2: %+v", len(got), got)
+	}
+	if got[0].Spool.Id != 10 || got[0].Location != "AMS A" || got[0].Slot != 1 || got[0].Diff() != 80 {
+		t.Errorf("first drift = %+v (diff %v), want spool 10 at AMS A:1, +80g", got[0], got[0].Diff())
+	}
+	if got[1].Spool.Id != 12 || got[1].Diff() != -100 {
+		t.Errorf("second drift = %+v (diff %v), want spool 12, -100g", got[1], got[1].Diff())
+	}
+}
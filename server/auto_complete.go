@@ -0,0 +1,116 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/dstockto/fil/models"
+	"github.com/dstockto/fil/plan"
+)
+
+// AutoComplete closes out every in-progress plate on printerName after the
+// printer reports FINISH. Deductions come from the plate's planned Needs
+// matched against the Spools loaded in the printer's locations; FinishedAt is
+// the printer's own LastFinishedAt so history reflects when the print
+// actually ended. Each completed plate leaves a PendingEvent the user can
+// accept or amend from the CLI/TUI — the planned amounts are a guess, and
+// the real usage is only known once someone looks at the slicer or the spool.
+//
+// Returns the recorded events. Plates that fail to complete are skipped with
+// a log line; a printer with nothing in progress is a no-op.
+func (s *PlanServer) AutoComplete(ctx context.Context, printerName string) ([]PendingEvent, error) {
+	if s.PlanOps == nil {
+		return nil, fmt.Errorf("plan ops not configured")
+	}
+	if s.Pending == nil {
+		return nil, fmt.Errorf("pending store not configured")
+	}
+
+	plates := FindInProgressPlates(s.PlansDir, printerName)
+	if len(plates) == 0 {
+		return nil, nil
+	}
+
+	finishedAt := time.Now().UTC()
+	if s.Printers != nil {
+		if t, ok := s.Printers.LastFinishedAt(printerName); ok {
+			finishedAt = t.UTC()
+		}
+	}
+
+	// One spool listing covers every plate on the bed. A Spoolman outage
+	// still completes the plates (the print did finish) — every Need just
+	// lands in Unmatched for manual deduction.
+	var locations []string
+	if s.PrinterLocations != nil {
+		locations = s.PrinterLocations.Locations(printerName)
+	}
+	var spools []models.FindSpool
+	var spoolErr error
+	if s.Spoolman != nil {
+		spools, spoolErr = s.Spoolman.FindSpoolsByName(ctx, "*", nil, nil)
+		if spoolErr != nil {
+			spoolErr = fmt.Errorf("list spools: %w", spoolErr)
+		}
+	}
+
+	var events []PendingEvent
+	for _, p := range plates {
+		deductions, unmatched := plan.MatchDeductions(spools, locations, p.Plate.Needs)
+		req := plan.CompleteRequest{
+			Plan:              p.Plan,
+			Project:           p.Project,
+			Plate:             p.Plate.Name,
+			Printer:           printerName,
+			StartedAt:         p.Plate.StartedAt,
+			EstimatedDuration: p.Plate.EstimatedDuration,
+			FinishedAt:        finishedAt,
+			Deductions:        deductions,
+			Filament:          p.Plate.Needs,
+		}
+		ev := PendingEvent{
+			Kind:       "complete",
+			Printer:    printerName,
+			Plates:     []PendingPlate{{Plan: p.Plan, Project: p.Project, Plate: p.Plate.Name}},
+			OccurredAt: finishedAt,
+			Deductions: deductions,
+			Unmatched:  unmatched,
+		}
+		var errs []string
+		if spoolErr != nil {
+			errs = append(errs, spoolErr.Error())
+		}
+		if _, err := s.PlanOps.Complete(ctx, req); err != nil {
+			// Complete saves the YAML before touching Spoolman, so an error
+			// is either "nothing happened" (load/save failed, plate still
+			// in-progress) or a partial deduction after the plate was
+			// marked completed. Only the latter deserves a pending record.
+			if stillInProgress(s.PlansDir, printerName, p) {
+				fmt.Printf("[auto-complete] %s: %s / %s: %v\n", printerName, p.Project, p.Plate.Name, err)
+				continue
+			}
+			errs = append(errs, err.Error())
+		}
+		ev.Error = strings.Join(errs, "; ")
+		stored, err := s.Pending.Add(ev)
+		if err != nil {
+			return events, fmt.Errorf("record pending event: %w", err)
+		}
+		fmt.Printf("[auto-complete] %s: completed %s / %s (pending confirmation %s)\n", printerName, p.Project, p.Plate.Name, stored.ID)
+		events = append(events, stored)
+	}
+	return events, nil
+}
+
+// stillInProgress reports whether p is still listed as in-progress on the
+// printer, i.e. a Complete call that errored never got as far as saving.
+func stillInProgress(plansDir, printerName string, p PlanPlate) bool {
+	for _, cur := range FindInProgressPlates(plansDir, printerName) {
+		if cur.Plan == p.Plan && cur.Project == p.Project && cur.Plate.Name == p.Plate.Name {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,141 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/dstockto/fil/models"
+	"github.com/dstockto/fil/plan"
+	"gopkg.in/yaml.v3"
+)
+
+const autoCompletePlanYAML = `projects:
+- name: Widget
+  status: in-progress
+  plates:
+  - name: Plate 1
+    status: in-progress
+    printer: X1C
+    started_at: "2026-05-01T08:00:00Z"
+    estimated_duration: 2h
+    needs:
+    - filament_id: 100
+      name: PLA white
+      amount: 25
+    - filament_id: 300
+      name: PLA red
+      amount: 5
+  - name: Plate 2
+    status: todo
+`
+
+func writeAutoCompletePlan(t *testing.T, dir string) {
+	t.Helper()
+	if err := os.WriteFile(filepath.Join(dir, "widget.yaml"), []byte(autoCompletePlanYAML), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// End to end through a real LocalPlanOps: the FINISH handler completes the
+// plate on disk, deducts the matched need from the loaded spool, stamps the
+// printer's own finish time, and leaves the unmatched need on a pending
+// event for the user to deal with.
+func TestAutoCompleteCompletesInProgressPlate(t *testing.T) {
+	dir := t.TempDir()
+	writeAutoCompletePlan(t, dir)
+
+	finishedAt := time.Date(2026, 5, 1, 10, 3, 0, 0, time.UTC)
+	pm := NewPrinterManager()
+	_ = pm.AddAdapter("X1C", &fakeAdapter{state: PrinterState{Name: "X1C", State: "finished", LastFinishedAt: finishedAt}})
+
+	sm := newFakeSpoolman(
+		makeSpool(1, "AMS A1", 500, 100, "PLA white"),
+		makeSpool(3, "Shelf", 500, 300, "PLA red"), // not loaded in the printer
+	)
+	locs := plan.StaticPrinterLocations{"X1C": {"AMS A1", "AMS A2"}}
+	s := &PlanServer{
+		PlansDir:         dir,
+		Printers:         pm,
+		Spoolman:         sm,
+		PrinterLocations: locs,
+		Pending:          NewPendingStore(dir),
+		PlanOps:          plan.NewLocal(sm, locs, plan.NewFilePlanStore(dir, "", ""), plan.NewFileHistoryWriter(dir), nil),
+	}
+
+	events, err := s.AutoComplete(context.Background(), "X1C")
+	if err != nil {
+		t.Fatalf("AutoComplete: %v", err)
+	}
+	if len(events) != 1 {
+		t.Fatalf("got %d events, want 1", len(events))
+	}
+	ev := events[0]
+	if ev.Kind != "complete" || ev.Printer != "X1C" || !ev.OccurredAt.Equal(finishedAt) {
+		t.Errorf("event = %+v", ev)
+	}
+	if len(ev.Deductions) != 1 || ev.Deductions[0] != (plan.SpoolDeduction{SpoolID: 1, Amount: 25}) {
+		t.Errorf("deductions = %+v", ev.Deductions)
+	}
+	if len(ev.Unmatched) != 1 || ev.Unmatched[0].FilamentID != 300 {
+		t.Errorf("unmatched = %+v", ev.Unmatched)
+	}
+	if sm.useCalls[1] != 25 || sm.useCalls[3] != 0 {
+		t.Errorf("spoolman use calls = %v", sm.useCalls)
+	}
+
+	data, _ := os.ReadFile(filepath.Join(dir, "widget.yaml"))
+	var pf models.PlanFile
+	if err := yaml.Unmarshal(data, &pf); err != nil {
+		t.Fatal(err)
+	}
+	if got := pf.Projects[0].Plates[0].Status; got != "completed" {
+		t.Errorf("plate status = %q, want completed", got)
+	}
+
+	stored, _ := s.Pending.List()
+	if len(stored) != 1 || stored[0].ID != ev.ID {
+		t.Errorf("pending store = %+v", stored)
+	}
+}
+
+func TestAutoCompleteNothingInProgress(t *testing.T) {
+	dir := t.TempDir()
+	writeAutoCompletePlan(t, dir)
+	fake := &fakePlanOps{}
+	s := &PlanServer{PlansDir: dir, PlanOps: fake, Pending: NewPendingStore(dir)}
+
+	events, err := s.AutoComplete(context.Background(), "MK4")
+	if err != nil || len(events) != 0 {
+		t.Errorf("got %v, %v; want no events", events, err)
+	}
+	if fake.completeCalled {
+		t.Error("Complete should not be called for an idle printer")
+	}
+}
+
+// When Complete fails before saving (the plate is still in-progress on
+// disk), there's nothing for the user to confirm — no pending record.
+func TestAutoCompleteSkipsPendingWhenCompleteFails(t *testing.T) {
+	dir := t.TempDir()
+	writeAutoCompletePlan(t, dir)
+	fake := &fakePlanOps{completeErr: errors.New("save plan: disk full")}
+	s := &PlanServer{PlansDir: dir, PlanOps: fake, Pending: NewPendingStore(dir)}
+
+	events, err := s.AutoComplete(context.Background(), "X1C")
+	if err != nil {
+		t.Fatalf("AutoComplete: %v", err)
+	}
+	if !fake.completeCalled {
+		t.Fatal("Complete was not called")
+	}
+	if len(events) != 0 {
+		t.Errorf("got %d events, want 0", len(events))
+	}
+	if fake.completeGot.FinishedAt.IsZero() {
+		t.Error("FinishedAt should fall back to now when the printer has no finish time")
+	}
+}
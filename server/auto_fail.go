@@ -0,0 +1,133 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/dstockto/fil/plan"
+)
+
+// IsAbortedPrint reports whether a state transition means a print ended
+// without finishing: the printer reported "failed", or a running/paused job
+// dropped back to idle before reaching 100% (a cancel from the screen or
+// the slicer).
+func IsAbortedPrint(event StateChangeEvent) bool {
+	if event.NewState == "failed" {
+		return true
+	}
+	if event.NewState != "idle" {
+		return false
+	}
+	return (event.OldState == "printing" || event.OldState == "paused") && event.Progress < 100
+}
+
+// estimateFailedFraction returns how far through the job the printer got, as
+// a 0..1 fraction of the planned filament. Progress is preferred — Bambu and
+// PrusaLink both derive it from the sliced job, which tracks extrusion more
+// closely than layer count on parts that taper. Layers are the fallback for
+// reports that carry no percentage.
+func estimateFailedFraction(event StateChangeEvent) float64 {
+	if event.Progress > 0 {
+		return min(float64(event.Progress)/100, 1)
+	}
+	if event.TotalLayers > 0 && event.Layer > 0 {
+		return min(float64(event.Layer)/float64(event.TotalLayers), 1)
+	}
+	return 0
+}
+
+// AutoFail records a pending Fail for every in-progress plate on printerName
+// after the printer aborts a job. Unlike auto-complete nothing is applied
+// yet: a cancel looks the same as a failure from the printer's side, so the
+// FailRequest (with UsedGrams estimated from progress × planned Needs) waits
+// for the user to accept, adjust, or dismiss it. The user is pinged through
+// the configured notifier so the estimate doesn't sit unnoticed.
+//
+// Returns nil without error when nothing was printing on the printer.
+func (s *PlanServer) AutoFail(ctx context.Context, printerName string, event StateChangeEvent) (*PendingEvent, error) {
+	if s.Pending == nil {
+		return nil, fmt.Errorf("pending store not configured")
+	}
+	plates := FindInProgressPlates(s.PlansDir, printerName)
+	if len(plates) == 0 {
+		return nil, nil
+	}
+
+	fraction := estimateFailedFraction(event)
+	failedAt := time.Now().UTC()
+	req := plan.FailRequest{
+		Printer:  printerName,
+		Cause:    "other",
+		Reason:   autoFailReason(event),
+		FailedAt: failedAt,
+	}
+	planned := 0.0
+	var refs []PendingPlate
+	for _, p := range plates {
+		req.Plates = append(req.Plates, plan.FailPlate{
+			Plan:              p.Plan,
+			Project:           p.Project,
+			Plate:             p.Plate.Name,
+			StartedAt:         p.Plate.StartedAt,
+			EstimatedDuration: p.Plate.EstimatedDuration,
+			Needs:             p.Plate.Needs,
+		})
+		refs = append(refs, PendingPlate{Plan: p.Plan, Project: p.Project, Plate: p.Plate.Name})
+		for _, n := range p.Plate.Needs {
+			planned += n.Amount
+		}
+	}
+	req.UsedGrams = planned * fraction
+
+	ev, err := s.Pending.Add(PendingEvent{
+		Kind:       "fail",
+		Printer:    printerName,
+		Plates:     refs,
+		OccurredAt: failedAt,
+		Fail:       &req,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("record pending event: %w", err)
+	}
+	fmt.Printf("[auto-fail] %s: recorded pending fail %s (~%.1fg)\n", printerName, ev.ID, req.UsedGrams)
+
+	NewNotifierAdapter(s.Notifier).Notify(ctx, "Failure needs confirmation",
+		fmt.Sprintf("%s: %s — about %.0fg wasted at %.0f%%. Confirm with: fil plan pending",
+			printerName, pendingPlateList(refs), req.UsedGrams, fraction*100))
+	return &ev, nil
+}
+
+// autoFailReason describes what the printer reported, so the history entry
+// explains itself even when the user accepts without editing.
+func autoFailReason(event StateChangeEvent) string {
+	var parts []string
+	if event.NewState == "failed" {
+		parts = append(parts, "printer reported failure")
+	} else {
+		parts = append(parts, fmt.Sprintf("print stopped from %s", event.OldState))
+	}
+	if event.Progress > 0 {
+		parts = append(parts, fmt.Sprintf("at %d%%", event.Progress))
+	} else if event.TotalLayers > 0 {
+		parts = append(parts, fmt.Sprintf("at layer %d/%d", event.Layer, event.TotalLayers))
+	}
+	var hms []string
+	for _, h := range event.HMSCodes {
+		hms = append(hms, h.Info().Label())
+	}
+	reason := "auto-detected: " + strings.Join(parts, " ")
+	if len(hms) > 0 {
+		reason += " (" + strings.Join(hms, ", ") + ")"
+	}
+	return reason
+}
+
+func pendingPlateList(plates []PendingPlate) string {
+	names := make([]string, 0, len(plates))
+	for _, p := range plates {
+		names = append(names, p.Project+" / "+p.Plate)
+	}
+	return strings.Join(names, ", ")
+}
@@ -0,0 +1,281 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"github.com/dstockto/fil/plan"
+)
+
+func TestIsAbortedPrint(t *testing.T) {
+	tests := []struct {
+		name  string
+		event StateChangeEvent
+		want  bool
+	}{
+		{"printer failed", StateChangeEvent{OldState: "printing", NewState: "failed", Progress: 40}, true},
+		{"cancelled mid-print", StateChangeEvent{OldState: "printing", NewState: "idle", Progress: 40}, true},
+		{"cancelled while paused", StateChangeEvent{OldState: "paused", NewState: "idle", Progress: 10}, true},
+		{"idle after full progress", StateChangeEvent{OldState: "printing", NewState: "idle", Progress: 100}, false},
+		{"finished", StateChangeEvent{OldState: "printing", NewState: "finished", Progress: 100}, false},
+		{"bed cleared", StateChangeEvent{OldState: "finished", NewState: "idle"}, false},
+		{"paused", StateChangeEvent{OldState: "printing", NewState: "paused", Progress: 50}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := IsAbortedPrint(tt.event); got != tt.want {
+				t.Errorf("IsAbortedPrint(%+v) = %v, want %v", tt.event, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestEstimateFailedFraction(t *testing.T) {
+	tests := []struct {
+		name  string
+		event StateChangeEvent
+		want  float64
+	}{
+		{"progress", StateChangeEvent{Progress: 40, Layer: 10, TotalLayers: 100}, 0.4},
+		{"layers when no progress", StateChangeEvent{Layer: 30, TotalLayers: 120}, 0.25},
+		{"nothing reported", StateChangeEvent{}, 0},
+		{"clamped", StateChangeEvent{Progress: 150}, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := estimateFailedFraction(tt.event); !floatClose(got, tt.want) {
+				t.Errorf("estimateFailedFraction = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+// The failure is only proposed: the pending event carries the FailRequest
+// with waste scaled from progress, and PlanOps.Fail is not called yet.
+func TestAutoFailRecordsPendingWithoutFailing(t *testing.T) {
+	dir := t.TempDir()
+	writeAutoCompletePlan(t, dir)
+	ops := &fakePlanOps{}
+	s := &PlanServer{PlansDir: dir, PlanOps: ops, Pending: NewPendingStore(dir)}
+
+	ev, err := s.AutoFail(context.Background(), "X1C", StateChangeEvent{
+		OldState: "printing",
+		NewState: "failed",
+		Progress: 40,
+		HMSCodes: []HMSCode{{Attr: 0x0C000300, Code: 0x00030008}},
+	})
+	if err != nil {
+		t.Fatalf("AutoFail: %v", err)
+	}
+	if ev == nil || ev.Fail == nil {
+		t.Fatalf("expected a pending fail event, got %+v", ev)
+	}
+	if ops.failCalled {
+		t.Error("Fail was applied before the user confirmed it")
+	}
+	if ev.Kind != "fail" || len(ev.Plates) != 1 || ev.Plates[0].Plate != "Plate 1" {
+		t.Errorf("event = %+v", ev)
+	}
+	// Needs total 30g; 40% of the way through.
+	if !floatClose(ev.Fail.UsedGrams, 12) {
+		t.Errorf("UsedGrams = %v, want 12", ev.Fail.UsedGrams)
+	}
+	if ev.Fail.Cause != "other" || ev.Fail.Printer != "X1C" {
+		t.Errorf("fail request = %+v", ev.Fail)
+	}
+	if want := "auto-detected: printer reported failure at 40% (possible spaghetti defects)"; ev.Fail.Reason != want {
+		t.Errorf("Reason = %q, want %q", ev.Fail.Reason, want)
+	}
+}
+
+func TestAutoFailNothingInProgress(t *testing.T) {
+	dir := t.TempDir()
+	s := &PlanServer{PlansDir: dir, PlanOps: &fakePlanOps{}, Pending: NewPendingStore(dir)}
+	ev, err := s.AutoFail(context.Background(), "X1C", StateChangeEvent{OldState: "printing", NewState: "failed"})
+	if err != nil || ev != nil {
+		t.Errorf("AutoFail = %+v, %v; want nil, nil", ev, err)
+	}
+}
+
+func TestAcceptPendingFailAppliesFail(t *testing.T) {
+	dir := t.TempDir()
+	writeAutoCompletePlan(t, dir)
+	ops := &fakePlanOps{}
+	s := &PlanServer{PlansDir: dir, PlanOps: ops, Pending: NewPendingStore(dir)}
+	ev, err := s.AutoFail(context.Background(), "X1C", StateChangeEvent{OldState: "printing", NewState: "idle", Progress: 50})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	w := httptest.NewRecorder()
+	s.Routes().ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/api/fil/pending/"+ev.ID+"/accept", nil))
+	if w.Code != http.StatusNoContent {
+		t.Fatalf("status = %d, body = %q", w.Code, w.Body.String())
+	}
+	if !ops.failCalled {
+		t.Fatal("accept did not apply the fail")
+	}
+	if !floatClose(ops.failGot.UsedGrams, 15) || len(ops.failGot.Plates) != 1 {
+		t.Errorf("fail request = %+v", ops.failGot)
+	}
+	if events, _ := s.Pending.List(); len(events) != 0 {
+		t.Errorf("event still pending: %+v", events)
+	}
+}
+
+// A fail that errors before its history is written stays queued for a
+// retry, remembering what each spool was already charged so the retry only
+// writes the difference.
+func TestAcceptPendingFailKeepsEventUntilRecorded(t *testing.T) {
+	dir := t.TempDir()
+	writeAutoCompletePlan(t, dir)
+	ops := &fakePlanOps{failErr: errors.New("list spools: connection refused")}
+	s := &PlanServer{PlansDir: dir, PlanOps: ops, Pending: NewPendingStore(dir)}
+	ev, err := s.AutoFail(context.Background(), "X1C", StateChangeEvent{OldState: "printing", NewState: "idle", Progress: 50})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	accept := func() int {
+		w := httptest.NewRecorder()
+		s.Routes().ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/api/fil/pending/"+ev.ID+"/accept", nil))
+		return w.Code
+	}
+	if code := accept(); code != http.StatusBadGateway {
+		t.Fatalf("status = %d, want 502", code)
+	}
+	kept, err := s.Pending.Get(ev.ID)
+	if err != nil {
+		t.Fatalf("event dropped: %v", err)
+	}
+	if kept.Error == "" || len(kept.Fail.Deducted) != 0 {
+		t.Errorf("kept event = %+v, want error and nothing deducted", kept)
+	}
+
+	// Deducted, but the history write failed.
+	deducted := []plan.SpoolDeduction{{SpoolID: 1, Amount: 15}}
+	ops.failRet = plan.FailResult{Deducted: deducted}
+	ops.failErr = errors.New("write history: disk full")
+	if code := accept(); code != http.StatusBadGateway {
+		t.Fatalf("status = %d, want 502", code)
+	}
+	if kept, _ = s.Pending.Get(ev.ID); !reflect.DeepEqual(kept.Fail.Deducted, deducted) {
+		t.Errorf("kept deductions = %+v, want %+v", kept.Fail.Deducted, deducted)
+	}
+
+	ops.failRet = plan.FailResult{Recorded: true}
+	ops.failErr = nil
+	if code := accept(); code != http.StatusNoContent {
+		t.Fatalf("status = %d, want 204", code)
+	}
+	if !reflect.DeepEqual(ops.failGot.Deducted, deducted) {
+		t.Errorf("retry deducted = %+v, want %+v", ops.failGot.Deducted, deducted)
+	}
+	if events, _ := s.Pending.List(); len(events) != 0 {
+		t.Errorf("event still pending: %+v", events)
+	}
+}
+
+// While one request is applying an event, another accept or amend must not
+// run it a second time.
+func TestAcceptPendingFailRefusesClaimedEvent(t *testing.T) {
+	dir := t.TempDir()
+	writeAutoCompletePlan(t, dir)
+	ops := &fakePlanOps{}
+	s := &PlanServer{PlansDir: dir, PlanOps: ops, Pending: NewPendingStore(dir)}
+	ev, err := s.AutoFail(context.Background(), "X1C", StateChangeEvent{OldState: "printing", NewState: "idle", Progress: 50})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.Pending.Claim(ev.ID); err != nil {
+		t.Fatal(err)
+	}
+
+	w := httptest.NewRecorder()
+	s.Routes().ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/api/fil/pending/"+ev.ID+"/accept", nil))
+	if w.Code != http.StatusConflict {
+		t.Fatalf("status = %d, want 409", w.Code)
+	}
+	if ops.failCalled {
+		t.Error("claimed event was applied")
+	}
+
+	s.Pending.Release(ev.ID)
+	w = httptest.NewRecorder()
+	s.Routes().ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/api/fil/pending/"+ev.ID+"/accept", nil))
+	if w.Code != http.StatusNoContent {
+		t.Fatalf("status after release = %d, body = %q", w.Code, w.Body.String())
+	}
+}
+
+func TestAmendPendingFailOverridesEstimate(t *testing.T) {
+	dir := t.TempDir()
+	writeAutoCompletePlan(t, dir)
+	ops := &fakePlanOps{}
+	s := &PlanServer{PlansDir: dir, PlanOps: ops, Pending: NewPendingStore(dir)}
+	ev, err := s.AutoFail(context.Background(), "X1C", StateChangeEvent{OldState: "printing", NewState: "failed", Progress: 50})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	grams := 3.5
+	body, _ := json.Marshal(PendingAmendRequest{UsedGrams: &grams, Cause: "bed_adhesion", Reason: "came loose"})
+	w := httptest.NewRecorder()
+	s.Routes().ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/api/fil/pending/"+ev.ID+"/amend", bytes.NewReader(body)))
+	if w.Code != http.StatusNoContent {
+		t.Fatalf("status = %d, body = %q", w.Code, w.Body.String())
+	}
+	if !ops.failCalled {
+		t.Fatal("amend did not apply the fail")
+	}
+	got := ops.failGot
+	if !floatClose(got.UsedGrams, 3.5) || got.Cause != "bed_adhesion" || got.Reason != "came loose" {
+		t.Errorf("fail request = %+v", got)
+	}
+}
+
+func TestAmendPendingFailRejectsUnknownCause(t *testing.T) {
+	dir := t.TempDir()
+	writeAutoCompletePlan(t, dir)
+	ops := &fakePlanOps{}
+	s := &PlanServer{PlansDir: dir, PlanOps: ops, Pending: NewPendingStore(dir)}
+	ev, _ := s.AutoFail(context.Background(), "X1C", StateChangeEvent{OldState: "printing", NewState: "failed", Progress: 50})
+
+	body, _ := json.Marshal(PendingAmendRequest{Cause: "gremlins"})
+	w := httptest.NewRecorder()
+	s.Routes().ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/api/fil/pending/"+ev.ID+"/amend", bytes.NewReader(body)))
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("status = %d, want 400", w.Code)
+	}
+	if ops.failCalled {
+		t.Error("Fail applied despite invalid cause")
+	}
+}
+
+// Dismissing is how a deliberate cancel gets dropped: the event goes away
+// and nothing is logged against the plate.
+func TestDismissPendingFailSkipsFail(t *testing.T) {
+	dir := t.TempDir()
+	writeAutoCompletePlan(t, dir)
+	ops := &fakePlanOps{}
+	s := &PlanServer{PlansDir: dir, PlanOps: ops, Pending: NewPendingStore(dir)}
+	ev, _ := s.AutoFail(context.Background(), "X1C", StateChangeEvent{OldState: "printing", NewState: "idle", Progress: 5})
+
+	w := httptest.NewRecorder()
+	s.Routes().ServeHTTP(w, httptest.NewRequest(http.MethodDelete, "/api/fil/pending/"+ev.ID, nil))
+	if w.Code != http.StatusNoContent {
+		t.Fatalf("status = %d, body = %q", w.Code, w.Body.String())
+	}
+	if ops.failCalled {
+		t.Error("dismiss applied the fail")
+	}
+	if events, _ := s.Pending.List(); len(events) != 0 {
+		t.Errorf("event still pending: %+v", events)
+	}
+}
@@ -0,0 +1,68 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/dstockto/fil/plan"
+)
+
+// IsNewPrint reports whether a state transition is a job starting, as
+// opposed to a paused job resuming.
+func IsNewPrint(event StateChangeEvent) bool {
+	return event.NewState == "printing" && event.OldState != "paused"
+}
+
+// AutoStart moves the plate matching job to in-progress on printerName when
+// the printer begins printing, so StartedAt is the real start rather than the
+// moment someone ran `fil plan next`. The first todo plate whose File matches
+// wins — repeated plates of the same sliced file are started in plan order.
+//
+// A job no plate claims is recorded as a pending "job" event so it can be
+// linked to a plate afterwards. Nothing happens when the printer already has
+// an in-progress plate (started by hand before the job came up) or reports
+// no job name.
+func (s *PlanServer) AutoStart(ctx context.Context, printerName, job string) (*PendingEvent, error) {
+	if s.PlanOps == nil {
+		return nil, fmt.Errorf("plan ops not configured")
+	}
+	if s.Pending == nil {
+		return nil, fmt.Errorf("pending store not configured")
+	}
+	if job == "" || len(FindInProgressPlates(s.PlansDir, printerName)) > 0 {
+		return nil, nil
+	}
+	if _, sending := s.sending.Load(printerName); sending {
+		return nil, nil
+	}
+
+	startedAt := time.Now().UTC()
+	if matches := FindTodoPlatesForJob(s.PlansDir, job); len(matches) > 0 {
+		p := matches[0]
+		_, err := s.PlanOps.Next(ctx, plan.NextRequest{
+			Plan:      p.Plan,
+			Project:   p.Project,
+			Plate:     p.Plate.Name,
+			Printer:   printerName,
+			StartedAt: startedAt,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("start %s / %s: %w", p.Project, p.Plate.Name, err)
+		}
+		fmt.Printf("[auto-start] %s: started %s / %s for job %q\n", printerName, p.Project, p.Plate.Name, job)
+		return nil, nil
+	}
+
+	ev, err := s.Pending.Add(PendingEvent{
+		Kind:       "job",
+		Printer:    printerName,
+		Job:        job,
+		OccurredAt: startedAt,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("record pending event: %w", err)
+	}
+	fmt.Printf("[auto-start] %s: no plate matches job %q; recorded pending %s\n", printerName, job, ev.ID)
+	return &ev, nil
+}
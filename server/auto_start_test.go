@@ -0,0 +1,177 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+const autoStartPlanYAML = `projects:
+- name: Brackets
+  status: todo
+  plates:
+  - name: Left
+    status: completed
+    file: bracket
+  - name: Right
+    status: todo
+    file: bracket
+  - name: Mirror
+    status: todo
+    file: bracket
+`
+
+func writeAutoStartPlan(t *testing.T, dir string) {
+	t.Helper()
+	if err := os.WriteFile(filepath.Join(dir, "brackets.yaml"), []byte(autoStartPlanYAML), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestIsNewPrint(t *testing.T) {
+	if !IsNewPrint(StateChangeEvent{OldState: "idle", NewState: "printing"}) {
+		t.Error("idle -> printing should be a new print")
+	}
+	if !IsNewPrint(StateChangeEvent{OldState: "finished", NewState: "printing"}) {
+		t.Error("finished -> printing should be a new print")
+	}
+	if IsNewPrint(StateChangeEvent{OldState: "paused", NewState: "printing"}) {
+		t.Error("resume should not be a new print")
+	}
+}
+
+// The first todo plate whose file matches is started on the printer; the
+// completed plate with the same file is passed over.
+func TestAutoStartStartsFirstMatchingPlate(t *testing.T) {
+	dir := t.TempDir()
+	writeAutoStartPlan(t, dir)
+	ops := &fakePlanOps{}
+	s := &PlanServer{PlansDir: dir, PlanOps: ops, Pending: NewPendingStore(dir)}
+
+	before := time.Now().UTC()
+	ev, err := s.AutoStart(context.Background(), "X1C", "Bracket.gcode.3mf")
+	if err != nil || ev != nil {
+		t.Fatalf("AutoStart = %+v, %v; want nil, nil", ev, err)
+	}
+	if !ops.nextCalled {
+		t.Fatal("Next was not called")
+	}
+	got := ops.nextGot
+	if got.Plan != "brackets.yaml" || got.Project != "Brackets" || got.Plate != "Right" || got.Printer != "X1C" {
+		t.Errorf("next request = %+v", got)
+	}
+	if got.StartedAt.Before(before) {
+		t.Errorf("StartedAt = %v, want the job start", got.StartedAt)
+	}
+}
+
+// A plate whose `after:` prerequisite is still printing elsewhere isn't the
+// one this job is for.
+func TestAutoStartSkipsBlockedPlate(t *testing.T) {
+	dir := t.TempDir()
+	yml := `projects:
+- name: Brackets
+  status: in-progress
+  plates:
+  - name: Clip
+    status: in-progress
+    printer: MK4
+  - name: Right
+    status: todo
+    file: bracket
+    after: [Clip]
+  - name: Mirror
+    status: todo
+    file: bracket
+`
+	if err := os.WriteFile(filepath.Join(dir, "brackets.yaml"), []byte(yml), 0644); err != nil {
+		t.Fatal(err)
+	}
+	ops := &fakePlanOps{}
+	s := &PlanServer{PlansDir: dir, PlanOps: ops, Pending: NewPendingStore(dir)}
+
+	if _, err := s.AutoStart(context.Background(), "X1C", "bracket"); err != nil {
+		t.Fatal(err)
+	}
+	if ops.nextGot.Plate != "Mirror" {
+		t.Errorf("started %q, want Mirror", ops.nextGot.Plate)
+	}
+}
+
+func TestAutoStartRecordsUnmatchedJob(t *testing.T) {
+	dir := t.TempDir()
+	writeAutoStartPlan(t, dir)
+	ops := &fakePlanOps{}
+	s := &PlanServer{PlansDir: dir, PlanOps: ops, Pending: NewPendingStore(dir)}
+
+	ev, err := s.AutoStart(context.Background(), "X1C", "benchy.gcode.3mf")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ops.nextCalled {
+		t.Error("Next called for a job no plate claims")
+	}
+	if ev == nil || ev.Kind != "job" || ev.Job != "benchy.gcode.3mf" || ev.Printer != "X1C" {
+		t.Errorf("pending event = %+v", ev)
+	}
+}
+
+// A plate started by hand before the job came up wins; auto-start stays out
+// of the way.
+func TestAutoStartSkipsWhenPrinterBusy(t *testing.T) {
+	dir := t.TempDir()
+	writeAutoCompletePlan(t, dir) // Widget / Plate 1 in progress on X1C
+	writeAutoStartPlan(t, dir)
+	ops := &fakePlanOps{}
+	s := &PlanServer{PlansDir: dir, PlanOps: ops, Pending: NewPendingStore(dir)}
+
+	ev, err := s.AutoStart(context.Background(), "X1C", "bracket")
+	if err != nil || ev != nil || ops.nextCalled {
+		t.Errorf("AutoStart = %+v, %v, next=%v; want no-op", ev, err, ops.nextCalled)
+	}
+	if events, _ := s.Pending.List(); len(events) != 0 {
+		t.Errorf("unexpected pending events: %+v", events)
+	}
+}
+
+func TestLinkPendingStartsPlateAtJobStart(t *testing.T) {
+	dir := t.TempDir()
+	ops := &fakePlanOps{}
+	s := &PlanServer{PlansDir: dir, PlanOps: ops, Pending: NewPendingStore(dir)}
+	startedAt := time.Date(2026, 5, 1, 8, 0, 0, 0, time.UTC)
+	ev, _ := s.Pending.Add(PendingEvent{Kind: "job", Printer: "X1C", Job: "benchy", OccurredAt: startedAt})
+
+	body, _ := json.Marshal(PendingLinkRequest{Plan: "brackets.yaml", Project: "Brackets", Plate: "Right"})
+	w := httptest.NewRecorder()
+	s.Routes().ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/api/fil/pending/"+ev.ID+"/link", bytes.NewReader(body)))
+	if w.Code != http.StatusNoContent {
+		t.Fatalf("status = %d, body = %q", w.Code, w.Body.String())
+	}
+	got := ops.nextGot
+	if got.Plate != "Right" || got.Printer != "X1C" || !got.StartedAt.Equal(startedAt) {
+		t.Errorf("next request = %+v", got)
+	}
+	if events, _ := s.Pending.List(); len(events) != 0 {
+		t.Errorf("linked event still pending: %+v", events)
+	}
+}
+
+func TestLinkPendingRejectsOtherKinds(t *testing.T) {
+	dir := t.TempDir()
+	ops := &fakePlanOps{}
+	s := &PlanServer{PlansDir: dir, PlanOps: ops, Pending: NewPendingStore(dir)}
+	ev, _ := s.Pending.Add(PendingEvent{Kind: "complete", Printer: "X1C"})
+
+	body, _ := json.Marshal(PendingLinkRequest{Plan: "brackets.yaml", Project: "Brackets", Plate: "Right"})
+	w := httptest.NewRecorder()
+	s.Routes().ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/api/fil/pending/"+ev.ID+"/link", bytes.NewReader(body)))
+	if w.Code != http.StatusBadRequest || ops.nextCalled {
+		t.Errorf("status = %d, next=%v; want 400 and no Next", w.Code, ops.nextCalled)
+	}
+}
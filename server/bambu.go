@@ -4,10 +4,13 @@ import (
 	"crypto/tls"
 	"encoding/json"
 	"fmt"
+	"os"
+	"slices"
 	"strings"
 	"sync"
 	"time"
 
+	"github.com/dstockto/fil/models"
 	mqtt "github.com/eclipse/paho.mqtt.golang"
 )
 
@@ -18,21 +21,36 @@ import (
 // printer keeps up at ~2/sec — 500ms gives a safety margin.
 const bambuPushTrayMinGap = 500 * time.Millisecond
 
+// bambuStartTimeout is how long StartPrint waits for the printer to echo
+// its project_file command. A var so tests can shorten it.
+var bambuStartTimeout = 30 * time.Second
+
 // BambuAdapter communicates with a Bambu Lab printer via MQTT.
 type BambuAdapter struct {
 	name       string
 	ip         string
 	serial     string
 	accessCode string
+	ftpAddr    string
 
 	mu             sync.RWMutex
 	client         mqtt.Client
 	state          PrinterState
 	hmsCodes       []HMSCode
 	stateCallbacks []func(StateChangeEvent)
+	telemetry      TelemetryRing
 
 	// pushThrottle paces ams_filament_setting publishes; see bambuPushTrayMinGap.
 	pushThrottle *paceThrottler
+
+	// capture, when set, records every MQTT message and connection change
+	// for later replay; see MQTTCapture.
+	capture *MQTTCapture
+	// now overrides time.Now during replay.
+	now func() time.Time
+
+	// startAck carries the printer's answer to the last project_file.
+	startAck chan error
 }
 
 // NewBambuAdapter creates a new Bambu printer adapter.
@@ -42,6 +60,7 @@ func NewBambuAdapter(name, ip, serial, accessCode string) *BambuAdapter {
 		ip:         ip,
 		serial:     serial,
 		accessCode: accessCode,
+		ftpAddr:    ip + ":990",
 		state: PrinterState{
 			Name:       name,
 			Type:       "bambu",
@@ -49,6 +68,7 @@ func NewBambuAdapter(name, ip, serial, accessCode string) *BambuAdapter {
 			ActiveTray: -1,
 		},
 		pushThrottle: newPaceThrottler(bambuPushTrayMinGap),
+		startAck:     make(chan error, 1),
 	}
 }
 
@@ -65,11 +85,11 @@ func (b *BambuAdapter) Connect() error {
 		SetTLSConfig(&tls.Config{InsecureSkipVerify: true}).
 		SetAutoReconnect(true).
 		SetConnectionLostHandler(func(client mqtt.Client, err error) {
-			b.mu.Lock()
-			b.state.State = "offline"
-			b.mu.Unlock()
+			b.capture.RecordEvent(captureConnectionLost)
+			b.connectionLost()
 		}).
 		SetOnConnectHandler(func(client mqtt.Client) {
+			b.capture.RecordEvent(captureConnected)
 			// Re-subscribe on reconnect
 			client.Subscribe(topic, 0, nil)
 			// Request full status
@@ -78,6 +98,7 @@ func (b *BambuAdapter) Connect() error {
 		})
 
 	opts.SetDefaultPublishHandler(func(client mqtt.Client, msg mqtt.Message) {
+		b.capture.Record(msg.Topic(), msg.Payload())
 		b.handleReport(msg.Payload())
 	})
 
@@ -94,7 +115,18 @@ func (b *BambuAdapter) Close() error {
 	if b.client != nil && b.client.IsConnected() {
 		b.client.Disconnect(250)
 	}
-	return nil
+	return b.capture.Close()
+}
+
+// SetCapture records this printer's MQTT traffic to c. Call before Connect.
+func (b *BambuAdapter) SetCapture(c *MQTTCapture) {
+	b.capture = c
+}
+
+func (b *BambuAdapter) connectionLost() {
+	b.mu.Lock()
+	b.state.State = "offline"
+	b.mu.Unlock()
 }
 
 // Status returns the current printer state.
@@ -119,12 +151,18 @@ func (b *BambuAdapter) PushTray(update TrayUpdate) error {
 		infoIdx = "GFL99" // fallback to generic PLA
 	}
 
+	// The external spool is addressed as ams 255, tray 254.
+	trayID := update.TrayID
+	if update.AmsID == ExternalAmsID {
+		trayID = bambuExternalTrayID
+	}
+
 	cmd := map[string]interface{}{
 		"print": map[string]interface{}{
 			"command":         "ams_filament_setting",
 			"sequence_id":     "0",
 			"ams_id":          update.AmsID,
-			"tray_id":         update.TrayID,
+			"tray_id":         trayID,
 			"tray_color":      update.Color,
 			"tray_type":       update.Type,
 			"nozzle_temp_min": update.TempMin,
@@ -146,6 +184,143 @@ func (b *BambuAdapter) PushTray(update TrayUpdate) error {
 	return token.Error()
 }
 
+// Pause pauses the current print.
+func (b *BambuAdapter) Pause() error {
+	return b.publish(map[string]interface{}{"print": map[string]interface{}{"command": "pause", "sequence_id": "0"}})
+}
+
+// Resume resumes a paused print.
+func (b *BambuAdapter) Resume() error {
+	return b.publish(map[string]interface{}{"print": map[string]interface{}{"command": "resume", "sequence_id": "0"}})
+}
+
+// Stop cancels the current print.
+func (b *BambuAdapter) Stop() error {
+	return b.publish(map[string]interface{}{"print": map[string]interface{}{"command": "stop", "sequence_id": "0"}})
+}
+
+// SetLight switches the chamber light.
+func (b *BambuAdapter) SetLight(on bool) error {
+	mode := "off"
+	if on {
+		mode = "on"
+	}
+	return b.publish(map[string]interface{}{
+		"system": map[string]interface{}{
+			"command":       "ledctrl",
+			"sequence_id":   "0",
+			"led_node":      "chamber_light",
+			"led_mode":      mode,
+			"led_on_time":   500,
+			"led_off_time":  500,
+			"loop_times":    0,
+			"interval_time": 0,
+		},
+	})
+}
+
+// SetSpeed switches the print speed level.
+func (b *BambuAdapter) SetSpeed(profile SpeedProfile) error {
+	return b.publish(map[string]interface{}{
+		"print": map[string]interface{}{
+			"command":     "print_speed",
+			"sequence_id": "0",
+			"param":       fmt.Sprintf("%d", profile.Level),
+		},
+	})
+}
+
+// StartPrint uploads the job to the SD card over FTPS and starts it with a
+// project_file command, then waits for the printer to echo the command back
+// with its result.
+func (b *BambuAdapter) StartPrint(job PrintJob) error {
+	if ext := models.JobExt(job.Name); ext != ".3mf" && ext != ".gcode.3mf" {
+		return fmt.Errorf("bambu %s: %s is not a sliced 3MF", b.name, job.Name)
+	}
+	f, err := os.Open(job.Path)
+	if err != nil {
+		return fmt.Errorf("bambu %s: %w", b.name, err)
+	}
+	defer f.Close()
+
+	if err := ftpsUpload(b.ftpAddr, "bblp", b.accessCode, &tls.Config{InsecureSkipVerify: true}, job.Name, f); err != nil {
+		return fmt.Errorf("bambu %s: %w", b.name, err)
+	}
+
+	// Drop an answer left over from an earlier job.
+	select {
+	case <-b.startAck:
+	default:
+	}
+	if err := b.publish(projectFileCommand(job)); err != nil {
+		return fmt.Errorf("bambu %s: %w", b.name, err)
+	}
+	return b.awaitStart()
+}
+
+// projectFileCommand builds the MQTT command that prints a plate of a 3MF
+// already on the SD card. use_ams with no ams_mapping keeps the filament
+// mapping the file was sliced with.
+func projectFileCommand(job PrintJob) map[string]interface{} {
+	plate := max(job.Plate, 1)
+	return map[string]interface{}{
+		"print": map[string]interface{}{
+			"command":        "project_file",
+			"sequence_id":    "0",
+			"param":          fmt.Sprintf("Metadata/plate_%d.gcode", plate),
+			"url":            "file:///sdcard/" + job.Name,
+			"subtask_name":   job.Name[:len(job.Name)-len(models.JobExt(job.Name))],
+			"md5":            "",
+			"profile_id":     "0",
+			"project_id":     "0",
+			"subtask_id":     "0",
+			"task_id":        "0",
+			"bed_type":       "auto",
+			"timelapse":      false,
+			"bed_leveling":   true,
+			"flow_cali":      false,
+			"vibration_cali": true,
+			"layer_inspect":  false,
+			"use_ams":        true,
+		},
+	}
+}
+
+// awaitStart waits for applyReport to see the project_file echo.
+func (b *BambuAdapter) awaitStart() error {
+	select {
+	case err := <-b.startAck:
+		if err != nil {
+			return fmt.Errorf("bambu %s: %w", b.name, err)
+		}
+		return nil
+	case <-time.After(bambuStartTimeout):
+		return fmt.Errorf("bambu %s: no answer to project_file after %s", b.name, bambuStartTimeout)
+	}
+}
+
+// publish sends a command on the printer's request topic.
+func (b *BambuAdapter) publish(cmd map[string]interface{}) error {
+	if b.client == nil || !b.client.IsConnected() {
+		return fmt.Errorf("not connected to %s", b.name)
+	}
+	payload, err := json.Marshal(cmd)
+	if err != nil {
+		return err
+	}
+	reqTopic := fmt.Sprintf("device/%s/request", b.serial)
+	token := b.client.Publish(reqTopic, 0, false, payload)
+	token.Wait()
+	return token.Error()
+}
+
+// Restore seeds the state from a snapshot saved before a restart.
+func (b *BambuAdapter) Restore(snap PrinterSnapshot) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.state.restore(snap)
+}
+
 // OnStateChange registers a callback for printer state transitions.
 func (b *BambuAdapter) OnStateChange(cb func(event StateChangeEvent)) {
 	b.mu.Lock()
@@ -153,23 +328,41 @@ func (b *BambuAdapter) OnStateChange(cb func(event StateChangeEvent)) {
 	b.stateCallbacks = append(b.stateCallbacks, cb)
 }
 
-// handleReport parses an MQTT status report and updates internal state.
+// handleReport parses an MQTT status report, updates internal state, and
+// fires the state-change callbacks if the report is a transition.
 func (b *BambuAdapter) handleReport(payload []byte) {
+	event, fire := b.applyReport(payload)
+	if !fire {
+		return
+	}
+	b.mu.RLock()
+	callbacks := b.stateCallbacks
+	b.mu.RUnlock()
+	for _, cb := range callbacks {
+		go cb(event)
+	}
+}
+
+// applyReport does handleReport's parsing and state update and returns the
+// event callbacks should see, if any. Split out so ReplayCapture can collect
+// events in order instead of racing the callback goroutines.
+func (b *BambuAdapter) applyReport(payload []byte) (StateChangeEvent, bool) {
 	var data map[string]interface{}
 	if err := json.Unmarshal(payload, &data); err != nil {
-		return
+		return StateChangeEvent{}, false
 	}
 
 	printData, ok := data["print"].(map[string]interface{})
 	if !ok {
-		return
+		return StateChangeEvent{}, false
 	}
 
 	b.mu.Lock()
 	defer b.mu.Unlock()
 
 	oldState := b.state.State
-	b.state.LastUpdated = time.Now()
+	oldProgress, oldLayer, oldTotalLayers := b.state.Progress, b.state.Layer, b.state.TotalLayers
+	b.state.LastUpdated = b.clock()
 
 	if gcodeState, ok := printData["gcode_state"].(string); ok {
 		b.state.State = normalizeState(gcodeState)
@@ -180,7 +373,7 @@ func (b *BambuAdapter) handleReport(payload []byte) {
 		// printer is parked at FINISH between prints — silently corrupting
 		// FinishedAt in plan history.
 		if b.state.State == "finished" && oldState != "finished" && oldState != "" && oldState != "offline" {
-			b.state.LastFinishedAt = time.Now()
+			b.state.LastFinishedAt = b.clock()
 		}
 	}
 
@@ -196,6 +389,34 @@ func (b *BambuAdapter) handleReport(payload []byte) {
 		b.state.CurrentFile = subtask
 	}
 
+	if printData["command"] == "project_file" {
+		var err error
+		if result, _ := printData["result"].(string); strings.EqualFold(result, "fail") {
+			reason, _ := printData["reason"].(string)
+			err = fmt.Errorf("printer refused the job: %s", reason)
+		}
+		select {
+		case b.startAck <- err:
+		default:
+		}
+	}
+
+	if lvl, ok := printData["spd_lvl"].(float64); ok {
+		b.state.Speed = speedProfileName(int(lvl))
+	}
+
+	if lights, ok := printData["lights_report"].([]interface{}); ok {
+		for _, l := range lights {
+			if lm, ok := l.(map[string]interface{}); ok && lm["node"] == "chamber_light" {
+				if mode, ok := lm["mode"].(string); ok {
+					b.state.Light = mode
+				}
+			}
+		}
+	}
+
+	b.applyTelemetry(printData)
+
 	if layer, ok := printData["layer_num"].(float64); ok {
 		b.state.Layer = int(layer)
 	}
@@ -209,9 +430,10 @@ func (b *BambuAdapter) handleReport(payload []byte) {
 		if trayNow, ok := amsData["tray_now"].(string); ok {
 			var trayIdx int
 			fmt.Sscanf(trayNow, "%d", &trayIdx)
-			b.state.ActiveTray = trayIdx
+			b.state.ActiveTray = bambuTrayNow(trayIdx)
 		}
 
+		// AMS HT units appear here too, with ids from 128 and one tray.
 		if amsList, ok := amsData["ams"].([]interface{}); ok {
 			var trays []TrayInfo
 			for _, ams := range amsList {
@@ -237,45 +459,26 @@ func (b *BambuAdapter) handleReport(payload []byte) {
 					if id, ok := t["id"].(string); ok {
 						fmt.Sscanf(id, "%d", &trayID)
 					}
-
-					color := ""
-					if c, ok := t["tray_color"].(string); ok && len(c) >= 6 {
-						color = c[:6] // strip alpha
-					}
-
-					trayType := ""
-					if tt, ok := t["tray_type"].(string); ok {
-						trayType = tt
-					}
-
-					tempMin, tempMax := 0, 0
-					if v, ok := t["nozzle_temp_min"].(string); ok {
-						fmt.Sscanf(v, "%d", &tempMin)
-					}
-					if v, ok := t["nozzle_temp_max"].(string); ok {
-						fmt.Sscanf(v, "%d", &tempMax)
-					}
-
-					infoIdx := ""
-					if v, ok := t["tray_info_idx"].(string); ok {
-						infoIdx = v
-					}
-
-					trays = append(trays, TrayInfo{
-						AmsID:   amsID,
-						TrayID:  trayID,
-						Color:   color,
-						Type:    trayType,
-						TempMin: tempMin,
-						TempMax: tempMax,
-						InfoIdx: infoIdx,
-					})
+					trays = append(trays, parseBambuTray(t, amsID, trayID))
+				}
+			}
+			// The external spool is reported separately; keep it.
+			for _, t := range b.state.Trays {
+				if t.AmsID == ExternalAmsID {
+					trays = append(trays, t)
 				}
 			}
 			b.state.Trays = trays
 		}
 	}
 
+	// The external spool holder is vt_tray, outside the ams block.
+	if vt, ok := printData["vt_tray"].(map[string]interface{}); ok {
+		ext := parseBambuTray(vt, ExternalAmsID, 0)
+		trays := slices.DeleteFunc(b.state.Trays, func(t TrayInfo) bool { return t.AmsID == ExternalAmsID })
+		b.state.Trays = append(trays, ext)
+	}
+
 	// Parse HMS codes
 	prevHMS := make([]HMSCode, len(b.hmsCodes))
 	copy(prevHMS, b.hmsCodes)
@@ -291,6 +494,7 @@ func (b *BambuAdapter) handleReport(payload []byte) {
 			codes = append(codes, HMSCode{Attr: int(attr), Code: int(code)})
 		}
 		b.hmsCodes = codes
+		b.state.HMS = hmsInfos(codes)
 	}
 
 	// Fire state change callbacks
@@ -300,24 +504,99 @@ func (b *BambuAdapter) handleReport(payload []byte) {
 		// constructor seeds it and ConnectionLostHandler resets to it, so
 		// offline->X is "first observation after (re)connect," not a real
 		// transition; firing on it would announce "print finished" on every
-		// server restart whenever the printer is parked at FINISH.
+		// server restart whenever the printer is parked at FINISH. With a
+		// restored snapshot the first report compares against the last real
+		// state instead, so a print that finished while the server was down
+		// still fires.
 		fireCallbacks = true
 	} else if b.state.State == "paused" && hasNewHMSCodes(prevHMS, b.hmsCodes) {
 		// Already paused but new HMS codes appeared — fire to notify about additional faults
 		fireCallbacks = true
 	}
 
-	if fireCallbacks {
-		event := StateChangeEvent{
-			OldState:     oldState,
-			NewState:     b.state.State,
-			HMSCodes:     b.hmsCodes,
-			PrevHMSCodes: prevHMS,
-		}
-		for _, cb := range b.stateCallbacks {
-			go cb(event)
-		}
+	if !fireCallbacks {
+		return StateChangeEvent{}, false
+	}
+	return StateChangeEvent{
+		OldState:     oldState,
+		NewState:     b.state.State,
+		HMSCodes:     b.hmsCodes,
+		PrevHMSCodes: prevHMS,
+		Progress:     max(oldProgress, b.state.Progress),
+		Layer:        max(oldLayer, b.state.Layer),
+		TotalLayers:  max(oldTotalLayers, b.state.TotalLayers),
+	}, true
+}
+
+// parseBambuTray reads one tray object from an ams or vt_tray report.
+func parseBambuTray(t map[string]interface{}, amsID, trayID int) TrayInfo {
+	color := ""
+	if c, ok := t["tray_color"].(string); ok && len(c) >= 6 {
+		color = c[:6] // strip alpha
+	}
+
+	trayType := ""
+	if tt, ok := t["tray_type"].(string); ok {
+		trayType = tt
+	}
+
+	tempMin, tempMax := 0, 0
+	if v, ok := t["nozzle_temp_min"].(string); ok {
+		fmt.Sscanf(v, "%d", &tempMin)
+	}
+	if v, ok := t["nozzle_temp_max"].(string); ok {
+		fmt.Sscanf(v, "%d", &tempMax)
+	}
+
+	infoIdx := ""
+	if v, ok := t["tray_info_idx"].(string); ok {
+		infoIdx = v
+	}
+
+	// remain arrives as a number; tray_weight as a string ("1000"). Both are
+	// absent for an empty tray.
+	remain := -1
+	if v, ok := t["remain"].(float64); ok {
+		remain = int(v)
+	}
+	trayWeight := 0
+	switch v := t["tray_weight"].(type) {
+	case string:
+		fmt.Sscanf(v, "%d", &trayWeight)
+	case float64:
+		trayWeight = int(v)
+	}
+
+	// Spools without an RFID tag report a tray_uuid of all zeros.
+	uuid := ""
+	if v, ok := t["tray_uuid"].(string); ok && strings.Trim(v, "0") != "" {
+		uuid = v
+	}
+
+	subBrand, _ := t["tray_sub_brands"].(string)
+
+	return TrayInfo{
+		AmsID:      amsID,
+		TrayID:     trayID,
+		Color:      color,
+		Type:       trayType,
+		TempMin:    tempMin,
+		TempMax:    tempMax,
+		InfoIdx:    infoIdx,
+		Remain:     remain,
+		TrayWeight: trayWeight,
+		UUID:       uuid,
+		SubBrand:   subBrand,
+	}
+}
+
+// clock returns the adapter's notion of now: the wall clock, or the capture
+// time of the report being replayed.
+func (b *BambuAdapter) clock() time.Time {
+	if b.now != nil {
+		return b.now()
 	}
+	return time.Now()
 }
 
 // hasNewHMSCodes returns true if current contains any codes not in prev.
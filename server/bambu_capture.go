@@ -0,0 +1,170 @@
+package server
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Connection markers an MQTTCapture writes alongside the reports, so a
+// replay sees the same offline gaps the adapter did.
+const (
+	captureConnected      = "connected"
+	captureConnectionLost = "connection_lost"
+)
+
+// CapturedReport is one line of a capture file: an MQTT message as it
+// arrived, or a connection marker (Event set, no payload).
+type CapturedReport struct {
+	Time    time.Time       `json:"t"`
+	Event   string          `json:"event,omitempty"`
+	Topic   string          `json:"topic,omitempty"`
+	Payload json.RawMessage `json:"payload,omitempty"`
+}
+
+// MQTTCapture appends a printer's raw MQTT traffic to a JSONL file so real
+// incidents can be replayed as regression fixtures. A nil *MQTTCapture
+// records nothing, so adapters can call it unconditionally.
+type MQTTCapture struct {
+	mu  sync.Mutex
+	f   *os.File
+	enc *json.Encoder
+}
+
+// NewMQTTCapture opens (appending) dir/<printer>.jsonl.
+func NewMQTTCapture(dir, printer string) (*MQTTCapture, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("create capture dir: %w", err)
+	}
+	path := filepath.Join(dir, captureFileName(printer))
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return nil, fmt.Errorf("open capture: %w", err)
+	}
+	return &MQTTCapture{f: f, enc: json.NewEncoder(f)}, nil
+}
+
+// captureFileName turns a printer name into a safe file name.
+func captureFileName(printer string) string {
+	safe := strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '-', r == '_', r == '.':
+			return r
+		default:
+			return '-'
+		}
+	}, printer)
+	return safe + ".jsonl"
+}
+
+// Path returns the capture file's path.
+func (c *MQTTCapture) Path() string {
+	if c == nil {
+		return ""
+	}
+	return c.f.Name()
+}
+
+// Record writes one MQTT message. Payloads that aren't JSON are kept as a
+// JSON string so the line stays parseable.
+func (c *MQTTCapture) Record(topic string, payload []byte) {
+	if c == nil {
+		return
+	}
+	raw := json.RawMessage(payload)
+	if !json.Valid(payload) {
+		raw, _ = json.Marshal(string(payload))
+	}
+	c.write(CapturedReport{Time: time.Now(), Topic: topic, Payload: raw})
+}
+
+// RecordEvent writes a connection marker.
+func (c *MQTTCapture) RecordEvent(event string) {
+	if c == nil {
+		return
+	}
+	c.write(CapturedReport{Time: time.Now(), Event: event})
+}
+
+func (c *MQTTCapture) write(r CapturedReport) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if err := c.enc.Encode(r); err != nil {
+		fmt.Printf("[capture] %s: %v\n", c.f.Name(), err)
+	}
+}
+
+// Close closes the capture file.
+func (c *MQTTCapture) Close() error {
+	if c == nil {
+		return nil
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.f.Close()
+}
+
+// ReadCapture loads a capture file written by MQTTCapture.
+func ReadCapture(path string) ([]CapturedReport, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var reports []CapturedReport
+	scanner := bufio.NewScanner(f)
+	// A full pushall report with four AMS units runs to tens of KB.
+	scanner.Buffer(make([]byte, 0, 64*1024), 4*1024*1024)
+	line := 0
+	for scanner.Scan() {
+		line++
+		if strings.TrimSpace(scanner.Text()) == "" {
+			continue
+		}
+		var r CapturedReport
+		if err := json.Unmarshal(scanner.Bytes(), &r); err != nil {
+			return nil, fmt.Errorf("%s:%d: %w", path, line, err)
+		}
+		reports = append(reports, r)
+	}
+	return reports, scanner.Err()
+}
+
+// ReplayedEvent is a state-change event a replay produced, stamped with the
+// capture time of the report that caused it.
+type ReplayedEvent struct {
+	Time  time.Time
+	Event StateChangeEvent
+}
+
+// ReplayCapture feeds reports through a fresh BambuAdapter exactly as the
+// MQTT handlers would — connection markers included — with the adapter's
+// clock pinned to each report's capture time. It returns the state-change
+// events in order and the adapter's final state.
+func ReplayCapture(name string, reports []CapturedReport) ([]ReplayedEvent, PrinterState) {
+	b := NewBambuAdapter(name, "", "", "")
+	var at time.Time
+	b.now = func() time.Time { return at }
+
+	var events []ReplayedEvent
+	for _, r := range reports {
+		at = r.Time
+		switch r.Event {
+		case captureConnectionLost:
+			b.connectionLost()
+			continue
+		case captureConnected:
+			continue
+		}
+		if ev, ok := b.applyReport(r.Payload); ok {
+			events = append(events, ReplayedEvent{Time: r.Time, Event: ev})
+		}
+	}
+	return events, b.Status()
+}
@@ -0,0 +1,103 @@
+package server
+
+import (
+	"fmt"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// replayFixture replays testdata/bambu/<name>.jsonl and returns its events
+// as "old->new" strings alongside the raw events and final state.
+func replayFixture(t *testing.T, name string) ([]string, []ReplayedEvent, PrinterState) {
+	t.Helper()
+	reports, err := ReadCapture(filepath.Join("testdata", "bambu", name+".jsonl"))
+	if err != nil {
+		t.Fatalf("read fixture: %v", err)
+	}
+	events, final := ReplayCapture("X1C", reports)
+	var transitions []string
+	for _, e := range events {
+		transitions = append(transitions, e.Event.OldState+"->"+e.Event.NewState)
+	}
+	return transitions, events, final
+}
+
+func assertTransitions(t *testing.T, got []string, want ...string) {
+	t.Helper()
+	if fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Errorf("transitions = %v, want %v", got, want)
+	}
+}
+
+// Captured across a server restart while the printer sat at FINISH, then a
+// dropped connection mid-print: neither reconnect may look like a finish.
+func TestReplayRestartParkedAtFinish(t *testing.T) {
+	got, events, final := replayFixture(t, "restart_parked_finish")
+
+	assertTransitions(t, got, "finished->idle", "idle->printing", "printing->finished")
+	finish := events[2]
+	if finish.Event.Progress != 100 || finish.Event.Layer != 60 || finish.Event.TotalLayers != 60 {
+		t.Errorf("finish event = %+v", finish.Event)
+	}
+	want := time.Date(2026, 5, 1, 10, 3, 0, 0, time.UTC)
+	if !final.LastFinishedAt.Equal(want) {
+		t.Errorf("LastFinishedAt = %v, want the FINISH report's time %v", final.LastFinishedAt, want)
+	}
+	if final.CurrentFile != "Widget plate 1" {
+		t.Errorf("CurrentFile = %q", final.CurrentFile)
+	}
+}
+
+func TestReplayHMSPauseThenCancel(t *testing.T) {
+	got, events, _ := replayFixture(t, "hms_pause_then_cancel")
+
+	assertTransitions(t, got, "printing->paused", "paused->paused", "paused->idle")
+	if e := events[0].Event; len(e.HMSCodes) != 1 || e.HMSCodes[0].HMSCodeString() != "0C00-0300-0003-0008" || e.IsLikelyUserPause() {
+		t.Errorf("pause event = %+v, want the spaghetti HMS code", e)
+	}
+	if e := events[1].Event; len(e.HMSCodes) != 2 {
+		t.Errorf("second pause event HMS = %v, want 2 codes", e.HMSCodes)
+	}
+	cancel := events[2].Event
+	if !IsAbortedPrint(cancel) || cancel.Progress != 31 || cancel.Layer != 25 {
+		t.Errorf("cancel event = %+v, want an abort at 31%%, layer 25", cancel)
+	}
+}
+
+func TestMQTTCaptureRoundTrip(t *testing.T) {
+	dir := t.TempDir()
+	c, err := NewMQTTCapture(dir, "X1C Carbon/2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	c.RecordEvent(captureConnected)
+	c.Record("device/1/report", []byte(`{"print":{"gcode_state":"RUNNING","mc_percent":5}}`))
+	c.Record("device/1/report", []byte("not json"))
+	c.RecordEvent(captureConnectionLost)
+	if err := c.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if filepath.Base(c.Path()) != "X1C-Carbon-2.jsonl" {
+		t.Errorf("capture file = %s", c.Path())
+	}
+
+	reports, err := ReadCapture(c.Path())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(reports) != 4 || reports[0].Event != captureConnected || reports[1].Topic != "device/1/report" || string(reports[2].Payload) != `"not json"` {
+		t.Fatalf("reports = %+v", reports)
+	}
+
+	_, final := ReplayCapture("X1C", reports)
+	if final.State != "offline" {
+		t.Errorf("final state = %q, want offline after connection_lost", final.State)
+	}
+
+	var nilCapture *MQTTCapture
+	nilCapture.Record("t", []byte("{}")) // must not panic
+	if err := nilCapture.Close(); err != nil {
+		t.Error(err)
+	}
+}
@@ -0,0 +1,99 @@
+package server
+
+import (
+	"math"
+	"strconv"
+)
+
+// bambuFanPercent converts Bambu's 0-15 fan gear to a percentage.
+func bambuFanPercent(gear float64) int {
+	return int(math.Round(gear / 15 * 100))
+}
+
+// reportNumber reads a numeric report field. Bambu sends temperatures as
+// numbers but fan gears and AMS values as strings.
+func reportNumber(m map[string]interface{}, key string) (float64, bool) {
+	switch v := m[key].(type) {
+	case float64:
+		return v, true
+	case string:
+		f, err := strconv.ParseFloat(v, 64)
+		return f, err == nil
+	}
+	return 0, false
+}
+
+// applyTelemetry merges the temperature, fan, and AMS fields of a report
+// into the current telemetry and records it. Reports are deltas, so absent
+// fields keep their last value. Called with b.mu held.
+func (b *BambuAdapter) applyTelemetry(printData map[string]interface{}) {
+	var t Telemetry
+	if b.state.Telemetry != nil {
+		t = *b.state.Telemetry
+		t.AMS = append([]AMSEnv(nil), t.AMS...)
+	}
+	seen := false
+	for key, dst := range map[string]*float64{
+		"nozzle_temper":        &t.Nozzle,
+		"nozzle_target_temper": &t.NozzleTarget,
+		"bed_temper":           &t.Bed,
+		"bed_target_temper":    &t.BedTarget,
+		"chamber_temper":       &t.Chamber,
+	} {
+		if v, ok := reportNumber(printData, key); ok {
+			*dst = v
+			seen = true
+		}
+	}
+	for key, dst := range map[string]*int{
+		"cooling_fan_speed":   &t.PartFan,
+		"big_fan1_speed":      &t.AuxFan,
+		"big_fan2_speed":      &t.ChamberFan,
+		"heatbreak_fan_speed": &t.HeatbreakFan,
+	} {
+		if v, ok := reportNumber(printData, key); ok {
+			*dst = bambuFanPercent(v)
+			seen = true
+		}
+	}
+
+	if amsData, ok := printData["ams"].(map[string]interface{}); ok {
+		if amsList, ok := amsData["ams"].([]interface{}); ok {
+			var units []AMSEnv
+			for _, ams := range amsList {
+				a, ok := ams.(map[string]interface{})
+				if !ok {
+					continue
+				}
+				var env AMSEnv
+				if id, ok := reportNumber(a, "id"); ok {
+					env.ID = int(id)
+				}
+				if v, ok := reportNumber(a, "humidity"); ok {
+					env.HumidityLevel = int(v)
+				}
+				if v, ok := reportNumber(a, "humidity_raw"); ok {
+					env.Humidity = int(v)
+				}
+				if v, ok := reportNumber(a, "temp"); ok {
+					env.Temp = v
+				}
+				units = append(units, env)
+			}
+			t.AMS = units
+			seen = true
+		}
+	}
+
+	if !seen {
+		return
+	}
+	t.At = b.clock()
+	b.state.Telemetry = &t
+	b.telemetry.Record(t)
+}
+
+// TelemetryHistory returns the recorded telemetry, oldest first.
+func (b *BambuAdapter) TelemetryHistory() []Telemetry {
+	return b.telemetry.Samples()
+}
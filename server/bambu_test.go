@@ -183,3 +183,98 @@ func TestBambuLastFinishedAtUpdatedOnSecondFinishAfterRunning(t *testing.T) {
 		t.Errorf("LastFinishedAt should advance on second FINISH; got %v not after %v", b.state.LastFinishedAt, first)
 	}
 }
+
+func TestBambuParsesTrayRemainAndWeight(t *testing.T) {
+	b := NewBambuAdapter("test", "127.0.0.1", "00M00A000000000", "12345678")
+	b.handleReport([]byte(`{"print":{"gcode_state":"IDLE","ams":{"ams":[{"id":"0","tray":[
+		{"id":"0","tray_color":"FF0000FF","tray_type":"PLA","remain":42,"tray_weight":"1000"},
+		{"id":"1","tray_color":"00FF00FF","tray_type":"PETG","remain":-1,"tray_weight":"0"},
+		{"id":"2"}
+	]}]}}}`))
+
+	trays := b.Status().Trays
+	if len(trays) != 3 {
+		t.Fatalf("got %d trays, want 3", len(trays))
+	}
+	if trays[0].Remain != 42 || trays[0].TrayWeight != 1000 {
+		t.Errorf("tray 0 = %+v, want remain 42 weight 1000", trays[0])
+	}
+	if g, ok := trays[0].EstimatedGrams(); !ok || g != 420 {
+		t.Errorf("EstimatedGrams = %v, %v; want 420, true", g, ok)
+	}
+	for _, tr := range trays[1:] {
+		if _, ok := tr.EstimatedGrams(); ok {
+			t.Errorf("tray %d has an estimate, want none: %+v", tr.TrayID, tr)
+		}
+	}
+}
+
+func TestBambuParsesExternalAndHTTrays(t *testing.T) {
+	b := NewBambuAdapter("test", "127.0.0.1", "00M00A000000000", "12345678")
+	b.handleReport([]byte(`{"print":{"gcode_state":"IDLE",
+		"ams":{"tray_now":"128","ams":[
+			{"id":"0","tray":[{"id":"0","tray_color":"FF0000FF","tray_type":"PLA","tray_uuid":"00000000000000000000000000000000"}]},
+			{"id":"128","tray":[{"id":"0","tray_color":"0000FFFF","tray_type":"PA-CF","remain":80,"tray_weight":"1000","tray_uuid":"A1B2C3D4E5F60718293A4B5C6D7E8F90","tray_sub_brands":"PA6-CF"}]}
+		]},
+		"vt_tray":{"id":"254","tray_color":"FFFFFFFF","tray_type":"TPU"}}}`))
+
+	st := b.Status()
+	want := map[[2]int]string{{0, 0}: "PLA", {HTAmsIDBase, 0}: "PA-CF", {ExternalAmsID, 0}: "TPU"}
+	if len(st.Trays) != len(want) {
+		t.Fatalf("got %d trays, want %d: %+v", len(st.Trays), len(want), st.Trays)
+	}
+	for _, tr := range st.Trays {
+		if want[[2]int{tr.AmsID, tr.TrayID}] != tr.Type {
+			t.Errorf("unexpected tray %+v", tr)
+		}
+		if hasUUID := tr.UUID != ""; hasUUID != (tr.AmsID == HTAmsIDBase) {
+			t.Errorf("tray %+v: only the HT spool has an RFID tag", tr)
+		}
+		if tr.AmsID == HTAmsIDBase && tr.SubBrand != "PA6-CF" {
+			t.Errorf("HT tray sub-brand = %q, want PA6-CF", tr.SubBrand)
+		}
+	}
+	if st.ActiveTray != TrayIndex(HTAmsIDBase, 0) {
+		t.Errorf("ActiveTray = %d, want the HT unit", st.ActiveTray)
+	}
+
+	// An AMS-only report keeps the external spool; tray_now 254 selects it.
+	b.handleReport([]byte(`{"print":{"ams":{"tray_now":"254","ams":[{"id":"0","tray":[{"id":"0","tray_type":"PLA"}]}]}}}`))
+	st = b.Status()
+	if len(st.Trays) != 2 || st.Trays[1].AmsID != ExternalAmsID {
+		t.Errorf("trays after AMS-only report = %+v, want AMS slot plus external", st.Trays)
+	}
+	if st.ActiveTray/4 != ExternalAmsID || st.ActiveTray%4 != 0 {
+		t.Errorf("ActiveTray = %d, want the external spool", st.ActiveTray)
+	}
+
+	b.handleReport([]byte(`{"print":{"ams":{"tray_now":"255"}}}`))
+	if st := b.Status(); st.ActiveTray != -1 {
+		t.Errorf("ActiveTray = %d, want -1 when nothing is loaded", st.ActiveTray)
+	}
+}
+
+func TestBambuParsesSpeedAndLight(t *testing.T) {
+	b := NewBambuAdapter("test", "127.0.0.1", "00M00A000000000", "12345678")
+	b.handleReport([]byte(`{"print":{"gcode_state":"RUNNING","spd_lvl":3,"lights_report":[{"node":"chamber_light","mode":"on"},{"node":"work_light","mode":"flashing"}]}}`))
+	st := b.Status()
+	if st.Speed != "sport" || st.Light != "on" {
+		t.Errorf("speed %q, light %q; want sport, on", st.Speed, st.Light)
+	}
+
+	// Partial reports leave both alone.
+	b.handleReport(reportPayload("RUNNING"))
+	if st := b.Status(); st.Speed != "sport" || st.Light != "on" {
+		t.Errorf("after partial report: speed %q, light %q", st.Speed, st.Light)
+	}
+}
+
+func TestLookupSpeedProfile(t *testing.T) {
+	p, err := LookupSpeedProfile(" Ludicrous ")
+	if err != nil || p.Level != 4 {
+		t.Errorf("LookupSpeedProfile = %+v, %v", p, err)
+	}
+	if _, err := LookupSpeedProfile("warp"); err == nil {
+		t.Error("expected an error for an unknown profile")
+	}
+}
@@ -0,0 +1,298 @@
+package server
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/net/dns/dnsmessage"
+)
+
+// BambuDiscoveryPort is the UDP port Bambu printers broadcast their SSDP
+// NOTIFY on, every few seconds while on the LAN.
+const BambuDiscoveryPort = 2021
+
+// mdnsAddr is the mDNS multicast group queries go to.
+var mdnsAddr = &net.UDPAddr{IP: net.IPv4(224, 0, 0, 251), Port: 5353}
+
+// mdnsServices maps the mDNS service types printer hosts advertise to the
+// printer type in config.
+var mdnsServices = map[string]string{
+	"_prusa-link._tcp.local.": "prusa",
+	"_octoprint._tcp.local.":  "octoprint",
+	"_moonraker._tcp.local.":  "klipper",
+}
+
+// bambuModels names the model codes Bambu printers announce.
+var bambuModels = map[string]string{
+	"3DPrinter-X1-Carbon": "X1 Carbon",
+	"3DPrinter-X1":        "X1",
+	"C13":                 "X1E",
+	"C11":                 "P1P",
+	"C12":                 "P1S",
+	"N1":                  "A1 mini",
+	"N2S":                 "A1",
+	"O1D":                 "H2D",
+}
+
+// DiscoveredPrinter is a printer found on the LAN.
+type DiscoveredPrinter struct {
+	Type   string // config type: "bambu", "prusa", "octoprint", or "klipper"
+	Name   string // what the printer calls itself
+	Model  string // Bambu only
+	IP     string
+	Port   int    // service port, mDNS only
+	Serial string // Bambu only
+}
+
+// Host is the address to configure: the IP, with the port when the service
+// doesn't run on its type's default.
+func (p DiscoveredPrinter) Host() string {
+	defaults := map[string]int{"prusa": 80, "octoprint": 80, "klipper": 7125}
+	if p.Port == 0 || p.Port == defaults[p.Type] {
+		return p.IP
+	}
+	return net.JoinHostPort(p.IP, fmt.Sprint(p.Port))
+}
+
+func (p DiscoveredPrinter) key() string {
+	if p.Serial != "" {
+		return "serial:" + p.Serial
+	}
+	return p.Type + "@" + p.Host()
+}
+
+// ParseBambuSSDP parses one Bambu SSDP NOTIFY. ok is false for anything
+// else on the port, including other vendors' SSDP.
+func ParseBambuSSDP(b []byte) (DiscoveredPrinter, bool) {
+	if !bytes.HasPrefix(b, []byte("NOTIFY ")) && !bytes.HasPrefix(b, []byte("HTTP/1.1 200")) {
+		return DiscoveredPrinter{}, false
+	}
+	// SSDP is HTTP-shaped; skip the request line and read the headers.
+	r := bufio.NewReader(bytes.NewReader(b))
+	if _, err := r.ReadString('\n'); err != nil {
+		return DiscoveredPrinter{}, false
+	}
+	var h http.Header = map[string][]string{}
+	for {
+		line, err := r.ReadString('\n')
+		line = strings.TrimSpace(line)
+		if k, v, ok := strings.Cut(line, ":"); ok {
+			h.Add(strings.TrimSpace(k), strings.TrimSpace(v))
+		}
+		if err != nil || line == "" {
+			break
+		}
+	}
+	if !strings.HasPrefix(h.Get("NT"), "urn:bambulab-com:device:3dprinter") {
+		return DiscoveredPrinter{}, false
+	}
+	p := DiscoveredPrinter{
+		Type:   "bambu",
+		Name:   h.Get("DevName.bambu.com"),
+		Model:  h.Get("DevModel.bambu.com"),
+		IP:     h.Get("Location"),
+		Serial: h.Get("USN"),
+	}
+	if m, ok := bambuModels[p.Model]; ok {
+		p.Model = m
+	}
+	if p.Serial == "" || net.ParseIP(p.IP) == nil {
+		return DiscoveredPrinter{}, false
+	}
+	return p, true
+}
+
+// ListenBambu reads Bambu SSDP broadcasts from conn until ctx is done,
+// calling found for every announcement (printers repeat theirs).
+func ListenBambu(ctx context.Context, conn net.PacketConn, found func(DiscoveredPrinter)) error {
+	return readPackets(ctx, conn, func(b []byte, _ net.Addr) {
+		if p, ok := ParseBambuSSDP(b); ok {
+			found(p)
+		}
+	})
+}
+
+// MDNSQuery builds a one-shot mDNS query for the printer services, asking
+// for unicast replies so a socket on any port can read them.
+func MDNSQuery() ([]byte, error) {
+	var types []string
+	for t := range mdnsServices {
+		types = append(types, t)
+	}
+	sort.Strings(types)
+
+	b := dnsmessage.NewBuilder(nil, dnsmessage.Header{})
+	if err := b.StartQuestions(); err != nil {
+		return nil, err
+	}
+	for _, t := range types {
+		err := b.Question(dnsmessage.Question{
+			Name:  dnsmessage.MustNewName(t),
+			Type:  dnsmessage.TypePTR,
+			Class: dnsmessage.ClassINET | 1<<15, // QU: unicast response
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	return b.Finish()
+}
+
+// ParseMDNSResponse extracts the printer services from an mDNS response.
+// from is the responder's address, used when it sent no A record.
+func ParseMDNSResponse(b []byte, from net.IP) []DiscoveredPrinter {
+	var m dnsmessage.Message
+	if err := m.Unpack(b); err != nil || !m.Header.Response {
+		return nil
+	}
+	records := append(append(m.Answers, m.Authorities...), m.Additionals...)
+
+	instances := map[string]string{} // instance name -> printer type
+	srv := map[string]*dnsmessage.SRVResource{}
+	addrs := map[string]net.IP{}
+	for _, rr := range records {
+		name := strings.ToLower(rr.Header.Name.String())
+		switch body := rr.Body.(type) {
+		case *dnsmessage.PTRResource:
+			if t, ok := mdnsServices[name]; ok {
+				instances[body.PTR.String()] = t
+			}
+		case *dnsmessage.SRVResource:
+			srv[name] = body
+		case *dnsmessage.AResource:
+			addrs[name] = net.IP(body.A[:])
+		}
+	}
+
+	var out []DiscoveredPrinter
+	for inst, typ := range instances {
+		p := DiscoveredPrinter{Type: typ, Name: instanceLabel(inst)}
+		if s, ok := srv[strings.ToLower(inst)]; ok {
+			p.Port = int(s.Port)
+			if ip, ok := addrs[strings.ToLower(s.Target.String())]; ok {
+				p.IP = ip.String()
+			}
+		}
+		if p.IP == "" && from != nil {
+			p.IP = from.String()
+		}
+		if p.IP != "" {
+			out = append(out, p)
+		}
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].key() < out[j].key() })
+	return out
+}
+
+// instanceLabel is the human part of a service instance name: "Voron" for
+// "Voron._moonraker._tcp.local.".
+func instanceLabel(inst string) string {
+	label, _, _ := strings.Cut(inst, "._")
+	return strings.ReplaceAll(label, `\ `, " ")
+}
+
+// BrowseMDNS sends the printer service query from conn to dst (normally the
+// mDNS group) and reports the services in the replies until ctx is done.
+func BrowseMDNS(ctx context.Context, conn net.PacketConn, dst net.Addr, found func(DiscoveredPrinter)) error {
+	q, err := MDNSQuery()
+	if err != nil {
+		return fmt.Errorf("build mDNS query: %w", err)
+	}
+	if _, err := conn.WriteTo(q, dst); err != nil {
+		return fmt.Errorf("send mDNS query: %w", err)
+	}
+	return readPackets(ctx, conn, func(b []byte, from net.Addr) {
+		var ip net.IP
+		if u, ok := from.(*net.UDPAddr); ok {
+			ip = u.IP
+		}
+		for _, p := range ParseMDNSResponse(b, ip) {
+			found(p)
+		}
+	})
+}
+
+// Discover listens for Bambu broadcasts and browses mDNS for timeout, and
+// returns each printer found once. It fails only when neither could run;
+// otherwise a problem with one (say, Bambu Studio holding the port) comes
+// back as warnings.
+func Discover(ctx context.Context, timeout time.Duration) ([]DiscoveredPrinter, []error, error) {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	var (
+		mu    sync.Mutex
+		seen  = map[string]DiscoveredPrinter{}
+		warns []error
+		wg    sync.WaitGroup
+	)
+	found := func(p DiscoveredPrinter) {
+		mu.Lock()
+		seen[p.key()] = p
+		mu.Unlock()
+	}
+	run := func(what string, open func() (net.PacketConn, error), listen func(net.PacketConn) error) {
+		conn, err := open()
+		if err != nil {
+			mu.Lock()
+			warns = append(warns, fmt.Errorf("%s: %w", what, err))
+			mu.Unlock()
+			return
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			defer conn.Close()
+			if err := listen(conn); err != nil {
+				mu.Lock()
+				warns = append(warns, fmt.Errorf("%s: %w", what, err))
+				mu.Unlock()
+			}
+		}()
+	}
+
+	run("Bambu discovery", func() (net.PacketConn, error) {
+		return net.ListenPacket("udp4", fmt.Sprintf(":%d", BambuDiscoveryPort))
+	}, func(c net.PacketConn) error { return ListenBambu(ctx, c, found) })
+	run("mDNS", func() (net.PacketConn, error) {
+		return net.ListenPacket("udp4", ":0")
+	}, func(c net.PacketConn) error { return BrowseMDNS(ctx, c, mdnsAddr, found) })
+	wg.Wait()
+
+	if len(warns) == 2 {
+		return nil, nil, errors.Join(warns...)
+	}
+	out := make([]DiscoveredPrinter, 0, len(seen))
+	for _, p := range seen {
+		out = append(out, p)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].key() < out[j].key() })
+	return out, warns, nil
+}
+
+// readPackets calls fn for each packet read from conn until ctx is done.
+func readPackets(ctx context.Context, conn net.PacketConn, fn func([]byte, net.Addr)) error {
+	buf := make([]byte, 9000)
+	for ctx.Err() == nil {
+		_ = conn.SetReadDeadline(time.Now().Add(200 * time.Millisecond))
+		n, from, err := conn.ReadFrom(buf)
+		if err != nil {
+			if errors.Is(err, os.ErrDeadlineExceeded) {
+				continue
+			}
+			return err
+		}
+		fn(buf[:n], from)
+	}
+	return nil
+}
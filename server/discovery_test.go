@@ -0,0 +1,157 @@
+package server
+
+import (
+	"context"
+	"net"
+	"sync"
+	"testing"
+	"time"
+
+	"golang.org/x/net/dns/dnsmessage"
+)
+
+const bambuNotify = "NOTIFY * HTTP/1.1\r\n" +
+	"HOST: 239.255.255.250:1990\r\n" +
+	"Server: UPnP/1.0\r\n" +
+	"Location: 192.168.1.50\r\n" +
+	"NT: urn:bambulab-com:device:3dprinter:1\r\n" +
+	"USN: 00M09A350100123\r\n" +
+	"Cache-Control: max-age=1800\r\n" +
+	"DevModel.bambu.com: C12\r\n" +
+	"DevName.bambu.com: Garage P1S\r\n" +
+	"DevConnect.bambu.com: lan\r\n" +
+	"DevBind.bambu.com: free\r\n\r\n"
+
+func TestParseBambuSSDP(t *testing.T) {
+	p, ok := ParseBambuSSDP([]byte(bambuNotify))
+	want := DiscoveredPrinter{Type: "bambu", Name: "Garage P1S", Model: "P1S", IP: "192.168.1.50", Serial: "00M09A350100123"}
+	if !ok || p != want {
+		t.Errorf("ParseBambuSSDP = %+v, %v; want %+v", p, ok, want)
+	}
+
+	router := "NOTIFY * HTTP/1.1\r\nLocation: http://192.168.1.1:5000/desc.xml\r\nNT: upnp:rootdevice\r\nUSN: uuid:abc\r\n\r\n"
+	if _, ok := ParseBambuSSDP([]byte(router)); ok {
+		t.Error("non-Bambu SSDP should be ignored")
+	}
+	if _, ok := ParseBambuSSDP([]byte("garbage")); ok {
+		t.Error("garbage should be ignored")
+	}
+}
+
+// TestListenBambuFakeBroadcaster plays a printer announcing itself (plus
+// some unrelated SSDP noise) at a local listener.
+func TestListenBambuFakeBroadcaster(t *testing.T) {
+	conn, err := net.ListenPacket("udp4", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	sender, err := net.Dial("udp4", conn.LocalAddr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer sender.Close()
+	go func() {
+		for i := 0; i < 3; i++ {
+			_, _ = sender.Write([]byte("M-SEARCH * HTTP/1.1\r\nST: ssdp:all\r\n\r\n"))
+			_, _ = sender.Write([]byte(bambuNotify))
+			time.Sleep(20 * time.Millisecond)
+		}
+	}()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 300*time.Millisecond)
+	defer cancel()
+	var mu sync.Mutex
+	var got []DiscoveredPrinter
+	err = ListenBambu(ctx, conn, func(p DiscoveredPrinter) {
+		mu.Lock()
+		got = append(got, p)
+		mu.Unlock()
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 3 || got[0].Serial != "00M09A350100123" {
+		t.Errorf("got %+v, want the printer's three announcements", got)
+	}
+}
+
+// fakeMDNSResponse answers like a Moonraker host: PTR in the answers, SRV
+// and A in the additionals, plus a service fil doesn't care about.
+func fakeMDNSResponse(t *testing.T) []byte {
+	t.Helper()
+	b := dnsmessage.NewBuilder(nil, dnsmessage.Header{Response: true, Authoritative: true})
+	must := func(err error) {
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	hdr := func(name string, typ dnsmessage.Type) dnsmessage.ResourceHeader {
+		return dnsmessage.ResourceHeader{Name: dnsmessage.MustNewName(name), Type: typ, Class: dnsmessage.ClassINET, TTL: 120}
+	}
+	must(b.StartAnswers())
+	must(b.PTRResource(hdr("_moonraker._tcp.local.", dnsmessage.TypePTR), dnsmessage.PTRResource{PTR: dnsmessage.MustNewName("Voron._moonraker._tcp.local.")}))
+	must(b.PTRResource(hdr("_ipp._tcp.local.", dnsmessage.TypePTR), dnsmessage.PTRResource{PTR: dnsmessage.MustNewName("Office._ipp._tcp.local.")}))
+	must(b.StartAdditionals())
+	must(b.SRVResource(hdr("Voron._moonraker._tcp.local.", dnsmessage.TypeSRV), dnsmessage.SRVResource{Port: 7125, Target: dnsmessage.MustNewName("voron.local.")}))
+	must(b.AResource(hdr("voron.local.", dnsmessage.TypeA), dnsmessage.AResource{A: [4]byte{192, 168, 1, 60}}))
+	msg, err := b.Finish()
+	must(err)
+	return msg
+}
+
+func TestBrowseMDNSFakeResponder(t *testing.T) {
+	responder, err := net.ListenPacket("udp4", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer responder.Close()
+	go func() {
+		buf := make([]byte, 1500)
+		n, from, err := responder.ReadFrom(buf)
+		if err != nil {
+			return
+		}
+		var q dnsmessage.Message
+		if err := q.Unpack(buf[:n]); err != nil || len(q.Questions) != len(mdnsServices) {
+			t.Errorf("query = %+v, %v; want one question per service", q.Questions, err)
+			return
+		}
+		_, _ = responder.WriteTo(fakeMDNSResponse(t), from)
+	}()
+
+	conn, err := net.ListenPacket("udp4", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	ctx, cancel := context.WithTimeout(context.Background(), 300*time.Millisecond)
+	defer cancel()
+	var got []DiscoveredPrinter
+	if err := BrowseMDNS(ctx, conn, responder.LocalAddr(), func(p DiscoveredPrinter) { got = append(got, p) }); err != nil {
+		t.Fatal(err)
+	}
+	want := DiscoveredPrinter{Type: "klipper", Name: "Voron", IP: "192.168.1.60", Port: 7125}
+	if len(got) != 1 || got[0] != want {
+		t.Fatalf("got %+v, want %+v", got, want)
+	}
+	if got[0].Host() != "192.168.1.60" {
+		t.Errorf("Host() = %q, want the bare IP on Moonraker's default port", got[0].Host())
+	}
+}
+
+func TestParseMDNSResponseFallsBackToSender(t *testing.T) {
+	b := dnsmessage.NewBuilder(nil, dnsmessage.Header{Response: true})
+	_ = b.StartAnswers()
+	_ = b.PTRResource(dnsmessage.ResourceHeader{Name: dnsmessage.MustNewName("_octoprint._tcp.local."), Class: dnsmessage.ClassINET},
+		dnsmessage.PTRResource{PTR: dnsmessage.MustNewName(`Prusa\ MK3._octoprint._tcp.local.`)})
+	msg, err := b.Finish()
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := ParseMDNSResponse(msg, net.IPv4(10, 0, 0, 9))
+	if len(got) != 1 || got[0].IP != "10.0.0.9" || got[0].Type != "octoprint" || got[0].Name != "Prusa MK3" {
+		t.Errorf("got %+v", got)
+	}
+}
@@ -0,0 +1,104 @@
+package server
+
+import (
+	"crypto/tls"
+	"fmt"
+	"io"
+	"net"
+	"net/textproto"
+	"regexp"
+	"strconv"
+	"time"
+)
+
+var pasvAddr = regexp.MustCompile(`\((\d+),(\d+),(\d+),(\d+),(\d+),(\d+)\)`)
+
+// ftpsUpload stores r as name on an implicit-TLS FTP server, the kind
+// Bambu printers run on port 990 for their SD card. Only the handful of
+// commands a passive-mode STOR needs are implemented.
+func ftpsUpload(addr, user, pass string, cfg *tls.Config, name string, r io.Reader) error {
+	// Some FTP servers insist the data connection resume the control
+	// connection's TLS session.
+	cfg = cfg.Clone()
+	if cfg.ClientSessionCache == nil {
+		cfg.ClientSessionCache = tls.NewLRUClientSessionCache(1)
+	}
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		return err
+	}
+	if cfg.ServerName == "" {
+		cfg.ServerName = host
+	}
+
+	dialer := &net.Dialer{Timeout: 10 * time.Second}
+	conn, err := tls.DialWithDialer(dialer, "tcp", addr, cfg)
+	if err != nil {
+		return fmt.Errorf("ftps connect: %w", err)
+	}
+	ctrl := textproto.NewConn(conn)
+	defer ctrl.Close()
+
+	if _, _, err := ctrl.ReadResponse(2); err != nil {
+		return fmt.Errorf("ftps greeting: %w", err)
+	}
+	cmd := func(expect int, format string, args ...any) (string, error) {
+		id, err := ctrl.Cmd(format, args...)
+		if err != nil {
+			return "", err
+		}
+		ctrl.StartResponse(id)
+		defer ctrl.EndResponse(id)
+		_, msg, err := ctrl.ReadResponse(expect)
+		return msg, err
+	}
+
+	if _, err := cmd(3, "USER %s", user); err != nil {
+		return fmt.Errorf("ftps login: %w", err)
+	}
+	if _, err := cmd(2, "PASS %s", pass); err != nil {
+		return fmt.Errorf("ftps login: %w", err)
+	}
+	for _, c := range []string{"TYPE I", "PBSZ 0", "PROT P"} {
+		if _, err := cmd(2, "%s", c); err != nil {
+			return fmt.Errorf("ftps %s: %w", c, err)
+		}
+	}
+
+	msg, err := cmd(2, "PASV")
+	if err != nil {
+		return fmt.Errorf("ftps PASV: %w", err)
+	}
+	m := pasvAddr.FindStringSubmatch(msg)
+	if m == nil {
+		return fmt.Errorf("ftps PASV: unexpected reply %q", msg)
+	}
+	hi, _ := strconv.Atoi(m[5])
+	lo, _ := strconv.Atoi(m[6])
+	// Dial the control connection's host rather than the advertised one;
+	// printers behind NAT or on a second interface advertise the wrong IP.
+	raw, err := dialer.Dial("tcp", net.JoinHostPort(host, strconv.Itoa(hi<<8|lo)))
+	if err != nil {
+		return fmt.Errorf("ftps data connection: %w", err)
+	}
+
+	// Like ftplib, handshake only after the server has taken the STOR:
+	// servers accept the data connection when the transfer starts.
+	if _, err := cmd(1, "STOR %s", name); err != nil {
+		raw.Close()
+		return fmt.Errorf("ftps STOR: %w", err)
+	}
+	data := tls.Client(raw, cfg)
+	if _, err := io.Copy(data, r); err != nil {
+		data.Close()
+		return fmt.Errorf("ftps upload: %w", err)
+	}
+	if err := data.Close(); err != nil {
+		return fmt.Errorf("ftps upload: %w", err)
+	}
+	if _, _, err := ctrl.ReadResponse(2); err != nil {
+		return fmt.Errorf("ftps upload: %w", err)
+	}
+	_, _ = cmd(2, "QUIT")
+	return nil
+}
@@ -9,6 +9,7 @@ import (
 	"os"
 	"path/filepath"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/dstockto/fil/models"
@@ -37,6 +38,20 @@ type PlanServer struct {
 	// verbs migrate from cmd/plan_*.go in subsequent PRs). The server uses a
 	// LocalPlanOps under the hood — Remote-Mode CLIs delegate here.
 	PlanOps plan.PlanOperations
+	// Spoolman and PrinterLocations let the server resolve deductions on its
+	// own (auto-complete) and reconcile amended ones. Nil disables matching.
+	Spoolman         plan.Spoolman
+	PrinterLocations plan.PrinterLocations
+	// Pending holds server-initiated transitions awaiting human confirmation.
+	Pending *PendingStore
+	// SpoolSwaps applies accepted "swap" events. Nil when detection is off.
+	SpoolSwaps *SpoolSwapDetector
+	// JobsDir holds sliced files plates can send to their printer.
+	JobsDir string
+
+	// sending marks printers a plan next is starting a job on; AutoStart
+	// leaves them alone.
+	sending sync.Map
 }
 
 // PlanSummary is the JSON representation returned by the list endpoint.
@@ -80,10 +95,26 @@ func (s *PlanServer) Routes() http.Handler {
 		{"POST", "/plans/{name}/next", s.handlePlanNext},
 		{"POST", "/plans/{name}/stop", s.handlePlanStop},
 		{"POST", "/plans/{name}/resolve", s.handlePlanResolve},
+		{"POST", "/plans/{name}/import-3mf", s.handleImportThreeMF},
+		{"PUT", "/jobs/{file}", s.handlePutJob},
 		{"POST", "/scan-history", s.handleScanHistoryPost},
 		{"GET", "/scan-history", s.handleScanHistoryGet},
+		{"GET", "/schedule", s.handleGetSchedule},
+		{"PUT", "/schedule", s.handlePutSchedule},
+		{"GET", "/pending", s.handleListPending},
+		{"POST", "/pending/{id}/accept", s.handleAcceptPending},
+		{"POST", "/pending/{id}/amend", s.handleAmendPending},
+		{"POST", "/pending/{id}/link", s.handleLinkPending},
+		{"DELETE", "/pending/{id}", s.handleDismissPending},
 		{"GET", "/printers", s.handleListPrinters},
+		{"GET", "/printers/{name}/events", s.handlePrinterEvents},
+		{"GET", "/printers/{name}/telemetry", s.handlePrinterTelemetry},
 		{"POST", "/printers/{name}/push-tray", s.handlePushTray},
+		{"POST", "/printers/{name}/pause", s.handlePrinterPause},
+		{"POST", "/printers/{name}/resume", s.handlePrinterResume},
+		{"POST", "/printers/{name}/stop", s.handlePrinterStop},
+		{"POST", "/printers/{name}/light/{mode}", s.handlePrinterLight},
+		{"POST", "/printers/{name}/speed/{profile}", s.handlePrinterSpeed},
 		{"GET", "/version", s.handleVersion},
 		{"GET", "/doctor", s.handleHealth},
 		{"POST", "/notify/test", s.handleNotifyTest},
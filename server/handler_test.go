@@ -679,7 +679,13 @@ func TestOnlyFilPrefixRoutes(t *testing.T) {
 		{http.MethodPost, "/scan-history"},
 		{http.MethodGet, "/scan-history"},
 		{http.MethodGet, "/printers"},
+		{http.MethodGet, "/printers/foo/events"},
+		{http.MethodGet, "/printers/foo/telemetry"},
 		{http.MethodPost, "/printers/foo/push-tray"},
+		{http.MethodPost, "/printers/foo/pause"},
+		{http.MethodPost, "/printers/foo/stop"},
+		{http.MethodPost, "/printers/foo/light/on"},
+		{http.MethodPost, "/printers/foo/speed/sport"},
 		{http.MethodGet, "/version"},
 		{http.MethodGet, "/doctor"},
 		{http.MethodPost, "/notify/test"},
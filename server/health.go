@@ -121,6 +121,7 @@ func (s *PlanServer) fsChecks() []api.Check {
 		{"pause_dir", s.PauseDir},
 		{"archive_dir", s.ArchiveDir},
 		{"assemblies_dir", s.AssembliesDir},
+		{"jobs_dir", s.JobsDir},
 		{"config_dir", s.ConfigDir},
 	}
 
@@ -23,6 +23,12 @@ func (f *fakeAdapter) Close() error                         { return nil }
 func (f *fakeAdapter) Status() PrinterState                 { return f.state }
 func (f *fakeAdapter) PushTray(TrayUpdate) error            { return nil }
 func (f *fakeAdapter) OnStateChange(func(StateChangeEvent)) {}
+func (f *fakeAdapter) Pause() error                         { return nil }
+func (f *fakeAdapter) Resume() error                        { return nil }
+func (f *fakeAdapter) Stop() error                          { return nil }
+func (f *fakeAdapter) SetLight(bool) error                  { return ErrNotSupported }
+func (f *fakeAdapter) SetSpeed(SpeedProfile) error          { return ErrNotSupported }
+func (f *fakeAdapter) StartPrint(PrintJob) error            { return ErrNotSupported }
 
 func readEntries(t *testing.T, path string) []HistoryEntry {
 	t.Helper()
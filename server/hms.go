@@ -0,0 +1,153 @@
+package server
+
+import (
+	_ "embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// hmsCatalogJSON is the bundled HMS catalog. Codes are verified against
+// https://wiki.bambulab.com/en/hms/home; add new ones as they turn up.
+//
+//go:embed hms_catalog.json
+var hmsCatalogJSON []byte
+
+// hmsOverrideFileName is the optional catalog (in the config dir) whose
+// entries replace or extend the bundled ones.
+const hmsOverrideFileName = "hms-codes.json"
+
+// HMSInfo is a catalog entry for an HMS code.
+type HMSInfo struct {
+	Code        string `json:"code"` // HMSCodeString form, e.g. "0C00-0300-0003-0008"
+	Description string `json:"description,omitempty"`
+	Severity    string `json:"severity,omitempty"` // "fatal", "serious", "common", or "info"
+	Action      string `json:"action,omitempty"`   // what to do about it
+}
+
+// Known reports whether the catalog has a description for the code.
+func (i HMSInfo) Known() bool {
+	return i.Description != ""
+}
+
+// Label is the description, or the bare code when the catalog doesn't know it.
+func (i HMSInfo) Label() string {
+	if i.Known() {
+		return i.Description
+	}
+	return i.Code
+}
+
+// Detail is the label followed by the suggested action, for notifications.
+func (i HMSInfo) Detail() string {
+	if i.Action == "" {
+		return i.Label()
+	}
+	return i.Label() + " — " + i.Action
+}
+
+var (
+	hmsMu      sync.RWMutex
+	hmsCatalog = mustParseHMSCatalog(hmsCatalogJSON)
+)
+
+func parseHMSCatalog(data []byte) (map[string]HMSInfo, error) {
+	var entries []HMSInfo
+	if err := json.Unmarshal(data, &entries); err != nil {
+		return nil, err
+	}
+	catalog := make(map[string]HMSInfo, len(entries))
+	for _, e := range entries {
+		code, err := ParseHMSCode(e.Code)
+		if err != nil {
+			return nil, err
+		}
+		e.Code = code.HMSCodeString()
+		catalog[e.Code] = e
+	}
+	return catalog, nil
+}
+
+func mustParseHMSCatalog(data []byte) map[string]HMSInfo {
+	catalog, err := parseHMSCatalog(data)
+	if err != nil {
+		panic(fmt.Sprintf("bundled HMS catalog: %v", err))
+	}
+	return catalog
+}
+
+// LoadHMSOverrides merges hms-codes.json from dir over the bundled catalog.
+// Each entry replaces the bundled one for its code. A missing file is not an
+// error; the count of entries loaded is returned.
+func LoadHMSOverrides(dir string) (int, error) {
+	data, err := os.ReadFile(filepath.Join(dir, hmsOverrideFileName))
+	if errors.Is(err, os.ErrNotExist) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+	overrides, err := parseHMSCatalog(data)
+	if err != nil {
+		return 0, fmt.Errorf("parse %s: %w", hmsOverrideFileName, err)
+	}
+
+	hmsMu.Lock()
+	defer hmsMu.Unlock()
+	for code, info := range overrides {
+		hmsCatalog[code] = info
+	}
+	return len(overrides), nil
+}
+
+// LookupHMS returns the catalog entry for a code in HMSCodeString form.
+func LookupHMS(code string) (HMSInfo, bool) {
+	hmsMu.RLock()
+	defer hmsMu.RUnlock()
+	info, ok := hmsCatalog[strings.ToUpper(strings.TrimSpace(code))]
+	return info, ok
+}
+
+// Info returns the catalog entry for h; an unknown code gets only Code.
+func (h HMSCode) Info() HMSInfo {
+	code := h.HMSCodeString()
+	if info, ok := LookupHMS(code); ok {
+		return info
+	}
+	return HMSInfo{Code: code}
+}
+
+// HMSDescription returns a human-friendly description for an HMS code,
+// or an empty string if the code is not recognized.
+func (h HMSCode) HMSDescription() string {
+	return h.Info().Description
+}
+
+// hmsInfos resolves codes for PrinterState and the event log.
+func hmsInfos(codes []HMSCode) []HMSInfo {
+	var infos []HMSInfo
+	for _, h := range codes {
+		infos = append(infos, h.Info())
+	}
+	return infos
+}
+
+// HMSLookupURL is Bambu's error-code page for a code, the same one Bambu
+// Studio opens from its HMS panel.
+func HMSLookupURL(code string) string {
+	return "https://e.bambulab.com/index.php?e=" + strings.ReplaceAll(code, "-", "") + "&s=device_hms&lang=en"
+}
+
+// logUnknownHMS prints a lookup link for each code the catalog doesn't know,
+// so it can be added to hms-codes.json.
+func logUnknownHMS(printer string, codes []HMSCode) {
+	for _, h := range codes {
+		if info := h.Info(); !info.Known() {
+			fmt.Printf("[hms] %s: unknown code %s, see %s\n", printer, info.Code, HMSLookupURL(info.Code))
+		}
+	}
+}
@@ -0,0 +1,84 @@
+package server
+
+import (
+	"maps"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestBundledHMSCatalog(t *testing.T) {
+	severities := map[string]bool{"fatal": true, "serious": true, "common": true, "info": true}
+	for code, info := range hmsCatalog {
+		if info.Description == "" || info.Action == "" {
+			t.Errorf("%s: missing description or action: %+v", code, info)
+		}
+		if !severities[info.Severity] {
+			t.Errorf("%s: severity %q", code, info.Severity)
+		}
+	}
+
+	info, ok := LookupHMS("0c00-0300-0003-0008")
+	if !ok || info.Severity != "serious" || info.Code != "0C00-0300-0003-0008" {
+		t.Errorf("LookupHMS lower-case = %+v, %v", info, ok)
+	}
+
+	unknown := HMSCode{Attr: 0x12340000, Code: 0x00010002}.Info()
+	if unknown.Known() || unknown.Code != "1234-0000-0001-0002" || unknown.Label() != unknown.Code {
+		t.Errorf("unknown = %+v", unknown)
+	}
+}
+
+func TestLoadHMSOverrides(t *testing.T) {
+	saved := maps.Clone(hmsCatalog)
+	t.Cleanup(func() { hmsCatalog = saved })
+
+	dir := t.TempDir()
+	if n, err := LoadHMSOverrides(dir); n != 0 || err != nil {
+		t.Fatalf("missing file: %d, %v", n, err)
+	}
+
+	data := `[
+  {"code": "0c00-0300-0003-0008", "description": "spaghetti", "severity": "fatal", "action": "Stop and clear the bed"},
+  {"code": "1234-0000-0001-0002", "description": "local code"}
+]`
+	if err := os.WriteFile(filepath.Join(dir, hmsOverrideFileName), []byte(data), 0644); err != nil {
+		t.Fatal(err)
+	}
+	n, err := LoadHMSOverrides(dir)
+	if err != nil || n != 2 {
+		t.Fatalf("LoadHMSOverrides = %d, %v", n, err)
+	}
+
+	spaghetti := HMSCode{Attr: 0x0C000300, Code: 0x00030008}.Info()
+	if spaghetti.Severity != "fatal" || spaghetti.Detail() != "spaghetti — Stop and clear the bed" {
+		t.Errorf("override not applied: %+v", spaghetti)
+	}
+	if d := (HMSCode{Attr: 0x12340000, Code: 0x00010002}).HMSDescription(); d != "local code" {
+		t.Errorf("added code = %q", d)
+	}
+	if _, ok := LookupHMS("0300-0D00-0001-0003"); !ok {
+		t.Error("bundled codes should survive an override")
+	}
+
+	if err := os.WriteFile(filepath.Join(dir, hmsOverrideFileName), []byte(`[{"code": "nope"}]`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := LoadHMSOverrides(dir); err == nil {
+		t.Error("expected an error for a bad code")
+	}
+}
+
+func TestBambuStatusCarriesHMS(t *testing.T) {
+	b := NewBambuAdapter("test", "127.0.0.1", "00M00A000000000", "12345678")
+	b.handleReport([]byte(`{"print":{"gcode_state":"PAUSE","hms":[{"attr":201327360,"code":196616}]}}`))
+	hms := b.Status().HMS
+	if len(hms) != 1 || hms[0].Code != "0C00-0300-0003-0008" || hms[0].Action == "" {
+		t.Errorf("HMS = %+v", hms)
+	}
+
+	b.handleReport([]byte(`{"print":{"gcode_state":"RUNNING","hms":[]}}`))
+	if hms := b.Status().HMS; len(hms) != 0 {
+		t.Errorf("HMS after clear = %+v", hms)
+	}
+}
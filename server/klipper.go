@@ -0,0 +1,479 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+const (
+	// klipperDefaultPort is Moonraker's default listen port, used when the
+	// configured ip has none.
+	klipperDefaultPort = "7125"
+	// klipperCallTimeout bounds how long a JSON-RPC request waits for its
+	// response before giving up.
+	klipperCallTimeout = 10 * time.Second
+)
+
+// klipperReconnectDelay is how long the adapter waits between attempts to
+// re-open a dropped Moonraker websocket. A var so tests can shorten it.
+var klipperReconnectDelay = 10 * time.Second
+
+// KlipperAdapter communicates with a Klipper printer via Moonraker's
+// JSON-RPC websocket. Status arrives as printer.objects subscription
+// updates; Moonraker only sends the fields that changed, so the adapter
+// keeps the merged objects and derives PrinterState from them.
+type KlipperAdapter struct {
+	name   string
+	host   string
+	apiKey string
+	// mmuMacro is the gcode macro that sets an MMU/ERCF gate's filament
+	// (e.g. Happy Hare's MMU_GATE_MAP). Empty means PushTray is unsupported.
+	mmuMacro string
+
+	mu             sync.RWMutex
+	conn           *websocket.Conn
+	state          PrinterState
+	objects        map[string]map[string]any
+	stateCallbacks []func(StateChangeEvent)
+	nextID         int
+	pending        map[int]chan klipperResponse
+
+	writeMu   sync.Mutex
+	stopCh    chan struct{}
+	closeOnce sync.Once
+}
+
+type klipperResponse struct {
+	Result json.RawMessage
+	Err    error
+}
+
+// klipperMessage is any frame Moonraker sends: a response to one of our
+// requests (ID set) or a notification (Method set).
+type klipperMessage struct {
+	ID     *int            `json:"id"`
+	Method string          `json:"method"`
+	Params json.RawMessage `json:"params"`
+	Result json.RawMessage `json:"result"`
+	Error  *struct {
+		Code    int    `json:"code"`
+		Message string `json:"message"`
+	} `json:"error"`
+}
+
+// NewKlipperAdapter creates a new Klipper (Moonraker) printer adapter. host
+// is the Moonraker address, with or without a port; apiKey and mmuMacro are
+// optional.
+func NewKlipperAdapter(name, host, apiKey, mmuMacro string) *KlipperAdapter {
+	if _, _, err := net.SplitHostPort(host); err != nil {
+		host = net.JoinHostPort(host, klipperDefaultPort)
+	}
+	return &KlipperAdapter{
+		name:     name,
+		host:     host,
+		apiKey:   apiKey,
+		mmuMacro: mmuMacro,
+		state: PrinterState{
+			Name:       name,
+			Type:       "klipper",
+			State:      "offline",
+			ActiveTray: -1,
+		},
+		objects: map[string]map[string]any{},
+		pending: map[int]chan klipperResponse{},
+		stopCh:  make(chan struct{}),
+	}
+}
+
+// Connect opens the websocket, subscribes to print status, and keeps the
+// connection alive in the background.
+func (k *KlipperAdapter) Connect() error {
+	done, err := k.open()
+	if err != nil {
+		return fmt.Errorf("klipper %s: connection failed: %w", k.name, err)
+	}
+	go k.reconnectLoop(done)
+	return nil
+}
+
+// Close shuts down the websocket and stops reconnecting.
+func (k *KlipperAdapter) Close() error {
+	k.closeOnce.Do(func() { close(k.stopCh) })
+	k.mu.RLock()
+	conn := k.conn
+	k.mu.RUnlock()
+	if conn != nil {
+		return conn.Close()
+	}
+	return nil
+}
+
+// Status returns the current printer state.
+func (k *KlipperAdapter) Status() PrinterState {
+	k.mu.RLock()
+	defer k.mu.RUnlock()
+	return k.state
+}
+
+// PushTray sets the filament on an MMU/ERCF gate through the configured
+// macro. The gate is the tray's slot (TrayID); Klipper has no per-tray
+// temperature or profile, so only material and color are sent.
+func (k *KlipperAdapter) PushTray(update TrayUpdate) error {
+	if k.mmuMacro == "" {
+		return fmt.Errorf("klipper %s: tray updates not supported", k.name)
+	}
+	color := strings.TrimPrefix(update.Color, "#")
+	if len(color) > 6 {
+		color = color[:6]
+	}
+	material := strings.ReplaceAll(strings.TrimSpace(update.Type), " ", "_")
+	script := fmt.Sprintf("%s GATE=%d MATERIAL=%s COLOR=%s", k.mmuMacro, update.TrayID, material, color)
+	if _, err := k.call("printer.gcode.script", map[string]any{"script": script}); err != nil {
+		return fmt.Errorf("klipper %s: %w", k.name, err)
+	}
+	return nil
+}
+
+// Pause pauses the current print.
+func (k *KlipperAdapter) Pause() error {
+	return k.control("printer.print.pause", nil)
+}
+
+// Resume resumes a paused print.
+func (k *KlipperAdapter) Resume() error {
+	return k.control("printer.print.resume", nil)
+}
+
+// Stop cancels the current print.
+func (k *KlipperAdapter) Stop() error {
+	return k.control("printer.print.cancel", nil)
+}
+
+// SetLight is not supported: Klipper lights are user-defined outputs with
+// no standard name.
+func (k *KlipperAdapter) SetLight(on bool) error {
+	return fmt.Errorf("klipper %s: light control %w", k.name, ErrNotSupported)
+}
+
+// SetSpeed applies the profile's percentage as a feed-rate override.
+func (k *KlipperAdapter) SetSpeed(profile SpeedProfile) error {
+	return k.control("printer.gcode.script", map[string]any{"script": fmt.Sprintf("M220 S%d", profile.Percent)})
+}
+
+// StartPrint is not supported on Klipper printers yet.
+func (k *KlipperAdapter) StartPrint(job PrintJob) error {
+	return fmt.Errorf("klipper %s: sending jobs %w", k.name, ErrNotSupported)
+}
+
+func (k *KlipperAdapter) control(method string, params any) error {
+	if _, err := k.call(method, params); err != nil {
+		return fmt.Errorf("klipper %s: %w", k.name, err)
+	}
+	return nil
+}
+
+// Restore seeds the state from a snapshot saved before a restart.
+func (k *KlipperAdapter) Restore(snap PrinterSnapshot) {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	k.state.restore(snap)
+}
+
+// OnStateChange registers a callback for printer state transitions.
+func (k *KlipperAdapter) OnStateChange(cb func(event StateChangeEvent)) {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	k.stateCallbacks = append(k.stateCallbacks, cb)
+}
+
+// open dials Moonraker, starts reading, and subscribes. The returned channel
+// closes when the connection drops.
+func (k *KlipperAdapter) open() (<-chan struct{}, error) {
+	header := http.Header{}
+	if k.apiKey != "" {
+		header.Set("X-Api-Key", k.apiKey)
+	}
+	dialer := websocket.Dialer{HandshakeTimeout: 5 * time.Second}
+	conn, _, err := dialer.Dial(fmt.Sprintf("ws://%s/websocket", k.host), header)
+	if err != nil {
+		return nil, err
+	}
+	k.mu.Lock()
+	k.conn = conn
+	k.mu.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		k.readLoop(conn)
+		close(done)
+	}()
+
+	if err := k.subscribe(); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	return done, nil
+}
+
+// reconnectLoop waits for the connection to drop, marks the printer
+// offline, and re-opens it until Close is called.
+func (k *KlipperAdapter) reconnectLoop(done <-chan struct{}) {
+	for {
+		select {
+		case <-k.stopCh:
+			return
+		case <-done:
+		}
+		k.markOffline()
+		for {
+			select {
+			case <-k.stopCh:
+				return
+			case <-time.After(klipperReconnectDelay):
+			}
+			if d, err := k.open(); err == nil {
+				done = d
+				break
+			}
+		}
+	}
+}
+
+func (k *KlipperAdapter) readLoop(conn *websocket.Conn) {
+	for {
+		_, data, err := conn.ReadMessage()
+		if err != nil {
+			return
+		}
+		k.handleMessage(data)
+	}
+}
+
+func (k *KlipperAdapter) handleMessage(data []byte) {
+	var msg klipperMessage
+	if err := json.Unmarshal(data, &msg); err != nil {
+		return
+	}
+
+	if msg.ID != nil && msg.Method == "" {
+		k.mu.Lock()
+		ch, ok := k.pending[*msg.ID]
+		delete(k.pending, *msg.ID)
+		k.mu.Unlock()
+		if !ok {
+			return
+		}
+		resp := klipperResponse{Result: msg.Result}
+		if msg.Error != nil {
+			resp.Err = fmt.Errorf("%s (%d)", msg.Error.Message, msg.Error.Code)
+		}
+		ch <- resp
+		return
+	}
+
+	switch msg.Method {
+	case "notify_status_update":
+		// params: [{object: {field: value}}, eventtime]
+		var params []json.RawMessage
+		if err := json.Unmarshal(msg.Params, &params); err != nil || len(params) == 0 {
+			return
+		}
+		var status map[string]map[string]any
+		if err := json.Unmarshal(params[0], &status); err != nil {
+			return
+		}
+		k.applyStatus(status)
+	case "notify_klippy_disconnected", "notify_klippy_shutdown":
+		k.markOffline()
+	case "notify_klippy_ready":
+		// Klipper restarts drop the subscription; re-establish it.
+		go func() { _ = k.subscribe() }()
+	}
+}
+
+// call sends a JSON-RPC request and waits for its response.
+func (k *KlipperAdapter) call(method string, params any) (json.RawMessage, error) {
+	k.mu.Lock()
+	conn := k.conn
+	k.nextID++
+	id := k.nextID
+	ch := make(chan klipperResponse, 1)
+	k.pending[id] = ch
+	k.mu.Unlock()
+
+	if conn == nil {
+		k.dropPending(id)
+		return nil, fmt.Errorf("not connected to %s", k.name)
+	}
+
+	req := map[string]any{"jsonrpc": "2.0", "method": method, "params": params, "id": id}
+	k.writeMu.Lock()
+	err := conn.WriteJSON(req)
+	k.writeMu.Unlock()
+	if err != nil {
+		k.dropPending(id)
+		return nil, err
+	}
+
+	select {
+	case resp := <-ch:
+		return resp.Result, resp.Err
+	case <-time.After(klipperCallTimeout):
+		k.dropPending(id)
+		return nil, fmt.Errorf("%s: timed out", method)
+	}
+}
+
+func (k *KlipperAdapter) dropPending(id int) {
+	k.mu.Lock()
+	delete(k.pending, id)
+	k.mu.Unlock()
+}
+
+// subscribe asks Moonraker for print status updates and applies the initial
+// snapshot that comes back with the subscription.
+func (k *KlipperAdapter) subscribe() error {
+	result, err := k.call("printer.objects.subscribe", map[string]any{
+		"objects": map[string]any{
+			"print_stats":    nil,
+			"display_status": nil,
+			"virtual_sdcard": nil,
+		},
+	})
+	if err != nil {
+		return fmt.Errorf("subscribe: %w", err)
+	}
+	var snapshot struct {
+		Status map[string]map[string]any `json:"status"`
+	}
+	if err := json.Unmarshal(result, &snapshot); err != nil {
+		return fmt.Errorf("subscribe: %w", err)
+	}
+	k.applyStatus(snapshot.Status)
+	return nil
+}
+
+func (k *KlipperAdapter) markOffline() {
+	k.mu.Lock()
+	k.state.State = "offline"
+	k.mu.Unlock()
+}
+
+// applyStatus merges a (partial) printer.objects update, refreshes the
+// derived PrinterState, and fires callbacks on a state transition.
+func (k *KlipperAdapter) applyStatus(update map[string]map[string]any) {
+	k.mu.Lock()
+	oldState := k.state.State
+	oldProgress := k.state.Progress
+	oldLayer := k.state.Layer
+	oldTotalLayers := k.state.TotalLayers
+
+	for obj, fields := range update {
+		if k.objects[obj] == nil {
+			k.objects[obj] = map[string]any{}
+		}
+		mergeKlipperFields(k.objects[obj], fields)
+	}
+	k.deriveState()
+	k.state.LastUpdated = time.Now()
+
+	// Same offline suppression as the other adapters: offline->X is the
+	// first observation after (re)connect, not a real transition.
+	transition := k.state.State != oldState && oldState != "" && oldState != "offline"
+	if transition && k.state.State == "finished" {
+		k.state.LastFinishedAt = time.Now()
+	}
+	var callbacks []func(StateChangeEvent)
+	if transition {
+		callbacks = k.stateCallbacks
+	}
+	event := StateChangeEvent{
+		OldState:    oldState,
+		NewState:    k.state.State,
+		Progress:    max(oldProgress, k.state.Progress),
+		Layer:       max(oldLayer, k.state.Layer),
+		TotalLayers: max(oldTotalLayers, k.state.TotalLayers),
+	}
+	k.mu.Unlock()
+
+	for _, cb := range callbacks {
+		go cb(event)
+	}
+}
+
+// mergeKlipperFields copies src into dst, descending into nested objects
+// (print_stats.info) so a partial update doesn't drop sibling fields.
+func mergeKlipperFields(dst, src map[string]any) {
+	for key, v := range src {
+		if sub, ok := v.(map[string]any); ok {
+			if existing, ok := dst[key].(map[string]any); ok {
+				mergeKlipperFields(existing, sub)
+				continue
+			}
+		}
+		dst[key] = v
+	}
+}
+
+// deriveState rebuilds the PrinterState fields from the merged objects.
+// Caller holds k.mu.
+func (k *KlipperAdapter) deriveState() {
+	stats := k.objects["print_stats"]
+	if s, ok := stats["state"].(string); ok {
+		k.state.State = normalizeKlipperState(s)
+	}
+	if f, ok := stats["filename"].(string); ok {
+		k.state.CurrentFile = f
+	}
+
+	// display_status follows M73 when the slicer emits it; virtual_sdcard
+	// is file position and the fallback.
+	progress, _ := k.objects["display_status"]["progress"].(float64)
+	if progress <= 0 {
+		progress, _ = k.objects["virtual_sdcard"]["progress"].(float64)
+	}
+	k.state.Progress = int(progress * 100)
+
+	if info, ok := stats["info"].(map[string]any); ok {
+		if l, ok := info["current_layer"].(float64); ok {
+			k.state.Layer = int(l)
+		}
+		if l, ok := info["total_layer"].(float64); ok {
+			k.state.TotalLayers = int(l)
+		}
+	}
+
+	k.state.RemainingMins = 0
+	if k.state.State == "printing" || k.state.State == "paused" {
+		// Moonraker has no remaining-time field; extrapolate from the
+		// printing time so far.
+		if elapsed, ok := stats["print_duration"].(float64); ok && progress > 0 {
+			k.state.RemainingMins = int((elapsed/progress - elapsed) / 60)
+		}
+	}
+}
+
+// normalizeKlipperState converts print_stats.state values to normalized states.
+func normalizeKlipperState(state string) string {
+	switch state {
+	case "printing":
+		return "printing"
+	case "paused":
+		return "paused"
+	case "complete":
+		return "finished"
+	case "error":
+		return "failed"
+	default:
+		// "standby", and "cancelled": a cancel drops back to idle, which
+		// auto-fail already treats as an aborted print when under 100%.
+		return "idle"
+	}
+}
@@ -0,0 +1,221 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// fakeMoonraker is a minimal Moonraker websocket: it answers
+// printer.objects.subscribe with a snapshot, records gcode scripts, and lets
+// the test push notify_status_update frames.
+type fakeMoonraker struct {
+	t        *testing.T
+	srv      *httptest.Server
+	snapshot map[string]any
+
+	mu      sync.Mutex
+	conn    *websocket.Conn
+	apiKey  string
+	scripts []string
+	ready   chan struct{}
+}
+
+func newFakeMoonraker(t *testing.T, snapshot map[string]any) *fakeMoonraker {
+	f := &fakeMoonraker{t: t, snapshot: snapshot, ready: make(chan struct{})}
+	upgrader := websocket.Upgrader{}
+	f.srv = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/websocket" {
+			http.NotFound(w, r)
+			return
+		}
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+		f.mu.Lock()
+		f.conn = conn
+		f.apiKey = r.Header.Get("X-Api-Key")
+		f.mu.Unlock()
+		f.serve(conn)
+	}))
+	t.Cleanup(f.srv.Close)
+	return f
+}
+
+func (f *fakeMoonraker) host() string {
+	return strings.TrimPrefix(f.srv.URL, "http://")
+}
+
+func (f *fakeMoonraker) serve(conn *websocket.Conn) {
+	for {
+		var req struct {
+			ID     int            `json:"id"`
+			Method string         `json:"method"`
+			Params map[string]any `json:"params"`
+		}
+		if err := conn.ReadJSON(&req); err != nil {
+			return
+		}
+		var result any = "ok"
+		switch req.Method {
+		case "printer.objects.subscribe":
+			result = map[string]any{"eventtime": 1.0, "status": f.snapshot}
+		case "printer.gcode.script":
+			f.mu.Lock()
+			f.scripts = append(f.scripts, req.Params["script"].(string))
+			f.mu.Unlock()
+		}
+		f.write(map[string]any{"jsonrpc": "2.0", "id": req.ID, "result": result})
+		if req.Method == "printer.objects.subscribe" {
+			close(f.ready)
+		}
+	}
+}
+
+func (f *fakeMoonraker) write(v any) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err := f.conn.WriteJSON(v); err != nil {
+		f.t.Errorf("fake moonraker write: %v", err)
+	}
+}
+
+func (f *fakeMoonraker) notify(status map[string]any) {
+	f.write(map[string]any{"jsonrpc": "2.0", "method": "notify_status_update", "params": []any{status, 2.0}})
+}
+
+func waitFor(t *testing.T, what string, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for %s", what)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func TestKlipperAdapterTracksPrint(t *testing.T) {
+	fake := newFakeMoonraker(t, map[string]any{
+		"print_stats":    map[string]any{"state": "standby", "filename": ""},
+		"display_status": map[string]any{"progress": 0.0},
+		"virtual_sdcard": map[string]any{"progress": 0.0},
+	})
+
+	k := NewKlipperAdapter("Voron", fake.host(), "secret", "")
+	var mu sync.Mutex
+	var events []StateChangeEvent
+	k.OnStateChange(func(e StateChangeEvent) {
+		mu.Lock()
+		events = append(events, e)
+		mu.Unlock()
+	})
+	if err := k.Connect(); err != nil {
+		t.Fatalf("Connect: %v", err)
+	}
+	defer k.Close()
+	<-fake.ready
+
+	if got := k.Status().State; got != "idle" {
+		t.Fatalf("initial state = %q, want idle", got)
+	}
+	if fake.apiKey != "secret" {
+		t.Errorf("X-Api-Key = %q, want secret", fake.apiKey)
+	}
+
+	fake.notify(map[string]any{
+		"print_stats": map[string]any{
+			"state": "printing", "filename": "bracket.gcode", "print_duration": 1800.0,
+			"info": map[string]any{"current_layer": 10, "total_layer": 40},
+		},
+		"display_status": map[string]any{"progress": 0.25},
+	})
+	waitFor(t, "printing", func() bool { return k.Status().State == "printing" })
+
+	st := k.Status()
+	if st.CurrentFile != "bracket.gcode" || st.Progress != 25 || st.Layer != 10 || st.TotalLayers != 40 {
+		t.Errorf("status = %+v, want bracket.gcode at 25%%, layer 10/40", st)
+	}
+	if st.RemainingMins != 90 {
+		t.Errorf("RemainingMins = %d, want 90 (30 min for 25%%)", st.RemainingMins)
+	}
+
+	// Partial update: only the layer changes; total_layer must survive.
+	fake.notify(map[string]any{"print_stats": map[string]any{"info": map[string]any{"current_layer": 39}}})
+	waitFor(t, "layer 39", func() bool { return k.Status().Layer == 39 })
+	if got := k.Status().TotalLayers; got != 40 {
+		t.Errorf("TotalLayers after partial update = %d, want 40", got)
+	}
+
+	fake.notify(map[string]any{
+		"print_stats":    map[string]any{"state": "complete"},
+		"display_status": map[string]any{"progress": 1.0},
+	})
+	waitFor(t, "finished", func() bool { return k.Status().State == "finished" })
+	if k.Status().LastFinishedAt.IsZero() {
+		t.Error("LastFinishedAt not stamped on printing->finished")
+	}
+
+	waitFor(t, "two callbacks", func() bool {
+		mu.Lock()
+		defer mu.Unlock()
+		return len(events) == 2
+	})
+	mu.Lock()
+	defer mu.Unlock()
+	var finish StateChangeEvent
+	for _, e := range events {
+		if e.NewState == "finished" {
+			finish = e
+		}
+	}
+	if finish.OldState != "printing" || finish.Progress != 100 || finish.Layer != 39 {
+		t.Errorf("finish event = %+v, want printing->finished at 100%%, layer 39", finish)
+	}
+}
+
+func TestKlipperAdapterPushTray(t *testing.T) {
+	fake := newFakeMoonraker(t, map[string]any{"print_stats": map[string]any{"state": "standby"}})
+
+	plain := NewKlipperAdapter("Voron", fake.host(), "", "")
+	if err := plain.PushTray(TrayUpdate{TrayID: 1, Color: "FF0000FF", Type: "PLA"}); err == nil ||
+		!strings.Contains(err.Error(), "not supported") {
+		t.Errorf("PushTray without mmu_macro = %v, want unsupported error", err)
+	}
+
+	k := NewKlipperAdapter("Voron", fake.host(), "", "MMU_GATE_MAP")
+	if err := k.Connect(); err != nil {
+		t.Fatalf("Connect: %v", err)
+	}
+	defer k.Close()
+
+	if err := k.PushTray(TrayUpdate{TrayID: 2, Color: "FF8800FF", Type: "Matte PLA"}); err != nil {
+		t.Fatalf("PushTray: %v", err)
+	}
+	fake.mu.Lock()
+	defer fake.mu.Unlock()
+	want := "MMU_GATE_MAP GATE=2 MATERIAL=Matte_PLA COLOR=FF8800"
+	if len(fake.scripts) != 1 || fake.scripts[0] != want {
+		t.Errorf("scripts = %q, want [%q]", fake.scripts, want)
+	}
+}
+
+func TestKlipperStateMessageParsing(t *testing.T) {
+	k := NewKlipperAdapter("Voron", "10.0.0.5", "", "")
+	if k.host != "10.0.0.5:7125" {
+		t.Errorf("host = %q, want default Moonraker port", k.host)
+	}
+	msg, _ := json.Marshal(map[string]any{"jsonrpc": "2.0", "method": "notify_klippy_shutdown"})
+	k.state.State = "printing"
+	k.handleMessage(msg)
+	if got := k.Status().State; got != "offline" {
+		t.Errorf("state after klippy shutdown = %q, want offline", got)
+	}
+}
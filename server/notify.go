@@ -55,7 +55,32 @@ func (n *Notifier) Send(title, message string) []error {
 	}
 
 	if n.config.NtfyTopic != "" {
-		if err := n.sendNtfy(title, message); err != nil {
+		if err := n.sendNtfy(title, message, ""); err != nil {
+			errs = append(errs, fmt.Errorf("ntfy: %w", err))
+		}
+	}
+
+	return errs
+}
+
+// SendAction is Send with a one-tap button. ntfy shows it as an HTTP POST to
+// actionURL; Pushover, whose links only open a browser, gets the message
+// alone, so message should say how to act on it from the CLI too.
+func (n *Notifier) SendAction(title, message, label, actionURL string) []error {
+	var errs []error
+
+	if n.config.PushoverAPIKey != "" && n.config.PushoverUserKey != "" {
+		if err := n.sendPushover(title, message); err != nil {
+			errs = append(errs, fmt.Errorf("pushover: %w", err))
+		}
+	}
+
+	if n.config.NtfyTopic != "" {
+		action := ""
+		if actionURL != "" {
+			action = fmt.Sprintf("http, %s, %s, method=POST, clear=true", label, actionURL)
+		}
+		if err := n.sendNtfy(title, message, action); err != nil {
 			errs = append(errs, fmt.Errorf("ntfy: %w", err))
 		}
 	}
@@ -161,7 +186,7 @@ func (n *Notifier) TestAll(message string, results map[string]string) {
 	}
 
 	if n.config.NtfyTopic != "" {
-		if err := n.sendNtfy("Fil test", message); err != nil {
+		if err := n.sendNtfy("Fil test", message, ""); err != nil {
 			results["ntfy"] = "error: " + err.Error()
 		} else {
 			results["ntfy"] = "sent"
@@ -228,7 +253,8 @@ func (n *Notifier) sendVoiceMonkey(text string) error {
 	return nil
 }
 
-func (n *Notifier) sendNtfy(title, message string) error {
+// sendNtfy posts a message; actions, when set, is an ntfy Actions header.
+func (n *Notifier) sendNtfy(title, message, actions string) error {
 	ntfyServer := n.config.NtfyServer
 	if ntfyServer == "" {
 		ntfyServer = "https://ntfy.sh"
@@ -241,6 +267,9 @@ func (n *Notifier) sendNtfy(title, message string) error {
 		return err
 	}
 	req.Header.Set("Title", title)
+	if actions != "" {
+		req.Header.Set("Actions", actions)
+	}
 
 	resp, err := http.DefaultClient.Do(req)
 	if err != nil {
@@ -104,3 +104,24 @@ func TestValidateVoiceMonkey(t *testing.T) {
 		t.Errorf("expected nil when both configured, got %v", err)
 	}
 }
+
+func TestSendActionSetsNtfyActionsHeader(t *testing.T) {
+	var gotTitle, gotActions string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotTitle = r.Header.Get("Title")
+		gotActions = r.Header.Get("Actions")
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer ts.Close()
+
+	n := NewNotifier(NotificationConfig{NtfyTopic: "fil", NtfyServer: ts.URL})
+	if errs := n.SendAction("Spool swap detected", "msg", "Move #3 to AMS A:2", "http://fil.local/api/fil/pending/x/accept"); len(errs) > 0 {
+		t.Fatalf("SendAction: %v", errs)
+	}
+	if gotTitle != "Spool swap detected" {
+		t.Errorf("title = %q", gotTitle)
+	}
+	if want := "http, Move #3 to AMS A:2, http://fil.local/api/fil/pending/x/accept, method=POST, clear=true"; gotActions != want {
+		t.Errorf("actions = %q, want %q", gotActions, want)
+	}
+}
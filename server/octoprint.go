@@ -0,0 +1,439 @@
+package server
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// octoPrintReconnectDelay is how long the push listener waits before
+// re-opening a dropped SockJS socket. A var so tests can shorten it.
+var octoPrintReconnectDelay = 10 * time.Second
+
+// OctoPrintAdapter communicates with a printer behind OctoPrint. It polls
+// /api/printer and /api/job like the Prusa adapter polls PrusaLink and,
+// when push is on, also listens on OctoPrint's SockJS socket for the same
+// "current" payloads so transitions arrive within a second instead of at
+// the next poll.
+type OctoPrintAdapter struct {
+	name    string
+	baseURL string
+	apiKey  string
+	push    bool
+	client  *http.Client
+
+	mu             sync.RWMutex
+	state          PrinterState
+	stateCallbacks []func(StateChangeEvent)
+	conn           *websocket.Conn
+	stopCh         chan struct{}
+	closeOnce      sync.Once
+}
+
+// NewOctoPrintAdapter creates a new OctoPrint printer adapter. host is the
+// OctoPrint address, optionally with a port or an http(s):// prefix.
+func NewOctoPrintAdapter(name, host, apiKey string, push bool) *OctoPrintAdapter {
+	base := strings.TrimRight(host, "/")
+	if !strings.HasPrefix(base, "http://") && !strings.HasPrefix(base, "https://") {
+		base = "http://" + base
+	}
+	return &OctoPrintAdapter{
+		name:    name,
+		baseURL: base,
+		apiKey:  apiKey,
+		push:    push,
+		client:  &http.Client{Timeout: 5 * time.Second},
+		state: PrinterState{
+			Name:       name,
+			Type:       "octoprint",
+			State:      "offline",
+			ActiveTray: -1,
+		},
+		stopCh: make(chan struct{}),
+	}
+}
+
+// Connect polls once to verify the API key, then keeps polling (and, with
+// push on, listening) in the background.
+func (o *OctoPrintAdapter) Connect() error {
+	if err := o.poll(); err != nil {
+		return fmt.Errorf("octoprint %s: initial poll failed: %w", o.name, err)
+	}
+	go o.pollLoop()
+	if o.push {
+		go o.pushLoop()
+	}
+	return nil
+}
+
+// Close stops polling and the push listener.
+func (o *OctoPrintAdapter) Close() error {
+	o.closeOnce.Do(func() { close(o.stopCh) })
+	o.mu.RLock()
+	conn := o.conn
+	o.mu.RUnlock()
+	if conn != nil {
+		return conn.Close()
+	}
+	return nil
+}
+
+// Status returns the current printer state.
+func (o *OctoPrintAdapter) Status() PrinterState {
+	o.mu.RLock()
+	defer o.mu.RUnlock()
+	return o.state
+}
+
+// PushTray is not supported on OctoPrint printers.
+func (o *OctoPrintAdapter) PushTray(update TrayUpdate) error {
+	return fmt.Errorf("octoprint %s: tray updates not supported", o.name)
+}
+
+// Pause pauses the current job.
+func (o *OctoPrintAdapter) Pause() error {
+	return o.command("/api/job", map[string]any{"command": "pause", "action": "pause"})
+}
+
+// Resume resumes the paused job.
+func (o *OctoPrintAdapter) Resume() error {
+	return o.command("/api/job", map[string]any{"command": "pause", "action": "resume"})
+}
+
+// Stop cancels the current job.
+func (o *OctoPrintAdapter) Stop() error {
+	return o.command("/api/job", map[string]any{"command": "cancel"})
+}
+
+// SetLight is not supported on OctoPrint printers.
+func (o *OctoPrintAdapter) SetLight(on bool) error {
+	return fmt.Errorf("octoprint %s: light control %w", o.name, ErrNotSupported)
+}
+
+// SetSpeed applies the profile's percentage as a feed-rate override.
+func (o *OctoPrintAdapter) SetSpeed(profile SpeedProfile) error {
+	return o.command("/api/printer/command", map[string]any{"commands": []string{fmt.Sprintf("M220 S%d", profile.Percent)}})
+}
+
+// StartPrint is not supported on OctoPrint printers yet.
+func (o *OctoPrintAdapter) StartPrint(job PrintJob) error {
+	return fmt.Errorf("octoprint %s: sending jobs %w", o.name, ErrNotSupported)
+}
+
+// command POSTs a JSON command; OctoPrint answers 204 on success and 409
+// when the printer isn't in a state that allows it.
+func (o *OctoPrintAdapter) command(path string, body any) error {
+	data, err := json.Marshal(body)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequest(http.MethodPost, o.baseURL+path, bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("X-Api-Key", o.apiKey)
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := o.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("octoprint %s: %w", o.name, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusNoContent && resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("octoprint %s: %s: HTTP %d", o.name, path, resp.StatusCode)
+	}
+	return nil
+}
+
+// Restore seeds the state from a snapshot saved before a restart.
+func (o *OctoPrintAdapter) Restore(snap PrinterSnapshot) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.state.restore(snap)
+}
+
+// OnStateChange registers a callback for printer state transitions.
+func (o *OctoPrintAdapter) OnStateChange(cb func(event StateChangeEvent)) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.stateCallbacks = append(o.stateCallbacks, cb)
+}
+
+func (o *OctoPrintAdapter) pollLoop() {
+	ticker := time.NewTicker(30 * time.Second)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-o.stopCh:
+			return
+		case <-ticker.C:
+			_ = o.poll()
+		}
+	}
+}
+
+func (o *OctoPrintAdapter) poll() error {
+	var printer struct {
+		State octoPrintState `json:"state"`
+	}
+	status, err := o.fetch("/api/printer", &printer)
+	if err != nil {
+		o.setOffline()
+		return err
+	}
+	if status == http.StatusConflict {
+		// OctoPrint is up but not connected to the printer's serial port.
+		o.setOffline()
+		return nil
+	}
+
+	var job octoPrintJob
+	if _, err := o.fetch("/api/job", &job); err != nil {
+		o.setOffline()
+		return err
+	}
+	job.State = printer.State
+	o.handleCurrent(job)
+	return nil
+}
+
+func (o *OctoPrintAdapter) setOffline() {
+	o.mu.Lock()
+	o.state.State = "offline"
+	o.mu.Unlock()
+}
+
+// octoPrintState is the state block shared by /api/printer and the push
+// socket's "current" message.
+type octoPrintState struct {
+	Text  string          `json:"text"`
+	Flags map[string]bool `json:"flags"`
+}
+
+// octoPrintJob mirrors /api/job and the push socket's "current" message;
+// /api/job's own state is a bare string, so poll() fills State from
+// /api/printer instead.
+type octoPrintJob struct {
+	State octoPrintState `json:"-"`
+	Job   struct {
+		File struct {
+			Name    string `json:"name"`
+			Display string `json:"display"`
+		} `json:"file"`
+	} `json:"job"`
+	Progress struct {
+		Completion    *float64 `json:"completion"`
+		PrintTimeLeft *float64 `json:"printTimeLeft"`
+	} `json:"progress"`
+}
+
+// handleCurrent applies a job/state snapshot from either the poll or the
+// push socket and fires the state-change callbacks if it is a transition.
+func (o *OctoPrintAdapter) handleCurrent(cur octoPrintJob) {
+	event, fire := o.applyCurrent(cur)
+	if !fire {
+		return
+	}
+	o.mu.RLock()
+	callbacks := o.stateCallbacks
+	o.mu.RUnlock()
+	for _, cb := range callbacks {
+		go cb(event)
+	}
+}
+
+// applyCurrent updates the printer state from a job/state snapshot and
+// returns the event callbacks should see, if any. The event is built under
+// the same lock as the update, so a poll and a push message racing each
+// other can't report a transition neither of them saw. Extracted from poll()
+// so the transition logic is testable without HTTP mocks.
+func (o *OctoPrintAdapter) applyCurrent(cur octoPrintJob) (StateChangeEvent, bool) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	oldState := o.state.State
+	o.state.LastUpdated = time.Now()
+
+	completion := 0.0
+	if cur.Progress.Completion != nil {
+		completion = *cur.Progress.Completion
+	}
+	o.state.State = normalizeOctoPrintState(cur.State.Flags, completion)
+	if cur.Job.File.Display != "" {
+		o.state.CurrentFile = cur.Job.File.Display
+	} else {
+		o.state.CurrentFile = cur.Job.File.Name
+	}
+	// Keep the last job's progress through the transition out of it, like
+	// the Prusa adapter, so auto-fail sees how far it got.
+	if o.state.State == "printing" || o.state.State == "paused" {
+		o.state.Progress = int(completion)
+		o.state.RemainingMins = 0
+		if cur.Progress.PrintTimeLeft != nil {
+			o.state.RemainingMins = int(*cur.Progress.PrintTimeLeft) / 60
+		}
+	}
+
+	// See PrusaAdapter.applyStatusUpdate for why offline is excluded.
+	if o.state.State == "finished" && oldState != "finished" && oldState != "" && oldState != "offline" {
+		o.state.LastFinishedAt = time.Now()
+	}
+	// offline->X is suppressed as in the other adapters.
+	if o.state.State == oldState || oldState == "" || oldState == "offline" {
+		return StateChangeEvent{}, false
+	}
+	return StateChangeEvent{
+		OldState: oldState,
+		NewState: o.state.State,
+		Progress: o.state.Progress,
+	}, true
+}
+
+// normalizeOctoPrintState converts OctoPrint's state flags to normalized
+// states. OctoPrint has no finished state: a completed job leaves the
+// printer operational with the job still at 100%, while a cancel leaves it
+// short of 100% and reads as idle.
+func normalizeOctoPrintState(flags map[string]bool, completion float64) string {
+	switch {
+	case flags["error"]:
+		return "failed"
+	case flags["closedOrError"]:
+		return "offline"
+	case flags["paused"] || flags["pausing"]:
+		return "paused"
+	case flags["printing"] || flags["cancelling"] || flags["finishing"]:
+		return "printing"
+	case completion >= 100:
+		return "finished"
+	default:
+		return "idle"
+	}
+}
+
+// fetch GETs path into v, returning the HTTP status. 409 is returned
+// without an error: OctoPrint uses it for "printer not connected".
+func (o *OctoPrintAdapter) fetch(path string, v any) (int, error) {
+	req, err := http.NewRequest(http.MethodGet, o.baseURL+path, nil)
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("X-Api-Key", o.apiKey)
+
+	resp, err := o.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusConflict {
+		return resp.StatusCode, nil
+	}
+	if resp.StatusCode != http.StatusOK {
+		return resp.StatusCode, fmt.Errorf("HTTP %d", resp.StatusCode)
+	}
+	return resp.StatusCode, json.NewDecoder(resp.Body).Decode(v)
+}
+
+// pushLoop keeps the SockJS listener running until Close, reconnecting
+// after octoPrintReconnectDelay. Polling carries on regardless, so a dead
+// socket only costs latency.
+func (o *OctoPrintAdapter) pushLoop() {
+	for {
+		if err := o.listen(); err != nil {
+			fmt.Printf("[octoprint] %s: push socket: %v\n", o.name, err)
+		}
+		select {
+		case <-o.stopCh:
+			return
+		case <-time.After(octoPrintReconnectDelay):
+		}
+	}
+}
+
+// listen opens OctoPrint's raw SockJS websocket, authenticates it with a
+// passive login session, and applies every "current" message until the
+// socket closes.
+func (o *OctoPrintAdapter) listen() error {
+	auth, err := o.login()
+	if err != nil {
+		return fmt.Errorf("login: %w", err)
+	}
+
+	wsURL := "ws" + strings.TrimPrefix(o.baseURL, "http") + "/sockjs/websocket"
+	dialer := websocket.Dialer{HandshakeTimeout: 5 * time.Second}
+	conn, _, err := dialer.Dial(wsURL, nil)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	if err := conn.WriteJSON(map[string]string{"auth": auth}); err != nil {
+		return err
+	}
+
+	o.mu.Lock()
+	o.conn = conn
+	o.mu.Unlock()
+	select {
+	case <-o.stopCh:
+		return nil
+	default:
+	}
+
+	for {
+		var msg struct {
+			Current *struct {
+				State octoPrintState `json:"state"`
+				octoPrintJob
+			} `json:"current"`
+		}
+		if err := conn.ReadJSON(&msg); err != nil {
+			select {
+			case <-o.stopCh:
+				return nil
+			default:
+				return err
+			}
+		}
+		if msg.Current == nil {
+			continue
+		}
+		cur := msg.Current.octoPrintJob
+		cur.State = msg.Current.State
+		o.handleCurrent(cur)
+	}
+}
+
+// login starts a passive session with the API key and returns the
+// "user:session" token the push socket expects.
+func (o *OctoPrintAdapter) login() (string, error) {
+	req, err := http.NewRequest(http.MethodPost, o.baseURL+"/api/login", bytes.NewBufferString(`{"passive":true}`))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("X-Api-Key", o.apiKey)
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := o.client.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("HTTP %d", resp.StatusCode)
+	}
+
+	var session struct {
+		Name    string `json:"name"`
+		Session string `json:"session"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&session); err != nil {
+		return "", err
+	}
+	return session.Name + ":" + session.Session, nil
+}
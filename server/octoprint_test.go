@@ -0,0 +1,195 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/gorilla/websocket"
+)
+
+// fakeOctoPrint serves /api/printer, /api/job, /api/login and the SockJS
+// websocket from mutable flags and job progress.
+type fakeOctoPrint struct {
+	mu         sync.Mutex
+	flags      map[string]bool
+	completion any // float64 or nil
+	timeLeft   any
+	conflict   bool
+	auth       string
+	socket     chan *websocket.Conn
+}
+
+func newFakeOctoPrint(t *testing.T) (*fakeOctoPrint, *httptest.Server) {
+	f := &fakeOctoPrint{flags: map[string]bool{"operational": true, "ready": true}, socket: make(chan *websocket.Conn, 1)}
+	upgrader := websocket.Upgrader{}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/sockjs/websocket" && r.Header.Get("X-Api-Key") != "key" {
+			http.Error(w, "bad key", http.StatusForbidden)
+			return
+		}
+		f.mu.Lock()
+		defer f.mu.Unlock()
+		switch r.URL.Path {
+		case "/api/printer":
+			if f.conflict {
+				http.Error(w, "Printer is not operational", http.StatusConflict)
+				return
+			}
+			_ = json.NewEncoder(w).Encode(map[string]any{"state": map[string]any{"text": "x", "flags": f.flags}})
+		case "/api/job":
+			_ = json.NewEncoder(w).Encode(f.job())
+		case "/api/login":
+			_ = json.NewEncoder(w).Encode(map[string]any{"name": "_api", "session": "abc"})
+		case "/sockjs/websocket":
+			f.mu.Unlock()
+			conn, err := upgrader.Upgrade(w, r, nil)
+			f.mu.Lock()
+			if err != nil {
+				return
+			}
+			var auth map[string]string
+			if err := conn.ReadJSON(&auth); err == nil {
+				f.auth = auth["auth"]
+			}
+			f.socket <- conn
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	t.Cleanup(srv.Close)
+	return f, srv
+}
+
+func (f *fakeOctoPrint) job() map[string]any {
+	return map[string]any{
+		"job":      map[string]any{"file": map[string]any{"name": "bracket.gcode", "display": "Bracket.gcode"}},
+		"progress": map[string]any{"completion": f.completion, "printTimeLeft": f.timeLeft},
+		"state":    "Operational",
+	}
+}
+
+func (f *fakeOctoPrint) set(flags map[string]bool, completion, timeLeft any) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.flags, f.completion, f.timeLeft = flags, completion, timeLeft
+}
+
+func TestOctoPrintPollTransitions(t *testing.T) {
+	fake, srv := newFakeOctoPrint(t)
+	o := NewOctoPrintAdapter("Ender", srv.URL, "key", false)
+
+	var mu sync.Mutex
+	var events []StateChangeEvent
+	o.OnStateChange(func(e StateChangeEvent) {
+		mu.Lock()
+		events = append(events, e)
+		mu.Unlock()
+	})
+
+	if err := o.poll(); err != nil {
+		t.Fatalf("poll: %v", err)
+	}
+	if got := o.Status().State; got != "idle" {
+		t.Fatalf("state = %q, want idle", got)
+	}
+
+	fake.set(map[string]bool{"operational": true, "printing": true}, 42.5, 1800.0)
+	if err := o.poll(); err != nil {
+		t.Fatalf("poll: %v", err)
+	}
+	st := o.Status()
+	if st.State != "printing" || st.Progress != 42 || st.RemainingMins != 30 || st.CurrentFile != "Bracket.gcode" {
+		t.Errorf("status = %+v, want printing Bracket.gcode at 42%%, 30 min left", st)
+	}
+
+	fake.set(map[string]bool{"operational": true, "ready": true}, 100.0, 0.0)
+	if err := o.poll(); err != nil {
+		t.Fatalf("poll: %v", err)
+	}
+	if got := o.Status(); got.State != "finished" || got.LastFinishedAt.IsZero() {
+		t.Errorf("after completion: state %q, LastFinishedAt %v; want finished and stamped", got.State, got.LastFinishedAt)
+	}
+
+	waitFor(t, "two callbacks", func() bool {
+		mu.Lock()
+		defer mu.Unlock()
+		return len(events) == 2
+	})
+
+	fake.mu.Lock()
+	fake.conflict = true
+	fake.mu.Unlock()
+	if err := o.poll(); err != nil {
+		t.Fatalf("poll with 409: %v", err)
+	}
+	if got := o.Status().State; got != "offline" {
+		t.Errorf("state with printer disconnected = %q, want offline", got)
+	}
+}
+
+func TestOctoPrintBadKey(t *testing.T) {
+	_, srv := newFakeOctoPrint(t)
+	o := NewOctoPrintAdapter("Ender", srv.URL, "wrong", false)
+	if err := o.Connect(); err == nil {
+		t.Fatal("Connect with a bad API key should fail")
+	}
+}
+
+func TestOctoPrintCancelReadsAsAbort(t *testing.T) {
+	o := NewOctoPrintAdapter("Ender", "octopi.local", "key", false)
+	if o.baseURL != "http://octopi.local" {
+		t.Errorf("baseURL = %q", o.baseURL)
+	}
+	pct := func(v float64) *float64 { return &v }
+
+	var printing, cancelled octoPrintJob
+	printing.State.Flags = map[string]bool{"printing": true}
+	printing.Progress.Completion = pct(60)
+	cancelled.State.Flags = map[string]bool{"operational": true, "ready": true}
+	cancelled.Progress.Completion = pct(61)
+
+	o.applyCurrent(printing)
+	if _, fire := o.applyCurrent(printing); fire {
+		t.Error("printing -> printing fired a transition")
+	}
+	event, fire := o.applyCurrent(cancelled)
+	if !fire || event.OldState != "printing" || event.NewState != "idle" {
+		t.Fatalf("event = %+v, %v; want printing -> idle", event, fire)
+	}
+	if !IsAbortedPrint(event) {
+		t.Error("a cancel under 100% should read as an aborted print")
+	}
+}
+
+func TestOctoPrintPushSocket(t *testing.T) {
+	fake, srv := newFakeOctoPrint(t)
+	o := NewOctoPrintAdapter("Ender", srv.URL, "key", true)
+	if err := o.Connect(); err != nil {
+		t.Fatalf("Connect: %v", err)
+	}
+	defer o.Close()
+
+	conn := <-fake.socket
+	fake.mu.Lock()
+	auth := fake.auth
+	fake.mu.Unlock()
+	if auth != "_api:abc" {
+		t.Errorf("socket auth = %q, want _api:abc", auth)
+	}
+
+	err := conn.WriteJSON(map[string]any{"current": map[string]any{
+		"state":    map[string]any{"text": "Printing", "flags": map[string]bool{"printing": true}},
+		"job":      map[string]any{"file": map[string]any{"name": "lid.gcode"}},
+		"progress": map[string]any{"completion": 12.0, "printTimeLeft": 600},
+	}})
+	if err != nil {
+		t.Fatalf("write current: %v", err)
+	}
+	waitFor(t, "pushed printing state", func() bool { return o.Status().State == "printing" })
+	if st := o.Status(); st.CurrentFile != "lid.gcode" || st.Progress != 12 || st.RemainingMins != 10 {
+		t.Errorf("status = %+v, want lid.gcode at 12%%, 10 min left", st)
+	}
+}
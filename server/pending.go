@@ -0,0 +1,588 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/dstockto/fil/models"
+	"github.com/dstockto/fil/plan"
+)
+
+// pendingFileName is the JSON file (in PlansDir) holding events the server
+// applied on its own and that still need a human to look at them.
+const pendingFileName = "pending-events.json"
+
+// ErrPendingNotFound is returned when a pending event ID doesn't exist —
+// usually because someone else already accepted or amended it.
+var ErrPendingNotFound = errors.New("pending event not found")
+
+// ErrPendingBusy is returned by Claim while another request is already
+// applying the same event.
+var ErrPendingBusy = errors.New("pending event is already being applied")
+
+// PendingEvent is an event the server detected without a human in the loop.
+// Five kinds exist:
+//
+//   - "complete": auto-complete on FINISH. Already applied — the plan YAML and
+//     Spoolman reflect it; accept keeps it, amend corrects the deductions.
+//   - "fail": an aborted print. Not applied yet, because a cancel and a
+//     failure look the same from the printer; accept or amend runs the Fail
+//     verb, dismiss drops it without touching Spoolman or history.
+//   - "job": a printer started a job no plate's File matched. Link starts
+//     the chosen plate as of the job's start; accept or dismiss drops it.
+//   - "swap": a printer slot now holds a different spool than the location
+//     orders say. Not applied yet; accept moves Swap.SpoolID into the slot,
+//     amend picks another of the Candidates, dismiss leaves Spoolman alone.
+//   - "register": a printer slot holds an RFID spool Spoolman doesn't know.
+//     Accept creates the filament (if needed) and spool in that slot; amend
+//     names an existing spool instead, which gets the tag and the slot.
+type PendingEvent struct {
+	ID         string                    `json:"id"`
+	Kind       string                    `json:"kind"` // "complete", "fail", "job", "swap", or "register"
+	Printer    string                    `json:"printer"`
+	Plates     []PendingPlate            `json:"plates"`
+	OccurredAt time.Time                 `json:"occurred_at"` // when the printer reported the transition
+	CreatedAt  time.Time                 `json:"created_at"`
+	Deductions []plan.SpoolDeduction     `json:"deductions,omitempty"`
+	Unmatched  []models.PlateRequirement `json:"unmatched,omitempty"`  // needs nobody deducted for yet
+	Error      string                    `json:"error,omitempty"`      // partial failure from the plan verb
+	Fail       *plan.FailRequest         `json:"fail,omitempty"`       // proposed request for kind "fail"
+	Job        string                    `json:"job,omitempty"`        // printer's job name for kind "job"
+	Swap       *plan.SpoolSwap           `json:"swap,omitempty"`       // proposed move for kind "swap"
+	Candidates []int                     `json:"candidates,omitempty"` // other spools that fit, for kind "swap"
+	Register   *plan.SpoolRegistration   `json:"register,omitempty"`   // proposed spool for kind "register"
+}
+
+// PendingPlate identifies one plate covered by a PendingEvent.
+type PendingPlate struct {
+	Plan    string `json:"plan"`
+	Project string `json:"project"`
+	Plate   string `json:"plate"`
+}
+
+// PendingAmendRequest is the body of POST /pending/{id}/amend. For "complete"
+// events, Deductions replace the recorded deductions wholesale and the server
+// applies only the per-spool difference to Spoolman. For "fail" events,
+// UsedGrams, Cause, and Reason override the proposed FailRequest (unset
+// fields keep the estimate) before it runs. For "swap" and "register" events,
+// SpoolID names the spool actually loaded.
+type PendingAmendRequest struct {
+	Deductions []plan.SpoolDeduction `json:"deductions,omitempty"`
+	UsedGrams  *float64              `json:"used_grams,omitempty"`
+	Cause      string                `json:"cause,omitempty"`
+	Reason     string                `json:"reason,omitempty"`
+	SpoolID    int                   `json:"spool_id,omitempty"`
+}
+
+// PendingLinkRequest is the body of POST /pending/{id}/link: the plate an
+// unmatched job was actually printing.
+type PendingLinkRequest struct {
+	Plan    string `json:"plan"`
+	Project string `json:"project"`
+	Plate   string `json:"plate"`
+}
+
+// PendingStore persists PendingEvents as a single JSON array. The list is
+// expected to stay short (a handful of unconfirmed plates), so every mutation
+// rewrites the whole file. Handlers that apply an event Claim it first so
+// two concurrent accepts or amends can't both write to Spoolman.
+type PendingStore struct {
+	path    string
+	mu      sync.Mutex
+	claimed map[string]bool
+}
+
+// NewPendingStore returns a store backed by pending-events.json in dir.
+func NewPendingStore(dir string) *PendingStore {
+	return &PendingStore{path: filepath.Join(dir, pendingFileName)}
+}
+
+// List returns all pending events, oldest first.
+func (p *PendingStore) List() ([]PendingEvent, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.loadLocked()
+}
+
+// Get returns the event with the given ID, or ErrPendingNotFound.
+func (p *PendingStore) Get(id string) (PendingEvent, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	events, err := p.loadLocked()
+	if err != nil {
+		return PendingEvent{}, err
+	}
+	for _, e := range events {
+		if e.ID == id {
+			return e, nil
+		}
+	}
+	return PendingEvent{}, ErrPendingNotFound
+}
+
+// Claim returns the event with the given ID and marks it as being applied
+// until Release. It returns ErrPendingBusy if it is already claimed.
+func (p *PendingStore) Claim(id string) (PendingEvent, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.claimed[id] {
+		return PendingEvent{}, ErrPendingBusy
+	}
+	events, err := p.loadLocked()
+	if err != nil {
+		return PendingEvent{}, err
+	}
+	for _, e := range events {
+		if e.ID == id {
+			if p.claimed == nil {
+				p.claimed = make(map[string]bool)
+			}
+			p.claimed[id] = true
+			return e, nil
+		}
+	}
+	return PendingEvent{}, ErrPendingNotFound
+}
+
+// Release ends a Claim.
+func (p *PendingStore) Release(id string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	delete(p.claimed, id)
+}
+
+// Add assigns an ID and creation time to ev, appends it, and returns the
+// stored copy.
+func (p *PendingStore) Add(ev PendingEvent) (PendingEvent, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	events, err := p.loadLocked()
+	if err != nil {
+		return PendingEvent{}, err
+	}
+	now := time.Now().UTC()
+	if ev.CreatedAt.IsZero() {
+		ev.CreatedAt = now
+	}
+	if ev.ID == "" {
+		ev.ID = strconv.FormatInt(now.UnixNano(), 36)
+	}
+	events = append(events, ev)
+	if err := p.saveLocked(events); err != nil {
+		return PendingEvent{}, err
+	}
+	return ev, nil
+}
+
+// Update replaces the stored event that has ev's ID, or returns
+// ErrPendingNotFound.
+func (p *PendingStore) Update(ev PendingEvent) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	events, err := p.loadLocked()
+	if err != nil {
+		return err
+	}
+	for i, e := range events {
+		if e.ID == ev.ID {
+			events[i] = ev
+			return p.saveLocked(events)
+		}
+	}
+	return ErrPendingNotFound
+}
+
+// Remove deletes the event with the given ID, or returns ErrPendingNotFound.
+func (p *PendingStore) Remove(id string) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	events, err := p.loadLocked()
+	if err != nil {
+		return err
+	}
+	for i, e := range events {
+		if e.ID == id {
+			events = append(events[:i], events[i+1:]...)
+			return p.saveLocked(events)
+		}
+	}
+	return ErrPendingNotFound
+}
+
+func (p *PendingStore) loadLocked() ([]PendingEvent, error) {
+	data, err := os.ReadFile(p.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("read pending events: %w", err)
+	}
+	var events []PendingEvent
+	if err := json.Unmarshal(data, &events); err != nil {
+		return nil, fmt.Errorf("parse pending events: %w", err)
+	}
+	return events, nil
+}
+
+func (p *PendingStore) saveLocked(events []PendingEvent) error {
+	if events == nil {
+		events = []PendingEvent{}
+	}
+	data, err := json.MarshalIndent(events, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp := p.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return fmt.Errorf("write pending events: %w", err)
+	}
+	return os.Rename(tmp, p.path)
+}
+
+// applyDeductionDeltas reconciles Spoolman with an amended deduction list.
+// applied is what the server already deducted; amended is what the user says
+// it should have been. Only the per-spool difference is written: positive
+// deltas go through the same initial-weight bump LocalPlanOps uses, negative
+// deltas hand filament back via a negative use (as `fil use -5` does).
+func applyDeductionDeltas(ctx context.Context, sm plan.Spoolman, applied, amended []plan.SpoolDeduction) error {
+	delta := map[int]float64{}
+	for _, d := range applied {
+		delta[d.SpoolID] -= d.Amount
+	}
+	for _, d := range amended {
+		delta[d.SpoolID] += d.Amount
+	}
+	ids := make([]int, 0, len(delta))
+	for id := range delta {
+		ids = append(ids, id)
+	}
+	sort.Ints(ids)
+
+	var errs []error
+	for _, id := range ids {
+		amount := delta[id]
+		if math.Abs(amount) < 0.05 {
+			continue
+		}
+		if amount > 0 {
+			spool, err := sm.FindSpoolByID(ctx, id)
+			if err != nil {
+				errs = append(errs, fmt.Errorf("spool #%d: %w", id, err))
+				continue
+			}
+			if amount > spool.RemainingWeight {
+				updates := map[string]any{"initial_weight": spool.InitialWeight + amount - spool.RemainingWeight}
+				if err := sm.PatchSpool(ctx, id, updates); err != nil {
+					errs = append(errs, fmt.Errorf("adjust initial weight for spool #%d: %w", id, err))
+					continue
+				}
+			}
+		}
+		if err := sm.UseFilament(ctx, id, amount); err != nil {
+			errs = append(errs, fmt.Errorf("spool #%d: %w", id, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func (s *PlanServer) handleListPending(w http.ResponseWriter, r *http.Request) {
+	var events []PendingEvent
+	if s.Pending != nil {
+		var err error
+		events, err = s.Pending.List()
+		if err != nil {
+			http.Error(w, fmt.Sprintf("failed to read pending events: %v", err), http.StatusInternalServerError)
+			return
+		}
+	}
+	if events == nil {
+		events = []PendingEvent{}
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(events)
+}
+
+// handleAcceptPending confirms the server's guess as-is. An applied
+// "complete" event is just dropped from the queue; a "fail" event runs its
+// proposed FailRequest first.
+func (s *PlanServer) handleAcceptPending(w http.ResponseWriter, r *http.Request) {
+	if s.Pending == nil {
+		http.Error(w, "pending events not configured", http.StatusBadRequest)
+		return
+	}
+	id := r.PathValue("id")
+	ev, err := s.Pending.Claim(id)
+	if err != nil {
+		writeClaimError(w, "accept", err)
+		return
+	}
+	defer s.Pending.Release(id)
+	switch ev.Kind {
+	case "fail":
+		if !s.runPendingFail(w, r, ev) {
+			return
+		}
+	case "swap":
+		if !s.runPendingSwap(w, r, ev) {
+			return
+		}
+	case "register":
+		if !s.runPendingRegister(w, r, ev) {
+			return
+		}
+	}
+	if err := s.Pending.Remove(id); err != nil {
+		if errors.Is(err, ErrPendingNotFound) {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
+		http.Error(w, fmt.Sprintf("accept: %v", err), http.StatusInternalServerError)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// handleAmendPending replaces an event's deductions with the caller's
+// corrected list, writes the difference to Spoolman, and drops the event.
+func (s *PlanServer) handleAmendPending(w http.ResponseWriter, r *http.Request) {
+	if s.Pending == nil {
+		http.Error(w, "pending events not configured", http.StatusBadRequest)
+		return
+	}
+	var req PendingAmendRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "invalid JSON body", http.StatusBadRequest)
+		return
+	}
+	for _, d := range req.Deductions {
+		if d.SpoolID <= 0 || d.Amount < 0 {
+			http.Error(w, fmt.Sprintf("invalid deduction %+v", d), http.StatusBadRequest)
+			return
+		}
+	}
+
+	id := r.PathValue("id")
+	ev, err := s.Pending.Claim(id)
+	if err != nil {
+		writeClaimError(w, "amend", err)
+		return
+	}
+	defer s.Pending.Release(id)
+	if ev.Kind == "job" {
+		http.Error(w, "job events have no deductions to amend; link them to a plate instead", http.StatusBadRequest)
+		return
+	}
+	if ev.Kind == "register" {
+		// The tag belongs to a spool Spoolman already has: move that one
+		// into the slot and tag it, as for a swap.
+		reg := ev.Register
+		if reg == nil {
+			http.Error(w, "pending registration has no spool", http.StatusInternalServerError)
+			return
+		}
+		ev.Swap = &plan.SpoolSwap{Location: reg.Location, Slot: reg.Slot, RFID: reg.RFID}
+		ev.Kind = "swap"
+	}
+	if ev.Kind == "swap" {
+		if ev.Swap == nil {
+			http.Error(w, "pending swap has no move", http.StatusInternalServerError)
+			return
+		}
+		if req.SpoolID <= 0 {
+			http.Error(w, "spool_id is required to amend a swap", http.StatusBadRequest)
+			return
+		}
+		if req.SpoolID != ev.Swap.SpoolID {
+			ev.Swap.SpoolID = req.SpoolID
+			ev.Swap.From = ""
+			if s.Spoolman != nil {
+				if spool, err := s.Spoolman.FindSpoolByID(r.Context(), req.SpoolID); err == nil {
+					ev.Swap.From = spool.Location
+				}
+			}
+		}
+		if !s.runPendingSwap(w, r, ev) {
+			return
+		}
+		if err := s.Pending.Remove(id); err != nil && !errors.Is(err, ErrPendingNotFound) {
+			http.Error(w, fmt.Sprintf("amend: %v", err), http.StatusInternalServerError)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+	if ev.Kind == "fail" {
+		if ev.Fail == nil {
+			http.Error(w, "pending fail has no request", http.StatusInternalServerError)
+			return
+		}
+		if req.UsedGrams != nil {
+			if *req.UsedGrams < 0 {
+				http.Error(w, "used_grams must not be negative", http.StatusBadRequest)
+				return
+			}
+			ev.Fail.UsedGrams = *req.UsedGrams
+		}
+		if req.Cause != "" {
+			if _, ok := validCauses[req.Cause]; !ok {
+				http.Error(w, fmt.Sprintf("invalid cause %q", req.Cause), http.StatusBadRequest)
+				return
+			}
+			ev.Fail.Cause = req.Cause
+		}
+		if req.Reason != "" {
+			ev.Fail.Reason = req.Reason
+		}
+		if !s.runPendingFail(w, r, ev) {
+			return
+		}
+		if err := s.Pending.Remove(id); err != nil && !errors.Is(err, ErrPendingNotFound) {
+			http.Error(w, fmt.Sprintf("amend: %v", err), http.StatusInternalServerError)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+	if s.Spoolman == nil {
+		http.Error(w, "spoolman not configured", http.StatusInternalServerError)
+		return
+	}
+	if err := applyDeductionDeltas(r.Context(), s.Spoolman, ev.Deductions, req.Deductions); err != nil {
+		// Leave the event in place so the user can retry; some spools may
+		// already have been adjusted, which the error message spells out.
+		http.Error(w, fmt.Sprintf("amend: %v", err), http.StatusBadGateway)
+		return
+	}
+	if err := s.Pending.Remove(id); err != nil && !errors.Is(err, ErrPendingNotFound) {
+		http.Error(w, fmt.Sprintf("amend: %v", err), http.StatusInternalServerError)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// handleDismissPending drops an event without applying anything. For a
+// pending fail this is the "that was a cancel, not a failure" answer.
+func (s *PlanServer) handleDismissPending(w http.ResponseWriter, r *http.Request) {
+	if s.Pending == nil {
+		http.Error(w, "pending events not configured", http.StatusBadRequest)
+		return
+	}
+	id := r.PathValue("id")
+	if _, err := s.Pending.Claim(id); err != nil {
+		writeClaimError(w, "dismiss", err)
+		return
+	}
+	defer s.Pending.Release(id)
+	if err := s.Pending.Remove(id); err != nil {
+		if errors.Is(err, ErrPendingNotFound) {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
+		http.Error(w, fmt.Sprintf("dismiss: %v", err), http.StatusInternalServerError)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// writeClaimError maps a failed PendingStore.Claim to a response: 404 for
+// an unknown event, 409 while another request is applying it.
+func writeClaimError(w http.ResponseWriter, verb string, err error) {
+	switch {
+	case errors.Is(err, ErrPendingNotFound):
+		http.Error(w, err.Error(), http.StatusNotFound)
+	case errors.Is(err, ErrPendingBusy):
+		http.Error(w, err.Error(), http.StatusConflict)
+	default:
+		http.Error(w, fmt.Sprintf("%s: %v", verb, err), http.StatusInternalServerError)
+	}
+}
+
+// handleLinkPending starts the plate an unmatched job was printing, stamping
+// the job's own start time and printer, then drops the event.
+func (s *PlanServer) handleLinkPending(w http.ResponseWriter, r *http.Request) {
+	if s.Pending == nil {
+		http.Error(w, "pending events not configured", http.StatusBadRequest)
+		return
+	}
+	if s.PlanOps == nil {
+		http.Error(w, "plan ops not configured", http.StatusInternalServerError)
+		return
+	}
+	var req PendingLinkRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "invalid JSON body", http.StatusBadRequest)
+		return
+	}
+	if req.Plan == "" || req.Project == "" || req.Plate == "" {
+		http.Error(w, "plan, project, and plate are required", http.StatusBadRequest)
+		return
+	}
+
+	id := r.PathValue("id")
+	ev, err := s.Pending.Claim(id)
+	if err != nil {
+		writeClaimError(w, "link", err)
+		return
+	}
+	defer s.Pending.Release(id)
+	if ev.Kind != "job" {
+		http.Error(w, fmt.Sprintf("cannot link a %q event", ev.Kind), http.StatusBadRequest)
+		return
+	}
+	_, err = s.PlanOps.Next(r.Context(), plan.NextRequest{
+		Plan:      req.Plan,
+		Project:   req.Project,
+		Plate:     req.Plate,
+		Printer:   ev.Printer,
+		StartedAt: ev.OccurredAt,
+	})
+	if err != nil {
+		http.Error(w, fmt.Sprintf("link: %v", err), http.StatusInternalServerError)
+		return
+	}
+	if err := s.Pending.Remove(id); err != nil && !errors.Is(err, ErrPendingNotFound) {
+		http.Error(w, fmt.Sprintf("link: %v", err), http.StatusInternalServerError)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// runPendingFail hands a pending fail's request to PlanOps.Fail, writing an
+// error response and returning false when it can't. Partial Spoolman errors
+// still count as applied once history is written. When it isn't, the event
+// stays queued with the error and what each spool was already charged, so
+// a retry (or an amend to UsedGrams) only writes the difference.
+func (s *PlanServer) runPendingFail(w http.ResponseWriter, r *http.Request, ev PendingEvent) bool {
+	if ev.Fail == nil {
+		http.Error(w, "pending fail has no request", http.StatusInternalServerError)
+		return false
+	}
+	if s.PlanOps == nil {
+		http.Error(w, "plan ops not configured", http.StatusInternalServerError)
+		return false
+	}
+	result, err := s.PlanOps.Fail(r.Context(), *ev.Fail)
+	if err != nil && !result.Recorded {
+		ev.Fail.Deducted = result.Deducted
+		ev.Error = err.Error()
+		if uerr := s.Pending.Update(ev); uerr != nil {
+			fmt.Printf("[pending] fail %s: save retry state: %v\n", ev.ID, uerr)
+		}
+		http.Error(w, fmt.Sprintf("fail: %v", err), http.StatusBadGateway)
+		return false
+	}
+	if err != nil {
+		fmt.Printf("[pending] fail %s applied with errors: %v\n", ev.ID, err)
+	}
+	return true
+}
@@ -0,0 +1,180 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/dstockto/fil/api"
+	"github.com/dstockto/fil/models"
+	"github.com/dstockto/fil/plan"
+)
+
+// fakeSpoolman is an in-memory plan.Spoolman for server tests. UseFilament
+// accumulates grams per spool so tests can assert on the net Spoolman delta.
+type fakeSpoolman struct {
+	spools     []models.FindSpool
+	useCalls   map[int]float64
+	patchCalls map[int]map[string]any
+}
+
+func newFakeSpoolman(spools ...models.FindSpool) *fakeSpoolman {
+	return &fakeSpoolman{
+		spools:     spools,
+		useCalls:   map[int]float64{},
+		patchCalls: map[int]map[string]any{},
+	}
+}
+
+func (f *fakeSpoolman) FindSpoolsByName(_ context.Context, _ string, _ api.SpoolFilter, _ map[string]string) ([]models.FindSpool, error) {
+	return f.spools, nil
+}
+
+func (f *fakeSpoolman) FindSpoolByID(_ context.Context, id int) (models.FindSpool, error) {
+	for _, s := range f.spools {
+		if s.Id == id {
+			return s, nil
+		}
+	}
+	return models.FindSpool{}, api.ErrSpoolNotFound
+}
+
+func (f *fakeSpoolman) UseFilament(_ context.Context, spoolID int, amount float64) error {
+	f.useCalls[spoolID] += amount
+	return nil
+}
+
+func (f *fakeSpoolman) PatchSpool(_ context.Context, spoolID int, updates map[string]any) error {
+	f.patchCalls[spoolID] = updates
+	return nil
+}
+
+func makeSpool(id int, loc string, remaining float64, filamentID int, name string) models.FindSpool {
+	s := models.FindSpool{Id: id, Location: loc, RemainingWeight: remaining, InitialWeight: 1000}
+	s.Filament.Id = filamentID
+	s.Filament.Name = name
+	return s
+}
+
+func TestPendingStoreRoundTrip(t *testing.T) {
+	store := NewPendingStore(t.TempDir())
+
+	events, err := store.List()
+	if err != nil || len(events) != 0 {
+		t.Fatalf("empty store: got %v, %v", events, err)
+	}
+
+	a, err := store.Add(PendingEvent{Kind: "complete", Printer: "X1C"})
+	if err != nil {
+		t.Fatalf("add: %v", err)
+	}
+	if a.ID == "" || a.CreatedAt.IsZero() {
+		t.Errorf("Add should assign ID and CreatedAt, got %+v", a)
+	}
+	b, _ := store.Add(PendingEvent{ID: "fixed", Kind: "complete", Printer: "MK4"})
+
+	events, _ = store.List()
+	if len(events) != 2 {
+		t.Fatalf("got %d events, want 2", len(events))
+	}
+	if got, err := store.Get(b.ID); err != nil || got.Printer != "MK4" {
+		t.Errorf("Get(%q) = %+v, %v", b.ID, got, err)
+	}
+
+	if err := store.Remove(a.ID); err != nil {
+		t.Fatalf("remove: %v", err)
+	}
+	if err := store.Remove(a.ID); err != ErrPendingNotFound {
+		t.Errorf("second remove: got %v, want ErrPendingNotFound", err)
+	}
+	events, _ = store.List()
+	if len(events) != 1 || events[0].ID != "fixed" {
+		t.Errorf("after remove: %+v", events)
+	}
+}
+
+func TestAcceptPendingRemovesEvent(t *testing.T) {
+	s := &PlanServer{PlansDir: t.TempDir()}
+	s.Pending = NewPendingStore(s.PlansDir)
+	ev, _ := s.Pending.Add(PendingEvent{Kind: "complete", Printer: "X1C"})
+
+	r := httptest.NewRequest(http.MethodPost, "/api/fil/pending/"+ev.ID+"/accept", nil)
+	w := httptest.NewRecorder()
+	s.Routes().ServeHTTP(w, r)
+	if w.Code != http.StatusNoContent {
+		t.Fatalf("status = %d, body = %q", w.Code, w.Body.String())
+	}
+	if events, _ := s.Pending.List(); len(events) != 0 {
+		t.Errorf("event still pending: %+v", events)
+	}
+
+	w = httptest.NewRecorder()
+	s.Routes().ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/api/fil/pending/"+ev.ID+"/accept", nil))
+	if w.Code != http.StatusNotFound {
+		t.Errorf("second accept: status = %d, want 404", w.Code)
+	}
+}
+
+// Amending writes only the difference between what the server already
+// deducted and what the user says was used: more on one spool, a give-back
+// on another, and a brand-new spool the server couldn't match.
+func TestAmendPendingAppliesDeltas(t *testing.T) {
+	sm := newFakeSpoolman(
+		makeSpool(1, "AMS A1", 500, 100, "PLA white"),
+		makeSpool(2, "AMS A2", 500, 200, "PETG black"),
+		makeSpool(3, "AMS A3", 500, 300, "PLA red"),
+	)
+	s := &PlanServer{PlansDir: t.TempDir(), Spoolman: sm}
+	s.Pending = NewPendingStore(s.PlansDir)
+	ev, _ := s.Pending.Add(PendingEvent{
+		Kind:    "complete",
+		Printer: "X1C",
+		Deductions: []plan.SpoolDeduction{
+			{SpoolID: 1, Amount: 10},
+			{SpoolID: 2, Amount: 8},
+		},
+	})
+
+	body, _ := json.Marshal(PendingAmendRequest{Deductions: []plan.SpoolDeduction{
+		{SpoolID: 1, Amount: 12},
+		{SpoolID: 2, Amount: 5},
+		{SpoolID: 3, Amount: 4},
+	}})
+	r := httptest.NewRequest(http.MethodPost, "/api/fil/pending/"+ev.ID+"/amend", bytes.NewReader(body))
+	w := httptest.NewRecorder()
+	s.Routes().ServeHTTP(w, r)
+	if w.Code != http.StatusNoContent {
+		t.Fatalf("status = %d, body = %q", w.Code, w.Body.String())
+	}
+
+	want := map[int]float64{1: 2, 2: -3, 3: 4}
+	for id, grams := range want {
+		if !floatClose(sm.useCalls[id], grams) {
+			t.Errorf("spool #%d: used %.1fg, want %.1fg", id, sm.useCalls[id], grams)
+		}
+	}
+	if events, _ := s.Pending.List(); len(events) != 0 {
+		t.Errorf("amended event still pending: %+v", events)
+	}
+}
+
+func TestAmendPendingRejectsNegativeAmounts(t *testing.T) {
+	s := &PlanServer{PlansDir: t.TempDir(), Spoolman: newFakeSpoolman()}
+	s.Pending = NewPendingStore(s.PlansDir)
+	ev, _ := s.Pending.Add(PendingEvent{Kind: "complete"})
+
+	body, _ := json.Marshal(PendingAmendRequest{Deductions: []plan.SpoolDeduction{{SpoolID: 1, Amount: -5}}})
+	w := httptest.NewRecorder()
+	s.Routes().ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/api/fil/pending/"+ev.ID+"/amend", bytes.NewReader(body)))
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("status = %d, want 400", w.Code)
+	}
+}
+
+func floatClose(a, b float64) bool {
+	d := a - b
+	return d < 0.001 && d > -0.001
+}
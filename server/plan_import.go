@@ -0,0 +1,101 @@
+package server
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/dstockto/fil/models"
+	"github.com/dstockto/fil/slicer"
+	"gopkg.in/yaml.v3"
+)
+
+// handleImportThreeMF builds a new plan from a sliced Bambu Studio /
+// OrcaSlicer 3MF posted as the raw request body: one Plate per sliced plate
+// with Needs and EstimatedDuration filled from the slice info. Needs whose
+// material and color identify a single Spoolman filament are linked; the rest
+// are left for `fil plan resolve`. The optional ?project= sets the project
+// name (default: the plan name). With JobsDir set the 3MF is kept there and
+// each plate's sliced field points at it. Responds 201 with the created plan as YAML.
+func (s *PlanServer) handleImportThreeMF(w http.ResponseWriter, r *http.Request) {
+	name := filepath.Base(r.PathValue("name"))
+	if name == "" || name == "." {
+		http.Error(w, "plan name required", http.StatusBadRequest)
+		return
+	}
+	if !strings.HasSuffix(name, ".yaml") && !strings.HasSuffix(name, ".yml") {
+		name += ".yaml"
+	}
+	if s.PlanOps == nil {
+		http.Error(w, "plan ops not configured", http.StatusInternalServerError)
+		return
+	}
+	if _, err := os.Stat(filepath.Join(s.PlansDir, name)); err == nil {
+		http.Error(w, fmt.Sprintf("plan %s already exists", name), http.StatusConflict)
+		return
+	}
+
+	r.Body = http.MaxBytesReader(w, r.Body, 200<<20)
+	data, err := io.ReadAll(r.Body)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("failed to read request body: %v", err), http.StatusBadRequest)
+		return
+	}
+	sliced, err := slicer.ParseThreeMF(data)
+	if err != nil {
+		status := http.StatusBadRequest
+		if errors.Is(err, slicer.ErrNotSliced) {
+			status = http.StatusUnprocessableEntity
+		}
+		http.Error(w, err.Error(), status)
+		return
+	}
+
+	base := strings.TrimSuffix(strings.TrimSuffix(name, ".yaml"), ".yml")
+	project := strings.TrimSpace(r.URL.Query().Get("project"))
+	if project == "" {
+		project = base
+	}
+	plates := slicer.Plates(sliced, base)
+	if s.JobsDir != "" {
+		// Keep the 3MF so plan next --send can start its plates.
+		stored, err := s.saveJob(base+".3mf", bytes.NewReader(data))
+		if err != nil {
+			http.Error(w, fmt.Sprintf("store sliced file: %v", err), http.StatusInternalServerError)
+			return
+		}
+		for i := range plates {
+			plates[i].Sliced = stored
+			plates[i].SlicedPlate = sliced[i].Index
+		}
+	}
+	if s.Spoolman != nil {
+		if spools, err := s.Spoolman.FindSpoolsByName(r.Context(), "*", nil, nil); err == nil {
+			slicer.MatchFilaments(plates, spools)
+		}
+	}
+	plan := models.PlanFile{
+		Projects: []models.Project{{Name: project, Status: "todo", Plates: plates}},
+	}
+	if err := s.PlanOps.SaveAll(r.Context(), name, plan); err != nil {
+		http.Error(w, fmt.Sprintf("save plan: %v", err), http.StatusInternalServerError)
+		return
+	}
+	if s.Watcher != nil {
+		s.Watcher.Reschedule()
+	}
+
+	out, err := yaml.Marshal(plan)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("encode plan: %v", err), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/x-yaml")
+	w.WriteHeader(http.StatusCreated)
+	_, _ = w.Write(out)
+}
@@ -0,0 +1,109 @@
+package server
+
+import (
+	"archive/zip"
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/dstockto/fil/models"
+	"gopkg.in/yaml.v3"
+)
+
+func testThreeMF(t *testing.T) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	f, err := zw.Create("Metadata/slice_info.config")
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, _ = f.Write([]byte(`<config><plate>
+  <metadata key="index" value="1"/>
+  <metadata key="prediction" value="3600"/>
+  <filament id="1" type="PLA" color="#FFFFFF" used_g="20.5"/>
+</plate></config>`))
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestImportThreeMFCreatesPlan(t *testing.T) {
+	s, _ := setupTestServer(t)
+	white := makeSpool(1, "Shelf", 500, 100, "Jade White")
+	white.Filament.Material = "PLA"
+	white.Filament.ColorHex = "ffffff"
+	s.Spoolman = newFakeSpoolman(white)
+
+	r := httptest.NewRequest(http.MethodPost, "/api/fil/plans/widget/import-3mf?project=Widget", bytes.NewReader(testThreeMF(t)))
+	w := httptest.NewRecorder()
+	s.Routes().ServeHTTP(w, r)
+	if w.Code != http.StatusCreated {
+		t.Fatalf("status = %d, body = %q", w.Code, w.Body.String())
+	}
+
+	data, err := os.ReadFile(filepath.Join(s.PlansDir, "widget.yaml"))
+	if err != nil {
+		t.Fatalf("plan not written: %v", err)
+	}
+	var got models.PlanFile
+	if err := yaml.Unmarshal(data, &got); err != nil {
+		t.Fatal(err)
+	}
+	if len(got.Projects) != 1 || got.Projects[0].Name != "Widget" || len(got.Projects[0].Plates) != 1 {
+		t.Fatalf("plan = %+v", got)
+	}
+	plate := got.Projects[0].Plates[0]
+	if plate.Name != "Plate 1" || plate.EstimatedDuration != "1h" || plate.File != "widget" {
+		t.Errorf("plate = %+v", plate)
+	}
+	if len(plate.Needs) != 1 || plate.Needs[0].FilamentID != 100 || plate.Needs[0].Amount != 20.5 {
+		t.Errorf("needs = %+v", plate.Needs)
+	}
+
+	w = httptest.NewRecorder()
+	s.Routes().ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/api/fil/plans/widget/import-3mf", bytes.NewReader(testThreeMF(t))))
+	if w.Code != http.StatusConflict {
+		t.Errorf("second import: status = %d, want 409", w.Code)
+	}
+}
+
+func TestImportThreeMFKeepsSlicedFile(t *testing.T) {
+	s, _ := setupTestServer(t)
+	s.JobsDir = t.TempDir()
+
+	w := httptest.NewRecorder()
+	s.Routes().ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/api/fil/plans/widget/import-3mf", bytes.NewReader(testThreeMF(t))))
+	if w.Code != http.StatusCreated {
+		t.Fatalf("status = %d, body = %q", w.Code, w.Body.String())
+	}
+	var got models.PlanFile
+	if err := yaml.Unmarshal(w.Body.Bytes(), &got); err != nil {
+		t.Fatal(err)
+	}
+	plate := got.Projects[0].Plates[0]
+	if plate.SlicedPlate != 1 || plate.Sliced == "" {
+		t.Fatalf("plate = %+v, want sliced plate 1 of a stored file", plate)
+	}
+	if _, err := os.Stat(filepath.Join(s.JobsDir, plate.Sliced)); err != nil {
+		t.Errorf("sliced file not kept: %v", err)
+	}
+}
+
+func TestImportThreeMFRejectsUnsliced(t *testing.T) {
+	s, _ := setupTestServer(t)
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	_, _ = zw.Create("3D/3dmodel.model")
+	_ = zw.Close()
+
+	w := httptest.NewRecorder()
+	s.Routes().ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/api/fil/plans/widget/import-3mf", bytes.NewReader(buf.Bytes())))
+	if w.Code != http.StatusUnprocessableEntity {
+		t.Errorf("status = %d, want 422", w.Code)
+	}
+}
@@ -9,7 +9,8 @@ import (
 )
 
 // handlePlanNext decodes a NextRequest from the body, fills in the plan name
-// from the URL path, and delegates to PlanOps.Next.
+// from the URL path, and delegates to PlanOps.Next. A sent job whose plate
+// can't be recorded answers 202 with the error, since the print is running.
 func (s *PlanServer) handlePlanNext(w http.ResponseWriter, r *http.Request) {
 	name := r.PathValue("name")
 	if name == "" {
@@ -33,7 +34,24 @@ func (s *PlanServer) handlePlanNext(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	sent := req.Send
+	if sent {
+		if !s.sendJob(w, req) {
+			return
+		}
+		// Hold AutoStart off until the plate is recorded: the printer can
+		// report the new job before Next has saved it.
+		defer s.sending.Delete(req.Printer)
+		req.Send = false
+	}
+
 	result, err := s.PlanOps.Next(r.Context(), req)
+	if err != nil && sent {
+		// The printer is already running the job; 202 tells the client
+		// that, so it doesn't report the plate as left untouched.
+		http.Error(w, fmt.Sprintf("record plate: %v", err), http.StatusAccepted)
+		return
+	}
 	if err != nil {
 		http.Error(w, fmt.Sprintf("next: %v", err), http.StatusInternalServerError)
 		return
@@ -41,3 +59,30 @@ func (s *PlanServer) handlePlanNext(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
 	_ = json.NewEncoder(w).Encode(result)
 }
+
+// sendJob starts the plate's sliced file on the printer, writing the error
+// response and returning false if that fails.
+func (s *PlanServer) sendJob(w http.ResponseWriter, req plan.NextRequest) bool {
+	if s.Printers == nil {
+		http.Error(w, "no printer connections configured", http.StatusBadRequest)
+		return false
+	}
+	adapter, ok := s.Printers.Adapter(req.Printer)
+	if !ok {
+		http.Error(w, fmt.Sprintf("printer %q not found", req.Printer), http.StatusNotFound)
+		return false
+	}
+	job, err := s.plateJob(req)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return false
+	}
+
+	s.sending.Store(req.Printer, true)
+	if err := adapter.StartPrint(job); err != nil {
+		s.sending.Delete(req.Printer)
+		http.Error(w, fmt.Sprintf("send job: %v", err), startStatus(err))
+		return false
+	}
+	return true
+}
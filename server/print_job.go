@@ -0,0 +1,130 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/dstockto/fil/models"
+	"github.com/dstockto/fil/plan"
+	"gopkg.in/yaml.v3"
+)
+
+// handlePutJob stores a sliced file (3MF, bgcode, or gcode) posted as the raw
+// body in JobsDir, for a plate's sliced field. Responds 201 with the stored
+// file name.
+func (s *PlanServer) handlePutJob(w http.ResponseWriter, r *http.Request) {
+	if s.JobsDir == "" {
+		http.Error(w, "jobs directory not configured", http.StatusBadRequest)
+		return
+	}
+	name := filepath.Base(r.PathValue("file"))
+	if models.JobExt(name) == "" {
+		http.Error(w, "expected a .3mf, .bgcode, or .gcode file", http.StatusBadRequest)
+		return
+	}
+
+	r.Body = http.MaxBytesReader(w, r.Body, 500<<20)
+	stored, err := s.saveJob(name, r.Body)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("failed to store job: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	_ = json.NewEncoder(w).Encode(map[string]string{"filename": stored})
+}
+
+// saveJob writes a sliced file to JobsDir under a timestamped name, like
+// assembly PDFs, so re-slicing never overwrites a file a plan still uses.
+// Uploads landing in the same second get a -2, -3, ... suffix.
+func (s *PlanServer) saveJob(name string, r io.Reader) (string, error) {
+	if err := os.MkdirAll(s.JobsDir, 0755); err != nil {
+		return "", err
+	}
+	ext := models.JobExt(name)
+	base := fmt.Sprintf("%s-%s", name[:len(name)-len(ext)], time.Now().Format("20060102150405"))
+	ext = strings.ToLower(ext)
+
+	stored := base + ext
+	f, err := os.OpenFile(filepath.Join(s.JobsDir, stored), os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0644)
+	for n := 2; errors.Is(err, os.ErrExist); n++ {
+		stored = fmt.Sprintf("%s-%d%s", base, n, ext)
+		f, err = os.OpenFile(filepath.Join(s.JobsDir, stored), os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0644)
+	}
+	if err != nil {
+		return "", err
+	}
+	if _, err := io.Copy(f, r); err != nil {
+		f.Close()
+		_ = os.Remove(f.Name())
+		return "", err
+	}
+	return stored, f.Close()
+}
+
+// plateJob finds the plate a NextRequest names and returns the print job
+// for its sliced file.
+func (s *PlanServer) plateJob(req plan.NextRequest) (PrintJob, error) {
+	if s.JobsDir == "" {
+		return PrintJob{}, fmt.Errorf("jobs directory not configured")
+	}
+	data, err := os.ReadFile(filepath.Join(s.PlansDir, filepath.Base(req.Plan)))
+	if err != nil {
+		return PrintJob{}, fmt.Errorf("read plan: %w", err)
+	}
+	var pf models.PlanFile
+	if err := yaml.Unmarshal(data, &pf); err != nil {
+		return PrintJob{}, fmt.Errorf("parse plan: %w", err)
+	}
+	var plate *models.Plate
+	for pi := range pf.Projects {
+		if pf.Projects[pi].Name != req.Project {
+			continue
+		}
+		for pj := range pf.Projects[pi].Plates {
+			if pf.Projects[pi].Plates[pj].Name == req.Plate {
+				plate = &pf.Projects[pi].Plates[pj]
+			}
+		}
+	}
+	if plate == nil {
+		return PrintJob{}, fmt.Errorf("plate %s/%s not found", req.Project, req.Plate)
+	}
+	if plate.Sliced == "" {
+		return PrintJob{}, fmt.Errorf("plate %s/%s has no sliced file", req.Project, req.Plate)
+	}
+	return PrintJob{
+		Path:  filepath.Join(s.JobsDir, filepath.Base(plate.Sliced)),
+		Name:  printerJobName(*plate),
+		Plate: plate.SlicedPlate,
+	}, nil
+}
+
+// printerJobName is the file name a plate's job gets on the printer: the
+// plate's File (so AutoStart and the ETA watcher recognize it) with the
+// sliced file's extension, or the sliced file's own name.
+func printerJobName(plate models.Plate) string {
+	ext := models.JobExt(plate.Sliced)
+	name := filepath.Base(plate.Sliced)
+	if plate.File != "" {
+		name = filepath.Base(strings.ReplaceAll(plate.File, "\\", "/"))
+	}
+	return name[:len(name)-len(models.JobExt(name))] + strings.ToLower(ext)
+}
+
+// startStatus maps a StartPrint error to a status: 501 when the printer
+// type can't take jobs, 502 when the printer refused or never answered.
+func startStatus(err error) int {
+	if errors.Is(err, ErrNotSupported) {
+		return http.StatusNotImplemented
+	}
+	return http.StatusBadGateway
+}
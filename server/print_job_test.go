@@ -0,0 +1,361 @@
+package server
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/tls"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/dstockto/fil/models"
+	"gopkg.in/yaml.v3"
+)
+
+// fakeFTPS is an implicit-TLS FTP stand-in that accepts one passive STOR
+// per connection, enough to exercise ftpsUpload.
+type fakeFTPS struct {
+	addr  string
+	pass  string
+	mu    sync.Mutex
+	files map[string][]byte
+}
+
+func newFakeFTPS(t *testing.T, pass string) *fakeFTPS {
+	t.Helper()
+	// Borrow httptest's self-signed certificate.
+	certSrv := httptest.NewTLSServer(http.NotFoundHandler())
+	cfg := &tls.Config{Certificates: certSrv.TLS.Certificates}
+	certSrv.Close()
+
+	ln, err := tls.Listen("tcp", "127.0.0.1:0", cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { ln.Close() })
+	f := &fakeFTPS{addr: ln.Addr().String(), pass: pass, files: map[string][]byte{}}
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go f.serve(conn, cfg)
+		}
+	}()
+	return f
+}
+
+func (f *fakeFTPS) serve(conn net.Conn, cfg *tls.Config) {
+	defer conn.Close()
+	r := bufio.NewReader(conn)
+	reply := func(format string, args ...any) { fmt.Fprintf(conn, format+"\r\n", args...) }
+	reply("220 ready")
+	var data net.Listener
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			return
+		}
+		verb, arg, _ := strings.Cut(strings.TrimSpace(line), " ")
+		switch verb {
+		case "USER":
+			reply("331 password please")
+		case "PASS":
+			if arg != f.pass {
+				reply("530 login incorrect")
+				continue
+			}
+			reply("230 logged in")
+		case "TYPE", "PBSZ", "PROT":
+			reply("200 ok")
+		case "PASV":
+			data, err = tls.Listen("tcp", "127.0.0.1:0", cfg)
+			if err != nil {
+				reply("425 no data port")
+				continue
+			}
+			port := data.Addr().(*net.TCPAddr).Port
+			// Advertise a bogus host: the client must use the control host.
+			reply("227 Entering Passive Mode (10,9,9,9,%d,%d)", port>>8, port&0xff)
+		case "STOR":
+			reply("150 send it")
+			dc, err := data.Accept()
+			if err != nil {
+				reply("425 no data connection")
+				continue
+			}
+			body, _ := io.ReadAll(dc)
+			dc.Close()
+			data.Close()
+			f.mu.Lock()
+			f.files[arg] = body
+			f.mu.Unlock()
+			reply("226 stored")
+		case "QUIT":
+			reply("221 bye")
+			return
+		default:
+			reply("502 not implemented")
+		}
+	}
+}
+
+func TestFTPSUpload(t *testing.T) {
+	ftp := newFakeFTPS(t, "12345678")
+	cfg := &tls.Config{InsecureSkipVerify: true}
+
+	if err := ftpsUpload(ftp.addr, "bblp", "12345678", cfg, "bracket.3mf", strings.NewReader("sliced")); err != nil {
+		t.Fatalf("ftpsUpload: %v", err)
+	}
+	if got := string(ftp.files["bracket.3mf"]); got != "sliced" {
+		t.Errorf("uploaded %q, want %q", got, "sliced")
+	}
+	if err := ftpsUpload(ftp.addr, "bblp", "wrong", cfg, "x.3mf", strings.NewReader("")); err == nil {
+		t.Error("expected a login error with the wrong access code")
+	}
+}
+
+func TestBambuStartPrintWaitsForEcho(t *testing.T) {
+	b := NewBambuAdapter("X1C", "127.0.0.1", "00M00A000000000", "12345678")
+	old := bambuStartTimeout
+	bambuStartTimeout = 50 * time.Millisecond
+	defer func() { bambuStartTimeout = old }()
+
+	if err := b.awaitStart(); err == nil || !strings.Contains(err.Error(), "no answer") {
+		t.Errorf("awaitStart with no echo = %v, want a timeout", err)
+	}
+
+	b.handleReport([]byte(`{"print":{"command":"project_file","result":"success"}}`))
+	if err := b.awaitStart(); err != nil {
+		t.Errorf("awaitStart after success echo = %v", err)
+	}
+	b.handleReport([]byte(`{"print":{"command":"project_file","result":"FAIL","reason":"sdcard missing"}}`))
+	if err := b.awaitStart(); err == nil || !strings.Contains(err.Error(), "sdcard missing") {
+		t.Errorf("awaitStart after failure echo = %v", err)
+	}
+
+	if err := b.StartPrint(PrintJob{Name: "bracket.bgcode"}); err == nil {
+		t.Error("expected StartPrint to refuse a non-3MF file")
+	}
+}
+
+func TestProjectFileCommand(t *testing.T) {
+	cmd := projectFileCommand(PrintJob{Name: "Bracket.gcode.3mf", Plate: 2})["print"].(map[string]interface{})
+	if cmd["param"] != "Metadata/plate_2.gcode" || cmd["url"] != "file:///sdcard/Bracket.gcode.3mf" || cmd["subtask_name"] != "Bracket" {
+		t.Errorf("project_file = %+v", cmd)
+	}
+	if cmd := projectFileCommand(PrintJob{Name: "a.3mf"})["print"].(map[string]interface{}); cmd["param"] != "Metadata/plate_1.gcode" {
+		t.Errorf("default plate param = %v", cmd["param"])
+	}
+}
+
+func TestPrusaStartPrint(t *testing.T) {
+	var uploaded []byte
+	var started bool
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/v1/files/usb/bracket.bgcode" {
+			http.NotFound(w, r)
+			return
+		}
+		switch r.Method {
+		case http.MethodPut:
+			if r.Header.Get("Overwrite-File") != "?1" {
+				t.Errorf("Overwrite-File = %q", r.Header.Get("Overwrite-File"))
+			}
+			uploaded, _ = io.ReadAll(r.Body)
+			w.WriteHeader(http.StatusCreated)
+		case http.MethodPost:
+			started = uploaded != nil
+			w.WriteHeader(http.StatusNoContent)
+		}
+	}))
+	defer srv.Close()
+
+	path := filepath.Join(t.TempDir(), "bracket-1.bgcode")
+	if err := os.WriteFile(path, []byte("GCDE"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	p := NewPrusaAdapter("MK4", strings.TrimPrefix(srv.URL, "http://"), "maker", "pw")
+	if err := p.StartPrint(PrintJob{Path: path, Name: "bracket.bgcode"}); err != nil {
+		t.Fatalf("StartPrint: %v", err)
+	}
+	if string(uploaded) != "GCDE" || !started {
+		t.Errorf("uploaded %q, started %v", uploaded, started)
+	}
+}
+
+const sendPlanYAML = `projects:
+  - name: Widget
+    status: todo
+    plates:
+      - name: Base
+        status: todo
+        file: widget
+        sliced: widget-1.3mf
+        sliced_plate: 2
+        needs: []
+      - name: Lid
+        status: todo
+        needs: []
+`
+
+func TestPlanNextSendsJob(t *testing.T) {
+	s, _ := setupTestServer(t)
+	s.JobsDir = t.TempDir()
+	if err := os.WriteFile(filepath.Join(s.PlansDir, "widget.yaml"), []byte(sendPlanYAML), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(s.JobsDir, "widget-1.3mf"), []byte("PK"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	s.Printers = NewPrinterManager()
+	sim := NewSimAdapter("X1C", SimScenario{})
+	if err := s.Printers.AddAdapter("X1C", sim); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Printers.AddAdapter("MK4", &fakeAdapter{}); err != nil {
+		t.Fatal(err)
+	}
+	defer s.Printers.Close()
+
+	next := func(plate, printer string) *httptest.ResponseRecorder {
+		body, _ := json.Marshal(map[string]any{"project": "Widget", "plate": plate, "printer": printer, "send": true})
+		w := httptest.NewRecorder()
+		s.Routes().ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/api/fil/plans/widget.yaml/next", bytes.NewReader(body)))
+		return w
+	}
+	plateStatus := func(name string) string {
+		data, _ := os.ReadFile(filepath.Join(s.PlansDir, "widget.yaml"))
+		var pf models.PlanFile
+		_ = yaml.Unmarshal(data, &pf)
+		for _, p := range pf.Projects[0].Plates {
+			if p.Name == name {
+				return p.Status
+			}
+		}
+		return ""
+	}
+
+	if w := next("Lid", "X1C"); w.Code != http.StatusBadRequest {
+		t.Errorf("plate without a sliced file: status %d, want 400", w.Code)
+	}
+	if w := next("Base", "MK4"); w.Code != http.StatusNotImplemented || plateStatus("Base") != "todo" {
+		t.Errorf("unsupported printer: status %d, plate %s; want 501 and still todo", w.Code, plateStatus("Base"))
+	}
+
+	if w := next("Base", "X1C"); w.Code != http.StatusOK {
+		t.Fatalf("send: status %d, body %q", w.Code, w.Body.String())
+	}
+	if st := sim.Status(); st.State != "printing" || st.CurrentFile != "widget.3mf" {
+		t.Errorf("printer = %s %q, want printing widget.3mf", st.State, st.CurrentFile)
+	}
+	if got := plateStatus("Base"); got != "in-progress" {
+		t.Errorf("plate status = %q, want in-progress", got)
+	}
+
+	// The printer is busy now, so a second send is refused and not recorded.
+	if w := next("Base", "X1C"); w.Code != http.StatusBadGateway {
+		t.Errorf("send to a busy printer: status %d, want 502", w.Code)
+	}
+}
+
+// A job the printer took stays reported as started even when the plate
+// can't be saved, so the client doesn't tell the user nothing happened.
+func TestPlanNextSendReportsUnrecordedPlate(t *testing.T) {
+	s, _ := setupTestServer(t)
+	s.JobsDir = t.TempDir()
+	if err := os.WriteFile(filepath.Join(s.PlansDir, "widget.yaml"), []byte(sendPlanYAML), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(s.JobsDir, "widget-1.3mf"), []byte("PK"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	s.PlanOps = &fakePlanOps{nextErr: errors.New("save plan: disk full")}
+	s.Printers = NewPrinterManager()
+	sim := NewSimAdapter("X1C", SimScenario{})
+	if err := s.Printers.AddAdapter("X1C", sim); err != nil {
+		t.Fatal(err)
+	}
+	defer s.Printers.Close()
+
+	body, _ := json.Marshal(map[string]any{"project": "Widget", "plate": "Base", "printer": "X1C", "send": true})
+	w := httptest.NewRecorder()
+	s.Routes().ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/api/fil/plans/widget.yaml/next", bytes.NewReader(body)))
+	if w.Code != http.StatusAccepted || !strings.Contains(w.Body.String(), "disk full") {
+		t.Errorf("status %d, body %q; want 202 with the save error", w.Code, w.Body.String())
+	}
+	if st := sim.Status(); st.State != "printing" {
+		t.Errorf("printer = %s, want printing", st.State)
+	}
+}
+
+func TestPutJobStoresSlicedFile(t *testing.T) {
+	s, _ := setupTestServer(t)
+	s.JobsDir = filepath.Join(t.TempDir(), "jobs")
+
+	w := httptest.NewRecorder()
+	s.Routes().ServeHTTP(w, httptest.NewRequest(http.MethodPut, "/api/fil/jobs/Bracket.gcode.3mf", strings.NewReader("PK")))
+	if w.Code != http.StatusCreated {
+		t.Fatalf("status = %d, body %q", w.Code, w.Body.String())
+	}
+	var got struct{ Filename string }
+	_ = json.NewDecoder(w.Body).Decode(&got)
+	if !strings.HasPrefix(got.Filename, "Bracket-") || !strings.HasSuffix(got.Filename, ".gcode.3mf") {
+		t.Errorf("filename = %q", got.Filename)
+	}
+	if data, err := os.ReadFile(filepath.Join(s.JobsDir, got.Filename)); err != nil || string(data) != "PK" {
+		t.Errorf("stored file = %q, %v", data, err)
+	}
+
+	w = httptest.NewRecorder()
+	s.Routes().ServeHTTP(w, httptest.NewRequest(http.MethodPut, "/api/fil/jobs/notes.txt", strings.NewReader("x")))
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("non-job upload: status %d, want 400", w.Code)
+	}
+}
+
+// Uploads of the same file in the same second must not overwrite each other.
+func TestSaveJobKeepsSameSecondUploads(t *testing.T) {
+	s := &PlanServer{JobsDir: t.TempDir()}
+	seen := map[string]bool{}
+	for i := 0; i < 3; i++ {
+		stored, err := s.saveJob("Bracket.gcode", strings.NewReader(strconv.Itoa(i)))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if seen[stored] {
+			t.Fatalf("upload %d reused %q", i, stored)
+		}
+		seen[stored] = true
+		if data, err := os.ReadFile(filepath.Join(s.JobsDir, stored)); err != nil || string(data) != strconv.Itoa(i) {
+			t.Errorf("%s = %q, %v", stored, data, err)
+		}
+	}
+}
+
+func TestPrinterJobName(t *testing.T) {
+	for _, tc := range []struct {
+		plate models.Plate
+		want  string
+	}{
+		{models.Plate{File: "widget", Sliced: "widget-20260101.3mf"}, "widget.3mf"},
+		{models.Plate{File: "Widget.gcode.3mf", Sliced: "w-1.gcode.3mf"}, "Widget.gcode.3mf"},
+		{models.Plate{Sliced: "w-1.bgcode"}, "w-1.bgcode"},
+	} {
+		if got := printerJobName(tc.plate); got != tc.want {
+			t.Errorf("printerJobName(%+v) = %q, want %q", tc.plate, got, tc.want)
+		}
+	}
+}
@@ -1,21 +1,74 @@
 package server
 
 import (
+	"errors"
 	"fmt"
+	"strings"
 	"time"
 )
 
+// ErrNotSupported is wrapped by adapter methods the printer type can't do
+// (e.g. a chamber light on a Prusa).
+var ErrNotSupported = errors.New("not supported")
+
+// SpeedProfile is one of Bambu's four print speed levels. Printers without
+// named levels get Percent as a feed-rate override (M220).
+type SpeedProfile struct {
+	Name    string
+	Level   int // Bambu spd_lvl
+	Percent int
+}
+
+// SpeedProfiles lists the speed levels slowest first.
+var SpeedProfiles = []SpeedProfile{
+	{Name: "silent", Level: 1, Percent: 50},
+	{Name: "standard", Level: 2, Percent: 100},
+	{Name: "sport", Level: 3, Percent: 124},
+	{Name: "ludicrous", Level: 4, Percent: 166},
+}
+
+// speedProfileName returns the name for a Bambu spd_lvl, or "" if unknown.
+func speedProfileName(level int) string {
+	for _, p := range SpeedProfiles {
+		if p.Level == level {
+			return p.Name
+		}
+	}
+	return ""
+}
+
+// LookupSpeedProfile finds a profile by name, case-insensitively.
+func LookupSpeedProfile(name string) (SpeedProfile, error) {
+	for _, p := range SpeedProfiles {
+		if strings.EqualFold(p.Name, strings.TrimSpace(name)) {
+			return p, nil
+		}
+	}
+	var names []string
+	for _, p := range SpeedProfiles {
+		names = append(names, p.Name)
+	}
+	return SpeedProfile{}, fmt.Errorf("unknown speed %q (want %s)", name, strings.Join(names, ", "))
+}
+
+// PrintJob is a sliced file to send to a printer.
+type PrintJob struct {
+	Path  string // local file to upload
+	Name  string // file name on the printer, e.g. "bracket.gcode.3mf"
+	Plate int    // plate number within a multi-plate 3MF; 0 means 1
+}
+
 // PrinterState represents the current state of a printer.
 type PrinterState struct {
 	Name          string    `json:"name"`
-	Type          string    `json:"type"`                     // "bambu" or "prusa"
+	Type          string    `json:"type"`                     // "bambu", "prusa", "klipper", "octoprint", or "sim"
 	State         string    `json:"state"`                    // "idle", "printing", "paused", "finished", "failed", "offline"
 	Progress      int       `json:"progress,omitempty"`       // 0-100
 	RemainingMins int       `json:"remaining_mins,omitempty"` // minutes remaining
 	CurrentFile   string    `json:"current_file,omitempty"`
 	Layer         int       `json:"layer,omitempty"`
 	TotalLayers   int       `json:"total_layers,omitempty"`
-	ActiveTray    int       `json:"active_tray,omitempty"` // active AMS tray index (-1 if unknown)
+	ActiveTray    int       `json:"active_tray,omitempty"` // TrayIndex of the active tray (-1 if unknown)
 	LastUpdated   time.Time `json:"last_updated"`
 	// LastFinishedAt records the most recent moment this printer transitioned
 	// into the "finished" state. Used by history logging so a print's wall-clock
@@ -25,6 +78,15 @@ type PrinterState struct {
 	// remains usable if the user clears the bed before completing in fil.
 	LastFinishedAt time.Time  `json:"last_finished_at,omitzero"`
 	Trays          []TrayInfo `json:"trays,omitempty"`
+	// Speed and Light are the speed profile name and chamber light ("on" or
+	// "off"), for printers that report them.
+	Speed string `json:"speed,omitempty"`
+	Light string `json:"light,omitempty"`
+	// HMS lists the printer's active HMS codes with their catalog entries.
+	HMS []HMSInfo `json:"hms,omitempty"`
+	// Telemetry is the latest temperature, fan, and AMS environment report,
+	// for printers that send one.
+	Telemetry *Telemetry `json:"telemetry,omitempty"`
 }
 
 // TrayInfo represents the state of a single filament tray/slot as reported by the printer.
@@ -36,6 +98,26 @@ type TrayInfo struct {
 	TempMin int    `json:"temp_min,omitempty"`
 	TempMax int    `json:"temp_max,omitempty"`
 	InfoIdx string `json:"info_idx,omitempty"` // Bambu filament profile ID
+	// Remain is the printer's estimate of filament left, 0-100 percent; -1
+	// when it has none (no RFID spool, or remaining tracking is off).
+	Remain int `json:"remain"`
+	// TrayWeight is the spool's nominal filament weight in grams (e.g. 1000),
+	// 0 when unknown.
+	TrayWeight int `json:"tray_weight,omitempty"`
+	// UUID is the spool's RFID tag (Bambu tray_uuid); empty for spools
+	// without one. Both tags on a Bambu spool share it, unlike tag_uid.
+	UUID string `json:"uuid,omitempty"`
+	// SubBrand is the product line an RFID spool reports, e.g. "PLA Basic".
+	SubBrand string `json:"sub_brand,omitempty"`
+}
+
+// EstimatedGrams converts Remain into grams of filament; ok is false when the
+// printer has no usable estimate for this tray.
+func (t TrayInfo) EstimatedGrams() (float64, bool) {
+	if t.Remain < 0 || t.TrayWeight <= 0 {
+		return 0, false
+	}
+	return float64(t.Remain) / 100 * float64(t.TrayWeight), true
 }
 
 // TrayUpdate contains the fields to push to a printer tray.
@@ -61,6 +143,13 @@ type StateChangeEvent struct {
 	NewState     string
 	HMSCodes     []HMSCode // current HMS codes at time of change
 	PrevHMSCodes []HMSCode // HMS codes before the change
+	// Progress, Layer, and TotalLayers are the furthest the job got before
+	// this transition. Adapters report the pre-transition values because
+	// printers often zero them in the same report that ends the job; used
+	// to estimate wasted filament when a print fails or is cancelled.
+	Progress    int
+	Layer       int
+	TotalLayers int
 }
 
 // IsLikelyUserPause returns true if the pause appears to be user-initiated.
@@ -80,45 +169,8 @@ func (h HMSCode) HMSCodeString() string {
 		(h.Code>>16)&0xFFFF, h.Code&0xFFFF)
 }
 
-// hmsDescriptions maps known HMS codes to human-friendly descriptions.
-// Verified against https://wiki.bambulab.com/en/hms/home
-// Add new codes as they are encountered in practice.
-var hmsDescriptions = map[string]string{
-	// Spaghetti / First layer
-	"0C00-0300-0003-0007": "possible first layer defects",
-	"0C00-0300-0003-0008": "possible spaghetti defects",
-	"0C00-0300-0003-000B": "inspecting first layer",
-	"0C00-0300-0002-0001": "filament exposure metering failed",
-	"0C00-0300-0002-0002": "first layer inspection terminated due to abnormal lidar data",
-
-	// Lidar
-	"0C00-0100-0001-0004": "micro lidar lens dirty",
-
-	// Toolhead
-	"0300-1200-0002-0001": "toolhead front cover fell off",
-
-	// Build plate / heatbed
-	"0300-0D00-0001-0003": "build plate not properly placed",
-	"0300-0D00-0002-0001": "heatbed homing abnormal: possible bulge on heatbed or dirty nozzle tip",
-
-	// AMS assist motor
-	"0700-8010-0012-0805": "AMS assist motor overloaded",
-
-	// AMS filament ran out (07XX-7000-0002-0007, XX = AMS number)
-	"0700-7000-0002-0007": "AMS 1 filament ran out",
-	"0701-7000-0002-0007": "AMS 2 filament ran out",
-	"0702-7000-0002-0007": "AMS 3 filament ran out",
-	"0703-7000-0002-0007": "AMS 4 filament ran out",
-}
-
-// HMSDescription returns a human-friendly description for an HMS code,
-// or an empty string if the code is not recognized.
-func (h HMSCode) HMSDescription() string {
-	return hmsDescriptions[h.HMSCodeString()]
-}
-
 // PrinterAdapter defines the interface for communicating with a printer.
-// Each printer type (Bambu, Prusa) implements this interface.
+// Each printer type (Bambu, Prusa, Klipper, OctoPrint, simulated) implements this interface.
 type PrinterAdapter interface {
 	// Connect establishes a connection to the printer.
 	Connect() error
@@ -130,9 +182,25 @@ type PrinterAdapter interface {
 	Status() PrinterState
 
 	// PushTray updates the filament metadata for a specific tray.
-	// Returns an error if the printer doesn't support writes (e.g. Prusa).
+	// Returns an error if the printer doesn't support writes (e.g. Prusa,
+	// or Klipper without an MMU macro).
 	PushTray(update TrayUpdate) error
 
 	// OnStateChange registers a callback for state transitions.
 	OnStateChange(func(event StateChangeEvent))
+
+	// Pause, Resume, and Stop control the current print.
+	Pause() error
+	Resume() error
+	Stop() error
+
+	// SetLight switches the chamber light and SetSpeed picks a speed
+	// profile. Both return an error wrapping ErrNotSupported on printers
+	// without the feature.
+	SetLight(on bool) error
+	SetSpeed(profile SpeedProfile) error
+
+	// StartPrint uploads a sliced file and starts printing it, returning
+	// once the printer has accepted (or refused) the job.
+	StartPrint(job PrintJob) error
 }
@@ -0,0 +1,71 @@
+package server
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+)
+
+// The control endpoints take everything from the path and need no body, so
+// a phone shortcut can hit them with a bare POST.
+
+func (s *PlanServer) handlePrinterPause(w http.ResponseWriter, r *http.Request) {
+	s.controlPrinter(w, r, PrinterAdapter.Pause)
+}
+
+func (s *PlanServer) handlePrinterResume(w http.ResponseWriter, r *http.Request) {
+	s.controlPrinter(w, r, PrinterAdapter.Resume)
+}
+
+func (s *PlanServer) handlePrinterStop(w http.ResponseWriter, r *http.Request) {
+	s.controlPrinter(w, r, PrinterAdapter.Stop)
+}
+
+func (s *PlanServer) handlePrinterLight(w http.ResponseWriter, r *http.Request) {
+	var on bool
+	switch r.PathValue("mode") {
+	case "on":
+		on = true
+	case "off":
+	default:
+		http.Error(w, fmt.Sprintf("invalid light mode %q (want on or off)", r.PathValue("mode")), http.StatusBadRequest)
+		return
+	}
+	s.controlPrinter(w, r, func(a PrinterAdapter) error { return a.SetLight(on) })
+}
+
+func (s *PlanServer) handlePrinterSpeed(w http.ResponseWriter, r *http.Request) {
+	profile, err := LookupSpeedProfile(r.PathValue("profile"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	s.controlPrinter(w, r, func(a PrinterAdapter) error { return a.SetSpeed(profile) })
+}
+
+// controlPrinter runs fn against the printer named in the path: 404 for an
+// unknown printer, 501 when the printer type can't do it, 204 on success.
+func (s *PlanServer) controlPrinter(w http.ResponseWriter, r *http.Request, fn func(PrinterAdapter) error) {
+	if s.Printers == nil {
+		http.Error(w, "no printer connections configured", http.StatusBadRequest)
+		return
+	}
+
+	name := r.PathValue("name")
+	adapter, ok := s.Printers.Adapter(name)
+	if !ok {
+		http.Error(w, fmt.Sprintf("printer %q not found", name), http.StatusNotFound)
+		return
+	}
+
+	if err := fn(adapter); err != nil {
+		status := http.StatusInternalServerError
+		if errors.Is(err, ErrNotSupported) {
+			status = http.StatusNotImplemented
+		}
+		http.Error(w, err.Error(), status)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
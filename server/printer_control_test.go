@@ -0,0 +1,62 @@
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestPrinterControlEndpoints(t *testing.T) {
+	s, _ := setupTestServer(t)
+	s.Printers = NewPrinterManager()
+	progress := 30
+	sim := NewSimAdapter("X1C", SimScenario{State: "printing", Steps: []SimStep{{After: time.Hour, Progress: &progress}}})
+	if err := s.Printers.AddAdapter("X1C", sim); err != nil {
+		t.Fatal(err)
+	}
+	defer s.Printers.Close()
+	if err := s.Printers.AddAdapter("MK4", &fakeAdapter{}); err != nil {
+		t.Fatal(err)
+	}
+
+	post := func(path string) int {
+		w := httptest.NewRecorder()
+		s.Routes().ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/api/fil/printers/"+path, nil))
+		return w.Code
+	}
+
+	if code := post("X1C/pause"); code != http.StatusNoContent {
+		t.Fatalf("pause = %d, want 204", code)
+	}
+	if got := sim.Status().State; got != "paused" {
+		t.Errorf("state after pause = %q", got)
+	}
+	if code := post("X1C/pause"); code != http.StatusInternalServerError {
+		t.Errorf("pausing a paused print = %d, want 500", code)
+	}
+	if code := post("X1C/resume"); code != http.StatusNoContent || sim.Status().State != "printing" {
+		t.Errorf("resume = %d, state %q", code, sim.Status().State)
+	}
+	if code := post("X1C/light/on"); code != http.StatusNoContent || sim.Status().Light != "on" {
+		t.Errorf("light on = %d, light %q", code, sim.Status().Light)
+	}
+	if code := post("X1C/speed/Sport"); code != http.StatusNoContent || sim.Status().Speed != "sport" {
+		t.Errorf("speed = %d, speed %q", code, sim.Status().Speed)
+	}
+	if code := post("X1C/stop"); code != http.StatusNoContent || sim.Status().State != "idle" {
+		t.Errorf("stop = %d, state %q", code, sim.Status().State)
+	}
+
+	for path, want := range map[string]int{
+		"X1C/light/dim":    http.StatusBadRequest,
+		"X1C/speed/warp":   http.StatusBadRequest,
+		"nope/pause":       http.StatusNotFound,
+		"MK4/light/on":     http.StatusNotImplemented,
+		"MK4/speed/silent": http.StatusNotImplemented,
+	} {
+		if code := post(path); code != want {
+			t.Errorf("%s = %d, want %d", path, code, want)
+		}
+	}
+}
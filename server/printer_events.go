@@ -0,0 +1,155 @@
+package server
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// printerEventsFileName is the JSONL log (in PlansDir, next to
+// print-history.jsonl) of every printer state transition.
+const printerEventsFileName = "printer-events.jsonl"
+
+// Event kinds, classified when the transition is logged so readers don't
+// have to re-derive them from state pairs.
+const (
+	EventStart  = "start"
+	EventFinish = "finish"
+	EventFail   = "fail"
+	EventPause  = "pause"
+	EventResume = "resume"
+)
+
+// PrinterEvent is one logged state transition.
+type PrinterEvent struct {
+	Timestamp   time.Time `json:"timestamp"`
+	Printer     string    `json:"printer"`
+	From        string    `json:"from"`
+	To          string    `json:"to"`
+	Kind        string    `json:"kind,omitempty"`
+	File        string    `json:"file,omitempty"`
+	Progress    int       `json:"progress,omitempty"`
+	Layer       int       `json:"layer,omitempty"`
+	TotalLayers int       `json:"total_layers,omitempty"`
+	HMS         []HMSInfo `json:"hms,omitempty"`
+}
+
+// eventKind classifies a transition. A paused->paused event (new HMS codes
+// mid-pause) is still a pause.
+func eventKind(event StateChangeEvent) string {
+	switch {
+	case IsNewPrint(event):
+		return EventStart
+	case event.NewState == "printing" && event.OldState == "paused":
+		return EventResume
+	case event.NewState == "paused":
+		return EventPause
+	case event.NewState == "finished":
+		return EventFinish
+	case IsAbortedPrint(event):
+		return EventFail
+	}
+	return ""
+}
+
+// EventLog appends PrinterEvents to printer-events.jsonl.
+type EventLog struct {
+	path string
+	mu   sync.Mutex
+}
+
+// NewEventLog returns a log backed by printer-events.jsonl in dir.
+func NewEventLog(dir string) *EventLog {
+	return &EventLog{path: filepath.Join(dir, printerEventsFileName)}
+}
+
+// Record appends a transition on printer. file is the job the printer
+// reported at the time.
+func (l *EventLog) Record(printer, file string, event StateChangeEvent) error {
+	ev := PrinterEvent{
+		Timestamp:   time.Now().UTC(),
+		Printer:     printer,
+		From:        event.OldState,
+		To:          event.NewState,
+		Kind:        eventKind(event),
+		File:        file,
+		Progress:    event.Progress,
+		Layer:       event.Layer,
+		TotalLayers: event.TotalLayers,
+		HMS:         hmsInfos(event.HMSCodes),
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	f, err := os.OpenFile(l.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return fmt.Errorf("open printer events: %w", err)
+	}
+	defer f.Close()
+	return json.NewEncoder(f).Encode(ev)
+}
+
+// handlePrinterEvents serves a printer's logged transitions, oldest first.
+// "since" (YYYY-MM-DD) and "limit" (most recent N) filter like /history.
+func (s *PlanServer) handlePrinterEvents(w http.ResponseWriter, r *http.Request) {
+	name := r.PathValue("name")
+
+	var since time.Time
+	if v := r.URL.Query().Get("since"); v != "" {
+		t, err := time.Parse("2006-01-02", v)
+		if err != nil {
+			http.Error(w, "since must be YYYY-MM-DD", http.StatusBadRequest)
+			return
+		}
+		since = t
+	}
+	limit, _ := strconv.Atoi(r.URL.Query().Get("limit"))
+
+	events, err := readPrinterEvents(filepath.Join(s.PlansDir, printerEventsFileName), name, since)
+	if err != nil {
+		http.Error(w, "failed to read printer events", http.StatusInternalServerError)
+		return
+	}
+	if limit > 0 && len(events) > limit {
+		events = events[len(events)-limit:]
+	}
+	if events == nil {
+		events = []PrinterEvent{}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(events)
+}
+
+// readPrinterEvents returns printer's events at or after since. A missing log
+// is empty; malformed lines are skipped.
+func readPrinterEvents(path, printer string, since time.Time) ([]PrinterEvent, error) {
+	f, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var events []PrinterEvent
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		var ev PrinterEvent
+		if err := json.Unmarshal(scanner.Bytes(), &ev); err != nil {
+			continue
+		}
+		if ev.Printer != printer || ev.Timestamp.Before(since) {
+			continue
+		}
+		events = append(events, ev)
+	}
+	return events, scanner.Err()
+}
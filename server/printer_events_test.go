@@ -0,0 +1,116 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestEventKind(t *testing.T) {
+	tests := []struct {
+		from, to string
+		progress int
+		want     string
+	}{
+		{"idle", "printing", 0, EventStart},
+		{"finished", "printing", 0, EventStart},
+		{"paused", "printing", 40, EventResume},
+		{"printing", "paused", 40, EventPause},
+		{"paused", "paused", 40, EventPause},
+		{"printing", "finished", 100, EventFinish},
+		{"printing", "failed", 40, EventFail},
+		{"paused", "idle", 40, EventFail},
+		{"finished", "idle", 100, ""},
+	}
+	for _, tt := range tests {
+		got := eventKind(StateChangeEvent{OldState: tt.from, NewState: tt.to, Progress: tt.progress})
+		if got != tt.want {
+			t.Errorf("%s -> %s: kind = %q, want %q", tt.from, tt.to, got, tt.want)
+		}
+	}
+}
+
+func TestPrinterManagerLogsEvents(t *testing.T) {
+	dir := t.TempDir()
+	pm := NewPrinterManager()
+	pm.SetEventLog(NewEventLog(dir))
+	file := "benchy.3mf"
+	sim := NewSimAdapter("Sim", SimScenario{
+		State: "idle",
+		Steps: []SimStep{
+			{After: time.Millisecond, State: "printing", File: &file},
+			{After: time.Millisecond, State: "paused", HMS: []string{"0C00-0300-0003-0008"}},
+		},
+	})
+	if err := pm.AddAdapter("Sim", sim); err != nil {
+		t.Fatal(err)
+	}
+	defer pm.Close()
+
+	path := filepath.Join(dir, printerEventsFileName)
+	var events []PrinterEvent
+	waitFor(t, "two logged events", func() bool {
+		events, _ = readPrinterEvents(path, "Sim", time.Time{})
+		return len(events) == 2
+	})
+	start, pause := events[0], events[1]
+	if start.Kind != EventStart || start.File != "benchy.3mf" {
+		t.Errorf("start = %+v", start)
+	}
+	if pause.Kind != EventPause || len(pause.HMS) != 1 || pause.HMS[0].Description != "possible spaghetti defects" {
+		t.Errorf("pause = %+v", pause)
+	}
+}
+
+func TestHandlePrinterEvents(t *testing.T) {
+	s, _ := setupTestServer(t)
+	day := func(d int) time.Time { return time.Date(2026, 5, d, 12, 0, 0, 0, time.UTC) }
+	var lines []byte
+	for _, ev := range []PrinterEvent{
+		{Timestamp: day(1), Printer: "X1C", From: "idle", To: "printing", Kind: EventStart},
+		{Timestamp: day(2), Printer: "MK4", From: "idle", To: "printing", Kind: EventStart},
+		{Timestamp: day(3), Printer: "X1C", From: "printing", To: "finished", Kind: EventFinish},
+		{Timestamp: day(4), Printer: "X1C", From: "finished", To: "printing", Kind: EventStart},
+	} {
+		b, _ := json.Marshal(ev)
+		lines = append(append(lines, b...), '\n')
+	}
+	lines = append(lines, "not json\n"...)
+	if err := os.WriteFile(filepath.Join(s.PlansDir, printerEventsFileName), lines, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	get := func(query string) []PrinterEvent {
+		t.Helper()
+		w := httptest.NewRecorder()
+		s.Routes().ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/fil/printers/X1C/events"+query, nil))
+		if w.Code != http.StatusOK {
+			t.Fatalf("status %d: %s", w.Code, w.Body.String())
+		}
+		var got []PrinterEvent
+		if err := json.NewDecoder(w.Body).Decode(&got); err != nil {
+			t.Fatal(err)
+		}
+		return got
+	}
+
+	if got := get(""); len(got) != 3 {
+		t.Errorf("all X1C events = %d, want 3", len(got))
+	}
+	if got := get("?since=2026-05-03"); len(got) != 2 || got[0].Kind != EventFinish {
+		t.Errorf("since = %+v", got)
+	}
+	if got := get("?limit=1"); len(got) != 1 || !got[0].Timestamp.Equal(day(4)) {
+		t.Errorf("limit = %+v", got)
+	}
+
+	w := httptest.NewRecorder()
+	s.Routes().ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/fil/printers/X1C/events?since=May", nil))
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("bad since: status %d, want 400", w.Code)
+	}
+}
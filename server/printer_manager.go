@@ -8,8 +8,10 @@ import (
 
 // PrinterManager manages connections to all configured printers.
 type PrinterManager struct {
-	mu       sync.RWMutex
-	adapters map[string]PrinterAdapter // keyed by printer name
+	mu        sync.RWMutex
+	adapters  map[string]PrinterAdapter // keyed by printer name
+	snapshots *SnapshotStore
+	events    *EventLog
 }
 
 // NewPrinterManager creates a new printer manager.
@@ -19,22 +21,84 @@ func NewPrinterManager() *PrinterManager {
 	}
 }
 
-// AddAdapter registers a printer adapter and connects to the printer.
+// SetSnapshots persists each printer's state to store on every transition
+// and restores it when the printer is added. Call before Add.
+func (pm *PrinterManager) SetSnapshots(store *SnapshotStore) {
+	pm.snapshots = store
+}
+
+// SetEventLog records every printer's state transitions to log. Call before
+// Add.
+func (pm *PrinterManager) SetEventLog(log *EventLog) {
+	pm.events = log
+}
+
+// AddAdapter registers a printer adapter and connects to the printer. A
+// caller with its own OnStateChange callbacks should use Add, register them,
+// then Connect: an adapter reports the transition from its restored state
+// during Connect, and a callback registered after it misses that.
 func (pm *PrinterManager) AddAdapter(name string, adapter PrinterAdapter) error {
-	if err := adapter.Connect(); err != nil {
-		return err
+	pm.Add(name, adapter)
+	return pm.Connect(name)
+}
+
+// Add registers a printer adapter without connecting it: it restores the
+// saved state and hooks up snapshots and the event log.
+func (pm *PrinterManager) Add(name string, adapter PrinterAdapter) {
+	if r, ok := adapter.(StateRestorer); ok && pm.snapshots != nil {
+		if snap, ok := pm.snapshots.Get(name); ok {
+			r.Restore(snap)
+		}
+	}
+	adapter.OnStateChange(func(event StateChangeEvent) { logUnknownHMS(name, event.HMSCodes) })
+	if pm.snapshots != nil {
+		adapter.OnStateChange(func(StateChangeEvent) { pm.saveSnapshot(adapter) })
+	}
+	if pm.events != nil {
+		adapter.OnStateChange(func(event StateChangeEvent) {
+			if err := pm.events.Record(name, adapter.Status().CurrentFile, event); err != nil {
+				fmt.Printf("[printer-events] %s: %v\n", name, err)
+			}
+		})
 	}
 	pm.mu.Lock()
 	pm.adapters[name] = adapter
 	pm.mu.Unlock()
+}
+
+// Connect connects a printer added with Add. A printer that can't connect
+// is dropped.
+func (pm *PrinterManager) Connect(name string) error {
+	pm.mu.RLock()
+	adapter, ok := pm.adapters[name]
+	pm.mu.RUnlock()
+	if !ok {
+		return fmt.Errorf("printer %q not found", name)
+	}
+	if err := adapter.Connect(); err != nil {
+		pm.mu.Lock()
+		delete(pm.adapters, name)
+		pm.mu.Unlock()
+		return err
+	}
 	return nil
 }
 
-// Close disconnects all printers.
+func (pm *PrinterManager) saveSnapshot(adapter PrinterAdapter) {
+	st := adapter.Status()
+	if err := pm.snapshots.Save(st); err != nil {
+		fmt.Printf("[printer-state] %s: %v\n", st.Name, err)
+	}
+}
+
+// Close saves each printer's state, then disconnects all printers.
 func (pm *PrinterManager) Close() {
 	pm.mu.RLock()
 	defer pm.mu.RUnlock()
 	for _, adapter := range pm.adapters {
+		if pm.snapshots != nil {
+			pm.saveSnapshot(adapter)
+		}
 		_ = adapter.Close()
 	}
 }
@@ -0,0 +1,119 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// printerStateFileName is the JSON file (in PlansDir) holding each printer's
+// last known state, so a restarted server doesn't start from "offline".
+const printerStateFileName = "printer-state.json"
+
+// PrinterSnapshot is the part of a PrinterState worth keeping across a
+// restart. State is the last state the printer actually reported, never
+// "offline".
+type PrinterSnapshot struct {
+	State          string     `json:"state"`
+	LastFinishedAt time.Time  `json:"last_finished_at,omitzero"`
+	CurrentFile    string     `json:"current_file,omitempty"`
+	Progress       int        `json:"progress,omitempty"`
+	Trays          []TrayInfo `json:"trays,omitempty"`
+	SavedAt        time.Time  `json:"saved_at"`
+}
+
+// StateRestorer is implemented by adapters that can start from a snapshot
+// instead of "offline". PrinterManager calls Restore before Connect.
+type StateRestorer interface {
+	Restore(snap PrinterSnapshot)
+}
+
+// restore seeds st from a snapshot.
+func (st *PrinterState) restore(snap PrinterSnapshot) {
+	if snap.State != "" {
+		st.State = snap.State
+	}
+	st.LastFinishedAt = snap.LastFinishedAt
+	st.CurrentFile = snap.CurrentFile
+	st.Progress = snap.Progress
+	st.Trays = append([]TrayInfo(nil), snap.Trays...)
+}
+
+// SnapshotStore persists PrinterSnapshots keyed by printer name as a single
+// JSON object, rewritten on every save like PendingStore.
+type SnapshotStore struct {
+	path  string
+	mu    sync.Mutex
+	snaps map[string]PrinterSnapshot // nil until first loaded
+}
+
+// NewSnapshotStore returns a store backed by printer-state.json in dir.
+func NewSnapshotStore(dir string) *SnapshotStore {
+	return &SnapshotStore{path: filepath.Join(dir, printerStateFileName)}
+}
+
+// Get returns the saved snapshot for a printer.
+func (s *SnapshotStore) Get(name string) (PrinterSnapshot, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.loadLocked(); err != nil {
+		fmt.Printf("[printer-state] %v\n", err)
+		return PrinterSnapshot{}, false
+	}
+	snap, ok := s.snaps[name]
+	return snap, ok
+}
+
+// Save records st for its printer. An offline state keeps the previous
+// snapshot's State so a restart sees what the printer was last doing.
+func (s *SnapshotStore) Save(st PrinterState) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.loadLocked(); err != nil {
+		return err
+	}
+	state := st.State
+	if state == "offline" {
+		state = s.snaps[st.Name].State
+	}
+	s.snaps[st.Name] = PrinterSnapshot{
+		State:          state,
+		LastFinishedAt: st.LastFinishedAt,
+		CurrentFile:    st.CurrentFile,
+		Progress:       st.Progress,
+		Trays:          st.Trays,
+		SavedAt:        time.Now().UTC(),
+	}
+
+	data, err := json.MarshalIndent(s.snaps, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp := s.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return fmt.Errorf("write printer state: %w", err)
+	}
+	return os.Rename(tmp, s.path)
+}
+
+func (s *SnapshotStore) loadLocked() error {
+	if s.snaps != nil {
+		return nil
+	}
+	s.snaps = map[string]PrinterSnapshot{}
+	data, err := os.ReadFile(s.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("read printer state: %w", err)
+	}
+	if err := json.Unmarshal(data, &s.snaps); err != nil {
+		return fmt.Errorf("parse printer state: %w", err)
+	}
+	return nil
+}
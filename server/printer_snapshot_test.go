@@ -0,0 +1,142 @@
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestSnapshotStoreKeepsLastRealState(t *testing.T) {
+	dir := t.TempDir()
+	store := NewSnapshotStore(dir)
+	finishedAt := time.Date(2026, 5, 1, 10, 3, 0, 0, time.UTC)
+
+	if err := store.Save(PrinterState{Name: "X1C", State: "finished", LastFinishedAt: finishedAt, CurrentFile: "bracket", Progress: 100}); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.Save(PrinterState{Name: "X1C", State: "offline", LastFinishedAt: finishedAt, CurrentFile: "bracket", Progress: 100}); err != nil {
+		t.Fatal(err)
+	}
+
+	snap, ok := NewSnapshotStore(dir).Get("X1C")
+	if !ok {
+		t.Fatal("snapshot not persisted")
+	}
+	if snap.State != "finished" || !snap.LastFinishedAt.Equal(finishedAt) || snap.CurrentFile != "bracket" {
+		t.Errorf("snapshot = %+v", snap)
+	}
+	if _, ok := store.Get("MK4"); ok {
+		t.Error("unexpected snapshot for an unknown printer")
+	}
+}
+
+// A restart between FINISH and plan complete keeps the printer's own finish
+// time, and a parked printer's first report after the restart fires nothing.
+func TestBambuRestoredSnapshot(t *testing.T) {
+	dir := t.TempDir()
+	finishedAt := time.Date(2026, 5, 1, 10, 3, 0, 0, time.UTC)
+	if err := NewSnapshotStore(dir).Save(PrinterState{
+		Name: "X1C", State: "finished", LastFinishedAt: finishedAt,
+		Trays: []TrayInfo{{AmsID: 0, TrayID: 1, Type: "PLA"}},
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	b := NewBambuAdapter("X1C", "127.0.0.1", "00M00A000000000", "12345678")
+	var mu sync.Mutex
+	var fired []StateChangeEvent
+	b.OnStateChange(func(e StateChangeEvent) {
+		mu.Lock()
+		fired = append(fired, e)
+		mu.Unlock()
+	})
+
+	// Bambu's Connect needs a broker, so restore the way AddAdapter does.
+	snap, _ := NewSnapshotStore(dir).Get("X1C")
+	b.Restore(snap)
+	if st := b.Status(); st.State != "finished" || !st.LastFinishedAt.Equal(finishedAt) || len(st.Trays) != 1 {
+		t.Fatalf("restored state = %+v", st)
+	}
+
+	b.handleReport(reportPayload("FINISH"))
+	time.Sleep(20 * time.Millisecond)
+	mu.Lock()
+	if len(fired) != 0 {
+		t.Errorf("parked printer fired %+v after restore", fired)
+	}
+	mu.Unlock()
+	if got := b.Status().LastFinishedAt; !got.Equal(finishedAt) {
+		t.Errorf("LastFinishedAt = %v, want the pre-restart %v", got, finishedAt)
+	}
+}
+
+func TestPrinterManagerSavesOnTransition(t *testing.T) {
+	dir := t.TempDir()
+	pm := NewPrinterManager()
+	pm.SetSnapshots(NewSnapshotStore(dir))
+	sim := NewSimAdapter("Sim", SimScenario{
+		State: "printing",
+		Steps: []SimStep{{After: time.Millisecond, State: "finished"}},
+	})
+	if err := pm.AddAdapter("Sim", sim); err != nil {
+		t.Fatal(err)
+	}
+	defer pm.Close()
+
+	waitFor(t, "finished snapshot", func() bool {
+		snap, _ := NewSnapshotStore(dir).Get("Sim")
+		return snap.State == "finished" && !snap.LastFinishedAt.IsZero()
+	})
+}
+
+// A print that finished while the server was down reads as a real
+// printing -> finished transition on the first poll after the restart.
+// Callbacks are registered the way serve does: after Add, before Connect.
+// A print that finished while the server was down reaches all of them.
+func TestPrinterManagerRestoresBeforeConnect(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`{"printer":{"state":"FINISHED"}}`))
+	}))
+	defer srv.Close()
+
+	dir := t.TempDir()
+	if err := NewSnapshotStore(dir).Save(PrinterState{Name: "MK4", State: "printing", Progress: 97}); err != nil {
+		t.Fatal(err)
+	}
+	pm := NewPrinterManager()
+	snapshots := NewSnapshotStore(dir)
+	pm.SetSnapshots(snapshots)
+	pm.SetEventLog(NewEventLog(dir))
+	pm.Add("MK4", NewPrusaAdapter("MK4", strings.TrimPrefix(srv.URL, "http://"), "", ""))
+	defer pm.Close()
+
+	p, _ := pm.Adapter("MK4")
+	events := make(chan StateChangeEvent, 1)
+	p.OnStateChange(func(e StateChangeEvent) { events <- e })
+	if err := pm.Connect("MK4"); err != nil {
+		t.Fatal(err)
+	}
+
+	select {
+	case e := <-events:
+		if e.OldState != "printing" || e.NewState != "finished" || e.Progress != 97 {
+			t.Errorf("event = %+v", e)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("no transition after restore")
+	}
+	if p.Status().LastFinishedAt.IsZero() {
+		t.Error("expected LastFinishedAt stamped")
+	}
+	if snap, _ := snapshots.Get("MK4"); snap.State != "finished" {
+		t.Errorf("snapshot state = %q, want finished", snap.State)
+	}
+	if data, _ := os.ReadFile(filepath.Join(dir, printerEventsFileName)); !strings.Contains(string(data), `"kind":"finish"`) {
+		t.Errorf("event log = %q, want the finish", data)
+	}
+}
@@ -3,13 +3,21 @@ package server
 import (
 	"encoding/json"
 	"fmt"
+	"io"
 	"net/http"
+	"net/url"
+	"os"
+	"strings"
 	"sync"
 	"time"
 
 	"github.com/icholy/digest"
 )
 
+// prusaUploadTimeout bounds a StartPrint upload; PrusaLink writes to USB
+// at a few hundred KB/s.
+const prusaUploadTimeout = 10 * time.Minute
+
 // PrusaAdapter communicates with a Prusa printer via PrusaLink REST API.
 type PrusaAdapter struct {
 	name     string
@@ -21,6 +29,7 @@ type PrusaAdapter struct {
 	state          PrinterState
 	stateCallbacks []func(StateChangeEvent)
 	stopCh         chan struct{}
+	telemetry      TelemetryRing
 }
 
 // NewPrusaAdapter creates a new Prusa printer adapter.
@@ -70,6 +79,121 @@ func (p *PrusaAdapter) PushTray(update TrayUpdate) error {
 	return fmt.Errorf("prusa %s: tray updates not supported", p.name)
 }
 
+// Pause pauses the current job.
+func (p *PrusaAdapter) Pause() error {
+	return p.jobCommand(http.MethodPut, "pause")
+}
+
+// Resume resumes the paused job.
+func (p *PrusaAdapter) Resume() error {
+	return p.jobCommand(http.MethodPut, "resume")
+}
+
+// Stop cancels the current job.
+func (p *PrusaAdapter) Stop() error {
+	return p.jobCommand(http.MethodDelete, "")
+}
+
+// SetLight is not supported on Prusa printers.
+func (p *PrusaAdapter) SetLight(on bool) error {
+	return fmt.Errorf("prusa %s: light control %w", p.name, ErrNotSupported)
+}
+
+// SetSpeed is not supported: PrusaLink has no speed endpoint.
+func (p *PrusaAdapter) SetSpeed(profile SpeedProfile) error {
+	return fmt.Errorf("prusa %s: speed control %w", p.name, ErrNotSupported)
+}
+
+// StartPrint uploads the job to the USB drive with PUT /api/v1/files/usb/…
+// and starts it with a POST to the same path.
+func (p *PrusaAdapter) StartPrint(job PrintJob) error {
+	info, err := os.Stat(job.Path)
+	if err != nil {
+		return fmt.Errorf("prusa %s: %w", p.name, err)
+	}
+	open := func() (io.ReadCloser, error) { return os.Open(job.Path) }
+	body, err := open()
+	if err != nil {
+		return fmt.Errorf("prusa %s: %w", p.name, err)
+	}
+	fileURL := fmt.Sprintf("http://%s/api/v1/files/usb/%s", p.ip, url.PathEscape(job.Name))
+
+	req, err := http.NewRequest(http.MethodPut, fileURL, body)
+	if err != nil {
+		body.Close()
+		return err
+	}
+	// Set GetBody so the digest transport can replay the upload after the
+	// 401 challenge without buffering it.
+	req.GetBody = open
+	req.ContentLength = info.Size()
+	req.Header.Set("Content-Type", "application/octet-stream")
+	req.Header.Set("Overwrite-File", "?1")
+
+	client := p.httpClient()
+	client.Timeout = prusaUploadTimeout
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("prusa %s: upload: %w", p.name, err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusCreated && resp.StatusCode != http.StatusNoContent {
+		return fmt.Errorf("prusa %s: upload: HTTP %d", p.name, resp.StatusCode)
+	}
+
+	req, err = http.NewRequest(http.MethodPost, fileURL, nil)
+	if err != nil {
+		return err
+	}
+	resp, err = p.httpClient().Do(req)
+	if err != nil {
+		return fmt.Errorf("prusa %s: start: %w", p.name, err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusNoContent {
+		return fmt.Errorf("prusa %s: start: HTTP %d", p.name, resp.StatusCode)
+	}
+	return nil
+}
+
+// jobCommand sends a PrusaLink job action (PUT /api/v1/job/{id}/pause, ...)
+// for the job currently on the printer.
+func (p *PrusaAdapter) jobCommand(method, action string) error {
+	job, err := p.fetchJob()
+	if err != nil {
+		return fmt.Errorf("prusa %s: %w", p.name, err)
+	}
+	id, ok := job["id"].(float64)
+	if !ok {
+		return fmt.Errorf("prusa %s: no active job", p.name)
+	}
+	path := fmt.Sprintf("/api/v1/job/%d", int(id))
+	if action != "" {
+		path += "/" + action
+	}
+
+	req, err := http.NewRequest(method, fmt.Sprintf("http://%s%s", p.ip, path), nil)
+	if err != nil {
+		return err
+	}
+	resp, err := p.httpClient().Do(req)
+	if err != nil {
+		return fmt.Errorf("prusa %s: %w", p.name, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusNoContent && resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("prusa %s: %s job: HTTP %d", p.name, strings.ToLower(method), resp.StatusCode)
+	}
+	return nil
+}
+
+// Restore seeds the state from a snapshot saved before a restart.
+func (p *PrusaAdapter) Restore(snap PrinterSnapshot) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.state.restore(snap)
+}
+
 // OnStateChange registers a callback for printer state transitions.
 func (p *PrusaAdapter) OnStateChange(cb func(event StateChangeEvent)) {
 	p.mu.Lock()
@@ -133,6 +257,7 @@ func (p *PrusaAdapter) poll() error {
 func (p *PrusaAdapter) dispatchStateChange(oldState string) {
 	p.mu.RLock()
 	newState := p.state.State
+	progress := p.state.Progress
 	callbacks := p.stateCallbacks
 	p.mu.RUnlock()
 
@@ -142,9 +267,12 @@ func (p *PrusaAdapter) dispatchStateChange(oldState string) {
 		// after (re)connect," not a real transition; firing on it would
 		// announce "print finished" on every server restart whenever the
 		// printer is parked at FINISHED.
+		// Progress is only refreshed while printing/paused, so on the way
+		// out of a job it still holds the last value the job reported.
 		event := StateChangeEvent{
 			OldState: oldState,
 			NewState: newState,
+			Progress: progress,
 		}
 		for _, cb := range callbacks {
 			go cb(event)
@@ -173,10 +301,48 @@ func (p *PrusaAdapter) applyStatusUpdate(status map[string]interface{}) string {
 				p.state.LastFinishedAt = time.Now()
 			}
 		}
+		p.applyTelemetry(printerData)
 	}
 	return oldState
 }
 
+// applyTelemetry records the temperatures and fan speeds PrusaLink reports
+// alongside the state. Called with p.mu held.
+func (p *PrusaAdapter) applyTelemetry(printerData map[string]interface{}) {
+	t := Telemetry{At: p.state.LastUpdated}
+	seen := false
+	for key, dst := range map[string]*float64{
+		"temp_nozzle":   &t.Nozzle,
+		"target_nozzle": &t.NozzleTarget,
+		"temp_bed":      &t.Bed,
+		"target_bed":    &t.BedTarget,
+	} {
+		if v, ok := printerData[key].(float64); ok {
+			*dst = v
+			seen = true
+		}
+	}
+	for key, dst := range map[string]*int{
+		"fan_print":  &t.PrintFanRPM,
+		"fan_hotend": &t.HotendFanRPM,
+	} {
+		if v, ok := printerData[key].(float64); ok {
+			*dst = int(v)
+			seen = true
+		}
+	}
+	if !seen {
+		return
+	}
+	p.state.Telemetry = &t
+	p.telemetry.Record(t)
+}
+
+// TelemetryHistory returns the recorded telemetry, oldest first.
+func (p *PrusaAdapter) TelemetryHistory() []Telemetry {
+	return p.telemetry.Samples()
+}
+
 func (p *PrusaAdapter) fetchStatus() (map[string]interface{}, error) {
 	return p.fetch("/api/v1/status")
 }
@@ -188,15 +354,7 @@ func (p *PrusaAdapter) fetchJob() (map[string]interface{}, error) {
 func (p *PrusaAdapter) fetch(path string) (map[string]interface{}, error) {
 	url := fmt.Sprintf("http://%s%s", p.ip, path)
 
-	client := &http.Client{
-		Timeout: 5 * time.Second,
-		Transport: &digest.Transport{
-			Username: p.username,
-			Password: p.password,
-		},
-	}
-
-	resp, err := client.Get(url)
+	resp, err := p.httpClient().Get(url)
 	if err != nil {
 		return nil, err
 	}
@@ -217,6 +375,16 @@ func (p *PrusaAdapter) fetch(path string) (map[string]interface{}, error) {
 	return data, nil
 }
 
+func (p *PrusaAdapter) httpClient() *http.Client {
+	return &http.Client{
+		Timeout: 5 * time.Second,
+		Transport: &digest.Transport{
+			Username: p.username,
+			Password: p.password,
+		},
+	}
+}
+
 func normalizePrusaState(state string) string {
 	switch state {
 	case "IDLE":
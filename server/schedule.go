@@ -0,0 +1,36 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"path/filepath"
+
+	"github.com/dstockto/fil/plan"
+)
+
+// handleGetSchedule returns the saved print queue from `fil plan schedule
+// --save`, or an empty schedule when none has been saved.
+func (s *PlanServer) handleGetSchedule(w http.ResponseWriter, r *http.Request) {
+	sched, err := plan.ReadSchedule(filepath.Join(s.PlansDir, plan.ScheduleFileName))
+	if err != nil {
+		http.Error(w, fmt.Sprintf("read schedule: %v", err), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(sched)
+}
+
+// handlePutSchedule replaces the saved print queue.
+func (s *PlanServer) handlePutSchedule(w http.ResponseWriter, r *http.Request) {
+	var sched plan.Schedule
+	if err := json.NewDecoder(r.Body).Decode(&sched); err != nil {
+		http.Error(w, fmt.Sprintf("invalid JSON: %v", err), http.StatusBadRequest)
+		return
+	}
+	if err := plan.WriteSchedule(filepath.Join(s.PlansDir, plan.ScheduleFileName), sched); err != nil {
+		http.Error(w, fmt.Sprintf("save schedule: %v", err), http.StatusInternalServerError)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
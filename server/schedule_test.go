@@ -0,0 +1,57 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestSchedule_PutThenGet(t *testing.T) {
+	s, _ := setupTestServer(t)
+	mux := s.Routes()
+
+	// Nothing saved yet: an empty schedule, not a 404.
+	req := httptest.NewRequest(http.MethodGet, "/api/fil/schedule", nil)
+	w := httptest.NewRecorder()
+	mux.ServeHTTP(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("GET expected 200, got %d: %s", w.Code, w.Body.String())
+	}
+
+	body := `{"generated_at":"2026-05-01T09:00:00Z","queues":{"X1C":[{"plan":"widget.yaml","project":"Widget","plate":"Plate 1","start":"2026-05-01T09:00:00Z","duration":"1h","load":[100]}]}}`
+	req = httptest.NewRequest(http.MethodPut, "/api/fil/schedule", strings.NewReader(body))
+	w = httptest.NewRecorder()
+	mux.ServeHTTP(w, req)
+	if w.Code != http.StatusNoContent {
+		t.Fatalf("PUT expected 204, got %d: %s", w.Code, w.Body.String())
+	}
+
+	req = httptest.NewRequest(http.MethodGet, "/api/fil/schedule", nil)
+	w = httptest.NewRecorder()
+	mux.ServeHTTP(w, req)
+	var got struct {
+		Queues map[string][]struct {
+			Plate string `json:"plate"`
+			Load  []int  `json:"load"`
+		} `json:"queues"`
+	}
+	if err := json.NewDecoder(w.Body).Decode(&got); err != nil {
+		t.Fatal(err)
+	}
+	q := got.Queues["X1C"]
+	if len(q) != 1 || q[0].Plate != "Plate 1" || len(q[0].Load) != 1 || q[0].Load[0] != 100 {
+		t.Errorf("queue = %+v", q)
+	}
+}
+
+func TestSchedule_PutRejectsBadJSON(t *testing.T) {
+	s, _ := setupTestServer(t)
+	req := httptest.NewRequest(http.MethodPut, "/api/fil/schedule", strings.NewReader("not json"))
+	w := httptest.NewRecorder()
+	s.Routes().ServeHTTP(w, req)
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400, got %d", w.Code)
+	}
+}
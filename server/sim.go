@@ -0,0 +1,373 @@
+package server
+
+import (
+	"fmt"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// SimScenario scripts a simulated printer: its starting trays and state,
+// then a list of timed steps. Loaded from YAML, e.g.
+//
+//	state: idle
+//	trays:
+//	  - {ams_id: 0, tray_id: 0, color: FF0000, type: PLA, remain: 80, tray_weight: 1000}
+//	steps:
+//	  - {after: 5s, state: printing, file: bracket.3mf, progress: 0, total_layers: 120, remaining_mins: 60}
+//	  - {after: 30s, progress: 50, layer: 60, remaining_mins: 30}
+//	  - {after: 10s, state: paused, hms: ["0C00-0300-0003-0008"]}
+//	  - {after: 10s, state: printing, hms: []}
+//	  - {after: 30s, state: finished, progress: 100, layer: 120, remaining_mins: 0}
+type SimScenario struct {
+	State string    `yaml:"state"` // initial state; default "idle"
+	Trays []SimTray `yaml:"trays"`
+	Steps []SimStep `yaml:"steps"`
+	// Loop restarts the steps after the last one instead of holding there.
+	Loop bool `yaml:"loop"`
+}
+
+// SimTray is a tray's contents in a scenario.
+type SimTray struct {
+	AmsID      int    `yaml:"ams_id"`
+	TrayID     int    `yaml:"tray_id"`
+	Color      string `yaml:"color"`
+	Type       string `yaml:"type"`
+	Remain     *int   `yaml:"remain"` // omitted means unknown (-1)
+	TrayWeight int    `yaml:"tray_weight"`
+}
+
+// SimStep changes the simulated printer After the previous step. Omitted
+// fields keep their current value; hms and trays replace the whole list
+// when present (hms: [] clears the codes).
+type SimStep struct {
+	After         time.Duration `yaml:"after"`
+	State         string        `yaml:"state"`
+	File          *string       `yaml:"file"`
+	Progress      *int          `yaml:"progress"`
+	Layer         *int          `yaml:"layer"`
+	TotalLayers   *int          `yaml:"total_layers"`
+	RemainingMins *int          `yaml:"remaining_mins"`
+	HMS           []string      `yaml:"hms"`
+	Trays         []SimTray     `yaml:"trays"`
+}
+
+// LoadSimScenario reads and validates a scenario file.
+func LoadSimScenario(path string) (SimScenario, error) {
+	var sc SimScenario
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return sc, fmt.Errorf("read scenario: %w", err)
+	}
+	if err := yaml.Unmarshal(data, &sc); err != nil {
+		return sc, fmt.Errorf("parse scenario %s: %w", path, err)
+	}
+	for i, step := range sc.Steps {
+		if step.State != "" && !isSimState(step.State) {
+			return sc, fmt.Errorf("scenario %s: step %d: unknown state %q", path, i+1, step.State)
+		}
+		for _, code := range step.HMS {
+			if _, err := ParseHMSCode(code); err != nil {
+				return sc, fmt.Errorf("scenario %s: step %d: %w", path, i+1, err)
+			}
+		}
+	}
+	if sc.Loop && len(sc.Steps) > 0 {
+		var total time.Duration
+		for _, step := range sc.Steps {
+			total += step.After
+		}
+		if total <= 0 {
+			return sc, fmt.Errorf("scenario %s: a looping scenario needs steps with a nonzero after", path)
+		}
+	}
+	return sc, nil
+}
+
+func isSimState(s string) bool {
+	switch s {
+	case "idle", "printing", "paused", "finished", "failed", "offline":
+		return true
+	}
+	return false
+}
+
+// ParseHMSCode parses the wiki form HMSCodeString produces
+// (e.g. "0C00-0300-0003-0008") back into an HMSCode.
+func ParseHMSCode(s string) (HMSCode, error) {
+	parts := strings.Split(strings.TrimSpace(s), "-")
+	if len(parts) != 4 {
+		return HMSCode{}, fmt.Errorf("invalid HMS code %q", s)
+	}
+	var words [4]int
+	for i, p := range parts {
+		v, err := strconv.ParseUint(p, 16, 16)
+		if err != nil {
+			return HMSCode{}, fmt.Errorf("invalid HMS code %q", s)
+		}
+		words[i] = int(v)
+	}
+	return HMSCode{Attr: words[0]<<16 | words[1], Code: words[2]<<16 | words[3]}, nil
+}
+
+// SimAdapter is a PrinterAdapter that plays a SimScenario instead of
+// talking to hardware, so the notification, history and auto-complete
+// paths can be exercised without a printer on the network.
+type SimAdapter struct {
+	name     string
+	scenario SimScenario
+
+	mu             sync.RWMutex
+	state          PrinterState
+	hmsCodes       []HMSCode
+	stateCallbacks []func(StateChangeEvent)
+	stopCh         chan struct{}
+	closeOnce      sync.Once
+}
+
+// NewSimAdapter creates a simulated printer that plays scenario once
+// connected.
+func NewSimAdapter(name string, scenario SimScenario) *SimAdapter {
+	return &SimAdapter{
+		name:     name,
+		scenario: scenario,
+		state: PrinterState{
+			Name:       name,
+			Type:       "sim",
+			State:      "offline",
+			ActiveTray: -1,
+		},
+		stopCh: make(chan struct{}),
+	}
+}
+
+// Connect sets the scenario's starting state and starts playing its steps.
+func (s *SimAdapter) Connect() error {
+	s.mu.Lock()
+	s.state.State = s.scenario.State
+	if s.state.State == "" {
+		s.state.State = "idle"
+	}
+	s.state.Trays = simTrays(s.scenario.Trays)
+	s.state.LastUpdated = time.Now()
+	s.mu.Unlock()
+
+	go s.play()
+	return nil
+}
+
+// Close stops the scenario.
+func (s *SimAdapter) Close() error {
+	s.closeOnce.Do(func() { close(s.stopCh) })
+	return nil
+}
+
+// Status returns the current simulated state.
+func (s *SimAdapter) Status() PrinterState {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	st := s.state
+	st.Trays = append([]TrayInfo(nil), s.state.Trays...)
+	return st
+}
+
+// PushTray applies the update to the simulated tray, adding it if the
+// scenario didn't define it.
+func (s *SimAdapter) PushTray(update TrayUpdate) error {
+	color := strings.TrimPrefix(update.Color, "#")
+	if len(color) > 6 {
+		color = color[:6]
+	}
+	tray := TrayInfo{
+		AmsID:   update.AmsID,
+		TrayID:  update.TrayID,
+		Color:   strings.ToUpper(color),
+		Type:    update.Type,
+		TempMin: update.TempMin,
+		TempMax: update.TempMax,
+		InfoIdx: update.InfoIdx,
+		Remain:  -1,
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for i, t := range s.state.Trays {
+		if t.AmsID == update.AmsID && t.TrayID == update.TrayID {
+			// A pushed profile doesn't tell the printer how much is left.
+			tray.Remain, tray.TrayWeight = t.Remain, t.TrayWeight
+			s.state.Trays[i] = tray
+			return nil
+		}
+	}
+	s.state.Trays = append(s.state.Trays, tray)
+	return nil
+}
+
+// Pause moves a simulated print to paused.
+func (s *SimAdapter) Pause() error {
+	return s.control("pause", SimStep{State: "paused"}, "printing")
+}
+
+// Resume moves a paused simulated print back to printing.
+func (s *SimAdapter) Resume() error {
+	return s.control("resume", SimStep{State: "printing"}, "paused")
+}
+
+// Stop cancels a simulated print, leaving the printer idle.
+func (s *SimAdapter) Stop() error {
+	return s.control("stop", SimStep{State: "idle"}, "printing", "paused")
+}
+
+// SetLight records the chamber light state.
+func (s *SimAdapter) SetLight(on bool) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.state.Light = "off"
+	if on {
+		s.state.Light = "on"
+	}
+	return nil
+}
+
+// SetSpeed records the speed profile.
+func (s *SimAdapter) SetSpeed(profile SpeedProfile) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.state.Speed = profile.Name
+	return nil
+}
+
+// StartPrint starts printing job.Name from the beginning if the simulated
+// printer isn't busy. The file is only checked for existence.
+func (s *SimAdapter) StartPrint(job PrintJob) error {
+	if _, err := os.Stat(job.Path); err != nil {
+		return fmt.Errorf("sim %s: %w", s.name, err)
+	}
+	zero := 0
+	name := job.Name
+	start := SimStep{State: "printing", File: &name, Progress: &zero, Layer: &zero}
+	return s.control("start", start, "idle", "finished", "failed")
+}
+
+// control applies step if the simulated printer is in one of the from
+// states, firing callbacks like a scenario step would.
+func (s *SimAdapter) control(action string, step SimStep, from ...string) error {
+	current := s.Status().State
+	if !slices.Contains(from, current) {
+		return fmt.Errorf("sim %s: can't %s while %s", s.name, action, current)
+	}
+	s.applyStep(step)
+	return nil
+}
+
+// OnStateChange registers a callback for simulated state transitions.
+func (s *SimAdapter) OnStateChange(cb func(event StateChangeEvent)) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.stateCallbacks = append(s.stateCallbacks, cb)
+}
+
+func (s *SimAdapter) play() {
+	for {
+		for _, step := range s.scenario.Steps {
+			select {
+			case <-s.stopCh:
+				return
+			case <-time.After(step.After):
+			}
+			s.applyStep(step)
+		}
+		if !s.scenario.Loop || len(s.scenario.Steps) == 0 {
+			return
+		}
+	}
+}
+
+// applyStep applies one scenario step and fires callbacks the way the
+// Bambu adapter does: on a state change, or on new HMS codes while paused.
+func (s *SimAdapter) applyStep(step SimStep) {
+	s.mu.Lock()
+	oldState := s.state.State
+	oldProgress, oldLayer, oldTotalLayers := s.state.Progress, s.state.Layer, s.state.TotalLayers
+	prevHMS := append([]HMSCode(nil), s.hmsCodes...)
+
+	if step.State != "" {
+		s.state.State = step.State
+	}
+	if step.File != nil {
+		s.state.CurrentFile = *step.File
+	}
+	if step.Progress != nil {
+		s.state.Progress = *step.Progress
+	}
+	if step.Layer != nil {
+		s.state.Layer = *step.Layer
+	}
+	if step.TotalLayers != nil {
+		s.state.TotalLayers = *step.TotalLayers
+	}
+	if step.RemainingMins != nil {
+		s.state.RemainingMins = *step.RemainingMins
+	}
+	if step.HMS != nil {
+		var codes []HMSCode
+		for _, c := range step.HMS {
+			if code, err := ParseHMSCode(c); err == nil {
+				codes = append(codes, code)
+			}
+		}
+		s.hmsCodes = codes
+		s.state.HMS = hmsInfos(codes)
+	}
+	if step.Trays != nil {
+		s.state.Trays = simTrays(step.Trays)
+	}
+	s.state.LastUpdated = time.Now()
+
+	changed := s.state.State != oldState && oldState != "offline"
+	if changed && s.state.State == "finished" {
+		s.state.LastFinishedAt = time.Now()
+	}
+	fire := changed || (s.state.State == "paused" && hasNewHMSCodes(prevHMS, s.hmsCodes))
+	event := StateChangeEvent{
+		OldState:     oldState,
+		NewState:     s.state.State,
+		HMSCodes:     s.hmsCodes,
+		PrevHMSCodes: prevHMS,
+		Progress:     max(oldProgress, s.state.Progress),
+		Layer:        max(oldLayer, s.state.Layer),
+		TotalLayers:  max(oldTotalLayers, s.state.TotalLayers),
+	}
+	callbacks := s.stateCallbacks
+	s.mu.Unlock()
+
+	if fire {
+		for _, cb := range callbacks {
+			go cb(event)
+		}
+	}
+}
+
+func simTrays(in []SimTray) []TrayInfo {
+	out := make([]TrayInfo, 0, len(in))
+	for _, t := range in {
+		remain := -1
+		if t.Remain != nil {
+			remain = *t.Remain
+		}
+		out = append(out, TrayInfo{
+			AmsID:      t.AmsID,
+			TrayID:     t.TrayID,
+			Color:      strings.ToUpper(strings.TrimPrefix(t.Color, "#")),
+			Type:       t.Type,
+			Remain:     remain,
+			TrayWeight: t.TrayWeight,
+		})
+	}
+	return out
+}
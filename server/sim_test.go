@@ -0,0 +1,197 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/dstockto/fil/plan"
+)
+
+func writeScenario(t *testing.T, body string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "scenario.yaml")
+	if err := os.WriteFile(path, []byte(body), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestLoadSimScenario(t *testing.T) {
+	sc, err := LoadSimScenario(writeScenario(t, `
+trays:
+  - {ams_id: 0, tray_id: 1, color: "#ff0000", type: PLA, remain: 80, tray_weight: 1000}
+steps:
+  - {after: 2s, state: printing, file: bracket.3mf, progress: 0}
+  - {after: 1m, state: paused, hms: ["0C00-0300-0003-0008"]}
+`))
+	if err != nil {
+		t.Fatalf("LoadSimScenario: %v", err)
+	}
+	if len(sc.Steps) != 2 || sc.Steps[1].After != time.Minute || *sc.Steps[0].File != "bracket.3mf" {
+		t.Errorf("steps = %+v", sc.Steps)
+	}
+	if sc.Steps[0].Layer != nil {
+		t.Error("omitted layer should stay nil so the step keeps the current value")
+	}
+
+	for name, body := range map[string]string{
+		"bad state": "steps:\n  - {after: 1s, state: exploded}\n",
+		"bad hms":   "steps:\n  - {after: 1s, hms: [nope]}\n",
+		"busy loop": "loop: true\nsteps:\n  - {state: printing}\n",
+	} {
+		if _, err := LoadSimScenario(writeScenario(t, body)); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+}
+
+func TestParseHMSCodeRoundTrip(t *testing.T) {
+	code, err := ParseHMSCode("0C00-0300-0003-0008")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := code.HMSCodeString(); got != "0C00-0300-0003-0008" {
+		t.Errorf("round trip = %s", got)
+	}
+	if code.HMSDescription() != "possible spaghetti defects" {
+		t.Errorf("description = %q", code.HMSDescription())
+	}
+}
+
+func TestSimAdapterPlaysScenario(t *testing.T) {
+	progress, fifty := 0, 50
+	file := "bracket.3mf"
+	sc := SimScenario{
+		Trays: []SimTray{{AmsID: 0, TrayID: 0, Color: "00FF00", Type: "PLA"}},
+		Steps: []SimStep{
+			{After: time.Millisecond, State: "printing", File: &file, Progress: &progress},
+			{After: time.Millisecond, Progress: &fifty},
+			{After: time.Millisecond, State: "paused", HMS: []string{"0C00-0300-0003-0008"}},
+			{After: time.Millisecond, HMS: []string{"0C00-0300-0003-0008", "0300-0D00-0001-0003"}},
+			{After: time.Millisecond, State: "failed", HMS: []string{}},
+		},
+	}
+	s := NewSimAdapter("Sim", sc)
+
+	var mu sync.Mutex
+	var events []StateChangeEvent
+	s.OnStateChange(func(e StateChangeEvent) {
+		mu.Lock()
+		events = append(events, e)
+		mu.Unlock()
+	})
+	if err := s.Connect(); err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	waitFor(t, "failed", func() bool { return s.Status().State == "failed" })
+	waitFor(t, "four callbacks", func() bool {
+		mu.Lock()
+		defer mu.Unlock()
+		return len(events) == 4
+	})
+
+	mu.Lock()
+	defer mu.Unlock()
+	seen := map[string]StateChangeEvent{}
+	for _, e := range events {
+		seen[fmt.Sprintf("%s->%s:%d", e.OldState, e.NewState, len(e.HMSCodes))] = e
+	}
+	for _, want := range []string{"idle->printing:0", "printing->paused:1", "paused->paused:2", "paused->failed:0"} {
+		if _, ok := seen[want]; !ok {
+			t.Errorf("missing event %s; got %v", want, seen)
+		}
+	}
+	if e := seen["paused->failed:0"]; e.Progress != 50 {
+		t.Errorf("failed event progress = %d, want 50", e.Progress)
+	}
+	if st := s.Status(); st.CurrentFile != "bracket.3mf" || len(st.Trays) != 1 || st.Trays[0].Remain != -1 {
+		t.Errorf("status = %+v", st)
+	}
+}
+
+func TestSimAdapterPushTray(t *testing.T) {
+	s := NewSimAdapter("Sim", SimScenario{Trays: []SimTray{{AmsID: 0, TrayID: 0, Color: "000000", Type: "PLA", TrayWeight: 1000}}})
+	if err := s.Connect(); err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	if err := s.PushTray(TrayUpdate{AmsID: 0, TrayID: 0, Color: "ff8800ff", Type: "PETG", TempMin: 230, TempMax: 250, InfoIdx: "GFG99"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.PushTray(TrayUpdate{AmsID: 1, TrayID: 2, Color: "FFFFFFFF", Type: "PLA"}); err != nil {
+		t.Fatal(err)
+	}
+	trays := s.Status().Trays
+	if len(trays) != 2 {
+		t.Fatalf("trays = %+v, want 2", trays)
+	}
+	if got := trays[0]; got.Color != "FF8800" || got.Type != "PETG" || got.InfoIdx != "GFG99" || got.TrayWeight != 1000 {
+		t.Errorf("pushed tray = %+v", got)
+	}
+	if got := trays[1]; got.AmsID != 1 || got.TrayID != 2 {
+		t.Errorf("added tray = %+v", got)
+	}
+}
+
+// End to end on a simulated printer: the scenario's FINISH triggers the
+// same auto-complete callback fil serve wires up, which completes the plate
+// and deducts from the loaded spool.
+func TestSimAdapterDrivesAutoComplete(t *testing.T) {
+	dir := t.TempDir()
+	writeAutoCompletePlan(t, dir)
+
+	sim := NewSimAdapter("X1C", SimScenario{
+		State: "printing",
+		Steps: []SimStep{{After: time.Millisecond, State: "finished"}},
+	})
+	pm := NewPrinterManager()
+	sm := newFakeSpoolman(makeSpool(1, "AMS A1", 500, 100, "PLA white"))
+	locs := plan.StaticPrinterLocations{"X1C": {"AMS A1"}}
+	s := &PlanServer{
+		PlansDir:         dir,
+		Printers:         pm,
+		Spoolman:         sm,
+		PrinterLocations: locs,
+		Pending:          NewPendingStore(dir),
+		PlanOps:          plan.NewLocal(sm, locs, plan.NewFilePlanStore(dir, "", ""), plan.NewFileHistoryWriter(dir), nil),
+	}
+
+	done := make(chan []PendingEvent, 1)
+	sim.OnStateChange(func(e StateChangeEvent) {
+		if e.NewState != "finished" {
+			return
+		}
+		events, err := s.AutoComplete(context.Background(), "X1C")
+		if err != nil {
+			t.Errorf("AutoComplete: %v", err)
+		}
+		done <- events
+	})
+	if err := pm.AddAdapter("X1C", sim); err != nil {
+		t.Fatal(err)
+	}
+	defer pm.Close()
+
+	select {
+	case events := <-done:
+		if len(events) != 1 || events[0].Kind != "complete" {
+			t.Fatalf("events = %+v, want one complete", events)
+		}
+		if events[0].OccurredAt.IsZero() {
+			t.Error("expected the simulated finish time on the event")
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("scenario never finished")
+	}
+	if sm.useCalls[1] != 25 {
+		t.Errorf("use calls = %v, want 25g from spool 1", sm.useCalls)
+	}
+}
@@ -0,0 +1,505 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/dstockto/fil/models"
+	"github.com/dstockto/fil/plan"
+)
+
+// SpoolSwapConfig mirrors cmd.SpoolSwapConfig for use in the server package.
+type SpoolSwapConfig struct {
+	Interval time.Duration
+	// MaxDeltaE is how far (CIEDE2000) a spool's color may be from the
+	// tray's and still count as a match.
+	MaxDeltaE float64
+	// AutoApply moves the spool without asking when exactly one spool fits
+	// (or its RFID matches).
+	AutoApply bool
+	// ActionBase is the plan server URL phones reach, for one-tap accept
+	// links. Optional.
+	ActionBase string
+}
+
+// SwapSpoolman is what the detector needs from Spoolman. *api.Client
+// satisfies it.
+type SwapSpoolman interface {
+	plan.Spoolman
+	plan.SpoolMover
+	plan.SpoolCreator
+}
+
+// SpoolSwapDetector watches printer trays for a slot that starts reporting a
+// different filament, which means someone swapped the spool by hand. When
+// the spool the location orders place in that slot no longer fits, it finds
+// the spool that does and either moves it there or leaves a pending "swap"
+// event to confirm.
+//
+// RFID spools (Bambu) are matched by tag first. A tag Spoolman doesn't know
+// is recorded on the spool it turns out to be; when no spool fits at all,
+// the detector leaves a pending "register" event offering to create one.
+type SpoolSwapDetector struct {
+	Config    SpoolSwapConfig
+	Spoolman  SwapSpoolman
+	Statuses  func() []PrinterState
+	Locations plan.StaticPrinterLocations
+	// AmsIDs returns the AmsID behind each of a printer's locations (see
+	// LocationAmsIDs). Optional; without it a tray's AmsID is its location
+	// index.
+	AmsIDs   func(printer string) []int
+	Pending  *PendingStore
+	Notifier *Notifier // optional
+
+	mu   sync.Mutex
+	last map[string]map[[2]int]TrayInfo // per printer, by AmsID and TrayID
+}
+
+// swapSlot is a tray whose reported filament changed since the last check.
+type swapSlot struct {
+	printer  string
+	location string
+	slot     int // 1-based
+	obs      plan.TrayObservation
+	tray     TrayInfo
+	prev     TrayInfo // what the slot reported before
+	baseline bool     // first sighting; only an unknown RFID tag is worth acting on
+}
+
+// Run checks once per Config.Interval until ctx is done.
+func (d *SpoolSwapDetector) Run(ctx context.Context) {
+	ticker := time.NewTicker(d.Config.Interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if _, err := d.Check(ctx); err != nil {
+				fmt.Printf("[spool-swap] %v\n", err)
+			}
+		}
+	}
+}
+
+// Check compares each printer's trays with the previous check and returns
+// the swaps it found, applied or not. The first check only records a
+// baseline, apart from RFID tags Spoolman hasn't seen. Slots that couldn't
+// be handled, say while Spoolman is down, are forgotten so the next check
+// sees them change again.
+func (d *SpoolSwapDetector) Check(ctx context.Context) ([]plan.SpoolSwap, error) {
+	changed := d.changedSlots()
+	if len(changed) == 0 {
+		return nil, nil
+	}
+	orders, err := d.Spoolman.GetLocationOrders(ctx)
+	if err != nil {
+		d.forget(changed)
+		return nil, fmt.Errorf("load location orders: %w", err)
+	}
+	spools, err := d.Spoolman.FindSpoolsByName(ctx, "*", nil, nil)
+	if err != nil {
+		d.forget(changed)
+		return nil, fmt.Errorf("list spools: %w", err)
+	}
+	byID := make(map[int]models.FindSpool, len(spools))
+	for _, s := range spools {
+		byID[s.Id] = s
+	}
+
+	var found []plan.SpoolSwap
+	var errs []string
+	var failed []swapSlot
+	fail := func(c swapSlot, err error) {
+		errs = append(errs, fmt.Sprintf("%s:%d: %v", c.location, c.slot, err))
+		failed = append(failed, c)
+	}
+	for _, c := range changed {
+		recorded := 0
+		if ids := orders[c.location]; c.slot <= len(ids) && ids[c.slot-1] > 0 {
+			recorded = ids[c.slot-1]
+		}
+		owner := rfidOwner(spools, c.obs.UUID)
+		if owner != 0 && (owner == recorded || c.baseline) {
+			continue
+		}
+		if sp, ok := byID[recorded]; ok && owner == 0 && plan.SpoolFitsTray(sp, c.obs, d.Config.MaxDeltaE) {
+			// e.g. the tray push after a `fil move`. A new tag belongs to
+			// the spool already recorded there.
+			if c.obs.UUID != "" && sp.RFID() == "" {
+				if err := d.tag(ctx, sp.Id, c.obs.UUID); err != nil {
+					fail(c, err)
+				}
+			}
+			continue
+		}
+		pool := make([]models.FindSpool, 0, len(spools))
+		for _, s := range spools {
+			if s.Id != recorded {
+				pool = append(pool, s)
+			}
+		}
+		candidates := plan.MatchTraySpools(c.obs, pool, d.Config.MaxDeltaE)
+		if len(candidates) == 0 {
+			if c.obs.UUID == "" {
+				fmt.Printf("[spool-swap] %s %s:%d now reports %s; no spool matches\n", c.printer, c.location, c.slot, trayLabel(c.obs))
+				continue
+			}
+			if err := d.offerRegistration(c); err != nil {
+				fail(c, err)
+			}
+			continue
+		}
+
+		best := candidates[0]
+		swap := plan.SpoolSwap{
+			Location:  c.location,
+			Slot:      c.slot,
+			SpoolID:   best.Spool.Id,
+			From:      best.Spool.Location,
+			Displaced: recorded,
+			Tray:      trayLabel(c.obs),
+		}
+		if !best.RFID {
+			swap.RFID = c.obs.UUID
+		}
+		found = append(found, swap)
+		if d.Config.AutoApply && plan.Unambiguous(candidates) {
+			if err := d.apply(ctx, swap); err != nil {
+				fail(c, err)
+				continue
+			}
+			d.notify("Spool swap applied", fmt.Sprintf("%s:%d now holds #%d %s", c.location, c.slot, best.Spool.Id, best.Spool.Filament.Name), "", "")
+			continue
+		}
+		if err := d.propose(c.printer, swap, best, candidates[1:]); err != nil {
+			fail(c, err)
+		}
+	}
+	d.forget(failed)
+	if len(errs) > 0 {
+		return found, fmt.Errorf("%s", strings.Join(errs, "; "))
+	}
+	return found, nil
+}
+
+// changedSlots records the current trays and returns the slots that now
+// report a different, non-empty filament, plus RFID-tagged slots on a
+// printer's first sighting. Emptied slots are ignored: a spool being pulled
+// says nothing about where it went.
+func (d *SpoolSwapDetector) changedSlots() []swapSlot {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.last == nil {
+		d.last = map[string]map[[2]int]TrayInfo{}
+	}
+
+	var out []swapSlot
+	for _, st := range d.Statuses() {
+		if st.State == "offline" {
+			continue
+		}
+		prev, seen := d.last[st.Name]
+		cur := make(map[[2]int]TrayInfo, len(st.Trays))
+		for _, t := range st.Trays {
+			cur[[2]int{t.AmsID, t.TrayID}] = t
+		}
+		d.last[st.Name] = cur
+
+		locs := d.Locations[st.Name]
+		var amsIDs []int
+		if d.AmsIDs != nil {
+			amsIDs = d.AmsIDs(st.Name)
+		}
+		for _, t := range st.Trays {
+			if t.Type == "" || t.Color == "" {
+				continue
+			}
+			p, ok := prev[[2]int{t.AmsID, t.TrayID}]
+			if !seen && t.UUID == "" {
+				continue
+			}
+			if seen && (!ok || strings.EqualFold(p.Color, t.Color) && p.Type == t.Type && p.UUID == t.UUID) {
+				continue
+			}
+			idx := LocationIndex(amsIDs, t.AmsID)
+			if idx < 0 || idx >= len(locs) {
+				continue
+			}
+			out = append(out, swapSlot{
+				printer:  st.Name,
+				location: locs[idx],
+				slot:     t.TrayID + 1,
+				obs:      plan.TrayObservation{Color: t.Color, Type: t.Type, UUID: t.UUID},
+				tray:     t,
+				prev:     p,
+				baseline: !seen,
+			})
+		}
+	}
+	return out
+}
+
+// forget puts slots back to what they reported before changedSlots saw
+// them, so the next check picks them up again. A slot from a printer's
+// first sighting drops the printer's baseline instead.
+func (d *SpoolSwapDetector) forget(slots []swapSlot) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	for _, c := range slots {
+		if c.baseline {
+			delete(d.last, c.printer)
+			continue
+		}
+		if trays, ok := d.last[c.printer]; ok {
+			trays[[2]int{c.tray.AmsID, c.tray.TrayID}] = c.prev
+		}
+	}
+}
+
+// propose replaces any pending swap for the same slot with a new one and
+// pings the user with a one-tap accept.
+func (d *SpoolSwapDetector) propose(printer string, swap plan.SpoolSwap, best plan.SwapCandidate, others []plan.SwapCandidate) error {
+	if d.Pending == nil {
+		return fmt.Errorf("pending store not configured")
+	}
+	if events, err := d.Pending.List(); err == nil {
+		for _, ev := range events {
+			if ev.Kind == "swap" && ev.Swap != nil && ev.Swap.Location == swap.Location && ev.Swap.Slot == swap.Slot {
+				_ = d.Pending.Remove(ev.ID)
+			}
+		}
+	}
+	var ids []int
+	for _, c := range others {
+		ids = append(ids, c.Spool.Id)
+	}
+	ev, err := d.Pending.Add(PendingEvent{
+		Kind:       "swap",
+		Printer:    printer,
+		OccurredAt: time.Now().UTC(),
+		Swap:       &swap,
+		Candidates: ids,
+	})
+	if err != nil {
+		return fmt.Errorf("record pending event: %w", err)
+	}
+	fmt.Printf("[spool-swap] %s %s:%d: proposed #%d (pending %s)\n", printer, swap.Location, swap.Slot, swap.SpoolID, ev.ID)
+
+	match := fmt.Sprintf("ΔE %.1f", best.DeltaE)
+	if best.RFID {
+		match = "RFID"
+	}
+	msg := fmt.Sprintf("%s:%d now reports %s. Looks like #%d %s (%s)", swap.Location, swap.Slot, swap.Tray, swap.SpoolID, best.Spool.Filament.Name, match)
+	if len(others) > 0 {
+		msg += fmt.Sprintf(", %d other spool(s) also fit", len(others))
+	}
+	msg += ". Confirm with: fil plan pending"
+	d.notify("Spool swap detected", msg, fmt.Sprintf("Move #%d to %s:%d", swap.SpoolID, swap.Location, swap.Slot), d.acceptURL(ev.ID))
+	return nil
+}
+
+// acceptURL is the one-tap accept link for a pending event, or "" without
+// Config.ActionBase.
+func (d *SpoolSwapDetector) acceptURL(id string) string {
+	if d.Config.ActionBase == "" {
+		return ""
+	}
+	return strings.TrimRight(d.Config.ActionBase, "/") + apiPrefixes[0] + "/pending/" + id + "/accept"
+}
+
+// offerRegistration leaves a pending "register" event for an RFID spool
+// Spoolman has no match for, replacing any older offer for the slot.
+func (d *SpoolSwapDetector) offerRegistration(c swapSlot) error {
+	if d.Pending == nil {
+		return fmt.Errorf("pending store not configured")
+	}
+	name := c.tray.SubBrand
+	if name == "" {
+		name = c.tray.Type
+	}
+	reg := plan.SpoolRegistration{
+		Location: c.location,
+		Slot:     c.slot,
+		RFID:     c.obs.UUID,
+		Vendor:   plan.BambuVendor,
+		Name:     name,
+		Material: c.tray.Type,
+		ColorHex: strings.ToUpper(c.tray.Color),
+		Weight:   float64(c.tray.TrayWeight),
+	}
+	if g, ok := c.tray.EstimatedGrams(); ok {
+		reg.Remaining = g
+	}
+	if events, err := d.Pending.List(); err == nil {
+		for _, ev := range events {
+			if ev.Kind != "register" || ev.Register == nil || ev.Register.Location != reg.Location || ev.Register.Slot != reg.Slot {
+				continue
+			}
+			if ev.Register.RFID == reg.RFID {
+				return nil // already offered, e.g. before a restart
+			}
+			_ = d.Pending.Remove(ev.ID)
+		}
+	}
+	ev, err := d.Pending.Add(PendingEvent{
+		Kind:       "register",
+		Printer:    c.printer,
+		OccurredAt: time.Now().UTC(),
+		Register:   &reg,
+	})
+	if err != nil {
+		return fmt.Errorf("record pending event: %w", err)
+	}
+	fmt.Printf("[spool-swap] %s %s:%d: unknown RFID spool %s (pending %s)\n", c.printer, reg.Location, reg.Slot, reg.RFID, ev.ID)
+
+	msg := fmt.Sprintf("%s:%d holds a %s %s (%s) Spoolman doesn't have. Add it with: fil plan pending", reg.Location, reg.Slot, reg.Vendor, reg.Name, trayLabel(c.obs))
+	d.notify("New RFID spool", msg, "Add to Spoolman", d.acceptURL(ev.ID))
+	return nil
+}
+
+// register creates the spool for an accepted registration and places it in
+// its slot. A spool already carrying the tag, say from an accept whose
+// placement failed, is placed instead of creating another.
+func (d *SpoolSwapDetector) register(ctx context.Context, reg plan.SpoolRegistration) (int, error) {
+	spools, err := d.Spoolman.FindSpoolsByName(ctx, "*", nil, nil)
+	if err != nil {
+		return 0, fmt.Errorf("list spools: %w", err)
+	}
+	id := rfidOwner(spools, reg.RFID)
+	if id == 0 {
+		spool, err := plan.RegisterSpool(ctx, d.Spoolman, reg)
+		if err != nil {
+			return 0, err
+		}
+		id = spool.Id
+	}
+	swap := plan.SpoolSwap{Location: reg.Location, Slot: reg.Slot, SpoolID: id}
+	if _, err := plan.ApplySpoolSwap(ctx, d.Spoolman, d.isPrinterLocation, swap); err != nil {
+		return id, fmt.Errorf("place spool #%d: %w", id, err)
+	}
+	fmt.Printf("[spool-swap] registered #%d %s %s in %s:%d\n", id, reg.Vendor, reg.Name, reg.Location, reg.Slot)
+	return id, nil
+}
+
+// tag records an RFID tag on a spool, keeping its other custom fields.
+func (d *SpoolSwapDetector) tag(ctx context.Context, spoolID int, uid string) error {
+	if err := d.Spoolman.EnsureSpoolField(ctx, models.RFIDExtraField, "RFID"); err != nil {
+		return fmt.Errorf("ensure rfid field: %w", err)
+	}
+	spool, err := d.Spoolman.FindSpoolByID(ctx, spoolID)
+	if err != nil {
+		return fmt.Errorf("load spool #%d: %w", spoolID, err)
+	}
+	extra := maps.Clone(spool.Extra)
+	if extra == nil {
+		extra = map[string]string{}
+	}
+	maps.Copy(extra, models.RFIDExtra(uid))
+	if err := d.Spoolman.PatchSpool(ctx, spoolID, map[string]any{"extra": extra}); err != nil {
+		return fmt.Errorf("tag spool #%d: %w", spoolID, err)
+	}
+	fmt.Printf("[spool-swap] recorded RFID %s on #%d\n", uid, spoolID)
+	return nil
+}
+
+// apply moves the spool in Spoolman, recording the tray's RFID tag on it
+// when the swap carries one.
+func (d *SpoolSwapDetector) apply(ctx context.Context, swap plan.SpoolSwap) error {
+	to, err := plan.ApplySpoolSwap(ctx, d.Spoolman, d.isPrinterLocation, swap)
+	if err != nil {
+		return err
+	}
+	if swap.RFID != "" {
+		if err := d.tag(ctx, swap.SpoolID, swap.RFID); err != nil {
+			return err
+		}
+	}
+	fmt.Printf("[spool-swap] moved #%d to %s:%d", swap.SpoolID, swap.Location, swap.Slot)
+	if swap.Displaced > 0 {
+		if to == "" {
+			to = "no location"
+		}
+		fmt.Printf(", #%d to %s", swap.Displaced, to)
+	}
+	fmt.Println()
+	return nil
+}
+
+func (d *SpoolSwapDetector) isPrinterLocation(loc string) bool {
+	for _, locs := range d.Locations {
+		for _, l := range locs {
+			if l == loc {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func (d *SpoolSwapDetector) notify(title, msg, label, actionURL string) {
+	if d.Notifier == nil || !d.Notifier.Enabled() || d.Notifier.IsQuietHours(time.Now()) {
+		return
+	}
+	for _, err := range d.Notifier.SendAction(title, msg, label, actionURL) {
+		fmt.Printf("[notify] %v\n", err)
+	}
+}
+
+// rfidOwner returns the unarchived spool tagged uid, or 0.
+func rfidOwner(spools []models.FindSpool, uid string) int {
+	if uid == "" {
+		return 0
+	}
+	for _, s := range spools {
+		if !s.Archived && strings.EqualFold(s.RFID(), uid) {
+			return s.Id
+		}
+	}
+	return 0
+}
+
+func trayLabel(obs plan.TrayObservation) string {
+	return fmt.Sprintf("%s #%s", obs.Type, obs.Color)
+}
+
+// runPendingSwap applies a pending swap, writing an error response and
+// returning false when it can't.
+func (s *PlanServer) runPendingSwap(w http.ResponseWriter, r *http.Request, ev PendingEvent) bool {
+	if ev.Swap == nil {
+		http.Error(w, "pending swap has no move", http.StatusInternalServerError)
+		return false
+	}
+	if s.SpoolSwaps == nil {
+		http.Error(w, "spool swap detection not configured", http.StatusInternalServerError)
+		return false
+	}
+	if err := s.SpoolSwaps.apply(r.Context(), *ev.Swap); err != nil {
+		http.Error(w, fmt.Sprintf("swap: %v", err), http.StatusBadGateway)
+		return false
+	}
+	return true
+}
+
+// runPendingRegister creates the spool a pending registration describes,
+// writing an error response and returning false when it can't.
+func (s *PlanServer) runPendingRegister(w http.ResponseWriter, r *http.Request, ev PendingEvent) bool {
+	if ev.Register == nil {
+		http.Error(w, "pending registration has no spool", http.StatusInternalServerError)
+		return false
+	}
+	if s.SpoolSwaps == nil {
+		http.Error(w, "spool swap detection not configured", http.StatusInternalServerError)
+		return false
+	}
+	if _, err := s.SpoolSwaps.register(r.Context(), *ev.Register); err != nil {
+		http.Error(w, fmt.Sprintf("register: %v", err), http.StatusBadGateway)
+		return false
+	}
+	return true
+}
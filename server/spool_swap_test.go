@@ -0,0 +1,264 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"testing"
+
+	"github.com/dstockto/fil/models"
+	"github.com/dstockto/fil/plan"
+)
+
+// fakeSwapSpoolman adds location orders, moves, and spool creation to
+// fakeSpoolman.
+type fakeSwapSpoolman struct {
+	*fakeSpoolman
+	orders    map[string][]int
+	ordersErr error
+	moves     map[int]string
+	created   []models.CreateSpoolRequest
+}
+
+func (f *fakeSwapSpoolman) GetLocationOrders(context.Context) (map[string][]int, error) {
+	return f.orders, f.ordersErr
+}
+
+func (f *fakeSwapSpoolman) PostSettingObject(_ context.Context, _ string, obj any) error {
+	f.orders = obj.(map[string][]int)
+	return nil
+}
+
+func (f *fakeSwapSpoolman) MoveSpool(_ context.Context, id int, to string) error {
+	f.moves[id] = to
+	return nil
+}
+
+func (f *fakeSwapSpoolman) GetVendors(context.Context) ([]models.Vendor, error) {
+	return nil, nil
+}
+
+func (f *fakeSwapSpoolman) CreateVendor(_ context.Context, name string) (*models.Vendor, error) {
+	return &models.Vendor{Id: 1, Name: name}, nil
+}
+
+func (f *fakeSwapSpoolman) GetFilaments(context.Context) ([]models.FilamentResponse, error) {
+	return nil, nil
+}
+
+func (f *fakeSwapSpoolman) CreateFilament(_ context.Context, req models.CreateFilamentRequest) (*models.FilamentResponse, error) {
+	return &models.FilamentResponse{Id: 50, Name: req.Name}, nil
+}
+
+func (f *fakeSwapSpoolman) CreateSpool(_ context.Context, req models.CreateSpoolRequest) (*models.FindSpool, error) {
+	f.created = append(f.created, req)
+	spool := models.FindSpool{Id: 90 + len(f.created) - 1, Location: req.Location, Extra: req.Extra}
+	f.spools = append(f.spools, spool)
+	return &spool, nil
+}
+
+func (f *fakeSwapSpoolman) EnsureSpoolField(context.Context, string, string) error {
+	return nil
+}
+
+func colorSpool(id int, loc, material, hex string) models.FindSpool {
+	s := makeSpool(id, loc, 500, id, "Spool")
+	s.Filament.Material = material
+	s.Filament.ColorHex = hex
+	return s
+}
+
+func newSwapDetector(t *testing.T, autoApply bool, spools ...models.FindSpool) (*SpoolSwapDetector, *fakeSwapSpoolman, *[]TrayInfo) {
+	t.Helper()
+	sm := &fakeSwapSpoolman{
+		fakeSpoolman: newFakeSpoolman(spools...),
+		orders:       map[string][]int{"AMS A": {10, 11}, "Shelf": {20, 21, 22}},
+		moves:        map[int]string{},
+	}
+	trays := []TrayInfo{
+		{AmsID: 0, TrayID: 0, Type: "PLA", Color: "000000"},
+		{AmsID: 0, TrayID: 1, Type: "PLA", Color: "FFFFFF"},
+	}
+	d := &SpoolSwapDetector{
+		Config:    SpoolSwapConfig{MaxDeltaE: 10, AutoApply: autoApply},
+		Spoolman:  sm,
+		Statuses:  func() []PrinterState { return []PrinterState{{Name: "X1C", State: "idle", Trays: trays}} },
+		Locations: plan.StaticPrinterLocations{"X1C": {"AMS A"}},
+		Pending:   NewPendingStore(t.TempDir()),
+	}
+	return d, sm, &trays
+}
+
+func TestSpoolSwapDetectorProposesSwap(t *testing.T) {
+	d, sm, trays := newSwapDetector(t, false,
+		colorSpool(10, "AMS A", "PLA", "000000"),
+		colorSpool(11, "AMS A", "PLA", "FFFFFF"),
+		colorSpool(20, "Shelf", "PLA", "FF0000"),
+		colorSpool(21, "Shelf", "PETG", "FF0000"),
+	)
+
+	// The first check is a baseline.
+	if found, err := d.Check(context.Background()); err != nil || len(found) != 0 {
+		t.Fatalf("baseline check = %v, %v", found, err)
+	}
+
+	(*trays)[1].Color = "FF0000"
+	found, err := d.Check(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := plan.SpoolSwap{Location: "AMS A", Slot: 2, SpoolID: 20, From: "Shelf", Displaced: 11, Tray: "PLA #FF0000"}
+	if len(found) != 1 || found[0] != want {
+		t.Fatalf("found = %+v, want %+v", found, want)
+	}
+	if len(sm.moves) != 0 {
+		t.Errorf("moves = %v, want none before confirmation", sm.moves)
+	}
+	events, _ := d.Pending.List()
+	if len(events) != 1 || events[0].Kind != "swap" || *events[0].Swap != want {
+		t.Fatalf("pending = %+v", events)
+	}
+
+	// Accepting the event applies the move.
+	s := &PlanServer{Pending: d.Pending, SpoolSwaps: d}
+	w := httptest.NewRecorder()
+	s.Routes().ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/api/fil/pending/"+events[0].ID+"/accept", nil))
+	if w.Code != http.StatusNoContent {
+		t.Fatalf("accept = %d %s", w.Code, w.Body)
+	}
+	if !slices.Equal(sm.orders["AMS A"], []int{10, 20}) || sm.moves[20] != "AMS A" || sm.moves[11] != "Shelf" {
+		t.Errorf("after accept: orders %v, moves %v", sm.orders, sm.moves)
+	}
+	if events, _ := d.Pending.List(); len(events) != 0 {
+		t.Errorf("pending after accept = %+v", events)
+	}
+}
+
+func TestSpoolSwapDetectorAutoApplies(t *testing.T) {
+	d, sm, trays := newSwapDetector(t, true,
+		colorSpool(10, "AMS A", "PLA", "000000"),
+		colorSpool(11, "AMS A", "PLA", "FFFFFF"),
+		colorSpool(20, "Shelf", "PLA", "FF0000"),
+	)
+	_, _ = d.Check(context.Background())
+
+	(*trays)[0].Color = "FF0000"
+	if _, err := d.Check(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(sm.orders["AMS A"], []int{20, 11}) || sm.moves[20] != "AMS A" {
+		t.Errorf("orders %v, moves %v; want #20 in slot 1", sm.orders, sm.moves)
+	}
+	if events, _ := d.Pending.List(); len(events) != 0 {
+		t.Errorf("pending = %+v, want none when auto-applied", events)
+	}
+}
+
+func TestSpoolSwapDetectorRetriesAfterSpoolmanError(t *testing.T) {
+	d, sm, trays := newSwapDetector(t, false,
+		colorSpool(10, "AMS A", "PLA", "000000"),
+		colorSpool(11, "AMS A", "PLA", "FFFFFF"),
+		colorSpool(20, "Shelf", "PLA", "FF0000"),
+	)
+	_, _ = d.Check(context.Background())
+
+	(*trays)[1].Color = "FF0000"
+	sm.ordersErr = errors.New("connection refused")
+	if _, err := d.Check(context.Background()); err == nil {
+		t.Fatal("Check with Spoolman down: want error")
+	}
+
+	sm.ordersErr = nil
+	found, err := d.Check(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(found) != 1 || found[0].SpoolID != 20 {
+		t.Errorf("found = %+v, want the swap seen while Spoolman was down", found)
+	}
+}
+
+func TestSpoolSwapDetectorIgnoresMatchingSpool(t *testing.T) {
+	// A `fil move` already recorded #20 in slot 2 and pushed its color.
+	d, sm, trays := newSwapDetector(t, true,
+		colorSpool(10, "AMS A", "PLA", "000000"),
+		colorSpool(20, "AMS A", "PLA", "FF0000"),
+		colorSpool(21, "Shelf", "PLA", "FF0000"),
+	)
+	sm.orders["AMS A"] = []int{10, 20}
+	_, _ = d.Check(context.Background())
+
+	(*trays)[1].Color = "FF0000"
+	found, err := d.Check(context.Background())
+	if err != nil || len(found) != 0 || len(sm.moves) != 0 {
+		t.Errorf("found %+v, moves %v, err %v; want nothing", found, sm.moves, err)
+	}
+}
+
+func TestSpoolSwapDetectorTagsRecordedSpool(t *testing.T) {
+	d, sm, trays := newSwapDetector(t, false,
+		colorSpool(10, "AMS A", "PLA", "000000"),
+		colorSpool(11, "AMS A", "PLA", "FFFFFF"),
+	)
+	(*trays)[1].UUID = "A1B2"
+
+	// An unknown tag is handled even on the baseline check.
+	if _, err := d.Check(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	extra, _ := sm.patchCalls[11]["extra"].(map[string]string)
+	if extra[models.RFIDExtraField] != `"A1B2"` {
+		t.Errorf("patch #11 = %v, want rfid recorded", sm.patchCalls[11])
+	}
+	if events, _ := d.Pending.List(); len(events) != 0 || len(sm.moves) != 0 {
+		t.Errorf("pending %+v, moves %v; want neither", events, sm.moves)
+	}
+}
+
+func TestSpoolSwapDetectorOffersRegistration(t *testing.T) {
+	d, sm, trays := newSwapDetector(t, true,
+		colorSpool(10, "AMS A", "PLA", "000000"),
+		colorSpool(11, "AMS A", "PLA", "FFFFFF"),
+	)
+	_, _ = d.Check(context.Background())
+
+	(*trays)[1] = TrayInfo{AmsID: 0, TrayID: 1, Type: "PETG", Color: "00ae42", UUID: "C3D4", SubBrand: "PETG HF", Remain: 50, TrayWeight: 1000}
+	if _, err := d.Check(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	events, _ := d.Pending.List()
+	if len(events) != 1 || events[0].Kind != "register" {
+		t.Fatalf("pending = %+v, want one register event", events)
+	}
+	want := plan.SpoolRegistration{Location: "AMS A", Slot: 2, RFID: "C3D4", Vendor: plan.BambuVendor, Name: "PETG HF", Material: "PETG", ColorHex: "00AE42", Weight: 1000, Remaining: 500}
+	if *events[0].Register != want {
+		t.Errorf("register = %+v, want %+v", *events[0].Register, want)
+	}
+	if len(sm.created) != 0 {
+		t.Error("registration must not be auto-applied")
+	}
+
+	// Spoolman takes the spool but the placement fails; the retry places
+	// that spool instead of creating a second one with the same tag.
+	s := &PlanServer{Pending: d.Pending, SpoolSwaps: d}
+	sm.ordersErr = errors.New("connection reset")
+	w := httptest.NewRecorder()
+	s.Routes().ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/api/fil/pending/"+events[0].ID+"/accept", nil))
+	if w.Code != http.StatusBadGateway {
+		t.Fatalf("accept with placement failing = %d %s", w.Code, w.Body)
+	}
+	sm.ordersErr = nil
+	w = httptest.NewRecorder()
+	s.Routes().ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/api/fil/pending/"+events[0].ID+"/accept", nil))
+	if w.Code != http.StatusNoContent {
+		t.Fatalf("accept = %d %s", w.Code, w.Body)
+	}
+	if len(sm.created) != 1 || sm.created[0].Location != "AMS A" || sm.created[0].Extra[models.RFIDExtraField] != `"C3D4"` {
+		t.Errorf("created = %+v", sm.created)
+	}
+	if !slices.Equal(sm.orders["AMS A"], []int{10, 90}) || sm.moves[11] != "" {
+		t.Errorf("orders %v, moves %v; want #90 in slot 2", sm.orders, sm.moves)
+	}
+}
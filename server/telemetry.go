@@ -0,0 +1,109 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// Telemetry is a printer's temperatures, fans, and AMS environment at one
+// moment. Zero means not reported.
+type Telemetry struct {
+	At           time.Time `json:"at"`
+	Nozzle       float64   `json:"nozzle,omitempty"` // °C
+	NozzleTarget float64   `json:"nozzle_target,omitempty"`
+	Bed          float64   `json:"bed,omitempty"`
+	BedTarget    float64   `json:"bed_target,omitempty"`
+	Chamber      float64   `json:"chamber,omitempty"`
+	// Fan speeds in percent (Bambu).
+	PartFan      int `json:"part_fan,omitempty"`
+	AuxFan       int `json:"aux_fan,omitempty"`
+	ChamberFan   int `json:"chamber_fan,omitempty"`
+	HeatbreakFan int `json:"heatbreak_fan,omitempty"`
+	// Fan speeds in RPM (PrusaLink).
+	PrintFanRPM  int      `json:"print_fan_rpm,omitempty"`
+	HotendFanRPM int      `json:"hotend_fan_rpm,omitempty"`
+	AMS          []AMSEnv `json:"ams,omitempty"`
+}
+
+// AMSEnv is one AMS unit's environment. Rising humidity means the
+// desiccant needs replacing.
+type AMSEnv struct {
+	ID            int     `json:"id"`
+	Humidity      int     `json:"humidity,omitempty"`       // percent, on firmware that reports it
+	HumidityLevel int     `json:"humidity_level,omitempty"` // Bambu's 1-5 index
+	Temp          float64 `json:"temp,omitempty"`           // °C
+}
+
+// TelemetrySource is implemented by adapters that keep telemetry history.
+type TelemetrySource interface {
+	TelemetryHistory() []Telemetry
+}
+
+// Ring buffer sizing: one sample per telemetryInterval, telemetrySamples
+// deep, so about two hours per printer.
+const (
+	telemetryInterval = 30 * time.Second
+	telemetrySamples  = 240
+)
+
+// TelemetryRing is a fixed-size history of Telemetry samples. A sample less
+// than telemetryInterval after the latest one is dropped, so a printer that
+// reports every second still keeps hours of history; the current values are
+// on PrinterState.
+type TelemetryRing struct {
+	mu      sync.Mutex
+	samples []Telemetry
+	next    int // write position once full
+}
+
+// Record adds t to the ring.
+func (r *TelemetryRing) Record(t Telemetry) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if n := len(r.samples); n > 0 {
+		last := (r.next - 1 + n) % n
+		if t.At.Sub(r.samples[last].At) < telemetryInterval {
+			return
+		}
+	}
+	if len(r.samples) < telemetrySamples {
+		r.samples = append(r.samples, t)
+		return
+	}
+	r.samples[r.next] = t
+	r.next = (r.next + 1) % telemetrySamples
+}
+
+// Samples returns the history, oldest first.
+func (r *TelemetryRing) Samples() []Telemetry {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	out := make([]Telemetry, 0, len(r.samples))
+	out = append(out, r.samples[r.next:]...)
+	return append(out, r.samples[:r.next]...)
+}
+
+// handlePrinterTelemetry serves a printer's telemetry history, oldest first.
+func (s *PlanServer) handlePrinterTelemetry(w http.ResponseWriter, r *http.Request) {
+	if s.Printers == nil {
+		http.Error(w, "no printer connections configured", http.StatusBadRequest)
+		return
+	}
+	name := r.PathValue("name")
+	adapter, ok := s.Printers.Adapter(name)
+	if !ok {
+		http.Error(w, fmt.Sprintf("printer %q not found", name), http.StatusNotFound)
+		return
+	}
+	src, ok := adapter.(TelemetrySource)
+	if !ok {
+		http.Error(w, "printer does not report telemetry", http.StatusNotImplemented)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(src.TelemetryHistory())
+}
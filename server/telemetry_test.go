@@ -0,0 +1,110 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestTelemetryRing(t *testing.T) {
+	var r TelemetryRing
+	start := time.Date(2026, 5, 1, 12, 0, 0, 0, time.UTC)
+	r.Record(Telemetry{At: start, Nozzle: 1})
+	r.Record(Telemetry{At: start.Add(10 * time.Second), Nozzle: 99}) // too soon, dropped
+	if got := r.Samples(); len(got) != 1 || got[0].Nozzle != 1 {
+		t.Fatalf("samples = %+v", got)
+	}
+
+	for i := 1; i < telemetrySamples+5; i++ {
+		r.Record(Telemetry{At: start.Add(time.Duration(i) * telemetryInterval), Nozzle: float64(i + 1)})
+	}
+	got := r.Samples()
+	if len(got) != telemetrySamples {
+		t.Fatalf("len = %d, want %d", len(got), telemetrySamples)
+	}
+	if got[0].Nozzle != 6 || got[len(got)-1].Nozzle != telemetrySamples+5 {
+		t.Errorf("oldest %v, newest %v", got[0].Nozzle, got[len(got)-1].Nozzle)
+	}
+	for i := 1; i < len(got); i++ {
+		if !got[i].At.After(got[i-1].At) {
+			t.Fatalf("samples out of order at %d", i)
+		}
+	}
+}
+
+func TestBambuTelemetry(t *testing.T) {
+	b := NewBambuAdapter("test", "127.0.0.1", "00M00A000000000", "12345678")
+	b.handleReport([]byte(`{"print":{"gcode_state":"RUNNING",
+		"nozzle_temper":219.8,"nozzle_target_temper":220,"bed_temper":55,"bed_target_temper":55,"chamber_temper":31,
+		"cooling_fan_speed":"15","big_fan1_speed":"0","big_fan2_speed":"6","heatbreak_fan_speed":"15",
+		"ams":{"ams":[{"id":"0","humidity":"4","humidity_raw":"23","temp":"26.5","tray":[]},{"id":"1","humidity":"2","temp":"25.0","tray":[]}]}}}`))
+
+	tel := b.Status().Telemetry
+	if tel == nil {
+		t.Fatal("no telemetry")
+	}
+	if tel.Nozzle != 219.8 || tel.NozzleTarget != 220 || tel.Bed != 55 || tel.Chamber != 31 {
+		t.Errorf("temps = %+v", tel)
+	}
+	if tel.PartFan != 100 || tel.AuxFan != 0 || tel.ChamberFan != 40 || tel.HeatbreakFan != 100 {
+		t.Errorf("fans = %d/%d/%d/%d", tel.PartFan, tel.AuxFan, tel.ChamberFan, tel.HeatbreakFan)
+	}
+	if len(tel.AMS) != 2 || tel.AMS[0].Humidity != 23 || tel.AMS[0].HumidityLevel != 4 || tel.AMS[1].ID != 1 || tel.AMS[1].Temp != 25 {
+		t.Errorf("ams = %+v", tel.AMS)
+	}
+
+	// Deltas keep what they don't mention.
+	b.handleReport([]byte(`{"print":{"nozzle_temper":221}}`))
+	tel = b.Status().Telemetry
+	if tel.Nozzle != 221 || tel.Bed != 55 || len(tel.AMS) != 2 {
+		t.Errorf("after delta = %+v", tel)
+	}
+	if n := len(b.TelemetryHistory()); n != 1 {
+		t.Errorf("history = %d samples, want 1 within the interval", n)
+	}
+}
+
+func TestHandlePrinterTelemetry(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`{"printer":{"state":"IDLE","temp_nozzle":24.5,"target_nozzle":0,"temp_bed":23,"fan_hotend":0,"fan_print":3200}}`))
+	}))
+	defer srv.Close()
+
+	s, _ := setupTestServer(t)
+	s.Printers = NewPrinterManager()
+	defer s.Printers.Close()
+	if err := s.Printers.AddAdapter("MK4", NewPrusaAdapter("MK4", strings.TrimPrefix(srv.URL, "http://"), "", "")); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Printers.AddAdapter("Fake", &fakeAdapter{}); err != nil {
+		t.Fatal(err)
+	}
+
+	get := func(name string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		s.Routes().ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/fil/printers/"+name+"/telemetry", nil))
+		return w
+	}
+
+	w := get("MK4")
+	if w.Code != http.StatusOK {
+		t.Fatalf("status %d: %s", w.Code, w.Body.String())
+	}
+	var samples []Telemetry
+	if err := json.NewDecoder(w.Body).Decode(&samples); err != nil {
+		t.Fatal(err)
+	}
+	if len(samples) != 1 || samples[0].Nozzle != 24.5 || samples[0].PrintFanRPM != 3200 {
+		t.Errorf("samples = %+v", samples)
+	}
+
+	if code := get("Fake").Code; code != http.StatusNotImplemented {
+		t.Errorf("fake = %d, want 501", code)
+	}
+	if code := get("Nope").Code; code != http.StatusNotFound {
+		t.Errorf("unknown = %d, want 404", code)
+	}
+}
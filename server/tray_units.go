@@ -0,0 +1,109 @@
+package server
+
+import (
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// Bambu tray addressing beyond the four-slot AMS units. The external spool
+// holder and each AMS HT are single-slot units, so their TrayInfo has
+// TrayID 0.
+const (
+	// ExternalAmsID is the AmsID of the external spool holder (vt_tray).
+	ExternalAmsID = 255
+	// bambuExternalTrayID is the tray_id Bambu uses for the external spool in
+	// ams_filament_setting and tray_now.
+	bambuExternalTrayID = 254
+	// HTAmsIDBase is the AmsID of the first AMS HT; HT units are 128-135.
+	HTAmsIDBase = 128
+	htUnits     = 8
+	amsUnits    = 4
+)
+
+// TrayIndex is the ActiveTray form of a tray: amsID*4 + trayID, so
+// ActiveTray/4 and ActiveTray%4 recover the AmsID and TrayID.
+func TrayIndex(amsID, trayID int) int {
+	return amsID*4 + trayID
+}
+
+// bambuTrayNow converts Bambu's tray_now to an ActiveTray index. Values
+// below 16 are already AMS slot indexes, 128-135 name an AMS HT, 254 is the
+// external spool, and 255 means nothing is loaded.
+func bambuTrayNow(n int) int {
+	switch {
+	case n == 255:
+		return -1
+	case n == bambuExternalTrayID:
+		return TrayIndex(ExternalAmsID, 0)
+	case n >= HTAmsIDBase && n < HTAmsIDBase+htUnits:
+		return TrayIndex(n, 0)
+	}
+	return n
+}
+
+// ParseTrayUnit parses a unit name from a printer's ams_units config:
+// "ams1"-"ams4", "ht1"-"ht8", or "external". It returns the unit's AmsID.
+func ParseTrayUnit(s string) (int, error) {
+	s = strings.ToLower(strings.TrimSpace(s))
+	if s == "external" {
+		return ExternalAmsID, nil
+	}
+	for _, u := range []struct {
+		prefix string
+		base   int
+		count  int
+	}{{"ams", 0, amsUnits}, {"ht", HTAmsIDBase, htUnits}} {
+		rest, ok := strings.CutPrefix(s, u.prefix)
+		if !ok {
+			continue
+		}
+		n, err := strconv.Atoi(rest)
+		if err != nil || n < 1 || n > u.count {
+			break
+		}
+		return u.base + n - 1, nil
+	}
+	return 0, fmt.Errorf("unknown tray unit %q (want ams1-ams%d, ht1-ht%d, or external)", s, amsUnits, htUnits)
+}
+
+// TrayUnitName is the ams_units name for an AmsID, e.g. "ht1".
+func TrayUnitName(amsID int) string {
+	switch {
+	case amsID == ExternalAmsID:
+		return "external"
+	case amsID >= HTAmsIDBase && amsID < HTAmsIDBase+htUnits:
+		return fmt.Sprintf("ht%d", amsID-HTAmsIDBase+1)
+	}
+	return fmt.Sprintf("ams%d", amsID+1)
+}
+
+// LocationAmsIDs resolves the AmsID behind each of a printer's locations.
+// A location named in units uses that unit; any other location keeps the
+// historical mapping of its index in the list. An invalid unit resolves to
+// -1, which matches no tray.
+func LocationAmsIDs(locations []string, units map[string]string) []int {
+	ids := make([]int, len(locations))
+	for i, loc := range locations {
+		ids[i] = i
+		if u, ok := units[loc]; ok {
+			id, err := ParseTrayUnit(u)
+			if err != nil {
+				id = -1
+			}
+			ids[i] = id
+		}
+	}
+	return ids
+}
+
+// LocationIndex maps a tray's AmsID back to its location index in amsIDs
+// (from LocationAmsIDs), or -1 when no location covers it. A nil amsIDs
+// means the index mapping, so amsID is returned as-is.
+func LocationIndex(amsIDs []int, amsID int) int {
+	if amsIDs == nil {
+		return amsID
+	}
+	return slices.Index(amsIDs, amsID)
+}
@@ -0,0 +1,42 @@
+package server
+
+import (
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestParseTrayUnit(t *testing.T) {
+	for in, want := range map[string]int{"ams1": 0, "AMS4": 3, "ht1": 128, "ht8": 135, " external ": ExternalAmsID} {
+		got, err := ParseTrayUnit(in)
+		if err != nil || got != want {
+			t.Errorf("ParseTrayUnit(%q) = %d, %v; want %d", in, got, err, want)
+		}
+		if err == nil && TrayUnitName(got) != strings.ToLower(strings.TrimSpace(in)) {
+			t.Errorf("TrayUnitName(%d) = %q, want %q", got, TrayUnitName(got), in)
+		}
+	}
+	for _, in := range []string{"", "ams0", "ams5", "ht9", "ht", "vt"} {
+		if _, err := ParseTrayUnit(in); err == nil {
+			t.Errorf("ParseTrayUnit(%q) succeeded, want error", in)
+		}
+	}
+}
+
+func TestLocationAmsIDs(t *testing.T) {
+	locs := []string{"X1C AMS", "X1C AMS 2", "X1C HT", "X1C External", "X1C Bad"}
+	units := map[string]string{"X1C HT": "ht1", "X1C External": "external", "X1C Bad": "ams9"}
+	got := LocationAmsIDs(locs, units)
+	if want := []int{0, 1, 128, ExternalAmsID, -1}; !slices.Equal(got, want) {
+		t.Fatalf("LocationAmsIDs = %v, want %v", got, want)
+	}
+	if i := LocationIndex(got, ExternalAmsID); i != 3 {
+		t.Errorf("LocationIndex(external) = %d, want 3", i)
+	}
+	if i := LocationIndex(got, 2); i != -1 {
+		t.Errorf("LocationIndex(ams3) = %d, want -1", i)
+	}
+	if i := LocationIndex(nil, 2); i != 2 {
+		t.Errorf("LocationIndex(nil, 2) = %d, want 2", i)
+	}
+}
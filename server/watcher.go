@@ -268,14 +268,54 @@ func (w *ETAWatcher) fireNotifications() {
 	w.scheduleNextLocked()
 }
 
+// PlanPlate is one plate found on disk, along with the plan file and project
+// it lives in. The Plate carries its Needs, StartedAt, and EstimatedDuration
+// so callers can build plan verbs without re-reading YAML.
+type PlanPlate struct {
+	Plan    string // plan file basename
+	Project string
+	Plate   models.Plate
+}
+
 // LookupInProgressPlate finds the in-progress plate assigned to a given printer
 // by scanning plan files. Returns project name and plate name, or empty strings if not found.
 func LookupInProgressPlate(plansDir, printerName string) (projectName, plateName string) {
+	plates := FindInProgressPlates(plansDir, printerName)
+	if len(plates) == 0 {
+		return "", ""
+	}
+	return plates[0].Project, plates[0].Plate.Name
+}
+
+// FindInProgressPlates returns every in-progress plate assigned to the given
+// printer, in plan-file then plan order. More than one result means the user
+// started several plates at once on the same bed.
+func FindInProgressPlates(plansDir, printerName string) []PlanPlate {
+	return findPlates(plansDir, func(_ models.PlanFile, _ models.Project, plate models.Plate) bool {
+		return plate.Status == "in-progress" && plate.Printer == printerName
+	})
+}
+
+// FindTodoPlatesForJob returns the not-yet-started plates whose File matches
+// job, in plan-file then plan order, skipping plates still waiting on an
+// `after:` prerequisite. Several results are normal when a plan prints the
+// same sliced file more than once.
+func FindTodoPlatesForJob(plansDir, job string) []PlanPlate {
+	return findPlates(plansDir, func(pf models.PlanFile, proj models.Project, plate models.Plate) bool {
+		return proj.Status != "completed" && plate.Status == "todo" && plate.MatchesJob(job) &&
+			len(pf.Blockers(proj.Name, plate)) == 0
+	})
+}
+
+// findPlates scans the plan files in plansDir and returns the plates match
+// accepts. Unreadable or malformed files are skipped.
+func findPlates(plansDir string, match func(models.PlanFile, models.Project, models.Plate) bool) []PlanPlate {
 	entries, err := os.ReadDir(plansDir)
 	if err != nil {
-		return "", ""
+		return nil
 	}
 
+	var out []PlanPlate
 	for _, e := range entries {
 		if e.IsDir() {
 			continue
@@ -297,12 +337,12 @@ func LookupInProgressPlate(plansDir, printerName string) (projectName, plateName
 
 		for _, proj := range plan.Projects {
 			for _, plate := range proj.Plates {
-				if plate.Status == "in-progress" && plate.Printer == printerName {
-					return proj.Name, plate.Name
+				if match(plan, proj, plate) {
+					out = append(out, PlanPlate{Plan: e.Name(), Project: proj.Name, Plate: plate})
 				}
 			}
 		}
 	}
 
-	return "", ""
+	return out
 }
@@ -0,0 +1,135 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/dstockto/fil/plan"
+)
+
+// WeightSyncConfig mirrors cmd.WeightSyncConfig for use in the server package.
+type WeightSyncConfig struct {
+	Interval time.Duration
+	// Tolerance is how many grams Spoolman and the printer may disagree
+	// before a spool is flagged.
+	Tolerance float64
+	// Correct writes the printer's estimate to Spoolman for flagged spools
+	// whose drift is at most MaxCorrect; bigger drifts usually mean the
+	// wrong spool is recorded in the slot, so those are only flagged.
+	Correct    bool
+	MaxCorrect float64
+}
+
+// WeightSync periodically compares each idle printer's per-tray remaining
+// estimate (Bambu AMS "remain") with Spoolman's remaining weight for the
+// spool recorded in that slot.
+type WeightSync struct {
+	Config    WeightSyncConfig
+	Spoolman  plan.Spoolman
+	Orders    func(ctx context.Context) (map[string][]int, error)
+	Statuses  func() []PrinterState
+	Locations plan.PrinterLocations
+	// AmsIDs returns the AmsID behind each of a printer's locations (see
+	// LocationAmsIDs). Optional; without it a tray's AmsID is its location
+	// index.
+	AmsIDs   func(printer string) []int
+	Notifier *Notifier // optional
+
+	mu      sync.Mutex
+	flagged map[int]bool // spools already notified about, until they agree again
+}
+
+// Run checks once per Config.Interval until ctx is done.
+func (w *WeightSync) Run(ctx context.Context) {
+	ticker := time.NewTicker(w.Config.Interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if _, _, err := w.Check(ctx); err != nil {
+				fmt.Printf("[weight-sync] %v\n", err)
+			}
+		}
+	}
+}
+
+// Check runs one comparison. It returns the drifts beyond tolerance and,
+// of those, the ones it corrected in Spoolman. Printers mid-print are
+// skipped: their trays are ahead of the deduction that lands on completion.
+func (w *WeightSync) Check(ctx context.Context) (drifts, corrected []plan.WeightDrift, err error) {
+	orders, err := w.Orders(ctx)
+	if err != nil {
+		return nil, nil, fmt.Errorf("load location orders: %w", err)
+	}
+	spools, err := w.Spoolman.FindSpoolsByName(ctx, "*", nil, nil)
+	if err != nil {
+		return nil, nil, fmt.Errorf("list spools: %w", err)
+	}
+
+	statuses := w.Statuses()
+	sort.Slice(statuses, func(i, j int) bool { return statuses[i].Name < statuses[j].Name })
+	for _, st := range statuses {
+		if st.State == "printing" || st.State == "paused" || st.State == "offline" {
+			continue
+		}
+		var amsIDs []int
+		if w.AmsIDs != nil {
+			amsIDs = w.AmsIDs(st.Name)
+		}
+		var readings []plan.TrayReading
+		for _, t := range st.Trays {
+			if g, ok := t.EstimatedGrams(); ok {
+				readings = append(readings, plan.TrayReading{AmsID: LocationIndex(amsIDs, t.AmsID), TrayID: t.TrayID, Remain: t.Remain, Grams: g})
+			}
+		}
+		drifts = append(drifts, plan.CompareTrayWeights(st.Name, w.Locations.Locations(st.Name), orders, spools, readings, w.Config.Tolerance)...)
+	}
+
+	var errs []string
+	var fresh []plan.WeightDrift
+	w.mu.Lock()
+	seen := map[int]bool{}
+	for _, d := range drifts {
+		if w.Config.Correct && math.Abs(d.Diff()) <= w.Config.MaxCorrect {
+			if err := w.Spoolman.PatchSpool(ctx, d.Spool.Id, map[string]any{"remaining_weight": d.Estimate}); err != nil {
+				errs = append(errs, fmt.Sprintf("correct #%d: %v", d.Spool.Id, err))
+			} else {
+				corrected = append(corrected, d)
+				fmt.Printf("[weight-sync] %s %s:%d #%d %.1fg -> %.1fg (AMS %d%%)\n",
+					d.Printer, d.Location, d.Slot, d.Spool.Id, d.Spool.RemainingWeight, d.Estimate, d.Remain)
+				continue
+			}
+		}
+		seen[d.Spool.Id] = true
+		if !w.flagged[d.Spool.Id] {
+			fresh = append(fresh, d)
+		}
+	}
+	w.flagged = seen
+	w.mu.Unlock()
+
+	if len(fresh) > 0 {
+		var lines []string
+		for _, d := range fresh {
+			lines = append(lines, fmt.Sprintf("%s:%d #%d %s: Spoolman %.0fg, AMS %.0fg (%d%%)",
+				d.Location, d.Slot, d.Spool.Id, d.Spool.Filament.Name, d.Spool.RemainingWeight, d.Estimate, d.Remain))
+		}
+		msg := strings.Join(lines, "\n")
+		fmt.Printf("[weight-sync] drift:\n%s\n", msg)
+		if w.Notifier != nil && w.Notifier.Enabled() && !w.Notifier.IsQuietHours(time.Now()) {
+			w.Notifier.Send("Spool weight drift", msg)
+		}
+	}
+
+	if len(errs) > 0 {
+		return drifts, corrected, fmt.Errorf("%s", strings.Join(errs, "; "))
+	}
+	return drifts, corrected, nil
+}
@@ -0,0 +1,55 @@
+package server
+
+import (
+	"context"
+	"testing"
+
+	"github.com/dstockto/fil/plan"
+)
+
+func TestWeightSyncCheck(t *testing.T) {
+	sm := newFakeSpoolman(
+		makeSpool(10, "AMS A", 500, 1, "Black"), // AMS says 440g: corrected
+		makeSpool(11, "AMS A", 900, 2, "White"), // AMS says 300g: too far, flagged
+		makeSpool(12, "AMS B", 700, 3, "Red"),   // printer is printing: skipped
+	)
+	statuses := []PrinterState{
+		{Name: "X1C", State: "idle", Trays: []TrayInfo{
+			{AmsID: 0, TrayID: 0, Remain: 44, TrayWeight: 1000},
+			{AmsID: 0, TrayID: 1, Remain: 30, TrayWeight: 1000},
+			{AmsID: 0, TrayID: 2, Remain: -1, TrayWeight: 1000},
+		}},
+		{Name: "P1S", State: "printing", Trays: []TrayInfo{
+			{AmsID: 0, TrayID: 0, Remain: 10, TrayWeight: 1000},
+		}},
+	}
+	w := &WeightSync{
+		Config:   WeightSyncConfig{Tolerance: 25, Correct: true, MaxCorrect: 100},
+		Spoolman: sm,
+		Orders: func(context.Context) (map[string][]int, error) {
+			return map[string][]int{"AMS A": {10, 11, -1}, "AMS B": {12}}, nil
+		},
+		Statuses:  func() []PrinterState { return statuses },
+		Locations: plan.StaticPrinterLocations{"X1C": {"AMS A"}, "P1S": {"AMS B"}},
+	}
+
+	drifts, corrected, err := w.Check(context.Background())
+	if err != nil {
+		t.Fatalf("Check: %v", err)
+	}
+	if len(drifts) != 2 {
+		t.Fatalf("got %d drifts, want 2: %+v", len(drifts), drifts)
+	}
+	if len(corrected) != 1 || corrected[0].Spool.Id != 10 {
+		t.Fatalf("corrected = %+v, want spool 10 only", corrected)
+	}
+	if got := sm.patchCalls[10]["remaining_weight"]; got != 440.0 {
+		t.Errorf("spool 10 patched to %v, want 440", got)
+	}
+	if _, ok := sm.patchCalls[11]; ok {
+		t.Error("spool 11 drift exceeds max_correct and should not be patched")
+	}
+	if !w.flagged[11] || w.flagged[10] {
+		t.Errorf("flagged = %v, want only spool 11", w.flagged)
+	}
+}
@@ -0,0 +1,312 @@
+package slicer
+
+import (
+	"bufio"
+	"bytes"
+	"compress/flate"
+	"compress/zlib"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ErrNoGcodeStats is returned when a G-code file carries none of the slicer
+// comments ParseGcode understands.
+var ErrNoGcodeStats = errors.New("no slicer filament or time stats found in G-code")
+
+// gcodeScanWindow is how much of each end of a text G-code file is scanned.
+// Bambu Studio writes its stats in a header block; PrusaSlicer and
+// OrcaSlicer put theirs (and the config dump) at the end.
+const gcodeScanWindow = 1 << 20
+
+// ReadGcodeFile parses the slicer stats out of a .gcode or .bgcode file.
+// See ParseGcode.
+func ReadGcodeFile(path string) (SlicedPlate, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return SlicedPlate{}, err
+	}
+	defer func() { _ = f.Close() }()
+	info, err := f.Stat()
+	if err != nil {
+		return SlicedPlate{}, err
+	}
+	return ParseGcode(f, info.Size())
+}
+
+// ParseGcode reads the per-extruder filament usage, types, and colors plus
+// the estimated print time from the comments PrusaSlicer, OrcaSlicer, and
+// Bambu Studio write into G-code. Binary G-code (.bgcode) is detected by its
+// magic and read from its metadata blocks. Multi-extruder and MMU files yield
+// one SlicedFilament per extruder that used any filament.
+func ParseGcode(r io.ReaderAt, size int64) (SlicedPlate, error) {
+	magic := make([]byte, 4)
+	if _, err := r.ReadAt(magic, 0); err == nil && string(magic) == "GCDE" {
+		kv, err := readBgcodeMetadata(io.NewSectionReader(r, 0, size))
+		if err != nil {
+			return SlicedPlate{}, err
+		}
+		return gcodeStats(kv)
+	}
+
+	kv := map[string]string{}
+	head := min(size, gcodeScanWindow)
+	scanGcodeComments(io.NewSectionReader(r, 0, head), kv)
+	if size > head {
+		start := max(head, size-gcodeScanWindow)
+		scanGcodeComments(io.NewSectionReader(r, start, size-start), kv)
+	}
+	return gcodeStats(kv)
+}
+
+// scanGcodeComments collects "; key = value" and Bambu's "; key : value"
+// comment lines into kv. Bambu also packs two pairs onto its time line
+// ("; model printing time: 1h 2m; total estimated time: 1h 9m").
+func scanGcodeComments(r io.Reader, kv map[string]string) {
+	sc := bufio.NewScanner(r)
+	sc.Buffer(make([]byte, 64*1024), 1<<20)
+	for sc.Scan() {
+		line := strings.TrimSpace(sc.Text())
+		if !strings.HasPrefix(line, ";") {
+			continue
+		}
+		for _, part := range strings.Split(strings.TrimPrefix(line, ";"), "; ") {
+			addGcodePair(kv, part)
+		}
+	}
+}
+
+func addGcodePair(kv map[string]string, s string) {
+	s = strings.TrimSpace(s)
+	i := strings.Index(s, "=")
+	if j := strings.Index(s, ":"); i < 0 || (j >= 0 && j < i) {
+		i = j
+	}
+	if i <= 0 {
+		return
+	}
+	key := strings.TrimSpace(s[:i])
+	if _, seen := kv[key]; !seen {
+		kv[key] = strings.TrimSpace(s[i+1:])
+	}
+}
+
+// gcodeStats turns collected key/values into a SlicedPlate.
+func gcodeStats(kv map[string]string) (SlicedPlate, error) {
+	var plate SlicedPlate
+
+	for _, key := range []string{
+		"estimated printing time (normal mode)", // PrusaSlicer, OrcaSlicer
+		"total estimated time",                  // Bambu Studio
+		"estimated printing time",
+	} {
+		if d, ok := parseSlicerDuration(kv[key]); ok {
+			plate.Duration = d
+			break
+		}
+	}
+
+	var grams []float64
+	for _, key := range []string{
+		"filament used [g]",         // PrusaSlicer, OrcaSlicer: per extruder
+		"total filament weight [g]", // Bambu Studio: per filament slot
+		"total filament used [g]",   // single total
+	} {
+		if v, ok := kv[key]; ok {
+			grams = splitFloats(v)
+			if len(grams) > 0 {
+				break
+			}
+		}
+	}
+	types := splitList(kv["filament_type"])
+	colors := splitList(kv["filament_colour"])
+	if len(colors) == 0 {
+		colors = splitList(kv["extruder_colour"])
+	}
+
+	for i, g := range grams {
+		if g <= 0 {
+			continue
+		}
+		f := SlicedFilament{Slot: i + 1, Grams: g}
+		if i < len(types) {
+			f.Type = types[i]
+		}
+		if i < len(colors) {
+			f.Color = normalizeColor(colors[i])
+		}
+		plate.Filaments = append(plate.Filaments, f)
+	}
+
+	if plate.Duration == 0 && len(plate.Filaments) == 0 {
+		return SlicedPlate{}, ErrNoGcodeStats
+	}
+	return plate, nil
+}
+
+// parseSlicerDuration reads the "1d 2h 3m 4s" form slicers print.
+func parseSlicerDuration(s string) (time.Duration, bool) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return 0, false
+	}
+	var total time.Duration
+	for _, field := range strings.Fields(s) {
+		if len(field) < 2 {
+			return 0, false
+		}
+		n, err := strconv.Atoi(field[:len(field)-1])
+		if err != nil {
+			return 0, false
+		}
+		switch field[len(field)-1] {
+		case 'd':
+			total += time.Duration(n) * 24 * time.Hour
+		case 'h':
+			total += time.Duration(n) * time.Hour
+		case 'm':
+			total += time.Duration(n) * time.Minute
+		case 's':
+			total += time.Duration(n) * time.Second
+		default:
+			return 0, false
+		}
+	}
+	return total, total > 0
+}
+
+func splitFloats(s string) []float64 {
+	var out []float64
+	for _, f := range strings.Split(s, ",") {
+		v, err := strconv.ParseFloat(strings.TrimSpace(f), 64)
+		if err != nil {
+			return nil
+		}
+		out = append(out, v)
+	}
+	return out
+}
+
+// splitList splits the ";"-separated per-extruder config values, dropping
+// the quotes PrusaSlicer puts around some of them.
+func splitList(s string) []string {
+	if strings.TrimSpace(s) == "" {
+		return nil
+	}
+	parts := strings.Split(s, ";")
+	for i, p := range parts {
+		parts[i] = strings.Trim(strings.TrimSpace(p), `"`)
+	}
+	return parts
+}
+
+// Binary G-code block types and compression schemes (libbgcode).
+const (
+	bgcodeBlockGcode     = 1
+	bgcodeBlockThumbnail = 5
+
+	bgcodeCompressNone    = 0
+	bgcodeCompressDeflate = 1
+)
+
+// readBgcodeMetadata collects the INI-style key/values from every metadata
+// block of a Prusa binary G-code file, stopping at the first G-code block —
+// metadata always precedes it.
+func readBgcodeMetadata(r io.ReadSeeker) (map[string]string, error) {
+	var hdr struct {
+		Magic    [4]byte
+		Version  uint32
+		Checksum uint16
+	}
+	if err := binary.Read(r, binary.LittleEndian, &hdr); err != nil {
+		return nil, fmt.Errorf("read bgcode header: %w", err)
+	}
+	checksumSize := int64(0)
+	if hdr.Checksum == 1 {
+		checksumSize = 4 // CRC32
+	}
+
+	kv := map[string]string{}
+	for {
+		var bh struct {
+			Type             uint16
+			Compression      uint16
+			UncompressedSize uint32
+		}
+		if err := binary.Read(r, binary.LittleEndian, &bh); err != nil {
+			if errors.Is(err, io.EOF) {
+				break
+			}
+			return nil, fmt.Errorf("read bgcode block: %w", err)
+		}
+		if bh.Type == bgcodeBlockGcode {
+			break
+		}
+		dataSize := int64(bh.UncompressedSize)
+		if bh.Compression != bgcodeCompressNone {
+			var compressed uint32
+			if err := binary.Read(r, binary.LittleEndian, &compressed); err != nil {
+				return nil, fmt.Errorf("read bgcode block: %w", err)
+			}
+			dataSize = int64(compressed)
+		}
+		paramsSize := int64(2) // encoding
+		if bh.Type == bgcodeBlockThumbnail {
+			paramsSize = 6 // format, width, height
+		}
+		if _, err := r.Seek(paramsSize, io.SeekCurrent); err != nil {
+			return nil, err
+		}
+		if dataSize > 64<<20 {
+			return nil, fmt.Errorf("bgcode block of %d bytes looks corrupt", dataSize)
+		}
+		data := make([]byte, dataSize)
+		if _, err := io.ReadFull(r, data); err != nil {
+			return nil, fmt.Errorf("read bgcode block: %w", err)
+		}
+		if _, err := r.Seek(checksumSize, io.SeekCurrent); err != nil {
+			return nil, err
+		}
+		if bh.Type == bgcodeBlockThumbnail {
+			continue
+		}
+
+		switch bh.Compression {
+		case bgcodeCompressNone:
+		case bgcodeCompressDeflate:
+			inflated, err := inflate(data)
+			if err != nil {
+				continue
+			}
+			data = inflated
+		default:
+			continue // heatshrink is only used for G-code blocks in practice
+		}
+		sc := bufio.NewScanner(bytes.NewReader(data))
+		sc.Buffer(make([]byte, 64*1024), 1<<20)
+		for sc.Scan() {
+			addGcodePair(kv, sc.Text())
+		}
+	}
+	return kv, nil
+}
+
+// inflate decompresses a deflate-compressed bgcode block. libbgcode writes a
+// zlib stream; raw deflate is accepted too.
+func inflate(data []byte) ([]byte, error) {
+	if zr, err := zlib.NewReader(bytes.NewReader(data)); err == nil {
+		defer func() { _ = zr.Close() }()
+		if out, err := io.ReadAll(zr); err == nil {
+			return out, nil
+		}
+	}
+	fr := flate.NewReader(bytes.NewReader(data))
+	defer func() { _ = fr.Close() }()
+	return io.ReadAll(fr)
+}
@@ -0,0 +1,153 @@
+package slicer
+
+import (
+	"bytes"
+	"compress/zlib"
+	"encoding/binary"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func parseGcodeString(t *testing.T, s string) SlicedPlate {
+	t.Helper()
+	plate, err := ParseGcode(strings.NewReader(s), int64(len(s)))
+	if err != nil {
+		t.Fatalf("ParseGcode: %v", err)
+	}
+	return plate
+}
+
+// PrusaSlicer writes its stats and config dump after the G-code body.
+func TestParseGcodePrusaSlicerMMU(t *testing.T) {
+	gcode := `; generated by PrusaSlicer 2.7.1+linux-x64 on 2026-05-01 at 10:00:00 UTC
+G28 ; home
+G1 X10 Y10
+; filament used [mm] = 1234.56, 0.00, 98.7
+; filament used [g] = 3.71, 0.00, 0.30
+; total filament used [g] = 4.01
+; estimated printing time (normal mode) = 1h 2m 3s
+; prusaslicer_config = begin
+; extruder_colour = "";"";""
+; filament_colour = #FF8000;#FFFFFF;#000000
+; filament_type = PETG;PLA;PLA
+; prusaslicer_config = end
+`
+	plate := parseGcodeString(t, gcode)
+	if plate.Duration != time.Hour+2*time.Minute+3*time.Second {
+		t.Errorf("Duration = %v", plate.Duration)
+	}
+	if len(plate.Filaments) != 2 {
+		t.Fatalf("filaments = %+v, want the two extruders that printed", plate.Filaments)
+	}
+	if f := plate.Filaments[0]; f.Slot != 1 || f.Type != "PETG" || f.Color != "FF8000" || f.Grams != 3.71 {
+		t.Errorf("extruder 1 = %+v", f)
+	}
+	if f := plate.Filaments[1]; f.Slot != 3 || f.Type != "PLA" || f.Color != "000000" || f.Grams != 0.30 {
+		t.Errorf("extruder 3 = %+v", f)
+	}
+}
+
+func TestParseGcodeBambuHeader(t *testing.T) {
+	gcode := `; HEADER_BLOCK_START
+; BambuStudio 01.09.07.52
+; model printing time: 1h 1m 20s; total estimated time: 1h 8m 2s
+; total layer number: 120
+; total filament length [mm] : 1234.56,23.10
+; total filament weight [g] : 36.80,0.69
+; filament_density: 1.24,1.24
+; HEADER_BLOCK_END
+
+; CONFIG_BLOCK_START
+; filament_colour = #FFFFFF;#F72323
+; filament_type = PLA;PLA
+; CONFIG_BLOCK_END
+G28
+`
+	plate := parseGcodeString(t, gcode)
+	if plate.Duration != time.Hour+8*time.Minute+2*time.Second {
+		t.Errorf("Duration = %v, want the total estimate", plate.Duration)
+	}
+	if len(plate.Filaments) != 2 || plate.Filaments[1].Color != "F72323" || plate.Filaments[1].Grams != 0.69 {
+		t.Errorf("filaments = %+v", plate.Filaments)
+	}
+}
+
+// Large files are only read at both ends; stats in the tail are still found.
+func TestParseGcodeScansTail(t *testing.T) {
+	var b strings.Builder
+	b.WriteString("; generated by OrcaSlicer 2.1.1\n")
+	body := strings.Repeat("G1 X1 Y1 E0.1\n", (2*gcodeScanWindow)/14+1)
+	b.WriteString(body)
+	b.WriteString("; filament used [g] = 12.5\n; filament_type = ASA\n; filament_colour = #101010\n")
+	b.WriteString("; estimated printing time (normal mode) = 45m 10s\n")
+	plate := parseGcodeString(t, b.String())
+	if plate.Duration != 45*time.Minute+10*time.Second || len(plate.Filaments) != 1 || plate.Filaments[0].Type != "ASA" {
+		t.Errorf("plate = %+v", plate)
+	}
+}
+
+func TestParseGcodeNoStats(t *testing.T) {
+	s := "G28\nG1 X10\n"
+	if _, err := ParseGcode(strings.NewReader(s), int64(len(s))); !errors.Is(err, ErrNoGcodeStats) {
+		t.Errorf("err = %v, want ErrNoGcodeStats", err)
+	}
+}
+
+// buildBgcode assembles a minimal binary G-code file: an uncompressed print
+// metadata block, a deflated slicer metadata block, and a G-code block that
+// parsing must stop at.
+func buildBgcode(t *testing.T) []byte {
+	t.Helper()
+	var out bytes.Buffer
+	w := func(v any) {
+		if err := binary.Write(&out, binary.LittleEndian, v); err != nil {
+			t.Fatal(err)
+		}
+	}
+	out.WriteString("GCDE")
+	w(uint32(1)) // version
+	w(uint16(1)) // CRC32 checksums
+
+	meta := []byte("filament used [g]=10.20,2.00\nestimated printing time (normal mode)=2h 5m 0s\n")
+	w(uint16(4)) // print metadata
+	w(uint16(bgcodeCompressNone))
+	w(uint32(len(meta)))
+	w(uint16(0)) // INI encoding
+	out.Write(meta)
+	w(uint32(0)) // checksum (not verified)
+
+	var z bytes.Buffer
+	zw := zlib.NewWriter(&z)
+	_, _ = zw.Write([]byte("filament_type=PLA;PETG\nfilament_colour=#00FF00;#0000FF\n"))
+	_ = zw.Close()
+	w(uint16(2)) // slicer metadata
+	w(uint16(bgcodeCompressDeflate))
+	w(uint32(100)) // uncompressed size (unused)
+	w(uint32(z.Len()))
+	w(uint16(0))
+	out.Write(z.Bytes())
+	w(uint32(0))
+
+	w(uint16(bgcodeBlockGcode))
+	w(uint16(3)) // heatshrink; never read
+	w(uint32(1000))
+	w(uint32(4))
+	out.Write([]byte{0, 0, 0, 0, 0, 0})
+	return out.Bytes()
+}
+
+func TestParseGcodeBinary(t *testing.T) {
+	data := buildBgcode(t)
+	plate, err := ParseGcode(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		t.Fatalf("ParseGcode: %v", err)
+	}
+	if plate.Duration != 2*time.Hour+5*time.Minute {
+		t.Errorf("Duration = %v", plate.Duration)
+	}
+	if len(plate.Filaments) != 2 || plate.Filaments[1].Type != "PETG" || plate.Filaments[1].Color != "0000FF" || plate.Filaments[0].Grams != 10.2 {
+		t.Errorf("filaments = %+v", plate.Filaments)
+	}
+}
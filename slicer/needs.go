@@ -0,0 +1,83 @@
+package slicer
+
+import (
+	"math"
+	"strings"
+
+	"github.com/dstockto/fil/models"
+)
+
+// ApplyAmounts writes the grams of each sliced filament onto the plate need
+// it corresponds to, replacing the need's Amount. A filament goes to the
+// need with the same material and color; failing that, to the only unclaimed
+// need with the same color, or the only unclaimed need with the same
+// material; and when a single filament and a single need are left over, they
+// are paired. Several filaments landing on one need (the same spool color
+// loaded in two extruders) are summed. Returns the indexes of the needs that
+// were set and the filaments nothing matched.
+func ApplyAmounts(needs []models.PlateRequirement, filaments []SlicedFilament) ([]int, []SlicedFilament) {
+	totals := map[int]float64{}
+	var pending []SlicedFilament
+
+	for _, f := range filaments {
+		idx := -1
+		for i, n := range needs {
+			if sameMaterial(n.Material, f.Type) && sameColor(n.Color, f.Color) {
+				idx = i
+				break
+			}
+		}
+		if idx < 0 {
+			pending = append(pending, f)
+			continue
+		}
+		totals[idx] += f.Grams
+	}
+
+	claim := func(match func(models.PlateRequirement, SlicedFilament) bool) {
+		var rest []SlicedFilament
+		for _, f := range pending {
+			idx := -1
+			for i, n := range needs {
+				if _, taken := totals[i]; taken || !match(n, f) {
+					continue
+				}
+				if idx >= 0 {
+					idx = -2 // ambiguous
+					break
+				}
+				idx = i
+			}
+			if idx < 0 {
+				rest = append(rest, f)
+				continue
+			}
+			totals[idx] += f.Grams
+		}
+		pending = rest
+	}
+	claim(func(n models.PlateRequirement, f SlicedFilament) bool { return sameColor(n.Color, f.Color) })
+	claim(func(n models.PlateRequirement, f SlicedFilament) bool { return sameMaterial(n.Material, f.Type) })
+	if len(pending) == 1 && len(needs)-len(totals) == 1 {
+		claim(func(models.PlateRequirement, SlicedFilament) bool { return true })
+	}
+
+	var set []int
+	for i := range needs {
+		if g, ok := totals[i]; ok {
+			needs[i].Amount = math.Round(g*10) / 10
+			set = append(set, i)
+		}
+	}
+	return set, pending
+}
+
+func sameMaterial(a, b string) bool {
+	return a != "" && strings.EqualFold(strings.TrimSpace(a), strings.TrimSpace(b))
+}
+
+func sameColor(a, b string) bool {
+	a = strings.TrimPrefix(strings.TrimSpace(a), "#")
+	b = strings.TrimPrefix(strings.TrimSpace(b), "#")
+	return a != "" && strings.EqualFold(a, b)
+}
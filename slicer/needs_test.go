@@ -0,0 +1,61 @@
+package slicer
+
+import (
+	"testing"
+
+	"github.com/dstockto/fil/models"
+)
+
+func TestApplyAmounts(t *testing.T) {
+	needs := []models.PlateRequirement{
+		{Name: "Jade White", Material: "PLA", Color: "ffffff"},
+		{Name: "Red", Material: "PLA", Color: "F72323"},
+		{Name: "Black", Material: "PETG"}, // no color: claimed by material
+	}
+	filaments := []SlicedFilament{
+		{Slot: 1, Type: "PLA", Color: "FFFFFF", Grams: 30.04},
+		{Slot: 2, Type: "PLA", Color: "FFFFFF", Grams: 5}, // same spool color in a second extruder
+		{Slot: 3, Type: "PLA+", Color: "F72323", Grams: 2.26},
+		{Slot: 4, Type: "PETG", Color: "000000", Grams: 8},
+		{Slot: 5, Type: "TPU", Color: "00FF00", Grams: 1},
+	}
+
+	set, unmatched := ApplyAmounts(needs, filaments)
+	if len(set) != 3 {
+		t.Errorf("set = %v, want all three needs", set)
+	}
+	want := []float64{35, 2.3, 8}
+	for i, w := range want {
+		if needs[i].Amount != w {
+			t.Errorf("need %d amount = %v, want %v", i, needs[i].Amount, w)
+		}
+	}
+	if len(unmatched) != 1 || unmatched[0].Slot != 5 {
+		t.Errorf("unmatched = %+v, want the TPU extruder", unmatched)
+	}
+}
+
+// With one need and one filament left, they're paired even when neither
+// color nor material agrees — the common single-color plate whose need was
+// typed in by hand.
+func TestApplyAmountsSingleLeftover(t *testing.T) {
+	needs := []models.PlateRequirement{{Name: "galaxy black", Material: "PLA", Amount: 100}}
+	set, unmatched := ApplyAmounts(needs, []SlicedFilament{{Slot: 1, Type: "PLA Silk", Color: "222222", Grams: 42}})
+	if len(set) != 1 || len(unmatched) != 0 || needs[0].Amount != 42 {
+		t.Errorf("set=%v unmatched=%+v needs=%+v", set, unmatched, needs)
+	}
+}
+
+func TestApplyAmountsAmbiguousMaterial(t *testing.T) {
+	needs := []models.PlateRequirement{
+		{Name: "white", Material: "PLA"},
+		{Name: "black", Material: "PLA"},
+	}
+	set, unmatched := ApplyAmounts(needs, []SlicedFilament{
+		{Slot: 1, Type: "PLA", Grams: 10},
+		{Slot: 2, Type: "PLA", Grams: 4},
+	})
+	if len(set) != 0 || len(unmatched) != 2 {
+		t.Errorf("set=%v unmatched=%+v; want nothing guessed", set, unmatched)
+	}
+}
@@ -0,0 +1,294 @@
+// Package slicer reads print metadata out of sliced files — Bambu Studio /
+// OrcaSlicer 3MF projects — and turns it into plan Plates.
+package slicer
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/dstockto/fil/models"
+)
+
+// Paths inside a Bambu/Orca 3MF archive. slice_info.config only exists once
+// the project has been sliced; the other two are optional extras.
+const (
+	sliceInfoPath       = "Metadata/slice_info.config"
+	modelSettingsPath   = "Metadata/model_settings.config"
+	projectSettingsPath = "Metadata/project_settings.config"
+)
+
+// ErrNotSliced is returned for 3MF files that carry no slice results — a
+// plain model export, or a project saved before slicing.
+var ErrNotSliced = errors.New("3mf has no slice info; slice the project in Bambu Studio or OrcaSlicer and save it first")
+
+// SlicedPlate is one sliced build plate from a project file.
+type SlicedPlate struct {
+	Index     int // 1-based plate number in the slicer
+	Name      string
+	Duration  time.Duration
+	Filaments []SlicedFilament
+}
+
+// SlicedFilament is one filament a plate uses.
+type SlicedFilament struct {
+	Slot    int     // 1-based filament index in the project
+	Type    string  // material, e.g. "PLA", "PETG"
+	Color   string  // RRGGBB, no leading '#'
+	Grams   float64 // slicer's usage estimate
+	Profile string  // filament preset name with the printer suffix removed, e.g. "Bambu PLA Basic"
+}
+
+type sliceInfo struct {
+	Plates []struct {
+		Metadata  []xmlMetadata `xml:"metadata"`
+		Filaments []struct {
+			ID    string `xml:"id,attr"`
+			Type  string `xml:"type,attr"`
+			Color string `xml:"color,attr"`
+			UsedG string `xml:"used_g,attr"`
+		} `xml:"filament"`
+	} `xml:"plate"`
+}
+
+type modelSettings struct {
+	Plates []struct {
+		Metadata []xmlMetadata `xml:"metadata"`
+	} `xml:"plate"`
+}
+
+type xmlMetadata struct {
+	Key   string `xml:"key,attr"`
+	Value string `xml:"value,attr"`
+}
+
+func metadataValue(md []xmlMetadata, key string) string {
+	for _, m := range md {
+		if m.Key == key {
+			return m.Value
+		}
+	}
+	return ""
+}
+
+// ParseThreeMF reads the sliced plates out of a Bambu Studio or OrcaSlicer
+// 3MF. Plates that were never sliced (no filament usage) are skipped.
+func ParseThreeMF(data []byte) ([]SlicedPlate, error) {
+	zr, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return nil, fmt.Errorf("read 3mf: %w", err)
+	}
+
+	raw, err := readZipFile(zr, sliceInfoPath)
+	if err != nil {
+		return nil, err
+	}
+	if raw == nil {
+		return nil, ErrNotSliced
+	}
+	var info sliceInfo
+	if err := xml.Unmarshal(raw, &info); err != nil {
+		return nil, fmt.Errorf("parse %s: %w", sliceInfoPath, err)
+	}
+
+	plateNames := map[int]string{}
+	if raw, err := readZipFile(zr, modelSettingsPath); err == nil && raw != nil {
+		var ms modelSettings
+		if xml.Unmarshal(raw, &ms) == nil {
+			for _, p := range ms.Plates {
+				id, _ := strconv.Atoi(metadataValue(p.Metadata, "plater_id"))
+				if name := strings.TrimSpace(metadataValue(p.Metadata, "plater_name")); id > 0 && name != "" {
+					plateNames[id] = name
+				}
+			}
+		}
+	}
+
+	var profiles []string
+	if raw, err := readZipFile(zr, projectSettingsPath); err == nil && raw != nil {
+		var ps struct {
+			FilamentSettingsID []string `json:"filament_settings_id"`
+		}
+		if json.Unmarshal(raw, &ps) == nil {
+			profiles = ps.FilamentSettingsID
+		}
+	}
+
+	var out []SlicedPlate
+	for i, p := range info.Plates {
+		idx, _ := strconv.Atoi(metadataValue(p.Metadata, "index"))
+		if idx <= 0 {
+			idx = i + 1
+		}
+		plate := SlicedPlate{Index: idx, Name: plateNames[idx]}
+		if plate.Name == "" {
+			plate.Name = fmt.Sprintf("Plate %d", idx)
+		}
+		if secs, err := strconv.ParseFloat(metadataValue(p.Metadata, "prediction"), 64); err == nil && secs > 0 {
+			plate.Duration = time.Duration(secs) * time.Second
+		}
+		for _, f := range p.Filaments {
+			grams, _ := strconv.ParseFloat(f.UsedG, 64)
+			if grams <= 0 {
+				continue
+			}
+			slot, _ := strconv.Atoi(f.ID)
+			sf := SlicedFilament{
+				Slot:  slot,
+				Type:  strings.TrimSpace(f.Type),
+				Color: normalizeColor(f.Color),
+				Grams: grams,
+			}
+			if slot > 0 && slot <= len(profiles) {
+				sf.Profile = profileName(profiles[slot-1])
+			}
+			plate.Filaments = append(plate.Filaments, sf)
+		}
+		if len(plate.Filaments) == 0 {
+			continue
+		}
+		out = append(out, plate)
+	}
+	if len(out) == 0 {
+		return nil, ErrNotSliced
+	}
+	return out, nil
+}
+
+// readZipFile returns the named entry's contents, or nil when the archive
+// doesn't contain it.
+func readZipFile(zr *zip.Reader, name string) ([]byte, error) {
+	for _, f := range zr.File {
+		if f.Name != name {
+			continue
+		}
+		rc, err := f.Open()
+		if err != nil {
+			return nil, fmt.Errorf("open %s: %w", name, err)
+		}
+		defer func() { _ = rc.Close() }()
+		data, err := io.ReadAll(rc)
+		if err != nil {
+			return nil, fmt.Errorf("read %s: %w", name, err)
+		}
+		return data, nil
+	}
+	return nil, nil
+}
+
+// normalizeColor turns the slicer's "#RRGGBB" or "#RRGGBBAA" into the bare
+// RRGGBB form plan needs and Spoolman use.
+func normalizeColor(c string) string {
+	c = strings.ToUpper(strings.TrimPrefix(strings.TrimSpace(c), "#"))
+	if len(c) == 8 {
+		c = c[:6]
+	}
+	if len(c) != 6 {
+		return ""
+	}
+	return c
+}
+
+// profileName strips the printer suffix Bambu Studio appends to filament
+// presets ("Bambu PLA Basic @BBL X1C" -> "Bambu PLA Basic").
+func profileName(preset string) string {
+	if i := strings.Index(preset, " @"); i >= 0 {
+		preset = preset[:i]
+	}
+	return strings.TrimSpace(preset)
+}
+
+// Plates converts sliced plates into plan Plates: one Need per filament with
+// the slicer's grams, material, and color, and EstimatedDuration from the
+// slicer's prediction. file becomes each plate's File so auto-start can match
+// the job when the printer picks it up.
+func Plates(sliced []SlicedPlate, file string) []models.Plate {
+	plates := make([]models.Plate, 0, len(sliced))
+	for _, sp := range sliced {
+		plate := models.Plate{
+			Name:   sp.Name,
+			Status: "todo",
+			File:   file,
+		}
+		if sp.Duration > 0 {
+			plate.EstimatedDuration = FormatDuration(sp.Duration)
+		}
+		for _, f := range sp.Filaments {
+			name := f.Profile
+			if name == "" {
+				name = f.Type
+			}
+			plate.Needs = append(plate.Needs, models.PlateRequirement{
+				Name:     name,
+				Material: f.Type,
+				Color:    f.Color,
+				Amount:   math.Round(f.Grams*10) / 10,
+			})
+		}
+		plates = append(plates, plate)
+	}
+	return plates
+}
+
+// FormatDuration renders d rounded to the minute in the form plan files use
+// for estimated_duration, e.g. "6h25m" or "45m".
+func FormatDuration(d time.Duration) string {
+	d = d.Round(time.Minute)
+	if d < time.Minute {
+		d = time.Minute
+	}
+	s := d.String()
+	s = strings.TrimSuffix(s, "0s")
+	if strings.HasSuffix(s, "h0m") {
+		s = strings.TrimSuffix(s, "0m")
+	}
+	return s
+}
+
+// MatchFilaments links needs to Spoolman filaments when the material and
+// color identify exactly one filament among spools, filling FilamentID, Name,
+// and Material from Spoolman. Needs that match nothing or several filaments
+// are left for interactive resolve. Returns how many needs were linked.
+func MatchFilaments(plates []models.Plate, spools []models.FindSpool) int {
+	type key struct{ material, color string }
+	byKey := map[key]map[int]models.FindSpool{}
+	for _, s := range spools {
+		k := key{strings.ToLower(s.Filament.Material), strings.ToUpper(strings.TrimPrefix(s.Filament.ColorHex, "#"))}
+		if k.material == "" || k.color == "" {
+			continue
+		}
+		if byKey[k] == nil {
+			byKey[k] = map[int]models.FindSpool{}
+		}
+		byKey[k][s.Filament.Id] = s
+	}
+
+	linked := 0
+	for i := range plates {
+		for j := range plates[i].Needs {
+			need := &plates[i].Needs[j]
+			if need.FilamentID != 0 || need.Color == "" {
+				continue
+			}
+			candidates := byKey[key{strings.ToLower(need.Material), strings.ToUpper(need.Color)}]
+			if len(candidates) != 1 {
+				continue
+			}
+			for id, s := range candidates {
+				need.FilamentID = id
+				need.Name = s.Filament.Name
+				need.Material = s.Filament.Material
+			}
+			linked++
+		}
+	}
+	return linked
+}
@@ -0,0 +1,182 @@
+package slicer
+
+import (
+	"archive/zip"
+	"bytes"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/dstockto/fil/models"
+)
+
+const testSliceInfo = `<?xml version="1.0" encoding="UTF-8"?>
+<config>
+  <header>
+    <header_item key="X-BBL-Client-Type" value="slicer"/>
+  </header>
+  <plate>
+    <metadata key="index" value="1"/>
+    <metadata key="prediction" value="5586"/>
+    <metadata key="weight" value="41.20"/>
+    <filament id="1" tray_info_idx="GFA00" type="PLA" color="#FFFFFF" used_m="12.73" used_g="38.03"/>
+    <filament id="3" tray_info_idx="GFA00" type="PLA" color="#F72323FF" used_m="1.06" used_g="3.171"/>
+  </plate>
+  <plate>
+    <metadata key="index" value="2"/>
+    <metadata key="prediction" value="7200"/>
+    <filament id="2" tray_info_idx="GFG00" type="PETG" color="#000000" used_m="5" used_g="15"/>
+  </plate>
+  <plate>
+    <metadata key="index" value="3"/>
+  </plate>
+</config>`
+
+const testModelSettings = `<?xml version="1.0" encoding="UTF-8"?>
+<config>
+  <plate>
+    <metadata key="plater_id" value="1"/>
+    <metadata key="plater_name" value="Body"/>
+  </plate>
+  <plate>
+    <metadata key="plater_id" value="2"/>
+    <metadata key="plater_name" value=""/>
+  </plate>
+</config>`
+
+const testProjectSettings = `{"filament_settings_id": ["Bambu PLA Basic @BBL X1C", "Generic PETG @BBL X1C", "Bambu PLA Basic @BBL X1C"]}`
+
+func buildThreeMF(t *testing.T, files map[string]string) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for name, body := range files {
+		f, err := zw.Create(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := f.Write([]byte(body)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestParseThreeMF(t *testing.T) {
+	data := buildThreeMF(t, map[string]string{
+		sliceInfoPath:       testSliceInfo,
+		modelSettingsPath:   testModelSettings,
+		projectSettingsPath: testProjectSettings,
+		"3D/3dmodel.model":  "<model/>",
+	})
+	plates, err := ParseThreeMF(data)
+	if err != nil {
+		t.Fatalf("ParseThreeMF: %v", err)
+	}
+	if len(plates) != 2 {
+		t.Fatalf("got %d plates, want 2 (unsliced plate 3 skipped): %+v", len(plates), plates)
+	}
+
+	p1 := plates[0]
+	if p1.Name != "Body" || p1.Duration != 5586*time.Second || len(p1.Filaments) != 2 {
+		t.Errorf("plate 1 = %+v", p1)
+	}
+	red := p1.Filaments[1]
+	if red.Slot != 3 || red.Color != "F72323" || red.Profile != "Bambu PLA Basic" || red.Grams != 3.171 {
+		t.Errorf("plate 1 filament 2 = %+v", red)
+	}
+	if plates[1].Name != "Plate 2" || plates[1].Filaments[0].Profile != "Generic PETG" {
+		t.Errorf("plate 2 = %+v", plates[1])
+	}
+}
+
+func TestParseThreeMFNotSliced(t *testing.T) {
+	data := buildThreeMF(t, map[string]string{"3D/3dmodel.model": "<model/>"})
+	if _, err := ParseThreeMF(data); !errors.Is(err, ErrNotSliced) {
+		t.Errorf("err = %v, want ErrNotSliced", err)
+	}
+	if _, err := ParseThreeMF([]byte("not a zip")); err == nil {
+		t.Error("expected an error for a non-zip file")
+	}
+}
+
+func TestPlates(t *testing.T) {
+	plates := Plates([]SlicedPlate{{
+		Index:    1,
+		Name:     "Body",
+		Duration: 5586 * time.Second,
+		Filaments: []SlicedFilament{
+			{Slot: 1, Type: "PLA", Color: "FFFFFF", Grams: 38.034, Profile: "Bambu PLA Basic"},
+			{Slot: 2, Type: "PETG", Color: "000000", Grams: 2},
+		},
+	}}, "widget")
+	if len(plates) != 1 {
+		t.Fatalf("got %d plates", len(plates))
+	}
+	p := plates[0]
+	if p.Status != "todo" || p.File != "widget" || p.EstimatedDuration != "1h33m" {
+		t.Errorf("plate = %+v", p)
+	}
+	want := []models.PlateRequirement{
+		{Name: "Bambu PLA Basic", Material: "PLA", Color: "FFFFFF", Amount: 38},
+		{Name: "PETG", Material: "PETG", Color: "000000", Amount: 2},
+	}
+	for i, n := range want {
+		if !reflect.DeepEqual(p.Needs[i], n) {
+			t.Errorf("need %d = %+v, want %+v", i, p.Needs[i], n)
+		}
+	}
+}
+
+func TestFormatDuration(t *testing.T) {
+	tests := map[time.Duration]string{
+		5586 * time.Second:           "1h33m",
+		2 * time.Hour:                "2h",
+		45 * time.Minute:             "45m",
+		10 * time.Second:             "1m",
+		25*time.Hour + 5*time.Minute: "25h5m",
+	}
+	for d, want := range tests {
+		if got := FormatDuration(d); got != want {
+			t.Errorf("FormatDuration(%v) = %q, want %q", d, got, want)
+		}
+	}
+}
+
+func TestMatchFilaments(t *testing.T) {
+	spool := func(id, filamentID int, name, material, color string) models.FindSpool {
+		var s models.FindSpool
+		s.Id = id
+		s.Filament.Id = filamentID
+		s.Filament.Name = name
+		s.Filament.Material = material
+		s.Filament.ColorHex = color
+		return s
+	}
+	spools := []models.FindSpool{
+		spool(1, 10, "Jade White", "PLA", "ffffff"),
+		spool(2, 10, "Jade White", "PLA", "ffffff"), // second spool, same filament
+		spool(3, 20, "Black", "PLA", "000000"),
+		spool(4, 21, "Charcoal", "PLA", "000000"), // two filaments share black
+	}
+	plates := []models.Plate{{Needs: []models.PlateRequirement{
+		{Name: "Bambu PLA Basic", Material: "PLA", Color: "FFFFFF", Amount: 10},
+		{Name: "Bambu PLA Basic", Material: "PLA", Color: "000000", Amount: 5},
+		{Name: "PETG", Material: "PETG", Color: "FFFFFF", Amount: 1},
+	}}}
+
+	if n := MatchFilaments(plates, spools); n != 1 {
+		t.Errorf("linked %d, want 1", n)
+	}
+	needs := plates[0].Needs
+	if needs[0].FilamentID != 10 || needs[0].Name != "Jade White" {
+		t.Errorf("white need = %+v", needs[0])
+	}
+	if needs[1].FilamentID != 0 || needs[2].FilamentID != 0 {
+		t.Errorf("ambiguous/unknown needs linked: %+v", needs[1:])
+	}
+}